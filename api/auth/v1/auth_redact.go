@@ -0,0 +1,38 @@
+package v1
+
+import "fmt"
+
+// The kratos logging middleware prints requests through Redact when it is
+// available, which keeps passwords and tokens out of the logs.
+
+func (x *LoginRequest) Redact() string {
+	switch k := x.GetIdentifier().(type) {
+	case *LoginRequest_Username:
+		return fmt.Sprintf("username:%q password:<redacted>", k.Username)
+	case *LoginRequest_Email:
+		return fmt.Sprintf("email:%q password:<redacted>", k.Email)
+	}
+	return "password:<redacted>"
+}
+
+func (x *RefreshRequest) Redact() string {
+	return "refresh_token:<redacted>"
+}
+
+func (x *LogoutRequest) Redact() string {
+	return "refresh_token:<redacted>"
+}
+
+func (x *CompleteMFALoginRequest) Redact() string {
+	return "mfa_token:<redacted> code:<redacted>"
+}
+
+func (x *CompletePasswordlessLoginRequest) Redact() string {
+	switch x.GetProof().(type) {
+	case *CompletePasswordlessLoginRequest_Code:
+		return "login_token:<redacted> code:<redacted>"
+	case *CompletePasswordlessLoginRequest_LinkToken:
+		return "login_token:<redacted> link_token:<redacted>"
+	}
+	return "login_token:<redacted>"
+}
@@ -19,18 +19,117 @@ var _ = binding.EncodeURL
 
 const _ = http.SupportPackageIsVersion1
 
+const OperationUsersAssignRole = "/api.users.v1.Users/AssignRole"
+const OperationUsersBatchGetUsers = "/api.users.v1.Users/BatchGetUsers"
+const OperationUsersBeginPasskeyRegistration = "/api.users.v1.Users/BeginPasskeyRegistration"
+const OperationUsersChangePassword = "/api.users.v1.Users/ChangePassword"
+const OperationUsersConfirmEmail = "/api.users.v1.Users/ConfirmEmail"
+const OperationUsersConfirmPhone = "/api.users.v1.Users/ConfirmPhone"
+const OperationUsersConfirmTOTP = "/api.users.v1.Users/ConfirmTOTP"
 const OperationUsersCreateUsers = "/api.users.v1.Users/CreateUsers"
+const OperationUsersDeletePasskey = "/api.users.v1.Users/DeletePasskey"
 const OperationUsersDeleteUsers = "/api.users.v1.Users/DeleteUsers"
+const OperationUsersEnrollTOTP = "/api.users.v1.Users/EnrollTOTP"
+const OperationUsersFinishPasskeyRegistration = "/api.users.v1.Users/FinishPasskeyRegistration"
 const OperationUsersGetUsers = "/api.users.v1.Users/GetUsers"
+const OperationUsersListExternalIdentities = "/api.users.v1.Users/ListExternalIdentities"
+const OperationUsersListPasskeys = "/api.users.v1.Users/ListPasskeys"
+const OperationUsersListUserRoles = "/api.users.v1.Users/ListUserRoles"
 const OperationUsersListUsers = "/api.users.v1.Users/ListUsers"
+const OperationUsersLookupUser = "/api.users.v1.Users/LookupUser"
+const OperationUsersPurgeUsers = "/api.users.v1.Users/PurgeUsers"
+const OperationUsersRegenerateRecoveryCodes = "/api.users.v1.Users/RegenerateRecoveryCodes"
+const OperationUsersRenamePasskey = "/api.users.v1.Users/RenamePasskey"
+const OperationUsersRequestPasswordReset = "/api.users.v1.Users/RequestPasswordReset"
+const OperationUsersResetPassword = "/api.users.v1.Users/ResetPassword"
+const OperationUsersResetTOTP = "/api.users.v1.Users/ResetTOTP"
+const OperationUsersRestoreUsers = "/api.users.v1.Users/RestoreUsers"
+const OperationUsersRevokeRole = "/api.users.v1.Users/RevokeRole"
+const OperationUsersSendEmailVerification = "/api.users.v1.Users/SendEmailVerification"
+const OperationUsersSendPhoneVerification = "/api.users.v1.Users/SendPhoneVerification"
+const OperationUsersSetPassword = "/api.users.v1.Users/SetPassword"
+const OperationUsersUnlinkExternalIdentity = "/api.users.v1.Users/UnlinkExternalIdentity"
+const OperationUsersUnlockUser = "/api.users.v1.Users/UnlockUser"
 const OperationUsersUpdateUsers = "/api.users.v1.Users/UpdateUsers"
+const OperationUsersVerifyPassword = "/api.users.v1.Users/VerifyPassword"
+const OperationUsersVerifyTOTP = "/api.users.v1.Users/VerifyTOTP"
 
 type UsersHTTPServer interface {
+	// AssignRole AssignRole grants a role to a user.
+	AssignRole(context.Context, *AssignRoleRequest) (*AssignRoleReply, error)
+	// BatchGetUsers BatchGetUsers resolves many ids at once; unknown ids are reported per entry.
+	BatchGetUsers(context.Context, *BatchGetUsersRequest) (*BatchGetUsersReply, error)
+	// BeginPasskeyRegistration BeginPasskeyRegistration starts registering a passkey for a user,
+	// returning the options to pass to navigator.credentials.create().
+	BeginPasskeyRegistration(context.Context, *BeginPasskeyRegistrationRequest) (*BeginPasskeyRegistrationReply, error)
+	// ChangePassword ChangePassword replaces the password of a user given the current one.
+	ChangePassword(context.Context, *ChangePasswordRequest) (*ChangePasswordReply, error)
+	// ConfirmEmail ConfirmEmail redeems the token of a verification link. The token alone
+	// authenticates the call.
+	ConfirmEmail(context.Context, *ConfirmEmailRequest) (*ConfirmEmailReply, error)
+	// ConfirmPhone ConfirmPhone checks a texted code.
+	ConfirmPhone(context.Context, *ConfirmPhoneRequest) (*ConfirmPhoneReply, error)
+	// ConfirmTOTP ConfirmTOTP enables TOTP given a first code from the authenticator, and
+	// returns the recovery codes.
+	ConfirmTOTP(context.Context, *ConfirmTOTPRequest) (*ConfirmTOTPReply, error)
 	CreateUsers(context.Context, *CreateUsersRequest) (*CreateUsersReply, error)
+	// DeletePasskey DeletePasskey removes a passkey. The last one can only be removed from
+	// users with a password.
+	DeletePasskey(context.Context, *DeletePasskeyRequest) (*DeletePasskeyReply, error)
 	DeleteUsers(context.Context, *DeleteUsersRequest) (*DeleteUsersReply, error)
+	// EnrollTOTP EnrollTOTP starts enrolling a TOTP second factor, returning the secret
+	// to load into an authenticator app.
+	EnrollTOTP(context.Context, *EnrollTOTPRequest) (*EnrollTOTPReply, error)
+	// FinishPasskeyRegistration FinishPasskeyRegistration stores the passkey the authenticator created.
+	FinishPasskeyRegistration(context.Context, *FinishPasskeyRegistrationRequest) (*FinishPasskeyRegistrationReply, error)
 	GetUsers(context.Context, *GetUsersRequest) (*GetUsersReply, error)
+	// ListExternalIdentities ListExternalIdentities lists the accounts at upstream OpenID Connect
+	// providers linked to a user through federated login.
+	ListExternalIdentities(context.Context, *ListExternalIdentitiesRequest) (*ListExternalIdentitiesReply, error)
+	ListPasskeys(context.Context, *ListPasskeysRequest) (*ListPasskeysReply, error)
+	// ListUserRoles ListUserRoles returns the roles held by a user.
+	ListUserRoles(context.Context, *ListUserRolesRequest) (*ListUserRolesReply, error)
 	ListUsers(context.Context, *ListUsersRequest) (*ListUsersReply, error)
+	// LookupUser LookupUser finds a user by username, email or phone, case-insensitively.
+	// The key travels in the query string, e.g. /users:lookup?email=a@b.c
+	LookupUser(context.Context, *LookupUserRequest) (*LookupUserReply, error)
+	// PurgeUsers PurgeUsers permanently deletes a user, deleted or not.
+	PurgeUsers(context.Context, *PurgeUsersRequest) (*PurgeUsersReply, error)
+	// RegenerateRecoveryCodes RegenerateRecoveryCodes replaces the recovery codes of a user.
+	RegenerateRecoveryCodes(context.Context, *RegenerateRecoveryCodesRequest) (*RegenerateRecoveryCodesReply, error)
+	RenamePasskey(context.Context, *RenamePasskeyRequest) (*RenamePasskeyReply, error)
+	// RequestPasswordReset RequestPasswordReset mails a password reset link. It replies the same
+	// whether or not a user has the email.
+	RequestPasswordReset(context.Context, *RequestPasswordResetRequest) (*RequestPasswordResetReply, error)
+	// ResetPassword ResetPassword redeems the token of a reset link. The token alone
+	// authenticates the call.
+	ResetPassword(context.Context, *ResetPasswordRequest) (*ResetPasswordReply, error)
+	// ResetTOTP ResetTOTP removes the TOTP and recovery codes of a user so that they can
+	// enroll again.
+	ResetTOTP(context.Context, *ResetTOTPRequest) (*ResetTOTPReply, error)
+	// RestoreUsers RestoreUsers undoes the soft delete of a user.
+	RestoreUsers(context.Context, *RestoreUsersRequest) (*RestoreUsersReply, error)
+	// RevokeRole RevokeRole takes a role away from a user.
+	RevokeRole(context.Context, *RevokeRoleRequest) (*RevokeRoleReply, error)
+	// SendEmailVerification SendEmailVerification mails a user a link to confirm their email.
+	SendEmailVerification(context.Context, *SendEmailVerificationRequest) (*SendEmailVerificationReply, error)
+	// SendPhoneVerification SendPhoneVerification texts a user a one-time code to confirm their
+	// phone.
+	SendPhoneVerification(context.Context, *SendPhoneVerificationRequest) (*SendPhoneVerificationReply, error)
+	// SetPassword SetPassword sets or replaces the password of a user.
+	SetPassword(context.Context, *SetPasswordRequest) (*SetPasswordReply, error)
+	// UnlinkExternalIdentity UnlinkExternalIdentity unlinks an upstream account from a user. The last
+	// one can only be unlinked from users with a password.
+	UnlinkExternalIdentity(context.Context, *UnlinkExternalIdentityRequest) (*UnlinkExternalIdentityReply, error)
+	// UnlockUser UnlockUser lifts the lockouts of a user and forgets their failed
+	// attempts. Lockouts of client IPs are left alone.
+	UnlockUser(context.Context, *UnlockUserRequest) (*UnlockUserReply, error)
 	UpdateUsers(context.Context, *UpdateUsersRequest) (*UpdateUsersReply, error)
+	// VerifyPassword VerifyPassword checks a password without revealing why it does not match.
+	VerifyPassword(context.Context, *VerifyPasswordRequest) (*VerifyPasswordReply, error)
+	// VerifyTOTP VerifyTOTP checks a TOTP or recovery code after the password step of a
+	// login. Each code is accepted once.
+	VerifyTOTP(context.Context, *VerifyTOTPRequest) (*VerifyTOTPReply, error)
 }
 
 func RegisterUsersHTTPServer(s *http.Server, srv UsersHTTPServer) {
@@ -40,6 +139,35 @@ func RegisterUsersHTTPServer(s *http.Server, srv UsersHTTPServer) {
 	r.DELETE("/users/{id}", _Users_DeleteUsers0_HTTP_Handler(srv))
 	r.GET("/users/{id}", _Users_GetUsers0_HTTP_Handler(srv))
 	r.GET("/users", _Users_ListUsers0_HTTP_Handler(srv))
+	r.GET("/users:lookup", _Users_LookupUser0_HTTP_Handler(srv))
+	r.GET("/users:batchGet", _Users_BatchGetUsers0_HTTP_Handler(srv))
+	r.POST("/users/{id}/restore", _Users_RestoreUsers0_HTTP_Handler(srv))
+	r.POST("/users/{id}/purge", _Users_PurgeUsers0_HTTP_Handler(srv))
+	r.PUT("/users/{id}/password", _Users_SetPassword0_HTTP_Handler(srv))
+	r.POST("/users/{id}/password/change", _Users_ChangePassword0_HTTP_Handler(srv))
+	r.POST("/users/{id}/password/verify", _Users_VerifyPassword0_HTTP_Handler(srv))
+	r.GET("/users/{id}/roles", _Users_ListUserRoles0_HTTP_Handler(srv))
+	r.POST("/users/{id}/roles", _Users_AssignRole0_HTTP_Handler(srv))
+	r.DELETE("/users/{id}/roles/{role}", _Users_RevokeRole0_HTTP_Handler(srv))
+	r.POST("/users/{id}/mfa/totp/enroll", _Users_EnrollTOTP0_HTTP_Handler(srv))
+	r.POST("/users/{id}/mfa/totp/confirm", _Users_ConfirmTOTP0_HTTP_Handler(srv))
+	r.POST("/users/{id}/mfa/totp/verify", _Users_VerifyTOTP0_HTTP_Handler(srv))
+	r.POST("/users/{id}/mfa/recovery-codes", _Users_RegenerateRecoveryCodes0_HTTP_Handler(srv))
+	r.DELETE("/users/{id}/mfa/totp", _Users_ResetTOTP0_HTTP_Handler(srv))
+	r.POST("/users/{id}/unlock", _Users_UnlockUser0_HTTP_Handler(srv))
+	r.POST("/users/{id}/email/verification", _Users_SendEmailVerification0_HTTP_Handler(srv))
+	r.POST("/users:confirmEmail", _Users_ConfirmEmail0_HTTP_Handler(srv))
+	r.POST("/users/{id}/phone/verification", _Users_SendPhoneVerification0_HTTP_Handler(srv))
+	r.POST("/users/{id}/phone/confirm", _Users_ConfirmPhone0_HTTP_Handler(srv))
+	r.POST("/users:requestPasswordReset", _Users_RequestPasswordReset0_HTTP_Handler(srv))
+	r.POST("/users:resetPassword", _Users_ResetPassword0_HTTP_Handler(srv))
+	r.GET("/users/{id}/identities", _Users_ListExternalIdentities0_HTTP_Handler(srv))
+	r.DELETE("/users/{id}/identities/{identity_id}", _Users_UnlinkExternalIdentity0_HTTP_Handler(srv))
+	r.POST("/users/{id}/passkeys:begin", _Users_BeginPasskeyRegistration0_HTTP_Handler(srv))
+	r.POST("/users/{id}/passkeys", _Users_FinishPasskeyRegistration0_HTTP_Handler(srv))
+	r.GET("/users/{id}/passkeys", _Users_ListPasskeys0_HTTP_Handler(srv))
+	r.PATCH("/users/{id}/passkeys/{passkey_id}", _Users_RenamePasskey0_HTTP_Handler(srv))
+	r.DELETE("/users/{id}/passkeys/{passkey_id}", _Users_DeletePasskey0_HTTP_Handler(srv))
 }
 
 func _Users_CreateUsers0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
@@ -149,20 +277,823 @@ func _Users_ListUsers0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context)
 	}
 }
 
+func _Users_LookupUser0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in LookupUserRequest
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersLookupUser)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.LookupUser(ctx, req.(*LookupUserRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*LookupUserReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_BatchGetUsers0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in BatchGetUsersRequest
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersBatchGetUsers)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.BatchGetUsers(ctx, req.(*BatchGetUsersRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*BatchGetUsersReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_RestoreUsers0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in RestoreUsersRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersRestoreUsers)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.RestoreUsers(ctx, req.(*RestoreUsersRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*RestoreUsersReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_PurgeUsers0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in PurgeUsersRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersPurgeUsers)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.PurgeUsers(ctx, req.(*PurgeUsersRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*PurgeUsersReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_SetPassword0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in SetPasswordRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersSetPassword)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.SetPassword(ctx, req.(*SetPasswordRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*SetPasswordReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_ChangePassword0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in ChangePasswordRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersChangePassword)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.ChangePassword(ctx, req.(*ChangePasswordRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*ChangePasswordReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_VerifyPassword0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in VerifyPasswordRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersVerifyPassword)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.VerifyPassword(ctx, req.(*VerifyPasswordRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*VerifyPasswordReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_ListUserRoles0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in ListUserRolesRequest
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersListUserRoles)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.ListUserRoles(ctx, req.(*ListUserRolesRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*ListUserRolesReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_AssignRole0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in AssignRoleRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersAssignRole)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.AssignRole(ctx, req.(*AssignRoleRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*AssignRoleReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_RevokeRole0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in RevokeRoleRequest
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersRevokeRole)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.RevokeRole(ctx, req.(*RevokeRoleRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*RevokeRoleReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_EnrollTOTP0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in EnrollTOTPRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersEnrollTOTP)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.EnrollTOTP(ctx, req.(*EnrollTOTPRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*EnrollTOTPReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_ConfirmTOTP0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in ConfirmTOTPRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersConfirmTOTP)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.ConfirmTOTP(ctx, req.(*ConfirmTOTPRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*ConfirmTOTPReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_VerifyTOTP0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in VerifyTOTPRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersVerifyTOTP)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.VerifyTOTP(ctx, req.(*VerifyTOTPRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*VerifyTOTPReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_RegenerateRecoveryCodes0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in RegenerateRecoveryCodesRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersRegenerateRecoveryCodes)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.RegenerateRecoveryCodes(ctx, req.(*RegenerateRecoveryCodesRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*RegenerateRecoveryCodesReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_ResetTOTP0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in ResetTOTPRequest
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersResetTOTP)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.ResetTOTP(ctx, req.(*ResetTOTPRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*ResetTOTPReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_UnlockUser0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in UnlockUserRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersUnlockUser)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.UnlockUser(ctx, req.(*UnlockUserRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*UnlockUserReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_SendEmailVerification0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in SendEmailVerificationRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersSendEmailVerification)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.SendEmailVerification(ctx, req.(*SendEmailVerificationRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*SendEmailVerificationReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_ConfirmEmail0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in ConfirmEmailRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersConfirmEmail)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.ConfirmEmail(ctx, req.(*ConfirmEmailRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*ConfirmEmailReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_SendPhoneVerification0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in SendPhoneVerificationRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersSendPhoneVerification)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.SendPhoneVerification(ctx, req.(*SendPhoneVerificationRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*SendPhoneVerificationReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_ConfirmPhone0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in ConfirmPhoneRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersConfirmPhone)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.ConfirmPhone(ctx, req.(*ConfirmPhoneRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*ConfirmPhoneReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_RequestPasswordReset0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in RequestPasswordResetRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersRequestPasswordReset)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.RequestPasswordReset(ctx, req.(*RequestPasswordResetRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*RequestPasswordResetReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_ResetPassword0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in ResetPasswordRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersResetPassword)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.ResetPassword(ctx, req.(*ResetPasswordRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*ResetPasswordReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_ListExternalIdentities0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in ListExternalIdentitiesRequest
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersListExternalIdentities)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.ListExternalIdentities(ctx, req.(*ListExternalIdentitiesRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*ListExternalIdentitiesReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_UnlinkExternalIdentity0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in UnlinkExternalIdentityRequest
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersUnlinkExternalIdentity)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.UnlinkExternalIdentity(ctx, req.(*UnlinkExternalIdentityRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*UnlinkExternalIdentityReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_BeginPasskeyRegistration0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in BeginPasskeyRegistrationRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersBeginPasskeyRegistration)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.BeginPasskeyRegistration(ctx, req.(*BeginPasskeyRegistrationRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*BeginPasskeyRegistrationReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_FinishPasskeyRegistration0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in FinishPasskeyRegistrationRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersFinishPasskeyRegistration)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.FinishPasskeyRegistration(ctx, req.(*FinishPasskeyRegistrationRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*FinishPasskeyRegistrationReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_ListPasskeys0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in ListPasskeysRequest
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersListPasskeys)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.ListPasskeys(ctx, req.(*ListPasskeysRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*ListPasskeysReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_RenamePasskey0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in RenamePasskeyRequest
+		if err := ctx.Bind(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersRenamePasskey)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.RenamePasskey(ctx, req.(*RenamePasskeyRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*RenamePasskeyReply)
+		return ctx.Result(200, reply)
+	}
+}
+
+func _Users_DeletePasskey0_HTTP_Handler(srv UsersHTTPServer) func(ctx http.Context) error {
+	return func(ctx http.Context) error {
+		var in DeletePasskeyRequest
+		if err := ctx.BindQuery(&in); err != nil {
+			return err
+		}
+		if err := ctx.BindVars(&in); err != nil {
+			return err
+		}
+		http.SetOperation(ctx, OperationUsersDeletePasskey)
+		h := ctx.Middleware(func(ctx context.Context, req interface{}) (interface{}, error) {
+			return srv.DeletePasskey(ctx, req.(*DeletePasskeyRequest))
+		})
+		out, err := h(ctx, &in)
+		if err != nil {
+			return err
+		}
+		reply := out.(*DeletePasskeyReply)
+		return ctx.Result(200, reply)
+	}
+}
+
 type UsersHTTPClient interface {
+	AssignRole(ctx context.Context, req *AssignRoleRequest, opts ...http.CallOption) (rsp *AssignRoleReply, err error)
+	BatchGetUsers(ctx context.Context, req *BatchGetUsersRequest, opts ...http.CallOption) (rsp *BatchGetUsersReply, err error)
+	BeginPasskeyRegistration(ctx context.Context, req *BeginPasskeyRegistrationRequest, opts ...http.CallOption) (rsp *BeginPasskeyRegistrationReply, err error)
+	ChangePassword(ctx context.Context, req *ChangePasswordRequest, opts ...http.CallOption) (rsp *ChangePasswordReply, err error)
+	ConfirmEmail(ctx context.Context, req *ConfirmEmailRequest, opts ...http.CallOption) (rsp *ConfirmEmailReply, err error)
+	ConfirmPhone(ctx context.Context, req *ConfirmPhoneRequest, opts ...http.CallOption) (rsp *ConfirmPhoneReply, err error)
+	ConfirmTOTP(ctx context.Context, req *ConfirmTOTPRequest, opts ...http.CallOption) (rsp *ConfirmTOTPReply, err error)
 	CreateUsers(ctx context.Context, req *CreateUsersRequest, opts ...http.CallOption) (rsp *CreateUsersReply, err error)
+	DeletePasskey(ctx context.Context, req *DeletePasskeyRequest, opts ...http.CallOption) (rsp *DeletePasskeyReply, err error)
 	DeleteUsers(ctx context.Context, req *DeleteUsersRequest, opts ...http.CallOption) (rsp *DeleteUsersReply, err error)
+	EnrollTOTP(ctx context.Context, req *EnrollTOTPRequest, opts ...http.CallOption) (rsp *EnrollTOTPReply, err error)
+	FinishPasskeyRegistration(ctx context.Context, req *FinishPasskeyRegistrationRequest, opts ...http.CallOption) (rsp *FinishPasskeyRegistrationReply, err error)
 	GetUsers(ctx context.Context, req *GetUsersRequest, opts ...http.CallOption) (rsp *GetUsersReply, err error)
+	ListExternalIdentities(ctx context.Context, req *ListExternalIdentitiesRequest, opts ...http.CallOption) (rsp *ListExternalIdentitiesReply, err error)
+	ListPasskeys(ctx context.Context, req *ListPasskeysRequest, opts ...http.CallOption) (rsp *ListPasskeysReply, err error)
+	ListUserRoles(ctx context.Context, req *ListUserRolesRequest, opts ...http.CallOption) (rsp *ListUserRolesReply, err error)
 	ListUsers(ctx context.Context, req *ListUsersRequest, opts ...http.CallOption) (rsp *ListUsersReply, err error)
+	LookupUser(ctx context.Context, req *LookupUserRequest, opts ...http.CallOption) (rsp *LookupUserReply, err error)
+	PurgeUsers(ctx context.Context, req *PurgeUsersRequest, opts ...http.CallOption) (rsp *PurgeUsersReply, err error)
+	RegenerateRecoveryCodes(ctx context.Context, req *RegenerateRecoveryCodesRequest, opts ...http.CallOption) (rsp *RegenerateRecoveryCodesReply, err error)
+	RenamePasskey(ctx context.Context, req *RenamePasskeyRequest, opts ...http.CallOption) (rsp *RenamePasskeyReply, err error)
+	RequestPasswordReset(ctx context.Context, req *RequestPasswordResetRequest, opts ...http.CallOption) (rsp *RequestPasswordResetReply, err error)
+	ResetPassword(ctx context.Context, req *ResetPasswordRequest, opts ...http.CallOption) (rsp *ResetPasswordReply, err error)
+	ResetTOTP(ctx context.Context, req *ResetTOTPRequest, opts ...http.CallOption) (rsp *ResetTOTPReply, err error)
+	RestoreUsers(ctx context.Context, req *RestoreUsersRequest, opts ...http.CallOption) (rsp *RestoreUsersReply, err error)
+	RevokeRole(ctx context.Context, req *RevokeRoleRequest, opts ...http.CallOption) (rsp *RevokeRoleReply, err error)
+	SendEmailVerification(ctx context.Context, req *SendEmailVerificationRequest, opts ...http.CallOption) (rsp *SendEmailVerificationReply, err error)
+	SendPhoneVerification(ctx context.Context, req *SendPhoneVerificationRequest, opts ...http.CallOption) (rsp *SendPhoneVerificationReply, err error)
+	SetPassword(ctx context.Context, req *SetPasswordRequest, opts ...http.CallOption) (rsp *SetPasswordReply, err error)
+	UnlinkExternalIdentity(ctx context.Context, req *UnlinkExternalIdentityRequest, opts ...http.CallOption) (rsp *UnlinkExternalIdentityReply, err error)
+	UnlockUser(ctx context.Context, req *UnlockUserRequest, opts ...http.CallOption) (rsp *UnlockUserReply, err error)
 	UpdateUsers(ctx context.Context, req *UpdateUsersRequest, opts ...http.CallOption) (rsp *UpdateUsersReply, err error)
+	VerifyPassword(ctx context.Context, req *VerifyPasswordRequest, opts ...http.CallOption) (rsp *VerifyPasswordReply, err error)
+	VerifyTOTP(ctx context.Context, req *VerifyTOTPRequest, opts ...http.CallOption) (rsp *VerifyTOTPReply, err error)
+}
+
+type UsersHTTPClientImpl struct {
+	cc *http.Client
+}
+
+func NewUsersHTTPClient(client *http.Client) UsersHTTPClient {
+	return &UsersHTTPClientImpl{client}
+}
+
+func (c *UsersHTTPClientImpl) AssignRole(ctx context.Context, in *AssignRoleRequest, opts ...http.CallOption) (*AssignRoleReply, error) {
+	var out AssignRoleReply
+	pattern := "/users/{id}/roles"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersAssignRole))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "POST", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
 }
 
-type UsersHTTPClientImpl struct {
-	cc *http.Client
+func (c *UsersHTTPClientImpl) BatchGetUsers(ctx context.Context, in *BatchGetUsersRequest, opts ...http.CallOption) (*BatchGetUsersReply, error) {
+	var out BatchGetUsersReply
+	pattern := "/users:batchGet"
+	path := binding.EncodeURL(pattern, in, true)
+	opts = append(opts, http.Operation(OperationUsersBatchGetUsers))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "GET", path, nil, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
 }
 
-func NewUsersHTTPClient(client *http.Client) UsersHTTPClient {
-	return &UsersHTTPClientImpl{client}
+func (c *UsersHTTPClientImpl) BeginPasskeyRegistration(ctx context.Context, in *BeginPasskeyRegistrationRequest, opts ...http.CallOption) (*BeginPasskeyRegistrationReply, error) {
+	var out BeginPasskeyRegistrationReply
+	pattern := "/users/{id}/passkeys:begin"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersBeginPasskeyRegistration))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "POST", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) ChangePassword(ctx context.Context, in *ChangePasswordRequest, opts ...http.CallOption) (*ChangePasswordReply, error) {
+	var out ChangePasswordReply
+	pattern := "/users/{id}/password/change"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersChangePassword))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "POST", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) ConfirmEmail(ctx context.Context, in *ConfirmEmailRequest, opts ...http.CallOption) (*ConfirmEmailReply, error) {
+	var out ConfirmEmailReply
+	pattern := "/users:confirmEmail"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersConfirmEmail))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "POST", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) ConfirmPhone(ctx context.Context, in *ConfirmPhoneRequest, opts ...http.CallOption) (*ConfirmPhoneReply, error) {
+	var out ConfirmPhoneReply
+	pattern := "/users/{id}/phone/confirm"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersConfirmPhone))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "POST", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) ConfirmTOTP(ctx context.Context, in *ConfirmTOTPRequest, opts ...http.CallOption) (*ConfirmTOTPReply, error) {
+	var out ConfirmTOTPReply
+	pattern := "/users/{id}/mfa/totp/confirm"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersConfirmTOTP))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "POST", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
 }
 
 func (c *UsersHTTPClientImpl) CreateUsers(ctx context.Context, in *CreateUsersRequest, opts ...http.CallOption) (*CreateUsersReply, error) {
@@ -178,6 +1109,19 @@ func (c *UsersHTTPClientImpl) CreateUsers(ctx context.Context, in *CreateUsersRe
 	return &out, nil
 }
 
+func (c *UsersHTTPClientImpl) DeletePasskey(ctx context.Context, in *DeletePasskeyRequest, opts ...http.CallOption) (*DeletePasskeyReply, error) {
+	var out DeletePasskeyReply
+	pattern := "/users/{id}/passkeys/{passkey_id}"
+	path := binding.EncodeURL(pattern, in, true)
+	opts = append(opts, http.Operation(OperationUsersDeletePasskey))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "DELETE", path, nil, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
 func (c *UsersHTTPClientImpl) DeleteUsers(ctx context.Context, in *DeleteUsersRequest, opts ...http.CallOption) (*DeleteUsersReply, error) {
 	var out DeleteUsersReply
 	pattern := "/users/{id}"
@@ -191,6 +1135,32 @@ func (c *UsersHTTPClientImpl) DeleteUsers(ctx context.Context, in *DeleteUsersRe
 	return &out, nil
 }
 
+func (c *UsersHTTPClientImpl) EnrollTOTP(ctx context.Context, in *EnrollTOTPRequest, opts ...http.CallOption) (*EnrollTOTPReply, error) {
+	var out EnrollTOTPReply
+	pattern := "/users/{id}/mfa/totp/enroll"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersEnrollTOTP))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "POST", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) FinishPasskeyRegistration(ctx context.Context, in *FinishPasskeyRegistrationRequest, opts ...http.CallOption) (*FinishPasskeyRegistrationReply, error) {
+	var out FinishPasskeyRegistrationReply
+	pattern := "/users/{id}/passkeys"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersFinishPasskeyRegistration))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "POST", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
 func (c *UsersHTTPClientImpl) GetUsers(ctx context.Context, in *GetUsersRequest, opts ...http.CallOption) (*GetUsersReply, error) {
 	var out GetUsersReply
 	pattern := "/users/{id}"
@@ -204,6 +1174,45 @@ func (c *UsersHTTPClientImpl) GetUsers(ctx context.Context, in *GetUsersRequest,
 	return &out, nil
 }
 
+func (c *UsersHTTPClientImpl) ListExternalIdentities(ctx context.Context, in *ListExternalIdentitiesRequest, opts ...http.CallOption) (*ListExternalIdentitiesReply, error) {
+	var out ListExternalIdentitiesReply
+	pattern := "/users/{id}/identities"
+	path := binding.EncodeURL(pattern, in, true)
+	opts = append(opts, http.Operation(OperationUsersListExternalIdentities))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "GET", path, nil, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) ListPasskeys(ctx context.Context, in *ListPasskeysRequest, opts ...http.CallOption) (*ListPasskeysReply, error) {
+	var out ListPasskeysReply
+	pattern := "/users/{id}/passkeys"
+	path := binding.EncodeURL(pattern, in, true)
+	opts = append(opts, http.Operation(OperationUsersListPasskeys))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "GET", path, nil, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) ListUserRoles(ctx context.Context, in *ListUserRolesRequest, opts ...http.CallOption) (*ListUserRolesReply, error) {
+	var out ListUserRolesReply
+	pattern := "/users/{id}/roles"
+	path := binding.EncodeURL(pattern, in, true)
+	opts = append(opts, http.Operation(OperationUsersListUserRoles))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "GET", path, nil, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
 func (c *UsersHTTPClientImpl) ListUsers(ctx context.Context, in *ListUsersRequest, opts ...http.CallOption) (*ListUsersReply, error) {
 	var out ListUsersReply
 	pattern := "/users"
@@ -217,6 +1226,188 @@ func (c *UsersHTTPClientImpl) ListUsers(ctx context.Context, in *ListUsersReques
 	return &out, nil
 }
 
+func (c *UsersHTTPClientImpl) LookupUser(ctx context.Context, in *LookupUserRequest, opts ...http.CallOption) (*LookupUserReply, error) {
+	var out LookupUserReply
+	pattern := "/users:lookup"
+	path := binding.EncodeURL(pattern, in, true)
+	opts = append(opts, http.Operation(OperationUsersLookupUser))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "GET", path, nil, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) PurgeUsers(ctx context.Context, in *PurgeUsersRequest, opts ...http.CallOption) (*PurgeUsersReply, error) {
+	var out PurgeUsersReply
+	pattern := "/users/{id}/purge"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersPurgeUsers))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "POST", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) RegenerateRecoveryCodes(ctx context.Context, in *RegenerateRecoveryCodesRequest, opts ...http.CallOption) (*RegenerateRecoveryCodesReply, error) {
+	var out RegenerateRecoveryCodesReply
+	pattern := "/users/{id}/mfa/recovery-codes"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersRegenerateRecoveryCodes))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "POST", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) RenamePasskey(ctx context.Context, in *RenamePasskeyRequest, opts ...http.CallOption) (*RenamePasskeyReply, error) {
+	var out RenamePasskeyReply
+	pattern := "/users/{id}/passkeys/{passkey_id}"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersRenamePasskey))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "PATCH", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) RequestPasswordReset(ctx context.Context, in *RequestPasswordResetRequest, opts ...http.CallOption) (*RequestPasswordResetReply, error) {
+	var out RequestPasswordResetReply
+	pattern := "/users:requestPasswordReset"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersRequestPasswordReset))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "POST", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) ResetPassword(ctx context.Context, in *ResetPasswordRequest, opts ...http.CallOption) (*ResetPasswordReply, error) {
+	var out ResetPasswordReply
+	pattern := "/users:resetPassword"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersResetPassword))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "POST", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) ResetTOTP(ctx context.Context, in *ResetTOTPRequest, opts ...http.CallOption) (*ResetTOTPReply, error) {
+	var out ResetTOTPReply
+	pattern := "/users/{id}/mfa/totp"
+	path := binding.EncodeURL(pattern, in, true)
+	opts = append(opts, http.Operation(OperationUsersResetTOTP))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "DELETE", path, nil, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) RestoreUsers(ctx context.Context, in *RestoreUsersRequest, opts ...http.CallOption) (*RestoreUsersReply, error) {
+	var out RestoreUsersReply
+	pattern := "/users/{id}/restore"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersRestoreUsers))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "POST", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) RevokeRole(ctx context.Context, in *RevokeRoleRequest, opts ...http.CallOption) (*RevokeRoleReply, error) {
+	var out RevokeRoleReply
+	pattern := "/users/{id}/roles/{role}"
+	path := binding.EncodeURL(pattern, in, true)
+	opts = append(opts, http.Operation(OperationUsersRevokeRole))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "DELETE", path, nil, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) SendEmailVerification(ctx context.Context, in *SendEmailVerificationRequest, opts ...http.CallOption) (*SendEmailVerificationReply, error) {
+	var out SendEmailVerificationReply
+	pattern := "/users/{id}/email/verification"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersSendEmailVerification))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "POST", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) SendPhoneVerification(ctx context.Context, in *SendPhoneVerificationRequest, opts ...http.CallOption) (*SendPhoneVerificationReply, error) {
+	var out SendPhoneVerificationReply
+	pattern := "/users/{id}/phone/verification"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersSendPhoneVerification))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "POST", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) SetPassword(ctx context.Context, in *SetPasswordRequest, opts ...http.CallOption) (*SetPasswordReply, error) {
+	var out SetPasswordReply
+	pattern := "/users/{id}/password"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersSetPassword))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "PUT", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) UnlinkExternalIdentity(ctx context.Context, in *UnlinkExternalIdentityRequest, opts ...http.CallOption) (*UnlinkExternalIdentityReply, error) {
+	var out UnlinkExternalIdentityReply
+	pattern := "/users/{id}/identities/{identity_id}"
+	path := binding.EncodeURL(pattern, in, true)
+	opts = append(opts, http.Operation(OperationUsersUnlinkExternalIdentity))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "DELETE", path, nil, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) UnlockUser(ctx context.Context, in *UnlockUserRequest, opts ...http.CallOption) (*UnlockUserReply, error) {
+	var out UnlockUserReply
+	pattern := "/users/{id}/unlock"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersUnlockUser))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "POST", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
 func (c *UsersHTTPClientImpl) UpdateUsers(ctx context.Context, in *UpdateUsersRequest, opts ...http.CallOption) (*UpdateUsersReply, error) {
 	var out UpdateUsersReply
 	pattern := "/users"
@@ -229,3 +1420,29 @@ func (c *UsersHTTPClientImpl) UpdateUsers(ctx context.Context, in *UpdateUsersRe
 	}
 	return &out, nil
 }
+
+func (c *UsersHTTPClientImpl) VerifyPassword(ctx context.Context, in *VerifyPasswordRequest, opts ...http.CallOption) (*VerifyPasswordReply, error) {
+	var out VerifyPasswordReply
+	pattern := "/users/{id}/password/verify"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersVerifyPassword))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "POST", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+func (c *UsersHTTPClientImpl) VerifyTOTP(ctx context.Context, in *VerifyTOTPRequest, opts ...http.CallOption) (*VerifyTOTPReply, error) {
+	var out VerifyTOTPReply
+	pattern := "/users/{id}/mfa/totp/verify"
+	path := binding.EncodeURL(pattern, in, false)
+	opts = append(opts, http.Operation(OperationUsersVerifyTOTP))
+	opts = append(opts, http.PathTemplate(pattern))
+	err := c.cc.Invoke(ctx, "POST", path, in, &out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
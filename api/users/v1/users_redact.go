@@ -0,0 +1,38 @@
+package v1
+
+import "fmt"
+
+// The kratos logging middleware prints requests through Redact when it is
+// available, which keeps passwords, codes and tokens out of the logs.
+
+func (x *SetPasswordRequest) Redact() string {
+	return fmt.Sprintf("id:%q password:<redacted>", x.GetId())
+}
+
+func (x *ChangePasswordRequest) Redact() string {
+	return fmt.Sprintf("id:%q current_password:<redacted> new_password:<redacted>", x.GetId())
+}
+
+func (x *VerifyPasswordRequest) Redact() string {
+	return fmt.Sprintf("id:%q password:<redacted>", x.GetId())
+}
+
+func (x *ConfirmTOTPRequest) Redact() string {
+	return fmt.Sprintf("id:%q code:<redacted>", x.GetId())
+}
+
+func (x *VerifyTOTPRequest) Redact() string {
+	return fmt.Sprintf("id:%q code:<redacted>", x.GetId())
+}
+
+func (x *ConfirmEmailRequest) Redact() string {
+	return "token:<redacted>"
+}
+
+func (x *ConfirmPhoneRequest) Redact() string {
+	return fmt.Sprintf("id:%q code:<redacted>", x.GetId())
+}
+
+func (x *ResetPasswordRequest) Redact() string {
+	return "token:<redacted> new_password:<redacted>"
+}
@@ -4,8 +4,10 @@ import (
 	"context"
 	"flag"
 	"os"
+	"users/internal/biz"
 	"users/internal/data"
 	"users/internal/dep"
+	"users/internal/server"
 
 	"users/internal/conf"
 
@@ -28,15 +30,18 @@ var (
 	Version string
 	// flagconf is the config flag.
 	flagconf string
+	// flagnormalize runs the one-off normalization of identifiers.
+	flagnormalize bool
 
 	id, _ = os.Hostname()
 )
 
 func init() {
 	flag.StringVar(&flagconf, "conf", "../../configs", "config path, eg: -conf config.yaml")
+	flag.BoolVar(&flagnormalize, "normalize-identifiers", false, "normalize the identifiers of users stored before they were normalized, then exit")
 }
 
-func newApp(logger log.Logger, gs *grpc.Server, hs *http.Server) *kratos.App {
+func newApp(logger log.Logger, gs *grpc.Server, hs *http.Server, ps *server.Purger) *kratos.App {
 	return kratos.New(
 		kratos.ID(id),
 		kratos.Name(Name),
@@ -46,6 +51,7 @@ func newApp(logger log.Logger, gs *grpc.Server, hs *http.Server) *kratos.App {
 		kratos.Server(
 			gs,
 			hs,
+			ps,
 		),
 	)
 }
@@ -84,13 +90,22 @@ func main() {
 		"service.version", Version,
 		"trace.id", tracing.TraceID(),
 		"span.id", tracing.SpanID(),
+		"principal", biz.PrincipalValuer(),
 	)
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
 
-	data.Migrate(ctx, bc.Data, logger)
+	if err := data.Migrate(ctx, bc.Data, logger); err != nil {
+		panic(err)
+	}
+	if flagnormalize {
+		if err := data.NormalizeIdentifiers(ctx, bc.Data, logger); err != nil {
+			panic(err)
+		}
+		return
+	}
 
-	app, cleanup, err := wireApp(ctx, &bc, bc.Server, bc.Data, logger)
+	app, cleanup, err := wireApp(ctx, &bc, bc.Server, bc.Data, bc.Biz, bc.Auth, bc.Federation, logger)
 	if err != nil {
 		panic(err)
 	}
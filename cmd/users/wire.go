@@ -21,7 +21,7 @@ import (
 )
 
 // wireApp init kratos application.
-func wireApp(context.Context, *conf.Bootstrap, *conf.Server, *conf.Data, log.Logger) (*kratos.App, func(), error) {
+func wireApp(context.Context, *conf.Bootstrap, *conf.Server, *conf.Data, *conf.Biz, *conf.Auth, *conf.Federation, log.Logger) (*kratos.App, func(), error) {
 	panic(
 		wire.Build(
 			server.ProviderSet,
@@ -25,42 +25,142 @@ import (
 // Injectors from wire.go:
 
 // wireApp init kratos application.
-func wireApp(contextContext context.Context, bootstrap *conf.Bootstrap, confServer *conf.Server, confData *conf.Data, logger log.Logger) (*kratos.App, func(), error) {
+func wireApp(contextContext context.Context, bootstrap *conf.Bootstrap, confServer *conf.Server, confData *conf.Data, confBiz *conf.Biz, auth *conf.Auth, federation *conf.Federation, logger log.Logger) (*kratos.App, func(), error) {
 	dataData, cleanup, err := data.NewData(confData, logger)
 	if err != nil {
 		return nil, nil, err
 	}
 	usersRepo := data.NewUsersRepo(dataData, logger)
-	usersUsecase := biz.NewUsersUsecase(usersRepo, logger)
-	usersService := service.NewUsersService(usersUsecase, logger)
+	usersUsecase, err := biz.NewUsersUsecase(usersRepo, confBiz, logger)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	credentialsRepo := data.NewCredentialsRepo(dataData, logger)
+	sessionsRepo := data.NewSessionsRepo(dataData, logger)
+	sessionsUsecase := biz.NewSessionsUsecase(sessionsRepo, usersRepo, auth, logger)
+	attemptStore, cleanup2, err := data.NewAttemptStore(confData, logger)
+	if err != nil {
+		cleanup()
+		return nil, nil, err
+	}
+	mfaRepo := data.NewMFARepo(dataData, logger)
 	meterProvider, err := dep.NewMeterProvider(bootstrap)
 	if err != nil {
+		cleanup2()
 		cleanup()
 		return nil, nil, err
 	}
 	meter, err := dep.NewMeter(bootstrap, meterProvider)
 	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	attemptLimiter, err := biz.NewAttemptLimiter(attemptStore, usersRepo, mfaRepo, confBiz, meter, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	credentialsUsecase := biz.NewCredentialsUsecase(credentialsRepo, usersRepo, sessionsUsecase, attemptLimiter, confBiz, logger)
+	rolesRepo := data.NewRolesRepo(dataData, logger)
+	accessUsecase := biz.NewAccessUsecase(rolesRepo, usersRepo, confBiz, logger)
+	mfaUsecase, err := biz.NewMFAUsecase(mfaRepo, usersRepo, attemptLimiter, confBiz, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	verificationRepo := data.NewVerificationRepo(dataData, logger)
+	mailer, err := data.NewMailer(confData, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	smsSender := data.NewSMSSender(logger)
+	verificationUsecase, err := biz.NewVerificationUsecase(usersRepo, verificationRepo, mailer, smsSender, confBiz, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	passwordResetUsecase, err := biz.NewPasswordResetUsecase(usersUsecase, credentialsUsecase, attemptLimiter, mailer, confBiz, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	refreshTokenRepo := data.NewRefreshTokenRepo(dataData, logger)
+	trustedProxies, err := biz.NewTrustedProxies(auth)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	authUsecase, err := biz.NewAuthUsecase(usersUsecase, credentialsUsecase, refreshTokenRepo, sessionsUsecase, attemptLimiter, mfaUsecase, trustedProxies, auth, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	oidcRepo := data.NewOIDCRepo(dataData, logger)
+	oidcUsecase, err := biz.NewOIDCUsecase(authUsecase, usersRepo, oidcRepo, auth, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	externalIdentitiesRepo := data.NewExternalIdentitiesRepo(dataData, logger)
+	upstream := data.NewUpstream(logger)
+	federationUsecase, err := biz.NewFederationUsecase(oidcUsecase, usersUsecase, credentialsUsecase, externalIdentitiesRepo, upstream, federation, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	passkeysRepo := data.NewPasskeysRepo(dataData, logger)
+	passkeysUsecase, err := biz.NewPasskeysUsecase(authUsecase, usersUsecase, credentialsUsecase, passkeysRepo, auth, logger)
+	if err != nil {
+		cleanup2()
+		cleanup()
+		return nil, nil, err
+	}
+	usersService := service.NewUsersService(usersUsecase, credentialsUsecase, accessUsecase, mfaUsecase, verificationUsecase, passwordResetUsecase, federationUsecase, passkeysUsecase, attemptLimiter, logger)
+	apiKeysRepo := data.NewAPIKeysRepo(dataData, logger)
+	apiKeysUsecase := biz.NewAPIKeysUsecase(apiKeysRepo, accessUsecase, logger)
+	passwordlessRepo := data.NewPasswordlessRepo(dataData, logger)
+	passwordlessUsecase, err := biz.NewPasswordlessUsecase(authUsecase, usersUsecase, passwordlessRepo, mailer, smsSender, confBiz, logger)
+	if err != nil {
+		cleanup2()
 		cleanup()
 		return nil, nil, err
 	}
+	authService := service.NewAuthService(authUsecase, apiKeysUsecase, sessionsUsecase, oidcUsecase, federationUsecase, passkeysUsecase, passwordlessUsecase, trustedProxies, logger)
 	textMapPropagator := dep.NewTextMapPropagator()
 	tracerProvider, err := dep.NewTracerProvider(contextContext, bootstrap, textMapPropagator)
 	if err != nil {
+		cleanup2()
 		cleanup()
 		return nil, nil, err
 	}
-	grpcServer, err := server.NewGRPCServer(confServer, usersService, logger, meter, tracerProvider)
+	grpcServer, err := server.NewGRPCServer(confServer, usersService, authService, authUsecase, apiKeysUsecase, accessUsecase, logger, meter, tracerProvider)
 	if err != nil {
+		cleanup2()
 		cleanup()
 		return nil, nil, err
 	}
-	httpServer, err := server.NewHTTPServer(confServer, usersService, logger, meter, tracerProvider)
+	httpServer, err := server.NewHTTPServer(confServer, usersService, authService, authUsecase, apiKeysUsecase, accessUsecase, logger, meter, tracerProvider)
 	if err != nil {
+		cleanup2()
 		cleanup()
 		return nil, nil, err
 	}
-	app := newApp(logger, grpcServer, httpServer)
+	purger := server.NewPurger(confBiz, usersUsecase, logger)
+	app := newApp(logger, grpcServer, httpServer, purger)
 	return app, func() {
+		cleanup2()
 		cleanup()
 	}, nil
 }
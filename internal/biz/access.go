@@ -0,0 +1,293 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"strings"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// Permission names an action on users, granted through roles.
+type Permission string
+
+const (
+	PermUsersCreate       Permission = "users.create"
+	PermUsersGet          Permission = "users.get"
+	PermUsersList         Permission = "users.list"
+	PermUsersUpdate       Permission = "users.update"
+	PermUsersDelete       Permission = "users.delete"
+	PermUsersRestore      Permission = "users.restore"
+	PermUsersPurge        Permission = "users.purge"
+	PermUsersPassword     Permission = "users.password"
+	PermUsersUnlock       Permission = "users.unlock"
+	PermRolesManage       Permission = "roles.manage"
+	PermAPIKeysManage     Permission = "apikeys.manage"
+	PermMFAVerify         Permission = "users.mfa.verify"
+	PermMFAReset          Permission = "users.mfa.reset"
+	PermSessionsCreate    Permission = "sessions.create"
+	PermSessionsList      Permission = "sessions.list"
+	PermSessionsRevoke    Permission = "sessions.revoke"
+	PermSessionsValidate  Permission = "sessions.validate"
+	PermOIDCClientsManage Permission = "oidc.clients.manage"
+)
+
+// Permissions lists every known permission.
+var Permissions = []Permission{
+	PermUsersCreate,
+	PermUsersGet,
+	PermUsersList,
+	PermUsersUpdate,
+	PermUsersDelete,
+	PermUsersRestore,
+	PermUsersPurge,
+	PermUsersPassword,
+	PermUsersUnlock,
+	PermRolesManage,
+	PermAPIKeysManage,
+	PermMFAVerify,
+	PermMFAReset,
+	PermSessionsCreate,
+	PermSessionsList,
+	PermSessionsRevoke,
+	PermSessionsValidate,
+	PermOIDCClientsManage,
+}
+
+// ParsePermissions validates permission names, as given for API key scopes.
+func ParsePermissions(names []string) ([]Permission, error) {
+	res := make([]Permission, 0, len(names))
+	seen := make(map[Permission]bool, len(names))
+	for _, name := range names {
+		perm := Permission(strings.TrimSpace(name))
+		known := false
+		for _, p := range Permissions {
+			known = known || p == perm
+		}
+		if !known {
+			return nil, errors.BadRequest("auth.apiKey", fmt.Sprintf("unknown permission %q", name))
+		}
+		if !seen[perm] {
+			seen[perm] = true
+			res = append(res, perm)
+		}
+	}
+	return res, nil
+}
+
+// The built-in roles. Their permissions are seeded by the data layer's
+// migrations. RoleUser grants nothing beyond the access everyone has to
+// their own record.
+const (
+	RoleAdmin   = "admin"
+	RoleSupport = "support"
+	RoleUser    = "user"
+)
+
+type RolesRepo interface {
+	// UserRoles returns the names of the roles held by a user.
+	UserRoles(context.Context, uuid.UUID) ([]string, error)
+	// UserPermissions returns the permissions granted by the roles of a user.
+	UserPermissions(context.Context, uuid.UUID) ([]Permission, error)
+	// RolePermissions returns the permissions granted by the given roles,
+	// ignoring unknown ones.
+	RolePermissions(context.Context, []string) ([]Permission, error)
+	// AssignRole grants a role to a user, returning a NotFound error for
+	// unknown roles. Assigning a held role is not an error.
+	AssignRole(context.Context, uuid.UUID, string) error
+	// RevokeRole takes a role away from a user. Revoking a role the user
+	// does not hold is not an error.
+	RevokeRole(context.Context, uuid.UUID, string) error
+}
+
+// Rule is the access an operation requires: the permission, unless Self
+// allows callers acting on their own record. A zero Permission with Self
+// leaves the operation to the record's owner alone.
+type Rule struct {
+	Permission Permission
+	Self       bool
+}
+
+type AccessUsecase struct {
+	repo   RolesRepo
+	users  UsersRepo
+	admins map[string]struct{}
+	log    *log.Helper
+}
+
+// NewAccessUsecase new an Access usecase.
+func NewAccessUsecase(repo RolesRepo, users UsersRepo, c *conf.Biz, logger log.Logger) *AccessUsecase {
+	admins := make(map[string]struct{})
+	for _, id := range c.GetAccess().GetAdminUserIds() {
+		admins[id] = struct{}{}
+	}
+	return &AccessUsecase{
+		repo:   repo,
+		users:  users,
+		admins: admins,
+		log:    log.NewHelper(logger),
+	}
+}
+
+// Authorize checks that the principal may perform an operation following
+// rule, on the user identified by target when the operation has one.
+func (uc *AccessUsecase) Authorize(ctx context.Context, p *Principal, rule Rule, target string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Biz Authorize")
+	defer span.End()
+	if p == nil {
+		err := errors.Unauthorized("auth.unauthenticated", "no authenticated principal")
+		span.AddEvent(err.Error())
+		return err
+	}
+	if p.Type == PrincipalAPIKey {
+		// API keys act for no user, so only their scopes count
+		if rule.Permission != "" && p.hasScope(rule.Permission) {
+			return nil
+		}
+		err := errors.Forbidden("auth.forbidden", "operation not permitted")
+		span.AddEvent(err.Error())
+		return err
+	}
+	if rule.Self && target != "" && target == p.UserID {
+		return nil
+	}
+	if rule.Permission != "" {
+		var (
+			ok  bool
+			err error
+		)
+		if p.Gateway {
+			ok, err = uc.rolesHavePermission(ctx, p.UserID, p.Roles, rule.Permission)
+		} else {
+			ok, err = uc.HasPermission(ctx, p.UserID, rule.Permission)
+		}
+		if err != nil {
+			span.AddEvent(err.Error())
+			return err
+		}
+		if ok {
+			return nil
+		}
+	}
+	err := errors.Forbidden("auth.forbidden", "operation not permitted")
+	span.AddEvent(err.Error())
+	return err
+}
+
+// HasPermission reports whether a user holds perm through one of its roles.
+// Configured admins hold every permission.
+func (uc *AccessUsecase) HasPermission(ctx context.Context, id string, perm Permission) (bool, error) {
+	if _, ok := uc.admins[id]; ok {
+		return true, nil
+	}
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		return false, nil
+	}
+	perms, err := uc.repo.UserPermissions(ctx, uid)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range perms {
+		if p == perm {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// rolesHavePermission is HasPermission for a user holding the given roles.
+func (uc *AccessUsecase) rolesHavePermission(ctx context.Context, id string, roles []string, perm Permission) (bool, error) {
+	if _, ok := uc.admins[id]; ok {
+		return true, nil
+	}
+	if len(roles) == 0 {
+		return false, nil
+	}
+	perms, err := uc.repo.RolePermissions(ctx, roles)
+	if err != nil {
+		return false, err
+	}
+	for _, p := range perms {
+		if p == perm {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (uc *AccessUsecase) UserRoles(ctx context.Context, id string) ([]string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz UserRoles")
+	defer span.End()
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if _, err := uc.users.FindByID(ctx, uid, ExcludeDeleted); err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	res, err := uc.repo.UserRoles(ctx, uid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+func (uc *AccessUsecase) AssignRole(ctx context.Context, id, role string) ([]string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz AssignRole")
+	defer span.End()
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if role == "" {
+		err := errors.BadRequest("users.assignRole", "role is required")
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if _, err := uc.users.FindByID(ctx, uid, ExcludeDeleted); err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if err := uc.repo.AssignRole(ctx, uid, role); err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	uc.log.WithContext(ctx).Infof("assigned role %s to user %s", role, uid)
+	return uc.repo.UserRoles(ctx, uid)
+}
+
+func (uc *AccessUsecase) RevokeRole(ctx context.Context, id, role string) ([]string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz RevokeRole")
+	defer span.End()
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if role == "" {
+		err := errors.BadRequest("users.revokeRole", "role is required")
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if p, ok := PrincipalFromContext(ctx); ok && p.UserID == id && role == RoleAdmin {
+		// keeps an admin from locking everybody out by accident
+		err := errors.BadRequest("users.revokeRole", fmt.Sprintf("cannot revoke your own %s role", RoleAdmin))
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if err := uc.repo.RevokeRole(ctx, uid, role); err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	uc.log.WithContext(ctx).Infof("revoked role %s from user %s", role, uid)
+	return uc.repo.UserRoles(ctx, uid)
+}
@@ -0,0 +1,256 @@
+package biz
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+const (
+	// apiKeyPrefix marks API key secrets so that they are easy to spot,
+	// e.g. by secret scanners.
+	apiKeyPrefix = "uk_"
+	// apiKeyDisplayLength is how much of a secret is kept in clear to tell
+	// keys apart.
+	apiKeyDisplayLength = len(apiKeyPrefix) + 8
+	// apiKeyTouchInterval bounds how often last use is written per key.
+	apiKeyTouchInterval = time.Minute
+)
+
+// APIKey authenticates a service calling without user context. Only the
+// hash of its secret is stored; the secret is handed out once, on creation
+// and on rotation.
+type APIKey struct {
+	ID         uuid.UUID
+	Name       string
+	Prefix     string
+	Hash       string
+	Scopes     []Permission
+	CreatedBy  string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+	RevokedAt  *time.Time
+}
+
+type APIKeysRepo interface {
+	SaveAPIKey(context.Context, *APIKey) (*APIKey, error)
+	// FindAPIKeyByHash returns the key whose secret hashes to the given
+	// hash, revoked or not, or a NotFound error.
+	FindAPIKeyByHash(context.Context, string) (*APIKey, error)
+	// FindAPIKey returns a key, revoked or not, or a NotFound error.
+	FindAPIKey(context.Context, uuid.UUID) (*APIKey, error)
+	// ListAPIKeys returns every key, oldest first.
+	ListAPIKeys(context.Context) ([]APIKey, error)
+	// RevokeAPIKey revokes a key, returning a NotFound error for unknown
+	// keys.
+	RevokeAPIKey(context.Context, uuid.UUID) (*APIKey, error)
+	// RotateAPIKey replaces the secret of an unrevoked key, returning a
+	// NotFound error when there is none.
+	RotateAPIKey(ctx context.Context, id uuid.UUID, hash, prefix string) (*APIKey, error)
+	// TouchAPIKey records the last use of a key.
+	TouchAPIKey(context.Context, uuid.UUID, time.Time) error
+}
+
+type APIKeysUsecase struct {
+	repo   APIKeysRepo
+	access *AccessUsecase
+	log    *log.Helper
+}
+
+// NewAPIKeysUsecase new an APIKeys usecase.
+func NewAPIKeysUsecase(repo APIKeysRepo, access *AccessUsecase, logger log.Logger) *APIKeysUsecase {
+	return &APIKeysUsecase{repo: repo, access: access, log: log.NewHelper(logger)}
+}
+
+// checkScopes returns a Forbidden error unless the calling principal holds
+// every scope, so that nobody hands out more than they may do themselves.
+func (uc *APIKeysUsecase) checkScopes(ctx context.Context, scopes []Permission) error {
+	p, _ := PrincipalFromContext(ctx)
+	for _, scope := range scopes {
+		err := uc.access.Authorize(ctx, p, Rule{Permission: scope}, "")
+		if errors.IsForbidden(err) {
+			return errors.Forbidden("auth.apiKey", fmt.Sprintf("scope %q is not held by the caller", scope))
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// CreateAPIKey creates a key and returns it along with its secret.
+func (uc *APIKeysUsecase) CreateAPIKey(ctx context.Context, name string, scopes []string, expiresAt *time.Time) (*APIKey, string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz CreateAPIKey")
+	defer span.End()
+	name = strings.TrimSpace(name)
+	if name == "" {
+		err := errors.BadRequest("auth.apiKey", "name is required")
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	perms, err := ParsePermissions(scopes)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	if len(perms) == 0 {
+		err := errors.BadRequest("auth.apiKey", "at least one scope is required")
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	if expiresAt != nil && !expiresAt.After(time.Now()) {
+		err := errors.BadRequest("auth.apiKey", "expiry must be in the future")
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	if err := uc.checkScopes(ctx, perms); err != nil {
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	secret, err := newAPIKeySecret()
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	key := &APIKey{
+		Name:      name,
+		Prefix:    secret[:apiKeyDisplayLength],
+		Hash:      hashAPIKeySecret(secret),
+		Scopes:    perms,
+		ExpiresAt: expiresAt,
+	}
+	if p, ok := PrincipalFromContext(ctx); ok {
+		key.CreatedBy = p.String()
+	}
+	res, err := uc.repo.SaveAPIKey(ctx, key)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	return res, secret, nil
+}
+
+func (uc *APIKeysUsecase) ListAPIKeys(ctx context.Context) ([]APIKey, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz ListAPIKeys")
+	defer span.End()
+	res, err := uc.repo.ListAPIKeys(ctx)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+func (uc *APIKeysUsecase) RevokeAPIKey(ctx context.Context, id string) (*APIKey, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz RevokeAPIKey")
+	defer span.End()
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	res, err := uc.repo.RevokeAPIKey(ctx, uid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+// RotateAPIKey replaces the secret of a key, keeping its name, scopes and
+// expiry. The previous secret stops working at once. Only callers holding
+// every scope of the key may rotate it.
+func (uc *APIKeysUsecase) RotateAPIKey(ctx context.Context, id string) (*APIKey, string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz RotateAPIKey")
+	defer span.End()
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	key, err := uc.repo.FindAPIKey(ctx, uid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	if err := uc.checkScopes(ctx, key.Scopes); err != nil {
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	secret, err := newAPIKeySecret()
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	res, err := uc.repo.RotateAPIKey(ctx, uid, hashAPIKeySecret(secret), secret[:apiKeyDisplayLength])
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	return res, secret, nil
+}
+
+// AuthenticateAPIKey returns the principal of a valid API key secret.
+func (uc *APIKeysUsecase) AuthenticateAPIKey(ctx context.Context, secret string) (*Principal, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz AuthenticateAPIKey")
+	defer span.End()
+	invalid := errors.Unauthorized("auth.unauthenticated", "invalid API key")
+	if !strings.HasPrefix(secret, apiKeyPrefix) {
+		span.AddEvent(invalid.Error())
+		return nil, invalid
+	}
+	key, err := uc.repo.FindAPIKeyByHash(ctx, hashAPIKeySecret(secret))
+	if errors.IsNotFound(err) {
+		span.AddEvent(invalid.Error())
+		return nil, invalid
+	}
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	now := time.Now()
+	if key.RevokedAt != nil || (key.ExpiresAt != nil && now.After(*key.ExpiresAt)) {
+		span.AddEvent(invalid.Error())
+		return nil, invalid
+	}
+	if key.LastUsedAt == nil || now.Sub(*key.LastUsedAt) >= apiKeyTouchInterval {
+		if err := uc.repo.TouchAPIKey(ctx, key.ID, now); err != nil {
+			uc.log.WithContext(ctx).Warnf("failed recording use of API key %s: %v", key.ID, err)
+		}
+	}
+	p := &Principal{
+		Type:     PrincipalAPIKey,
+		APIKeyID: key.ID.String(),
+		Scopes:   key.Scopes,
+	}
+	if key.ExpiresAt != nil {
+		p.ExpiresAt = *key.ExpiresAt
+	}
+	return p, nil
+}
+
+func newAPIKeySecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// hashAPIKeySecret needs no salt nor stretching: secrets are random and
+// long enough not to be guessed.
+func hashAPIKeySecret(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,208 @@
+package biz
+
+import (
+	"context"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+type memAPIKeys struct {
+	APIKeysRepo
+	mu   sync.Mutex
+	keys map[uuid.UUID]*APIKey
+}
+
+func (r *memAPIKeys) SaveAPIKey(_ context.Context, k *APIKey) (*APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved := *k
+	saved.ID = uuid.New()
+	saved.CreatedAt = time.Now()
+	r.keys[saved.ID] = &saved
+	return &saved, nil
+}
+
+func (r *memAPIKeys) FindAPIKeyByHash(_ context.Context, hash string) (*APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, k := range r.keys {
+		if k.Hash == hash {
+			found := *k
+			return &found, nil
+		}
+	}
+	return nil, errors.NotFound("auth.apiKey", "API key not found")
+}
+
+func (r *memAPIKeys) FindAPIKey(_ context.Context, id uuid.UUID) (*APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k, ok := r.keys[id]
+	if !ok {
+		return nil, errors.NotFound("auth.apiKey", "API key not found")
+	}
+	found := *k
+	return &found, nil
+}
+
+func (r *memAPIKeys) RevokeAPIKey(_ context.Context, id uuid.UUID) (*APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k, ok := r.keys[id]
+	if !ok {
+		return nil, errors.NotFound("auth.apiKey", "API key not found")
+	}
+	if k.RevokedAt == nil {
+		k.RevokedAt = ptr(time.Now())
+	}
+	found := *k
+	return &found, nil
+}
+
+func (r *memAPIKeys) RotateAPIKey(_ context.Context, id uuid.UUID, hash, prefix string) (*APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	k, ok := r.keys[id]
+	if !ok || k.RevokedAt != nil {
+		return nil, errors.NotFound("auth.apiKey", "API key not found")
+	}
+	k.Hash, k.Prefix = hash, prefix
+	found := *k
+	return &found, nil
+}
+
+func (r *memAPIKeys) TouchAPIKey(_ context.Context, id uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[id].LastUsedAt = &at
+	return nil
+}
+
+// memRoles grants the permissions of roles as configured, and roles to
+// users as assigned.
+type memRoles struct {
+	RolesRepo
+	perms map[string][]Permission
+	roles map[uuid.UUID][]string
+}
+
+func (r *memRoles) UserPermissions(ctx context.Context, id uuid.UUID) ([]Permission, error) {
+	return r.RolePermissions(ctx, r.roles[id])
+}
+
+func (r *memRoles) RolePermissions(_ context.Context, roles []string) ([]Permission, error) {
+	var res []Permission
+	for _, role := range roles {
+		res = append(res, r.perms[role]...)
+	}
+	return res, nil
+}
+
+func newTestAPIKeys(t *testing.T, roles *memRoles) (*APIKeysUsecase, *memAPIKeys) {
+	t.Helper()
+	repo := &memAPIKeys{keys: make(map[uuid.UUID]*APIKey)}
+	logger := log.NewStdLogger(testWriter{t})
+	return NewAPIKeysUsecase(repo, NewAccessUsecase(roles, nil, &conf.Biz{}, logger), logger), repo
+}
+
+func TestCreateAPIKey(t *testing.T) {
+	support := uuid.New()
+	uc, repo := newTestAPIKeys(t, &memRoles{
+		perms: map[string][]Permission{RoleSupport: {PermUsersGet, PermUsersList}},
+		roles: map[uuid.UUID][]string{support: {RoleSupport}},
+	})
+	ctx := NewPrincipalContext(context.Background(), &Principal{Type: PrincipalUser, UserID: support.String()})
+
+	tests := []struct {
+		name      string
+		keyName   string
+		scopes    []string
+		expiresAt *time.Time
+		want      func(error) bool
+	}{
+		{"no name", " ", []string{"users.get"}, nil, errors.IsBadRequest},
+		{"no scope", "billing", nil, nil, errors.IsBadRequest},
+		{"unknown scope", "billing", []string{"users.get", "users.everything"}, nil, errors.IsBadRequest},
+		{"expired", "billing", []string{"users.get"}, ptr(time.Now().Add(-time.Second)), errors.IsBadRequest},
+		// nobody hands out more than they may do themselves
+		{"scope not held", "billing", []string{"users.get", "users.delete"}, nil, errors.IsForbidden},
+	}
+	for _, tt := range tests {
+		if _, _, err := uc.CreateAPIKey(ctx, tt.keyName, tt.scopes, tt.expiresAt); !tt.want(err) {
+			t.Errorf("%s: err = %v", tt.name, err)
+		}
+	}
+	if len(repo.keys) != 0 {
+		t.Fatalf("%d keys created by rejected calls", len(repo.keys))
+	}
+
+	key, secret, err := uc.CreateAPIKey(ctx, " billing ", []string{"users.get", " users.list", "users.get"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if key.Name != "billing" || len(key.Scopes) != 2 || key.CreatedBy != "user:"+support.String() {
+		t.Errorf("created %+v", key)
+	}
+	if !strings.HasPrefix(secret, apiKeyPrefix) || !strings.HasPrefix(secret, key.Prefix) || strings.Contains(key.Hash, secret) {
+		t.Errorf("secret %q stored as %q, %q", secret, key.Prefix, key.Hash)
+	}
+	p, err := uc.AuthenticateAPIKey(context.Background(), secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.APIKeyID != key.ID.String() || len(p.Scopes) != 2 {
+		t.Errorf("principal %+v", p)
+	}
+}
+
+func TestRotateAPIKey(t *testing.T) {
+	admin, support := uuid.New(), uuid.New()
+	uc, _ := newTestAPIKeys(t, &memRoles{
+		perms: map[string][]Permission{RoleAdmin: Permissions, RoleSupport: {PermUsersGet}},
+		roles: map[uuid.UUID][]string{admin: {RoleAdmin}, support: {RoleSupport}},
+	})
+	ctx := NewPrincipalContext(context.Background(), &Principal{Type: PrincipalUser, UserID: admin.String()})
+	key, secret, err := uc.CreateAPIKey(ctx, "billing", []string{"users.get", "users.delete"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	supportCtx := NewPrincipalContext(context.Background(), &Principal{Type: PrincipalUser, UserID: support.String()})
+	if _, _, err := uc.RotateAPIKey(supportCtx, key.ID.String()); !errors.IsForbidden(err) {
+		t.Errorf("rotating without every scope err = %v, want Forbidden", err)
+	}
+	if _, _, err := uc.RotateAPIKey(ctx, uuid.NewString()); !errors.IsNotFound(err) {
+		t.Errorf("rotating an unknown key err = %v, want NotFound", err)
+	}
+
+	rotated, newSecret, err := uc.RotateAPIKey(ctx, key.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rotated.ID != key.ID || rotated.Name != key.Name || len(rotated.Scopes) != 2 || newSecret == secret {
+		t.Errorf("rotated %+v", rotated)
+	}
+	if _, err := uc.AuthenticateAPIKey(context.Background(), secret); !errors.IsUnauthorized(err) {
+		t.Errorf("previous secret err = %v, want Unauthorized", err)
+	}
+	if _, err := uc.AuthenticateAPIKey(context.Background(), newSecret); err != nil {
+		t.Errorf("new secret: %v", err)
+	}
+
+	if _, err := uc.RevokeAPIKey(ctx, key.ID.String()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uc.AuthenticateAPIKey(context.Background(), newSecret); !errors.IsUnauthorized(err) {
+		t.Errorf("revoked key err = %v, want Unauthorized", err)
+	}
+	if _, _, err := uc.RotateAPIKey(ctx, key.ID.String()); !errors.IsNotFound(err) {
+		t.Errorf("rotating a revoked key err = %v, want NotFound", err)
+	}
+}
@@ -0,0 +1,475 @@
+package biz
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"os"
+	"strings"
+	"time"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+const (
+	defaultAccessTokenTTL  = 15 * time.Minute
+	defaultRefreshTokenTTL = 30 * 24 * time.Hour
+)
+
+// RefreshToken is a stored refresh token. Only the hash of the opaque token
+// handed to the client is kept. Every rotation revokes the presented token
+// and issues a new one in the same family, so a revoked token coming back
+// means it leaked.
+type RefreshToken struct {
+	ID        uuid.UUID
+	FamilyID  uuid.UUID
+	UserID    uuid.UUID
+	Hash      string
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+type RefreshTokenRepo interface {
+	SaveRefreshToken(context.Context, *RefreshToken) error
+	// FindRefreshToken returns the token with the given hash, revoked or
+	// not, or a NotFound error.
+	FindRefreshToken(context.Context, string) (*RefreshToken, error)
+	// RotateRefreshToken revokes a token and saves its successor atomically.
+	// It reports false, saving nothing, when the token was already revoked.
+	RotateRefreshToken(ctx context.Context, old uuid.UUID, next *RefreshToken) (bool, error)
+	// RevokeRefreshTokenFamily revokes every token of a family and ends the
+	// session of the same ID.
+	RevokeRefreshTokenFamily(context.Context, uuid.UUID) error
+}
+
+// accessClaims are the claims of issued access tokens.
+type accessClaims struct {
+	jwt.RegisteredClaims
+	// SessionID names the session the token was issued in
+	SessionID string `json:"sid,omitempty"`
+	// Scope is set on tokens issued to OpenID Connect clients
+	Scope string `json:"scope,omitempty"`
+}
+
+// TokenPair is what a successful Login or Refresh hands out.
+type TokenPair struct {
+	AccessToken      string
+	AccessExpiresAt  time.Time
+	RefreshToken     string
+	RefreshExpiresAt time.Time
+}
+
+type AuthUsecase struct {
+	users      *UsersUsecase
+	creds      *CredentialsUsecase
+	repo       RefreshTokenRepo
+	sessions   *SessionsUsecase
+	attempts   *AttemptLimiter
+	mfa        *MFAUsecase
+	proxies    *TrustedProxies
+	gateway    *conf.Auth_Gateway
+	signer     *tokenSigner
+	issuer     string
+	audience   string
+	accessTTL  time.Duration
+	refreshTTL time.Duration
+	log        *log.Helper
+}
+
+// NewAuthUsecase new an Auth usecase.
+func NewAuthUsecase(users *UsersUsecase, creds *CredentialsUsecase, repo RefreshTokenRepo, sessions *SessionsUsecase, attempts *AttemptLimiter, mfa *MFAUsecase, proxies *TrustedProxies, c *conf.Auth, logger log.Logger) (*AuthUsecase, error) {
+	helper := log.NewHelper(logger)
+	if c.GetGateway().GetEnabled() && !proxies.Configured() && c.GetGateway().GetSecret() == "" {
+		return nil, errors.InternalServer("auth.gateway", "the gateway needs trusted proxies or a secret")
+	}
+	if len(c.GetKeys()) == 0 {
+		helper.Warn("no signing keys configured, issued tokens will not survive restarts")
+	}
+	signer, err := newTokenSigner(c.GetKeys())
+	if err != nil {
+		return nil, err
+	}
+	if path := c.GetJwksFile(); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := signer.addJWKS(data); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	accessTTL := c.GetAccessTokenTtl().AsDuration()
+	if accessTTL <= 0 {
+		accessTTL = defaultAccessTokenTTL
+	}
+	refreshTTL := c.GetRefreshTokenTtl().AsDuration()
+	if refreshTTL <= 0 {
+		refreshTTL = defaultRefreshTokenTTL
+	}
+	return &AuthUsecase{
+		users:      users,
+		creds:      creds,
+		repo:       repo,
+		sessions:   sessions,
+		attempts:   attempts,
+		mfa:        mfa,
+		proxies:    proxies,
+		gateway:    c.GetGateway(),
+		signer:     signer,
+		issuer:     c.GetIssuer(),
+		audience:   c.GetAudience(),
+		accessTTL:  accessTTL,
+		refreshTTL: refreshTTL,
+		log:        helper,
+	}, nil
+}
+
+// JWKS returns the public keys access tokens can be verified with.
+func (uc *AuthUsecase) JWKS() JWKS {
+	return uc.signer.jwks()
+}
+
+// Authenticate verifies an access token and returns the principal it was
+// issued to. Tokens naming a session are only accepted while it is active.
+func (uc *AuthUsecase) Authenticate(ctx context.Context, token string) (*Principal, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz Authenticate")
+	defer span.End()
+	claims, err := uc.parseAccessToken(ctx, token)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	// tokens of OpenID Connect clients are only good for the userinfo
+	// endpoint, clients are not granted the API on behalf of users
+	if claims.Scope != "" {
+		err := errors.Unauthorized("auth.unauthenticated", "access token was issued to an OpenID Connect client")
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return &Principal{
+		Type:      PrincipalUser,
+		UserID:    claims.Subject,
+		SessionID: claims.SessionID,
+		TokenID:   claims.ID,
+		ExpiresAt: claims.ExpiresAt.Time,
+	}, nil
+}
+
+// GatewayEnabled reports whether callers may be identified by the gateway.
+func (uc *AuthUsecase) GatewayEnabled() bool {
+	return uc.gateway.GetEnabled()
+}
+
+// AuthenticateGateway returns the principal the API gateway vouches for,
+// userID holding the comma-separated roles. The request must come from a
+// trusted proxy at peer and carry the gateway secret, when either is
+// configured.
+func (uc *AuthUsecase) AuthenticateGateway(ctx context.Context, peer, secret, userID, roles string) (*Principal, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz AuthenticateGateway")
+	defer span.End()
+	var err error
+	switch {
+	case !uc.gateway.GetEnabled():
+		err = errors.Unauthorized("auth.unauthenticated", "gateway identities are not accepted")
+	case uc.proxies.Configured() && !uc.proxies.Trusted(peer):
+		err = errors.Unauthorized("auth.unauthenticated", "caller identity forwarded by an untrusted peer")
+	case uc.gateway.GetSecret() != "" && subtle.ConstantTimeCompare([]byte(secret), []byte(uc.gateway.GetSecret())) != 1:
+		err = errors.Unauthorized("auth.unauthenticated", "invalid gateway secret")
+	}
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		err = errors.Unauthorized("auth.unauthenticated", "invalid forwarded user ID")
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	p := &Principal{Type: PrincipalUser, UserID: uid.String(), Gateway: true, Roles: []string{}}
+	for _, role := range strings.Split(roles, ",") {
+		if role = strings.TrimSpace(role); role != "" {
+			p.Roles = append(p.Roles, role)
+		}
+	}
+	return p, nil
+}
+
+// parseAccessToken verifies an access token and returns its claims.
+func (uc *AuthUsecase) parseAccessToken(ctx context.Context, token string) (*accessClaims, error) {
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{
+			jwt.SigningMethodEdDSA.Alg(),
+			jwt.SigningMethodES256.Alg(),
+			jwt.SigningMethodRS256.Alg(),
+		}),
+		jwt.WithExpirationRequired(),
+	}
+	if uc.issuer != "" {
+		opts = append(opts, jwt.WithIssuer(uc.issuer))
+	}
+	if uc.audience != "" {
+		opts = append(opts, jwt.WithAudience(uc.audience))
+	}
+	var claims accessClaims
+	t, err := jwt.ParseWithClaims(token, &claims, uc.signer.keyfunc, opts...)
+	if errors.Is(err, jwt.ErrTokenExpired) {
+		return nil, errors.Unauthorized("auth.unauthenticated", "access token expired")
+	}
+	if err != nil {
+		return nil, errors.Unauthorized("auth.unauthenticated", "invalid access token")
+	}
+	if typ, _ := t.Header["typ"].(string); uc.signer.signedHere(t) && typ != typeAccessToken {
+		return nil, errors.Unauthorized("auth.unauthenticated", "not an access token")
+	}
+	if claims.Subject == "" {
+		return nil, errors.Unauthorized("auth.unauthenticated", "access token has no subject")
+	}
+	if claims.SessionID != "" {
+		s, err := uc.sessions.ValidateSession(ctx, claims.SessionID)
+		if err != nil {
+			return nil, err
+		}
+		if s == nil || s.UserID.String() != claims.Subject {
+			return nil, errors.Unauthorized("auth.unauthenticated", "session ended")
+		}
+	}
+	return &claims, nil
+}
+
+// Login checks a password against the user holding key and starts a new
+// session from client, along with its refresh token family. Unknown users,
+// locked accounts and wrong passwords are indistinguishable to the caller.
+// Users with a second factor get an MFA token instead, see CompleteMFALogin.
+func (uc *AuthUsecase) Login(ctx context.Context, key LookupKey, password string, client Client) (*TokenPair, string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz Login")
+	defer span.End()
+	uid, err := uc.checkCredentials(ctx, key, password, client.IP)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	res, mfaToken, err := uc.startLogin(ctx, uid, client)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	return res, mfaToken, nil
+}
+
+// checkCredentials returns the ID of the user holding key when password is
+// theirs. Failures lock out the user and the client IP for a while once
+// they add up.
+func (uc *AuthUsecase) checkCredentials(ctx context.Context, key LookupKey, password, ip string) (uuid.UUID, error) {
+	invalid := errors.Unauthorized("auth.login", "invalid credentials")
+	if err := uc.attempts.Check(ctx, ScopeLogin, uuid.Nil, ip); err != nil {
+		return uuid.Nil, err
+	}
+	user, err := uc.users.LookupUser(ctx, key)
+	if errors.IsNotFound(err) {
+		// as slow as a wrong password, so that timing does not tell
+		// whether the user exists
+		uc.creds.burn(password)
+		uc.attempts.Fail(ctx, ScopeLogin, uuid.Nil, ip)
+		return uuid.Nil, invalid
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	uid, err := uuid.Parse(user.ID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if uc.attempts.Locked(ctx, ScopeLogin, uid) {
+		// answered like a wrong password, so that a lockout does not tell
+		// the account exists; the lock is not extended either, or anyone
+		// could keep it closed for good. Client IPs guessing too much are
+		// still told to slow down.
+		uc.creds.burn(password)
+		uc.attempts.Fail(ctx, ScopeLogin, uuid.Nil, ip)
+		return uuid.Nil, invalid
+	}
+	ok, err := uc.creds.verify(ctx, uid, password)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !ok {
+		uc.attempts.Fail(ctx, ScopeLogin, uid, ip)
+		return uuid.Nil, invalid
+	}
+	uc.attempts.Succeed(ctx, ScopeLogin, uid)
+	return uid, nil
+}
+
+// Refresh exchanges a refresh token for a new token pair. Presenting a token
+// that was already exchanged revokes its whole family, logging out both the
+// legitimate client and whoever replayed it.
+func (uc *AuthUsecase) Refresh(ctx context.Context, token string) (*TokenPair, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz Refresh")
+	defer span.End()
+	res, err := uc.refresh(ctx, token, "")
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+// refresh exchanges a refresh token issued to clientID, empty for tokens from
+// Login.
+func (uc *AuthUsecase) refresh(ctx context.Context, token, clientID string) (*TokenPair, error) {
+	invalid := errors.Unauthorized("auth.refresh", "invalid refresh token")
+	old, err := uc.repo.FindRefreshToken(ctx, hashRefreshToken(token))
+	if errors.IsNotFound(err) {
+		return nil, invalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	// a token presented by another client is rejected before anything else,
+	// so that it cannot revoke the family either
+	var scope string
+	session, err := uc.sessions.repo.FindSession(ctx, old.FamilyID)
+	switch {
+	case errors.IsNotFound(err):
+		// issued before sessions were recorded
+		if clientID != "" {
+			return nil, invalid
+		}
+	case err != nil:
+		return nil, err
+	case session.ClientID != clientID:
+		return nil, invalid
+	default:
+		scope = session.Scope
+	}
+	if old.RevokedAt != nil {
+		uc.log.WithContext(ctx).Warnf("refresh token reuse detected for user %s, revoking family %s", old.UserID, old.FamilyID)
+		if err := uc.repo.RevokeRefreshTokenFamily(ctx, old.FamilyID); err != nil {
+			return nil, err
+		}
+		uc.sessions.forget(old.FamilyID)
+		return nil, invalid
+	}
+	if time.Now().After(old.ExpiresAt) {
+		return nil, invalid
+	}
+	if _, err := uc.users.GetByID(ctx, old.UserID.String(), ExcludeDeleted); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, invalid
+		}
+		return nil, err
+	}
+	res, err := uc.issue(ctx, old.UserID, old.FamilyID, &old.ID, scope)
+	if err != nil {
+		return nil, err
+	}
+	if err := uc.sessions.extend(ctx, old.FamilyID, res.RefreshExpiresAt); err != nil {
+		uc.log.WithContext(ctx).Warnf("failed extending session %s: %v", old.FamilyID, err)
+	}
+	return res, nil
+}
+
+// Logout ends the session of a refresh token, revoking its family. Unknown
+// tokens are ignored.
+func (uc *AuthUsecase) Logout(ctx context.Context, token string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Biz Logout")
+	defer span.End()
+	rt, err := uc.repo.FindRefreshToken(ctx, hashRefreshToken(token))
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	if err := uc.repo.RevokeRefreshTokenFamily(ctx, rt.FamilyID); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	uc.sessions.forget(rt.FamilyID)
+	return nil
+}
+
+// issue signs an access token for the user and stores a new refresh token in
+// family, rotating out the token replaced when it is set. The family is the
+// session of the tokens, and scope what an OpenID Connect client was granted.
+func (uc *AuthUsecase) issue(ctx context.Context, userID, family uuid.UUID, replaced *uuid.UUID, scope string) (*TokenPair, error) {
+	now := time.Now()
+	claims := accessClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    uc.issuer,
+			Subject:   userID.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(uc.accessTTL)),
+		},
+		SessionID: family.String(),
+		Scope:     scope,
+	}
+	if uc.audience != "" {
+		claims.Audience = jwt.ClaimStrings{uc.audience}
+	}
+	access, err := uc.signer.sign(typeAccessToken, claims)
+	if err != nil {
+		return nil, err
+	}
+	refresh, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+	rt := &RefreshToken{
+		ID:        uuid.New(),
+		FamilyID:  family,
+		UserID:    userID,
+		Hash:      hashRefreshToken(refresh),
+		ExpiresAt: now.Add(uc.refreshTTL),
+	}
+	if replaced == nil {
+		err = uc.repo.SaveRefreshToken(ctx, rt)
+	} else {
+		var rotated bool
+		rotated, err = uc.repo.RotateRefreshToken(ctx, *replaced, rt)
+		if err == nil && !rotated {
+			// lost a race against another exchange of the same token
+			uc.log.WithContext(ctx).Warnf("refresh token reuse detected for user %s, revoking family %s", userID, family)
+			if err := uc.repo.RevokeRefreshTokenFamily(ctx, family); err != nil {
+				return nil, err
+			}
+			uc.sessions.forget(family)
+			return nil, errors.Unauthorized("auth.refresh", "invalid refresh token")
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &TokenPair{
+		AccessToken:      access,
+		AccessExpiresAt:  claims.ExpiresAt.Time,
+		RefreshToken:     refresh,
+		RefreshExpiresAt: rt.ExpiresAt,
+	}, nil
+}
+
+func newRefreshToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
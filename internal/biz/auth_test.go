@@ -0,0 +1,171 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"testing"
+	"users/internal/conf"
+)
+
+func TestLogin(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin"), Email: ptr("erin@example.com")}
+	nopass := &Users{ID: uuid.NewString(), Username: ptr("frank")}
+	auth := newTestAuth(t, &conf.Auth{Keys: []*conf.Auth_Key{testSigningKey(t)}}, user, nopass)
+	auth.setPassword(t, uuid.MustParse(user.ID), "correct horse")
+
+	tests := []struct {
+		name     string
+		key      LookupKey
+		password string
+		ok       bool
+	}{
+		{"by username", LookupKey{FieldUsername, "erin"}, "correct horse", true},
+		{"by email", LookupKey{FieldEmail, " Erin@Example.com "}, "correct horse", true},
+		{"wrong password", LookupKey{FieldUsername, "erin"}, "wrong horse", false},
+		{"unknown user", LookupKey{FieldUsername, "mallory"}, "correct horse", false},
+		{"user without password", LookupKey{FieldUsername, "frank"}, "", false},
+	}
+	for _, tt := range tests {
+		pair, mfaToken, err := auth.Login(ctx, tt.key, tt.password, Client{Device: "laptop", IP: "203.0.113.5"})
+		if !tt.ok {
+			// every failure looks the same, so that it does not tell
+			// which users exist
+			if !errors.IsUnauthorized(err) || errors.Reason(err) != "auth.login" || errors.FromError(err).Message != "invalid credentials" {
+				t.Errorf("%s: err = %v, want invalid credentials", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if mfaToken != "" {
+			t.Errorf("%s: MFA token without a second factor", tt.name)
+		}
+		p, err := auth.Authenticate(ctx, pair.AccessToken)
+		if err != nil {
+			t.Errorf("%s: Authenticate: %v", tt.name, err)
+			continue
+		}
+		if p.UserID != user.ID || p.SessionID == "" {
+			t.Errorf("%s: principal %+v, want user %s with a session", tt.name, p, user.ID)
+		}
+		s, err := auth.sessions.FindSession(ctx, uuid.MustParse(p.SessionID))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if s.Device != "laptop" || s.IP != "203.0.113.5" {
+			t.Errorf("%s: session from %q at %q", tt.name, s.Device, s.IP)
+		}
+	}
+}
+
+func TestRefresh(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin")}
+	auth := newTestAuth(t, &conf.Auth{Keys: []*conf.Auth_Key{testSigningKey(t)}}, user)
+	auth.setPassword(t, uuid.MustParse(user.ID), "correct horse")
+	first, _, err := auth.Login(ctx, LookupKey{FieldUsername, "erin"}, "correct horse", Client{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	second, err := auth.Refresh(ctx, first.RefreshToken)
+	if err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	if second.RefreshToken == first.RefreshToken {
+		t.Error("the refresh token was not rotated")
+	}
+	if _, err := auth.Authenticate(ctx, second.AccessToken); err != nil {
+		t.Errorf("refreshed access token: %v", err)
+	}
+	if _, err := auth.Refresh(ctx, "unknown"); !errors.IsUnauthorized(err) {
+		t.Errorf("unknown token err = %v, want Unauthorized", err)
+	}
+
+	// the first token coming back means it leaked: the whole family goes,
+	// logging out whoever holds its latest token too
+	if _, err := auth.Refresh(ctx, first.RefreshToken); !errors.IsUnauthorized(err) {
+		t.Errorf("reused token err = %v, want Unauthorized", err)
+	}
+	if _, err := auth.Refresh(ctx, second.RefreshToken); !errors.IsUnauthorized(err) {
+		t.Errorf("latest token of a revoked family err = %v, want Unauthorized", err)
+	}
+	if _, err := auth.Authenticate(ctx, second.AccessToken); !errors.IsUnauthorized(err) {
+		t.Errorf("access token of a revoked family err = %v, want Unauthorized", err)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin")}
+	auth := newTestAuth(t, &conf.Auth{Keys: []*conf.Auth_Key{testSigningKey(t)}}, user)
+	auth.setPassword(t, uuid.MustParse(user.ID), "correct horse")
+	pair, _, err := auth.Login(ctx, LookupKey{FieldUsername, "erin"}, "correct horse", Client{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := auth.Logout(ctx, pair.RefreshToken); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.Refresh(ctx, pair.RefreshToken); !errors.IsUnauthorized(err) {
+		t.Errorf("refresh after logout err = %v, want Unauthorized", err)
+	}
+	if _, err := auth.Authenticate(ctx, pair.AccessToken); !errors.IsUnauthorized(err) {
+		t.Errorf("access token after logout err = %v, want Unauthorized", err)
+	}
+	if err := auth.Logout(ctx, "unknown"); err != nil {
+		t.Errorf("unknown token: %v", err)
+	}
+}
+
+func TestLoginLockout(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin")}
+	uid := uuid.MustParse(user.ID)
+	auth := newTestAuth(t, &conf.Auth{Keys: []*conf.Auth_Key{testSigningKey(t)}}, user)
+	auth.setPassword(t, uid, "correct horse")
+	login := func(name, password, ip string) error {
+		_, _, err := auth.Login(ctx, LookupKey{FieldUsername, name}, password, Client{IP: ip})
+		return err
+	}
+
+	for i := 0; i < defaultAccountLockoutThreshold; i++ {
+		if err := login("erin", "wrong horse", fmt.Sprintf("198.51.100.%d", i)); !errors.IsUnauthorized(err) {
+			t.Fatalf("failure %d err = %v, want Unauthorized", i+1, err)
+		}
+	}
+	// a locked account answers like an unknown one
+	locked := login("erin", "correct horse", "203.0.113.5")
+	unknown := login("mallory", "correct horse", "203.0.113.5")
+	if !errors.IsUnauthorized(locked) || errors.FromError(locked).Message != errors.FromError(unknown).Message {
+		t.Errorf("locked account err = %v, unknown user err = %v, want the same", locked, unknown)
+	}
+	// and trying on does not extend the lock
+	n := auth.attempts.failures[accountAttemptsKey(ScopeLogin, uid)]
+	if n != defaultAccountLockoutThreshold {
+		t.Errorf("%d failures counted against the locked account, want %d", n, defaultAccountLockoutThreshold)
+	}
+	auth.attempts.expire(accountAttemptsKey(ScopeLogin, uid))
+	if err := login("erin", "correct horse", "203.0.113.5"); err != nil {
+		t.Errorf("login after the lockout: %v", err)
+	}
+
+	// client IPs guessing too much are told so
+	ip := "192.0.2.7"
+	for i := 0; i < defaultIPLockoutThreshold; i++ {
+		if err := login(fmt.Sprintf("user%d", i), "guess", ip); !errors.IsUnauthorized(err) {
+			t.Fatalf("guess %d err = %v, want Unauthorized", i+1, err)
+		}
+	}
+	if err := login("erin", "correct horse", ip); errors.Code(err) != 429 || errors.Reason(err) != "auth.locked" {
+		t.Errorf("login from a locked IP err = %v, want auth.locked", err)
+	}
+	if err := login("erin", "correct horse", "203.0.113.5"); err != nil {
+		t.Errorf("login from another IP: %v", err)
+	}
+}
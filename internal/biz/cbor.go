@@ -0,0 +1,115 @@
+package biz
+
+import (
+	"errors"
+	"math"
+)
+
+// cborMaxDepth bounds the nesting of decoded CBOR items.
+const cborMaxDepth = 16
+
+var errCBOR = errors.New("malformed CBOR")
+
+// decodeCBOR decodes the first CBOR item of b and returns it along with the
+// bytes that follow it. It covers what WebAuthn attestation objects and COSE
+// keys use: integers decode to int64, byte strings to []byte, text strings
+// to string, arrays to []any and maps to map[any]any, keyed by int64 or
+// string. Tags are skipped, floats and indefinite lengths are rejected.
+func decodeCBOR(b []byte) (any, []byte, error) {
+	return decodeCBORItem(b, 0)
+}
+
+func decodeCBORItem(b []byte, depth int) (any, []byte, error) {
+	if depth > cborMaxDepth || len(b) == 0 {
+		return nil, nil, errCBOR
+	}
+	major, info := b[0]>>5, b[0]&0x1f
+	b = b[1:]
+	if major == 7 {
+		switch info {
+		case 20:
+			return false, b, nil
+		case 21:
+			return true, b, nil
+		case 22, 23:
+			return nil, b, nil
+		}
+		return nil, nil, errCBOR
+	}
+	var arg uint64
+	switch {
+	case info < 24:
+		arg = uint64(info)
+	case info <= 27:
+		n := 1 << (info - 24)
+		if len(b) < n {
+			return nil, nil, errCBOR
+		}
+		for _, c := range b[:n] {
+			arg = arg<<8 | uint64(c)
+		}
+		b = b[n:]
+	default:
+		return nil, nil, errCBOR
+	}
+
+	switch major {
+	case 0:
+		if arg > math.MaxInt64 {
+			return nil, nil, errCBOR
+		}
+		return int64(arg), b, nil
+	case 1:
+		if arg > math.MaxInt64 {
+			return nil, nil, errCBOR
+		}
+		return -1 - int64(arg), b, nil
+	case 2, 3:
+		if arg > uint64(len(b)) {
+			return nil, nil, errCBOR
+		}
+		if major == 3 {
+			return string(b[:arg]), b[arg:], nil
+		}
+		return b[:arg:arg], b[arg:], nil
+	case 4:
+		// every item takes at least a byte
+		if arg > uint64(len(b)) {
+			return nil, nil, errCBOR
+		}
+		res := make([]any, arg)
+		for i := range res {
+			var err error
+			if res[i], b, err = decodeCBORItem(b, depth+1); err != nil {
+				return nil, nil, err
+			}
+		}
+		return res, b, nil
+	case 5:
+		if arg > uint64(len(b))/2 {
+			return nil, nil, errCBOR
+		}
+		res := make(map[any]any, arg)
+		for i := uint64(0); i < arg; i++ {
+			k, rest, err := decodeCBORItem(b, depth+1)
+			if err != nil {
+				return nil, nil, err
+			}
+			switch k.(type) {
+			case int64, string:
+			default:
+				return nil, nil, errCBOR
+			}
+			if _, dup := res[k]; dup {
+				return nil, nil, errCBOR
+			}
+			if res[k], b, err = decodeCBORItem(rest, depth+1); err != nil {
+				return nil, nil, err
+			}
+		}
+		return res, b, nil
+	case 6:
+		return decodeCBORItem(b, depth+1)
+	}
+	return nil, nil, errCBOR
+}
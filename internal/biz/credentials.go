@@ -0,0 +1,276 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"unicode/utf8"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+const (
+	defaultMinPasswordLength = 8
+	// maxPasswordLength bounds the work an attacker can make the hasher do.
+	maxPasswordLength = 1024
+)
+
+type CredentialsRepo interface {
+	// FindPasswordHash returns the encoded password hash of a user, or a
+	// NotFound error when the user has no password.
+	FindPasswordHash(context.Context, uuid.UUID) (string, error)
+	// SavePasswordHash sets the encoded password hash of a user.
+	SavePasswordHash(context.Context, uuid.UUID, string) error
+	// ReplacePasswordHash sets the password hash of a user only if it is
+	// still previous, an empty previous meaning no password. It reports
+	// whether the hash was replaced.
+	ReplacePasswordHash(ctx context.Context, id uuid.UUID, previous, hash string) (bool, error)
+}
+
+// CredentialsUsecase manages user passwords. Hashes never leave this usecase
+// and the repository.
+type CredentialsUsecase struct {
+	repo      CredentialsRepo
+	users     UsersRepo
+	sessions  *SessionsUsecase
+	attempts  *AttemptLimiter
+	params    argon2Params
+	dummy     string
+	minLength int
+	log       *log.Helper
+}
+
+// NewCredentialsUsecase new a Credentials usecase.
+func NewCredentialsUsecase(repo CredentialsRepo, users UsersRepo, sessions *SessionsUsecase, attempts *AttemptLimiter, c *conf.Biz, logger log.Logger) *CredentialsUsecase {
+	minLength := int(c.GetPassword().GetMinLength())
+	if minLength <= 0 {
+		minLength = defaultMinPasswordLength
+	}
+	params := newArgon2Params(c.GetPassword())
+	return &CredentialsUsecase{
+		repo:      repo,
+		users:     users,
+		sessions:  sessions,
+		attempts:  attempts,
+		params:    params,
+		dummy:     dummyHash(params),
+		minLength: minLength,
+		log:       log.NewHelper(logger),
+	}
+}
+
+func (uc *CredentialsUsecase) checkPassword(reason, password string) error {
+	n := utf8.RuneCountInString(password)
+	if n < uc.minLength {
+		return errors.BadRequest(reason, fmt.Sprintf("password must be at least %d characters", uc.minLength))
+	}
+	if len(password) > maxPasswordLength {
+		return errors.BadRequest(reason, fmt.Sprintf("password must be at most %d bytes", maxPasswordLength))
+	}
+	return nil
+}
+
+// SetPassword sets the password of a live user, replacing any previous one,
+// and ends the sessions of the user but the caller's.
+func (uc *CredentialsUsecase) SetPassword(ctx context.Context, id, password string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Biz SetPassword")
+	defer span.End()
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	if err := uc.checkPassword("users.setPassword", password); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	if _, err := uc.users.FindByID(ctx, uid, ExcludeDeleted); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	if err := uc.savePassword(ctx, uid, password); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	if err := uc.endSessions(ctx, uid); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
+
+// ChangePassword replaces the password of a user after checking the current
+// one, and ends the other sessions of the user. Wrong current passwords count
+// against the login lockout of the user.
+func (uc *CredentialsUsecase) ChangePassword(ctx context.Context, id, current, password string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Biz ChangePassword")
+	defer span.End()
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	if err := uc.checkPassword("users.changePassword", password); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	if err := uc.attempts.Check(ctx, ScopeLogin, uid, ""); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	incorrect := errors.Unauthorized("users.changePassword", "current password is incorrect")
+	encoded, ok, _, err := uc.match(ctx, uid, current)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	if !ok {
+		uc.attempts.Fail(ctx, ScopeLogin, uid, "")
+		span.AddEvent(incorrect.Error())
+		return incorrect
+	}
+	uc.attempts.Succeed(ctx, ScopeLogin, uid)
+	// swapping against the hash that matched keeps a change that happened
+	// meanwhile from being overwritten with a password checked against the
+	// one it replaced
+	ok, err = uc.replacePassword(ctx, uid, encoded, password)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	if !ok {
+		span.AddEvent(incorrect.Error())
+		return incorrect
+	}
+	if err := uc.endSessions(ctx, uid); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
+
+// VerifyPassword reports whether password is the password of a live user.
+// Users without a password never verify. A matching hash made with weaker
+// parameters than the configured ones is replaced by a fresh hash. Mismatches
+// count against the login lockout of the user.
+func (uc *CredentialsUsecase) VerifyPassword(ctx context.Context, id, password string) (bool, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz VerifyPassword")
+	defer span.End()
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return false, err
+	}
+	if err := uc.attempts.Check(ctx, ScopeLogin, uid, ""); err != nil {
+		span.AddEvent(err.Error())
+		return false, err
+	}
+	ok, err := uc.verify(ctx, uid, password)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return false, err
+	}
+	if !ok {
+		uc.attempts.Fail(ctx, ScopeLogin, uid, "")
+		return false, nil
+	}
+	uc.attempts.Succeed(ctx, ScopeLogin, uid)
+	return true, nil
+}
+
+func (uc *CredentialsUsecase) verify(ctx context.Context, uid uuid.UUID, password string) (bool, error) {
+	encoded, ok, rehash, err := uc.match(ctx, uid, password)
+	if err != nil {
+		return false, err
+	}
+	if ok && rehash {
+		// the password itself is fine, so a failed upgrade must not fail
+		// the verification; it will be retried on the next one. The swap
+		// does nothing when the password changed since it was read.
+		if _, err := uc.replacePassword(ctx, uid, encoded, password); err != nil {
+			uc.log.WithContext(ctx).Warnf("failed rehashing password of user %s: %v", uid, err)
+		}
+	}
+	return ok, nil
+}
+
+// match reports whether password is the one of a live user, along with the
+// hash it matched and whether that hash was made with weaker parameters than
+// the configured ones.
+func (uc *CredentialsUsecase) match(ctx context.Context, uid uuid.UUID, password string) (string, bool, bool, error) {
+	if len(password) > maxPasswordLength {
+		return "", false, false, nil
+	}
+	if _, err := uc.users.FindByID(ctx, uid, ExcludeDeleted); err != nil {
+		return "", false, false, err
+	}
+	encoded, err := uc.repo.FindPasswordHash(ctx, uid)
+	if errors.IsNotFound(err) {
+		uc.burn(password)
+		return "", false, false, nil
+	}
+	if err != nil {
+		return "", false, false, err
+	}
+	ok, rehash, err := verifyPassword(password, encoded, uc.params)
+	if err != nil {
+		uc.log.WithContext(ctx).Errorf("unreadable password hash for user %s: %v", uid, err)
+		return "", false, false, errors.InternalServer("users.verifyPassword", "unreadable password hash")
+	}
+	return encoded, ok, rehash, nil
+}
+
+// burn does the work of verifying password for a user who has none.
+func (uc *CredentialsUsecase) burn(password string) {
+	if len(password) > maxPasswordLength {
+		return
+	}
+	_, _, _ = verifyPassword(password, uc.dummy, uc.params)
+}
+
+func (uc *CredentialsUsecase) savePassword(ctx context.Context, uid uuid.UUID, password string) error {
+	encoded, err := hashPassword(password, uc.params)
+	if err != nil {
+		return err
+	}
+	return uc.repo.SavePasswordHash(ctx, uid, encoded)
+}
+
+// endSessions ends the sessions and refresh tokens of a user whose password
+// changed, so that whoever knew the previous one is logged out. The session
+// of the caller is kept when it is the user's own.
+func (uc *CredentialsUsecase) endSessions(ctx context.Context, uid uuid.UUID) error {
+	var keep string
+	if p, ok := PrincipalFromContext(ctx); ok && p.UserID == uid.String() {
+		keep = p.SessionID
+	}
+	n, err := uc.sessions.RevokeAllSessions(ctx, uid.String(), keep)
+	if err != nil {
+		return err
+	}
+	uc.log.WithContext(ctx).Infof("password of user %s changed, ended %d sessions", uid, n)
+	return nil
+}
+
+// currentHash returns the password hash of a user, empty when the user has
+// no password.
+func (uc *CredentialsUsecase) currentHash(ctx context.Context, uid uuid.UUID) (string, error) {
+	encoded, err := uc.repo.FindPasswordHash(ctx, uid)
+	if errors.IsNotFound(err) {
+		return "", nil
+	}
+	return encoded, err
+}
+
+// replacePassword sets a new password unless the hash changed from previous
+// in the meantime, which makes tokens bound to previous single-use.
+func (uc *CredentialsUsecase) replacePassword(ctx context.Context, uid uuid.UUID, previous, password string) (bool, error) {
+	encoded, err := hashPassword(password, uc.params)
+	if err != nil {
+		return false, err
+	}
+	return uc.repo.ReplacePasswordHash(ctx, uid, previous, encoded)
+}
@@ -0,0 +1,267 @@
+package biz
+
+import (
+	"context"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"strings"
+	"testing"
+	"time"
+	"users/internal/conf"
+)
+
+// startSession gives a user an active session.
+func (ta *testAuth) startSession(t *testing.T, uid uuid.UUID) uuid.UUID {
+	t.Helper()
+	s, err := ta.sessions.SaveSession(context.Background(), &Session{ID: uuid.New(), UserID: uid, ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.ID
+}
+
+// active reports whether a session is still active.
+func (ta *testAuth) active(t *testing.T, id uuid.UUID) bool {
+	t.Helper()
+	s, err := ta.sessions.FindSession(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s.active(time.Now())
+}
+
+func TestCheckPassword(t *testing.T) {
+	auth := newTestAuth(t, &conf.Auth{})
+	tests := []struct {
+		password string
+		ok       bool
+	}{
+		{"", false},
+		{"1234567", false},
+		{"12345678", true},
+		// characters are counted, not bytes
+		{"pässwör", false},
+		{"pässwörd", true},
+		{strings.Repeat("a", maxPasswordLength), true},
+		{strings.Repeat("a", maxPasswordLength+1), false},
+	}
+	for _, tt := range tests {
+		err := auth.creds.checkPassword("users.setPassword", tt.password)
+		if tt.ok && err != nil {
+			t.Errorf("checkPassword(%q): %v", tt.password, err)
+		}
+		if !tt.ok && !errors.IsBadRequest(err) {
+			t.Errorf("checkPassword(%q) err = %v, want BadRequest", tt.password, err)
+		}
+	}
+}
+
+func TestVerifyPassword(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin")}
+	nopass := &Users{ID: uuid.NewString(), Username: ptr("frank")}
+	auth := newTestAuth(t, &conf.Auth{}, user, nopass)
+	auth.setPassword(t, uuid.MustParse(user.ID), "correct horse")
+	tests := []struct {
+		name, id, password string
+		ok                 bool
+		notFound           bool
+	}{
+		{"right password", user.ID, "correct horse", true, false},
+		{"wrong password", user.ID, "wrong horse", false, false},
+		{"too long", user.ID, strings.Repeat("a", maxPasswordLength+1), false, false},
+		{"no password", nopass.ID, "correct horse", false, false},
+		{"unknown user", uuid.NewString(), "correct horse", false, true},
+	}
+	for _, tt := range tests {
+		ok, err := auth.creds.VerifyPassword(ctx, tt.id, tt.password)
+		if tt.notFound {
+			if !errors.IsNotFound(err) {
+				t.Errorf("%s: err = %v, want NotFound", tt.name, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if ok != tt.ok {
+			t.Errorf("%s: VerifyPassword = %t, want %t", tt.name, ok, tt.ok)
+		}
+	}
+}
+
+func TestVerifyPasswordRehashes(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin")}
+	uid := uuid.MustParse(user.ID)
+	weak := argon2Params{Time: 1, Memory: 32, Threads: 1, KeyLength: 32, SaltLength: 16}
+
+	tests := []struct {
+		name string
+		// concurrent changes the password while the rehash is underway
+		concurrent bool
+	}{
+		{"rehashed", false},
+		{"changed meanwhile", true},
+	}
+	for _, tt := range tests {
+		auth := newTestAuth(t, &conf.Auth{}, user)
+		old, err := hashPassword("correct horse", weak)
+		if err != nil {
+			t.Fatal(err)
+		}
+		auth.hashes.hashes[uid] = old
+		changed, err := hashPassword("battery staple", auth.creds.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tt.concurrent {
+			auth.hashes.beforeReplace = func() {
+				auth.hashes.mu.Lock()
+				defer auth.hashes.mu.Unlock()
+				auth.hashes.hashes[uid] = changed
+			}
+		}
+		ok, err := auth.creds.VerifyPassword(ctx, user.ID, "correct horse")
+		if err != nil || !ok {
+			t.Fatalf("%s: VerifyPassword = %t, %v", tt.name, ok, err)
+		}
+		stored := auth.hashes.hashes[uid]
+		if tt.concurrent {
+			if stored != changed {
+				t.Errorf("%s: the rehash overwrote the password set meanwhile", tt.name)
+			}
+			continue
+		}
+		p, _, _, err := decodeHash(stored)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p != auth.creds.params {
+			t.Errorf("%s: hash made with %+v, want %+v", tt.name, p, auth.creds.params)
+		}
+		if ok, _, _ := verifyPassword("correct horse", stored, auth.creds.params); !ok {
+			t.Errorf("%s: the password no longer matches its new hash", tt.name)
+		}
+	}
+}
+
+func TestChangePassword(t *testing.T) {
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin")}
+	uid := uuid.MustParse(user.ID)
+	auth := newTestAuth(t, &conf.Auth{}, user)
+	auth.setPassword(t, uid, "correct horse")
+	own := auth.startSession(t, uid)
+	other := auth.startSession(t, uid)
+	ctx := NewPrincipalContext(context.Background(), &Principal{Type: PrincipalUser, UserID: user.ID, SessionID: own.String()})
+
+	if err := auth.creds.ChangePassword(ctx, user.ID, "wrong horse", "battery staple"); !errors.IsUnauthorized(err) {
+		t.Errorf("wrong current password err = %v, want Unauthorized", err)
+	}
+	if err := auth.creds.ChangePassword(ctx, user.ID, "correct horse", "short"); !errors.IsBadRequest(err) {
+		t.Errorf("short new password err = %v, want BadRequest", err)
+	}
+	if !auth.active(t, other) {
+		t.Fatal("a failed change ended a session")
+	}
+	if err := auth.creds.ChangePassword(ctx, user.ID, "correct horse", "battery staple"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := auth.creds.VerifyPassword(ctx, user.ID, "battery staple"); !ok {
+		t.Error("the new password does not verify")
+	}
+	if ok, _ := auth.creds.VerifyPassword(ctx, user.ID, "correct horse"); ok {
+		t.Error("the old password still verifies")
+	}
+	if !auth.active(t, own) {
+		t.Error("the change ended the session it was made from")
+	}
+	if auth.active(t, other) {
+		t.Error("the change left another session active")
+	}
+}
+
+func TestChangePasswordChangedMeanwhile(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin")}
+	uid := uuid.MustParse(user.ID)
+	auth := newTestAuth(t, &conf.Auth{}, user)
+	auth.setPassword(t, uid, "correct horse")
+	reset, err := hashPassword("set by a reset", auth.creds.params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	auth.hashes.beforeReplace = func() {
+		auth.hashes.mu.Lock()
+		defer auth.hashes.mu.Unlock()
+		auth.hashes.hashes[uid] = reset
+	}
+	if err := auth.creds.ChangePassword(ctx, user.ID, "correct horse", "battery staple"); !errors.IsUnauthorized(err) {
+		t.Errorf("change racing a reset err = %v, want Unauthorized", err)
+	}
+	if auth.hashes.hashes[uid] != reset {
+		t.Error("the change overwrote the password set meanwhile")
+	}
+}
+
+func TestSetPasswordEndsSessions(t *testing.T) {
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin")}
+	uid := uuid.MustParse(user.ID)
+	auth := newTestAuth(t, &conf.Auth{}, user)
+	auth.setPassword(t, uid, "correct horse")
+	sessions := []uuid.UUID{auth.startSession(t, uid), auth.startSession(t, uid)}
+	// set by an admin, whose own session has nothing to do with the user
+	ctx := NewPrincipalContext(context.Background(), &Principal{Type: PrincipalUser, UserID: uuid.NewString(), SessionID: sessions[0].String()})
+	if err := auth.creds.SetPassword(ctx, user.ID, "battery staple"); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range sessions {
+		if auth.active(t, id) {
+			t.Errorf("session %s outlived the password", id)
+		}
+	}
+	if err := auth.creds.SetPassword(ctx, uuid.NewString(), "battery staple"); !errors.IsNotFound(err) {
+		t.Errorf("unknown user err = %v, want NotFound", err)
+	}
+}
+
+func TestPasswordChecksLockOut(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin")}
+	uid := uuid.MustParse(user.ID)
+	tests := []struct {
+		name  string
+		check func(*testAuth, string) error
+	}{
+		{"VerifyPassword", func(auth *testAuth, password string) error {
+			ok, err := auth.creds.VerifyPassword(ctx, user.ID, password)
+			if err == nil && !ok {
+				err = errors.Unauthorized("test", "mismatch")
+			}
+			return err
+		}},
+		{"ChangePassword", func(auth *testAuth, password string) error {
+			return auth.creds.ChangePassword(ctx, user.ID, password, "correct horse")
+		}},
+	}
+	for _, tt := range tests {
+		auth := newTestAuth(t, &conf.Auth{}, user)
+		auth.setPassword(t, uid, "correct horse")
+		for i := 0; i < defaultAccountLockoutThreshold; i++ {
+			if err := tt.check(auth, "wrong horse"); !errors.IsUnauthorized(err) {
+				t.Fatalf("%s: failure %d err = %v, want Unauthorized", tt.name, i+1, err)
+			}
+		}
+		if err := tt.check(auth, "correct horse"); errors.Code(err) != 429 {
+			t.Errorf("%s: right password while locked err = %v, want 429", tt.name, err)
+		}
+		auth.attempts.expire(accountAttemptsKey(ScopeLogin, uid))
+		if err := tt.check(auth, "correct horse"); err != nil {
+			t.Errorf("%s: right password after the lockout: %v", tt.name, err)
+		}
+		if n := auth.attempts.failures[accountAttemptsKey(ScopeLogin, uid)]; n != 0 {
+			t.Errorf("%s: %d failures left after a success", tt.name, n)
+		}
+	}
+}
@@ -0,0 +1,511 @@
+package biz
+
+import (
+	"context"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"regexp"
+	"sync"
+	"testing"
+	"time"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"go.opentelemetry.io/otel/metric/noop"
+)
+
+// The repositories below keep their rows in memory. They embed the
+// interface they fake, so that a test reaching a method nobody implemented
+// panics instead of passing by accident.
+
+type memUsers struct {
+	UsersRepo
+	mu    sync.Mutex
+	users map[uuid.UUID]*Users
+}
+
+func newMemUsers(users ...*Users) *memUsers {
+	r := &memUsers{users: make(map[uuid.UUID]*Users)}
+	for _, u := range users {
+		r.users[uuid.MustParse(u.ID)] = u
+	}
+	return r
+}
+
+func (r *memUsers) Save(_ context.Context, u *Users) (*Users, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved := *u
+	saved.ID = uuid.NewString()
+	r.users[uuid.MustParse(saved.ID)] = &saved
+	return &saved, nil
+}
+
+func (r *memUsers) FindByID(_ context.Context, id uuid.UUID, _ DeletedScope) (*Users, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok {
+		return nil, errors.NotFound("users.notFound", "user not found")
+	}
+	return u, nil
+}
+
+func (r *memUsers) FindByKey(_ context.Context, key LookupKey) (*Users, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, u := range r.users {
+		if key.Field == FieldPhone && u.Phone != nil && *u.Phone == key.Value {
+			return u, nil
+		}
+		if key.Field == FieldEmail && u.Email != nil && *u.Email == key.Value {
+			return u, nil
+		}
+		if key.Field == FieldUsername && u.Username != nil && *u.Username == key.Value {
+			return u, nil
+		}
+	}
+	return nil, errors.NotFound("users.notFound", "user not found")
+}
+
+func (r *memUsers) MarkEmailVerified(_ context.Context, id uuid.UUID, email string) (*Users, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok || u.Email == nil || *u.Email != email {
+		return nil, errors.NotFound("users.notFound", "user not found")
+	}
+	u.EmailVerifiedAt = ptr(time.Now())
+	return u, nil
+}
+
+func (r *memUsers) MarkPhoneVerified(_ context.Context, id uuid.UUID, phone string) (*Users, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	u, ok := r.users[id]
+	if !ok || u.Phone == nil || *u.Phone != phone {
+		return nil, errors.NotFound("users.notFound", "user not found")
+	}
+	u.PhoneVerifiedAt = ptr(time.Now())
+	return u, nil
+}
+
+type memSessions struct {
+	SessionsRepo
+	mu       sync.Mutex
+	sessions map[uuid.UUID]*Session
+}
+
+func (r *memSessions) SaveSession(_ context.Context, s *Session) (*Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved := *s
+	r.sessions[s.ID] = &saved
+	return s, nil
+}
+
+func (r *memSessions) FindSession(_ context.Context, id uuid.UUID) (*Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.sessions[id]
+	if !ok {
+		return nil, errors.NotFound("auth.session", "session not found")
+	}
+	found := *s
+	return &found, nil
+}
+
+func (r *memSessions) TouchSession(context.Context, uuid.UUID, time.Time, *time.Time) error {
+	return nil
+}
+
+func (r *memSessions) ListSessions(_ context.Context, userID uuid.UUID) ([]Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var res []Session
+	for _, s := range r.sessions {
+		if s.UserID == userID && s.active(time.Now()) {
+			res = append(res, *s)
+		}
+	}
+	return res, nil
+}
+
+func (r *memSessions) RevokeSession(_ context.Context, userID, id uuid.UUID) (*Session, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s, ok := r.sessions[id]
+	if !ok || s.UserID != userID || !s.active(time.Now()) {
+		return nil, errors.NotFound("auth.session", "session not found")
+	}
+	s.RevokedAt = ptr(time.Now())
+	revoked := *s
+	return &revoked, nil
+}
+
+func (r *memSessions) RevokeSessions(_ context.Context, userID uuid.UUID, except *uuid.UUID) ([]uuid.UUID, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var ids []uuid.UUID
+	for id, s := range r.sessions {
+		if s.UserID == userID && s.active(time.Now()) && (except == nil || id != *except) {
+			s.RevokedAt = ptr(time.Now())
+			ids = append(ids, id)
+		}
+	}
+	return ids, nil
+}
+
+// memRefreshTokens ends the sessions of the families it revokes in sessions.
+type memRefreshTokens struct {
+	RefreshTokenRepo
+	mu       sync.Mutex
+	tokens   map[string]*RefreshToken
+	sessions *memSessions
+}
+
+func (r *memRefreshTokens) SaveRefreshToken(_ context.Context, rt *RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.tokens[rt.Hash] = rt
+	return nil
+}
+
+func (r *memRefreshTokens) FindRefreshToken(_ context.Context, hash string) (*RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	rt, ok := r.tokens[hash]
+	if !ok {
+		return nil, errors.NotFound("auth.refresh", "refresh token not found")
+	}
+	found := *rt
+	return &found, nil
+}
+
+func (r *memRefreshTokens) RotateRefreshToken(_ context.Context, old uuid.UUID, next *RefreshToken) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rt := range r.tokens {
+		if rt.ID == old {
+			if rt.RevokedAt != nil {
+				return false, nil
+			}
+			rt.RevokedAt = ptr(time.Now())
+			r.tokens[next.Hash] = next
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (r *memRefreshTokens) RevokeRefreshTokenFamily(_ context.Context, family uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, rt := range r.tokens {
+		if rt.FamilyID == family && rt.RevokedAt == nil {
+			rt.RevokedAt = ptr(time.Now())
+		}
+	}
+	r.sessions.mu.Lock()
+	defer r.sessions.mu.Unlock()
+	if s, ok := r.sessions.sessions[family]; ok && s.RevokedAt == nil {
+		s.RevokedAt = ptr(time.Now())
+	}
+	return nil
+}
+
+type memCredentials struct {
+	CredentialsRepo
+	mu     sync.Mutex
+	hashes map[uuid.UUID]string
+	// beforeReplace runs before a hash is swapped, e.g. to change it
+	// meanwhile
+	beforeReplace func()
+}
+
+func (r *memCredentials) FindPasswordHash(_ context.Context, id uuid.UUID) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	h, ok := r.hashes[id]
+	if !ok {
+		return "", errors.NotFound("users.password", "no password set")
+	}
+	return h, nil
+}
+
+func (r *memCredentials) SavePasswordHash(_ context.Context, id uuid.UUID, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.hashes[id] = hash
+	return nil
+}
+
+func (r *memCredentials) ReplacePasswordHash(_ context.Context, id uuid.UUID, previous, hash string) (bool, error) {
+	if r.beforeReplace != nil {
+		r.beforeReplace()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.hashes[id] != previous {
+		return false, nil
+	}
+	r.hashes[id] = hash
+	return true, nil
+}
+
+type memMFA struct {
+	MFARepo
+	mu       sync.Mutex
+	totp     map[uuid.UUID]*TOTP
+	attempts map[uuid.UUID]int
+}
+
+func (r *memMFA) FindTOTP(_ context.Context, id uuid.UUID) (*TOTP, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.totp[id]
+	if !ok {
+		return nil, errors.NotFound("users.mfa", "TOTP is not enabled")
+	}
+	found := *t
+	return &found, nil
+}
+
+func (r *memMFA) UseTOTPStep(_ context.Context, id uuid.UUID, step int64) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	t, ok := r.totp[id]
+	if !ok || t.LastStep >= step {
+		return false, nil
+	}
+	t.LastStep = step
+	return true, nil
+}
+
+// UseTOTPAttempt counts attempts until they are reset, whatever window.
+func (r *memMFA) UseTOTPAttempt(_ context.Context, id uuid.UUID, limit int, _ time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts[id]++
+	return r.attempts[id] <= limit, nil
+}
+
+func (r *memMFA) ResetTOTPAttempts(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.attempts, id)
+	return nil
+}
+
+// memAttempts counts failures and keeps locks until they are reset, ignoring
+// their ttl.
+type memAttempts struct {
+	mu       sync.Mutex
+	failures map[string]int
+	locks    map[string]time.Time
+}
+
+func newMemAttempts() *memAttempts {
+	return &memAttempts{failures: make(map[string]int), locks: make(map[string]time.Time)}
+}
+
+func (s *memAttempts) Fail(_ context.Context, key string, _ time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[key]++
+	return s.failures[key], nil
+}
+
+func (s *memAttempts) Lock(_ context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.locks[key] = until
+	return nil
+}
+
+func (s *memAttempts) LockedUntil(_ context.Context, key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.locks[key], nil
+}
+
+func (s *memAttempts) Reset(_ context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		delete(s.failures, key)
+		delete(s.locks, key)
+	}
+	return nil
+}
+
+// expire ends the lock of key as if its time had passed.
+func (s *memAttempts) expire(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.locks, key)
+}
+
+// memMailer hands sent mail to the test.
+type memMailer struct {
+	sent chan *Mail
+}
+
+func newMemMailer() *memMailer {
+	return &memMailer{sent: make(chan *Mail, 16)}
+}
+
+func (m *memMailer) Send(_ context.Context, mail *Mail) error {
+	m.sent <- mail
+	return nil
+}
+
+// next returns the next mail sent, waiting a while for mail sent in the
+// background.
+func (m *memMailer) next(t *testing.T) *Mail {
+	t.Helper()
+	select {
+	case mail := <-m.sent:
+		return mail
+	case <-time.After(5 * time.Second):
+		t.Fatal("no mail sent")
+		return nil
+	}
+}
+
+// none fails when mail was sent, waiting a little for mail sent in the
+// background.
+func (m *memMailer) none(t *testing.T) {
+	t.Helper()
+	select {
+	case mail := <-m.sent:
+		t.Errorf("mail sent to %s: %s", mail.To, mail.Subject)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+// memSMS hands sent text messages to the test, as mail without subject.
+type memSMS struct {
+	memMailer
+}
+
+func newMemSMS() *memSMS {
+	return &memSMS{*newMemMailer()}
+}
+
+func (m *memSMS) Send(ctx context.Context, to, body string) error {
+	return m.memMailer.Send(ctx, &Mail{To: to, Body: body})
+}
+
+// testToken returns the token of a link to https://example.com/?token= in
+// body.
+func testToken(t *testing.T, body string) string {
+	t.Helper()
+	m := regexp.MustCompile(`https://example\.com/\?token=(\S+)`).FindStringSubmatch(body)
+	if m == nil {
+		t.Fatalf("no link in %q", body)
+	}
+	return m[1]
+}
+
+// testCode returns the six digit code in body.
+func testCode(t *testing.T, body string) string {
+	t.Helper()
+	m := regexp.MustCompile(`\b\d{6}\b`).FindString(body)
+	if m == "" {
+		t.Fatalf("no code in %q", body)
+	}
+	return m
+}
+
+// testAuth is an AuthUsecase over in-memory repositories.
+type testAuth struct {
+	*AuthUsecase
+	users    *memUsers
+	sessions *memSessions
+	tokens   *memRefreshTokens
+	hashes   *memCredentials
+	attempts *memAttempts
+	mfaRepo  *memMFA
+	mfa      *MFAUsecase
+}
+
+// testPassword keeps hashing cheap in tests.
+var testPassword = &conf.Biz_Password{Time: 1, MemoryKib: 64, Threads: 1}
+
+// setPassword gives a user a password.
+func (ta *testAuth) setPassword(t *testing.T, uid uuid.UUID, password string) {
+	t.Helper()
+	if err := ta.creds.savePassword(context.Background(), uid, password); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// testEncryptionKey seals the TOTP secrets of tests.
+const testEncryptionKey = "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="
+
+// enableTOTP gives a user a confirmed TOTP and returns its secret.
+func (ta *testAuth) enableTOTP(t *testing.T, uid uuid.UUID) []byte {
+	t.Helper()
+	secret := []byte("12345678901234567890")
+	sealed, err := ta.mfa.sealer.seal(secret, uid[:])
+	if err != nil {
+		t.Fatal(err)
+	}
+	ta.mfaRepo.mu.Lock()
+	defer ta.mfaRepo.mu.Unlock()
+	ta.mfaRepo.totp[uid] = &TOTP{UserID: uid, Secret: sealed, ConfirmedAt: ptr(time.Now())}
+	return secret
+}
+
+func newTestAuth(t *testing.T, c *conf.Auth, users ...*Users) *testAuth {
+	t.Helper()
+	logger := log.NewStdLogger(testWriter{t})
+	ta := &testAuth{
+		users:    newMemUsers(users...),
+		sessions: &memSessions{sessions: make(map[uuid.UUID]*Session)},
+		hashes:   &memCredentials{hashes: make(map[uuid.UUID]string)},
+		attempts: newMemAttempts(),
+		mfaRepo:  &memMFA{totp: make(map[uuid.UUID]*TOTP), attempts: make(map[uuid.UUID]int)},
+	}
+	ta.tokens = &memRefreshTokens{tokens: make(map[string]*RefreshToken), sessions: ta.sessions}
+	proxies, err := NewTrustedProxies(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bc := &conf.Biz{Mfa: &conf.Biz_Mfa{EncryptionKey: testEncryptionKey}, Password: testPassword}
+	attempts, err := NewAttemptLimiter(ta.attempts, ta.users, ta.mfaRepo, bc, noop.NewMeterProvider().Meter("test"), logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ta.mfa, err = NewMFAUsecase(ta.mfaRepo, ta.users, attempts, bc, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	usersUsecase, err := NewUsersUsecase(ta.users, bc, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sessions := NewSessionsUsecase(ta.sessions, ta.users, c, logger)
+	creds := NewCredentialsUsecase(ta.hashes, ta.users, sessions, attempts, bc, logger)
+	ta.AuthUsecase, err = NewAuthUsecase(usersUsecase, creds, ta.tokens, sessions, attempts, ta.mfa, proxies, c, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ta
+}
+
+// testWriter sends logs to the test log.
+type testWriter struct {
+	t *testing.T
+}
+
+func (w testWriter) Write(p []byte) (int, error) {
+	w.t.Helper()
+	w.t.Log(string(p))
+	return len(p), nil
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -0,0 +1,594 @@
+package biz
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// FederationPath prefixes the endpoints of federated login, which are
+// <FederationPath><provider>/login and <FederationPath><provider>/callback.
+const FederationPath = "/oauth2/federation/"
+
+const (
+	purposeFederation = "federation"
+	// FederationFlowTTL bounds how long a login can take at the provider.
+	FederationFlowTTL = 10 * time.Minute
+	// upstreamMetadataTTL is how long discovery documents and keys of
+	// providers are cached.
+	upstreamMetadataTTL = time.Hour
+	// upstreamKeysMinAge stops tokens with unknown key IDs from making us
+	// fetch the keys of a provider over and over.
+	upstreamKeysMinAge = time.Minute
+)
+
+var defaultFederationScopes = []string{"profile", "email"}
+
+// ExternalIdentity links an account at an upstream OpenID Connect provider,
+// known by its issuer and subject, to a user.
+type ExternalIdentity struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Provider    string
+	Issuer      string
+	Subject     string
+	Email       string
+	CreatedAt   time.Time
+	LastLoginAt time.Time
+}
+
+type ExternalIdentitiesRepo interface {
+	// SaveIdentity links an identity, returning a Conflict error when it is
+	// linked already.
+	SaveIdentity(context.Context, *ExternalIdentity) (*ExternalIdentity, error)
+	// FindIdentity returns the identity with the given issuer and subject,
+	// or a NotFound error.
+	FindIdentity(ctx context.Context, issuer, subject string) (*ExternalIdentity, error)
+	// ListIdentities returns the identities of a user, oldest first.
+	ListIdentities(context.Context, uuid.UUID) ([]ExternalIdentity, error)
+	// DeleteIdentity unlinks an identity of a user, returning a NotFound
+	// error when there is none.
+	DeleteIdentity(ctx context.Context, userID, id uuid.UUID) error
+	// TouchIdentity records a login with an identity.
+	TouchIdentity(ctx context.Context, id uuid.UUID, at time.Time) error
+}
+
+// UpstreamMetadata is the part of the discovery document of an upstream
+// provider federated login uses.
+type UpstreamMetadata struct {
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+}
+
+// Upstream talks to upstream OpenID Connect providers.
+type Upstream interface {
+	// Discover reads the discovery document of issuer.
+	Discover(ctx context.Context, issuer string) (*UpstreamMetadata, error)
+	// FetchJWKS reads the JWKS document at uri.
+	FetchJWKS(ctx context.Context, uri string) ([]byte, error)
+	// ExchangeCode redeems an authorization code at a token endpoint,
+	// authenticating with HTTP basic authentication, and returns the ID
+	// token.
+	ExchangeCode(ctx context.Context, endpoint, clientID, clientSecret string, form url.Values) (string, error)
+}
+
+// FederationProvider is an upstream provider as shown on the login page.
+type FederationProvider struct {
+	Name        string
+	DisplayName string
+}
+
+// FederatedLogin is the outcome of a callback from an upstream provider.
+type FederatedLogin struct {
+	Session *Session
+	// Cookie is the value of the login cookie of a new session, empty when
+	// the identity was linked to the session the browser had.
+	Cookie string
+	// PendingMFA is set instead of Session and Cookie when the user has a
+	// second factor to prove, see VerifyBrowserMFA.
+	PendingMFA string
+	ReturnTo   string
+	Linked     bool
+}
+
+// upstreamClaims are the claims read from ID tokens of upstream providers.
+type upstreamClaims struct {
+	jwt.RegisteredClaims
+	Nonce             string `json:"nonce"`
+	Email             string `json:"email"`
+	EmailVerified     bool   `json:"email_verified"`
+	PreferredUsername string `json:"preferred_username"`
+	Picture           string `json:"picture"`
+}
+
+// upstreamProvider caches the discovery document and keys of a provider.
+type upstreamProvider struct {
+	conf *conf.Federation_Provider
+
+	mu        sync.Mutex
+	meta      *UpstreamMetadata
+	keys      *tokenSigner
+	fetchedAt time.Time
+}
+
+// FederationUsecase logs browsers in to the OpenID Connect provider with
+// accounts at upstream providers. Identities are linked to users on their
+// first login: to the user the browser is logged in as when linking, to the
+// user with the same verified email when the provider allows it, or to a
+// new user.
+type FederationUsecase struct {
+	providers map[string]*upstreamProvider
+	order     []string
+	oidc      *OIDCUsecase
+	users     *UsersUsecase
+	creds     *CredentialsUsecase
+	repo      ExternalIdentitiesRepo
+	upstream  Upstream
+	log       *log.Helper
+}
+
+// NewFederationUsecase new a Federation usecase.
+func NewFederationUsecase(oidc *OIDCUsecase, users *UsersUsecase, creds *CredentialsUsecase, repo ExternalIdentitiesRepo, upstream Upstream, c *conf.Federation, logger log.Logger) (*FederationUsecase, error) {
+	helper := log.NewHelper(logger)
+	uc := &FederationUsecase{
+		providers: make(map[string]*upstreamProvider),
+		oidc:      oidc,
+		users:     users,
+		creds:     creds,
+		repo:      repo,
+		upstream:  upstream,
+		log:       helper,
+	}
+	for _, p := range c.GetProviders() {
+		name := p.GetName()
+		if name == "" || strings.ContainsAny(name, "/?#") || url.PathEscape(name) != name {
+			return nil, fmt.Errorf("federation: invalid provider name %q", name)
+		}
+		if _, ok := uc.providers[name]; ok {
+			return nil, fmt.Errorf("federation: duplicate provider %q", name)
+		}
+		if p.GetIssuer() == "" || p.GetClientId() == "" {
+			return nil, fmt.Errorf("federation: provider %q needs an issuer and a client ID", name)
+		}
+		uc.providers[name] = &upstreamProvider{conf: p}
+		uc.order = append(uc.order, name)
+	}
+	if len(uc.order) > 0 && !oidc.Enabled() {
+		helper.Warn("federation providers are configured but the OpenID Connect endpoints are not served, see auth.issuer")
+	}
+	return uc, nil
+}
+
+// Providers lists the configured providers, in configuration order.
+func (uc *FederationUsecase) Providers() []FederationProvider {
+	if !uc.oidc.Enabled() {
+		return nil
+	}
+	res := make([]FederationProvider, 0, len(uc.order))
+	for _, name := range uc.order {
+		p := uc.providers[name].conf
+		display := p.GetDisplayName()
+		if display == "" {
+			display = name
+		}
+		res = append(res, FederationProvider{Name: name, DisplayName: display})
+	}
+	return res
+}
+
+// StartLogin starts a login at provider, to come back to returnTo, a path on
+// this server. When linking, the identity is linked to the user of session
+// instead. It returns where to send the browser along with the value of the
+// cookie that ties the callback to it.
+func (uc *FederationUsecase) StartLogin(ctx context.Context, provider, returnTo string, link *Session) (string, string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz StartLogin")
+	defer span.End()
+	p, ok := uc.providers[provider]
+	if !ok {
+		err := errors.NotFound("federation.provider", "unknown provider")
+		span.AddEvent(err.Error())
+		return "", "", err
+	}
+	if !strings.HasPrefix(returnTo, "/") || strings.HasPrefix(returnTo, "//") || strings.HasPrefix(returnTo, "/\\") {
+		err := errors.BadRequest("federation.login", "return_to must be a path on this server")
+		span.AddEvent(err.Error())
+		return "", "", err
+	}
+	meta, err := uc.metadata(ctx, p)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", "", err
+	}
+	var state, nonce, verifier string
+	for _, v := range []*string{&state, &nonce, &verifier} {
+		if *v, err = randomToken(); err != nil {
+			span.AddEvent(err.Error())
+			return "", "", err
+		}
+	}
+	flow := url.Values{
+		"provider":  {provider},
+		"nonce":     {nonce},
+		"verifier":  {verifier},
+		"return_to": {returnTo},
+	}
+	if link != nil {
+		flow.Set("link", link.ID.String())
+	}
+	cookie, err := uc.oidc.cookies.sign(purposeFederation, tokenClaims{
+		Subject: state,
+		Binding: flow.Encode(),
+		Expires: time.Now().Add(FederationFlowTTL).Unix(),
+	})
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", "", err
+	}
+	scopes := p.conf.GetScopes()
+	if len(scopes) == 0 {
+		scopes = defaultFederationScopes
+	}
+	sum := sha256.Sum256([]byte(verifier))
+	params := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {p.conf.GetClientId()},
+		"redirect_uri":          {uc.callbackURL(provider)},
+		"scope":                 {strings.Join(append([]string{"openid"}, scopes...), " ")},
+		"state":                 {state},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	}
+	u, err := url.Parse(meta.AuthorizationEndpoint)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", "", err
+	}
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String(), cookie, nil
+}
+
+// Callback completes a login at provider with the parameters it redirected
+// the browser back with and the cookie StartLogin set. The identity is
+// linked to a user if needed, and the browser logged in unless it was
+// linking.
+func (uc *FederationUsecase) Callback(ctx context.Context, provider string, params url.Values, cookie string, client Client) (*FederatedLogin, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz Callback")
+	defer span.End()
+	res, err := uc.callback(ctx, provider, params, cookie, client)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+func (uc *FederationUsecase) callback(ctx context.Context, provider string, params url.Values, cookie string, client Client) (*FederatedLogin, error) {
+	p, ok := uc.providers[provider]
+	if !ok {
+		return nil, errors.NotFound("federation.provider", "unknown provider")
+	}
+	expired := errors.BadRequest("federation.callback", "the login expired or was started in another browser, please try again")
+	flowClaims, err := uc.oidc.cookies.verify("federation.callback", purposeFederation, cookie)
+	if err != nil {
+		return nil, expired
+	}
+	flow, err := url.ParseQuery(flowClaims.Binding)
+	if err != nil || flow.Get("provider") != provider ||
+		subtle.ConstantTimeCompare([]byte(params.Get("state")), []byte(flowClaims.Subject)) != 1 {
+		return nil, expired
+	}
+	if e := params.Get("error"); e != "" {
+		return nil, errors.Unauthorized("federation.callback", fmt.Sprintf("the provider refused the login: %s %s", e, params.Get("error_description")))
+	}
+	meta, err := uc.metadata(ctx, p)
+	if err != nil {
+		return nil, err
+	}
+	idToken, err := uc.upstream.ExchangeCode(ctx, meta.TokenEndpoint, p.conf.GetClientId(), p.conf.GetClientSecret(), url.Values{
+		"grant_type":    {"authorization_code"},
+		"code":          {params.Get("code")},
+		"redirect_uri":  {uc.callbackURL(provider)},
+		"code_verifier": {flow.Get("verifier")},
+	})
+	if err != nil {
+		return nil, errors.Unauthorized("federation.callback", "the provider did not accept the login").WithCause(err)
+	}
+	claims, err := uc.verifyIDToken(ctx, p, meta, idToken)
+	if err != nil {
+		return nil, err
+	}
+	if subtle.ConstantTimeCompare([]byte(claims.Nonce), []byte(flow.Get("nonce"))) != 1 {
+		return nil, errors.Unauthorized("federation.callback", "ID token nonce mismatch")
+	}
+
+	var link *Session
+	if id := flow.Get("link"); id != "" {
+		// the session may have ended while the user was away
+		if link, err = uc.oidc.auth.sessions.ValidateSession(ctx, id); err != nil {
+			return nil, err
+		}
+		if link == nil {
+			return nil, expired
+		}
+	}
+	identity, err := uc.resolve(ctx, p, meta.Issuer, claims, link)
+	if err != nil {
+		return nil, err
+	}
+	res := &FederatedLogin{ReturnTo: flow.Get("return_to"), Linked: link != nil}
+	if link != nil {
+		res.Session = link
+		return res, nil
+	}
+	client.Device = p.conf.GetName()
+	login, err := uc.oidc.loginBrowser(ctx, identity.UserID, client)
+	if err != nil {
+		return nil, err
+	}
+	res.Session, res.Cookie, res.PendingMFA = login.Session, login.Cookie, login.PendingMFA
+	return res, nil
+}
+
+// resolve returns the identity of claims, linking it first if needed.
+func (uc *FederationUsecase) resolve(ctx context.Context, p *upstreamProvider, issuer string, claims *upstreamClaims, link *Session) (*ExternalIdentity, error) {
+	identity, err := uc.repo.FindIdentity(ctx, issuer, claims.Subject)
+	if err == nil {
+		if link != nil && link.UserID != identity.UserID {
+			return nil, errors.Conflict("federation.link", "this account is linked to another user")
+		}
+		if _, err := uc.users.GetByID(ctx, identity.UserID.String(), ExcludeDeleted); err != nil {
+			return nil, err
+		}
+		if err := uc.repo.TouchIdentity(ctx, identity.ID, time.Now()); err != nil {
+			uc.log.WithContext(ctx).Warnf("failed recording login with identity %s: %v", identity.ID, err)
+		}
+		return identity, nil
+	}
+	if !errors.IsNotFound(err) {
+		return nil, err
+	}
+
+	identity = &ExternalIdentity{
+		Provider:    p.conf.GetName(),
+		Issuer:      issuer,
+		Subject:     claims.Subject,
+		Email:       claims.Email,
+		LastLoginAt: time.Now(),
+	}
+	email := NormalizeEmail(claims.Email)
+	switch {
+	case link != nil:
+		identity.UserID = link.UserID
+	case p.conf.GetLinkVerifiedEmail() && claims.EmailVerified && email != "":
+		user, err := uc.users.LookupUser(ctx, LookupKey{Field: FieldEmail, Value: email})
+		if err == nil {
+			if identity.UserID, err = uuid.Parse(user.ID); err != nil {
+				return nil, err
+			}
+			break
+		}
+		if !errors.IsNotFound(err) {
+			return nil, err
+		}
+		fallthrough
+	default:
+		if !p.conf.GetCreateUsers() {
+			return nil, errors.Forbidden("federation.login", "no user is linked to this account")
+		}
+		user, err := uc.createUser(ctx, claims, email)
+		if err != nil {
+			return nil, err
+		}
+		if identity.UserID, err = uuid.Parse(user.ID); err != nil {
+			return nil, err
+		}
+	}
+	res, err := uc.repo.SaveIdentity(ctx, identity)
+	if err != nil {
+		return nil, err
+	}
+	uc.log.WithContext(ctx).Infof("linked %s identity %s to user %s", identity.Provider, identity.Subject, identity.UserID)
+	return res, nil
+}
+
+// createUser creates the user of a first login. The username is the one the
+// provider suggests when it is free, with a random suffix otherwise.
+func (uc *FederationUsecase) createUser(ctx context.Context, claims *upstreamClaims, email string) (*Users, error) {
+	if email == "" {
+		return nil, errors.BadRequest("federation.login", "the provider did not share an email address")
+	}
+	base := NormalizeUsername(claims.PreferredUsername)
+	if base == "" {
+		base, _, _ = strings.Cut(email, "@")
+	}
+	phone := ""
+	u := &Users{Username: &base, Email: &email, Phone: &phone}
+	if claims.Picture != "" {
+		u.Avatar = &claims.Picture
+	}
+	user, err := uc.users.CreateUsers(ctx, u)
+	if errors.IsConflict(err) {
+		if existing, lerr := uc.users.LookupUser(ctx, LookupKey{Field: FieldEmail, Value: email}); lerr == nil && existing != nil {
+			return nil, errors.Conflict("federation.login", "a user with this email exists already, log in and link this account instead")
+		}
+		suffix, rerr := newNumericCode(4)
+		if rerr != nil {
+			return nil, rerr
+		}
+		username := base + "-" + suffix
+		u.Username = &username
+		user, err = uc.users.CreateUsers(ctx, u)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if claims.EmailVerified {
+		uid, err := uuid.Parse(user.ID)
+		if err != nil {
+			return nil, err
+		}
+		if user, err = uc.users.repo.MarkEmailVerified(ctx, uid, email); err != nil {
+			return nil, err
+		}
+	}
+	return user, nil
+}
+
+// ListIdentities returns the identities linked to a user.
+func (uc *FederationUsecase) ListIdentities(ctx context.Context, userID string) ([]ExternalIdentity, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz ListIdentities")
+	defer span.End()
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	res, err := uc.repo.ListIdentities(ctx, uid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+// UnlinkIdentity unlinks an identity from a user, who must keep a password
+// or another identity to log in with.
+func (uc *FederationUsecase) UnlinkIdentity(ctx context.Context, userID, id string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Biz UnlinkIdentity")
+	defer span.End()
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	iid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	identities, err := uc.repo.ListIdentities(ctx, uid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	if len(identities) == 1 && identities[0].ID == iid {
+		hash, err := uc.creds.currentHash(ctx, uid)
+		if err != nil {
+			span.AddEvent(err.Error())
+			return err
+		}
+		if hash == "" {
+			err := errors.BadRequest("federation.unlink", "set a password before unlinking the last account")
+			span.AddEvent(err.Error())
+			return err
+		}
+	}
+	if err := uc.repo.DeleteIdentity(ctx, uid, iid); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (uc *FederationUsecase) callbackURL(provider string) string {
+	return uc.oidc.issuer + FederationPath + provider + "/callback"
+}
+
+// metadata returns the discovery document of a provider, cached.
+func (uc *FederationUsecase) metadata(ctx context.Context, p *upstreamProvider) (*UpstreamMetadata, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.meta != nil && time.Since(p.fetchedAt) < upstreamMetadataTTL {
+		return p.meta, nil
+	}
+	issuer := strings.TrimSuffix(p.conf.GetIssuer(), "/")
+	meta, err := uc.upstream.Discover(ctx, issuer)
+	if err != nil {
+		return nil, errors.ServiceUnavailable("federation.upstream", fmt.Sprintf("provider %s is unavailable", p.conf.GetName())).WithCause(err)
+	}
+	if strings.TrimSuffix(meta.Issuer, "/") != issuer || meta.AuthorizationEndpoint == "" || meta.TokenEndpoint == "" || meta.JWKSURI == "" {
+		return nil, errors.ServiceUnavailable("federation.upstream", fmt.Sprintf("provider %s has an invalid discovery document", p.conf.GetName()))
+	}
+	keys, err := uc.fetchKeys(ctx, meta.JWKSURI)
+	if err != nil {
+		return nil, err
+	}
+	p.meta, p.keys, p.fetchedAt = meta, keys, time.Now()
+	return meta, nil
+}
+
+func (uc *FederationUsecase) fetchKeys(ctx context.Context, uri string) (*tokenSigner, error) {
+	data, err := uc.upstream.FetchJWKS(ctx, uri)
+	if err != nil {
+		return nil, errors.ServiceUnavailable("federation.upstream", "failed fetching the keys of the provider").WithCause(err)
+	}
+	keys := &tokenSigner{}
+	if err := keys.addJWKS(data); err != nil {
+		return nil, errors.ServiceUnavailable("federation.upstream", "the provider published invalid keys").WithCause(err)
+	}
+	return keys, nil
+}
+
+// verifyIDToken checks an ID token of a provider, fetching its keys again
+// when the token names one we do not know, as after a key rotation.
+func (uc *FederationUsecase) verifyIDToken(ctx context.Context, p *upstreamProvider, meta *UpstreamMetadata, token string) (*upstreamClaims, error) {
+	keyfunc := func(t *jwt.Token) (interface{}, error) {
+		p.mu.Lock()
+		keys := p.keys
+		p.mu.Unlock()
+		key, err := keys.keyfunc(t)
+		if err == nil {
+			return key, nil
+		}
+		p.mu.Lock()
+		defer p.mu.Unlock()
+		if time.Since(p.fetchedAt) < upstreamKeysMinAge {
+			return nil, err
+		}
+		fresh, ferr := uc.fetchKeys(ctx, meta.JWKSURI)
+		if ferr != nil {
+			return nil, ferr
+		}
+		p.keys, p.fetchedAt = fresh, time.Now()
+		return fresh.keyfunc(t)
+	}
+	var claims upstreamClaims
+	_, err := jwt.ParseWithClaims(token, &claims, keyfunc,
+		jwt.WithValidMethods([]string{
+			jwt.SigningMethodRS256.Alg(),
+			jwt.SigningMethodES256.Alg(),
+			jwt.SigningMethodEdDSA.Alg(),
+		}),
+		jwt.WithIssuer(meta.Issuer),
+		jwt.WithAudience(p.conf.GetClientId()),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(time.Minute),
+	)
+	if err != nil {
+		return nil, errors.Unauthorized("federation.callback", "invalid ID token").WithCause(err)
+	}
+	if claims.Subject == "" {
+		return nil, errors.Unauthorized("federation.callback", "ID token has no subject")
+	}
+	return &claims, nil
+}
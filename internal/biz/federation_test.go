@@ -0,0 +1,430 @@
+package biz
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type memIdentities struct {
+	ExternalIdentitiesRepo
+	mu         sync.Mutex
+	identities []*ExternalIdentity
+}
+
+func (r *memIdentities) SaveIdentity(_ context.Context, i *ExternalIdentity) (*ExternalIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.identities {
+		if e.Issuer == i.Issuer && e.Subject == i.Subject {
+			return nil, errors.Conflict("federation.link", "identity is linked already")
+		}
+	}
+	saved := *i
+	saved.ID = uuid.New()
+	r.identities = append(r.identities, &saved)
+	return &saved, nil
+}
+
+func (r *memIdentities) FindIdentity(_ context.Context, issuer, subject string) (*ExternalIdentity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.identities {
+		if e.Issuer == issuer && e.Subject == subject {
+			found := *e
+			return &found, nil
+		}
+	}
+	return nil, errors.NotFound("federation.identity", "identity not found")
+}
+
+func (r *memIdentities) TouchIdentity(_ context.Context, id uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.identities {
+		if e.ID == id {
+			e.LastLoginAt = at
+		}
+	}
+	return nil
+}
+
+// httpUpstream talks to test providers over HTTP, the way the data layer
+// does.
+type httpUpstream struct {
+	client *http.Client
+}
+
+func (u httpUpstream) Discover(ctx context.Context, issuer string) (*UpstreamMetadata, error) {
+	body, err := u.get(ctx, issuer+"/.well-known/openid-configuration")
+	if err != nil {
+		return nil, err
+	}
+	var meta UpstreamMetadata
+	if err := json.Unmarshal(body, &meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
+
+func (u httpUpstream) FetchJWKS(ctx context.Context, uri string) ([]byte, error) {
+	return u.get(ctx, uri)
+}
+
+func (u httpUpstream) ExchangeCode(ctx context.Context, endpoint, clientID, clientSecret string, form url.Values) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(clientID, clientSecret)
+	body, err := u.do(req)
+	if err != nil {
+		return "", err
+	}
+	var res struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		return "", err
+	}
+	return res.IDToken, nil
+}
+
+func (u httpUpstream) get(ctx context.Context, uri string) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	return u.do(req)
+}
+
+func (u httpUpstream) do(req *http.Request) ([]byte, error) {
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s: %s", req.Method, req.URL, resp.Status)
+	}
+	return body, nil
+}
+
+// testProvider is an upstream OpenID Connect provider that logs everybody
+// in as Subject.
+type testProvider struct {
+	t      *testing.T
+	srv    *httptest.Server
+	signer *tokenSigner
+
+	mu      sync.Mutex
+	grants  map[string]url.Values
+	Subject string
+	Email   string
+	// Nonce, when set, replaces the nonce of the authorization request in
+	// ID tokens.
+	Nonce string
+	// Audience, when set, replaces the client ID in ID tokens.
+	Audience string
+}
+
+const (
+	testUpstreamClientID     = "users-client"
+	testUpstreamClientSecret = "users-secret"
+)
+
+func newTestProvider(t *testing.T) *testProvider {
+	t.Helper()
+	signer, err := newTokenSigner([]*conf.Auth_Key{testSigningKey(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &testProvider{
+		t:       t,
+		signer:  signer,
+		grants:  make(map[string]url.Values),
+		Subject: "upstream-subject",
+		Email:   "erin@example.com",
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", func(w http.ResponseWriter, r *http.Request) {
+		p.writeJSON(w, UpstreamMetadata{
+			Issuer:                p.srv.URL,
+			AuthorizationEndpoint: p.srv.URL + "/authorize",
+			TokenEndpoint:         p.srv.URL + "/token",
+			JWKSURI:               p.srv.URL + "/jwks",
+		})
+	})
+	mux.HandleFunc("/jwks", func(w http.ResponseWriter, r *http.Request) {
+		p.writeJSON(w, p.signer.jwks())
+	})
+	mux.HandleFunc("/token", p.token)
+	p.srv = httptest.NewServer(mux)
+	t.Cleanup(p.srv.Close)
+	return p
+}
+
+// authorize plays the user logging in at the provider: it takes the URL
+// StartLogin sent the browser to and returns the parameters the browser
+// comes back to the callback with.
+func (p *testProvider) authorize(location string) url.Values {
+	p.t.Helper()
+	u, err := url.Parse(location)
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	if got := u.Scheme + "://" + u.Host + u.Path; got != p.srv.URL+"/authorize" {
+		p.t.Fatalf("login sent to %s, want the authorization endpoint", got)
+	}
+	q := u.Query()
+	if q.Get("client_id") != testUpstreamClientID || q.Get("code_challenge_method") != "S256" || q.Get("response_type") != "code" {
+		p.t.Fatalf("unexpected authorization request %s", u.RawQuery)
+	}
+	code, err := randomToken()
+	if err != nil {
+		p.t.Fatal(err)
+	}
+	p.mu.Lock()
+	p.grants[code] = q
+	p.mu.Unlock()
+	return url.Values{"code": {code}, "state": {q.Get("state")}}
+}
+
+func (p *testProvider) token(w http.ResponseWriter, r *http.Request) {
+	id, secret, ok := r.BasicAuth()
+	if !ok || id != testUpstreamClientID || secret != testUpstreamClientSecret {
+		http.Error(w, "invalid_client", http.StatusUnauthorized)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "invalid_request", http.StatusBadRequest)
+		return
+	}
+	p.mu.Lock()
+	grant, ok := p.grants[r.PostForm.Get("code")]
+	delete(p.grants, r.PostForm.Get("code"))
+	p.mu.Unlock()
+	sum := sha256.Sum256([]byte(r.PostForm.Get("code_verifier")))
+	if !ok || grant.Get("redirect_uri") != r.PostForm.Get("redirect_uri") ||
+		grant.Get("code_challenge") != base64.RawURLEncoding.EncodeToString(sum[:]) {
+		http.Error(w, "invalid_grant", http.StatusBadRequest)
+		return
+	}
+	nonce, audience := grant.Get("nonce"), testUpstreamClientID
+	if p.Nonce != "" {
+		nonce = p.Nonce
+	}
+	if p.Audience != "" {
+		audience = p.Audience
+	}
+	now := time.Now()
+	idToken, err := p.signer.sign(typeIDToken, upstreamClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			Issuer:    p.srv.URL,
+			Subject:   p.Subject,
+			Audience:  jwt.ClaimStrings{audience},
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(time.Minute)),
+		},
+		Nonce:             nonce,
+		Email:             p.Email,
+		EmailVerified:     true,
+		PreferredUsername: "erin",
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	p.writeJSON(w, map[string]string{"id_token": idToken, "token_type": "Bearer"})
+}
+
+func (p *testProvider) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		p.t.Error(err)
+	}
+}
+
+func newTestFederation(t *testing.T, provider *conf.Federation_Provider, users ...*Users) (*FederationUsecase, *testAuth, *memIdentities, *testProvider) {
+	t.Helper()
+	upstream := newTestProvider(t)
+	provider.Issuer = upstream.srv.URL
+	provider.ClientId = testUpstreamClientID
+	provider.ClientSecret = testUpstreamClientSecret
+	oidc, auth, _ := newTestOIDC(t, users...)
+	logger := log.NewStdLogger(testWriter{t})
+	usersUC, err := NewUsersUsecase(auth.users, &conf.Biz{}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &memIdentities{}
+	uc, err := NewFederationUsecase(oidc, usersUC, nil, repo, httpUpstream{client: upstream.srv.Client()},
+		&conf.Federation{Providers: []*conf.Federation_Provider{provider}}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uc, auth, repo, upstream
+}
+
+func TestFederationCallback(t *testing.T) {
+	ctx := context.Background()
+	uc, auth, repo, upstream := newTestFederation(t, &conf.Federation_Provider{Name: "partner", CreateUsers: true})
+
+	location, cookie, err := uc.StartLogin(ctx, "partner", "/oauth2/authorize?client_id=x", nil)
+	if err != nil {
+		t.Fatalf("StartLogin: %v", err)
+	}
+	params := upstream.authorize(location)
+	if got, _ := url.Parse(location); got.Query().Get("redirect_uri") != testIssuer+FederationPath+"partner/callback" {
+		t.Errorf("redirect_uri = %q", got.Query().Get("redirect_uri"))
+	}
+	login, err := uc.Callback(ctx, "partner", params, cookie, Client{})
+	if err != nil {
+		t.Fatalf("Callback: %v", err)
+	}
+	if login.Session == nil || login.Cookie == "" || login.PendingMFA != "" || login.Linked {
+		t.Fatalf("login = %+v, want a new session", login)
+	}
+	if login.ReturnTo != "/oauth2/authorize?client_id=x" {
+		t.Errorf("ReturnTo = %q", login.ReturnTo)
+	}
+	if session, err := uc.oidc.BrowserSession(ctx, login.Cookie); err != nil || session == nil || session.ID != login.Session.ID {
+		t.Errorf("BrowserSession = %v, %v, want the new session", session, err)
+	}
+	if len(repo.identities) != 1 || repo.identities[0].UserID != login.Session.UserID || repo.identities[0].Issuer != upstream.srv.URL {
+		t.Fatalf("identities = %+v, want one of the new user", repo.identities)
+	}
+	user, err := auth.users.FindByID(ctx, login.Session.UserID, ExcludeDeleted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *user.Username != "erin" || *user.Email != upstream.Email || user.EmailVerifiedAt == nil {
+		t.Errorf("created user = %s %s verified %v", *user.Username, *user.Email, user.EmailVerifiedAt)
+	}
+
+	// the code is used up
+	if _, err := uc.Callback(ctx, "partner", params, cookie, Client{}); !errors.IsUnauthorized(err) {
+		t.Errorf("replayed callback err = %v, want Unauthorized", err)
+	}
+
+	// the next login finds the identity
+	location, cookie, err = uc.StartLogin(ctx, "partner", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, err := uc.Callback(ctx, "partner", upstream.authorize(location), cookie, Client{})
+	if err != nil {
+		t.Fatalf("second Callback: %v", err)
+	}
+	if again.Session.UserID != login.Session.UserID || len(repo.identities) != 1 || len(auth.users.users) != 1 {
+		t.Errorf("second login as %s with %d identities and %d users, want the same user", again.Session.UserID, len(repo.identities), len(auth.users.users))
+	}
+}
+
+func TestFederationCallbackRejections(t *testing.T) {
+	ctx := context.Background()
+	foreign, err := newTokenSigner([]*conf.Auth_Key{testSigningKey(t)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		setup  func(p *testProvider, params url.Values, cookie *string)
+		reason string
+		check  func(error) bool
+	}{
+		{"state mismatch", func(_ *testProvider, params url.Values, _ *string) {
+			params.Set("state", "forged")
+		}, "federation.callback", errors.IsBadRequest},
+		{"no cookie", func(_ *testProvider, _ url.Values, cookie *string) {
+			*cookie = ""
+		}, "federation.callback", errors.IsBadRequest},
+		{"provider error", func(_ *testProvider, params url.Values, _ *string) {
+			params.Set("error", "access_denied")
+		}, "federation.callback", errors.IsUnauthorized},
+		{"wrong code", func(_ *testProvider, params url.Values, _ *string) {
+			params.Set("code", "forged")
+		}, "federation.callback", errors.IsUnauthorized},
+		{"nonce mismatch", func(p *testProvider, _ url.Values, _ *string) {
+			p.Nonce = "replayed"
+		}, "federation.callback", errors.IsUnauthorized},
+		{"other audience", func(p *testProvider, _ url.Values, _ *string) {
+			p.Audience = "another-client"
+		}, "federation.callback", errors.IsUnauthorized},
+		{"foreign key", func(p *testProvider, _ url.Values, _ *string) {
+			p.signer = foreign
+		}, "federation.callback", errors.IsUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _, repo, upstream := newTestFederation(t, &conf.Federation_Provider{Name: "partner", CreateUsers: true})
+			location, cookie, err := uc.StartLogin(ctx, "partner", "/", nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			params := upstream.authorize(location)
+			tt.setup(upstream, params, &cookie)
+			login, err := uc.Callback(ctx, "partner", params, cookie, Client{})
+			if !tt.check(err) || errors.Reason(err) != tt.reason {
+				t.Fatalf("Callback = %+v, %v, want %s", login, err, tt.reason)
+			}
+			if len(repo.identities) != 0 {
+				t.Errorf("a rejected login linked %+v", repo.identities)
+			}
+		})
+	}
+}
+
+func TestFederationCallbackWaitsForMFA(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin"), Email: ptr("erin@example.com")}
+	uc, auth, _, upstream := newTestFederation(t, &conf.Federation_Provider{Name: "partner", LinkVerifiedEmail: true}, user)
+	uid := uuid.MustParse(user.ID)
+	secret := auth.enableTOTP(t, uid)
+
+	location, cookie, err := uc.StartLogin(ctx, "partner", "/", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	login, err := uc.Callback(ctx, "partner", upstream.authorize(location), cookie, Client{})
+	if err != nil {
+		t.Fatalf("Callback: %v", err)
+	}
+	if login.Session != nil || login.Cookie != "" || login.PendingMFA == "" {
+		t.Fatalf("login = %+v, want a pending second factor", login)
+	}
+	if len(auth.sessions.sessions) != 0 {
+		t.Error("a session started before the second factor was verified")
+	}
+	if !uc.oidc.HasPendingMFA(login.PendingMFA) {
+		t.Error("the pending cookie is not recognized")
+	}
+	browser, err := uc.oidc.VerifyBrowserMFA(ctx, login.PendingMFA, totpCode(secret, totpStep(time.Now())), Client{})
+	if err != nil {
+		t.Fatalf("VerifyBrowserMFA: %v", err)
+	}
+	if browser.Session == nil || browser.Session.UserID != uid {
+		t.Errorf("session = %+v, want one of %s", browser.Session, uid)
+	}
+}
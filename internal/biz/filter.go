@@ -0,0 +1,265 @@
+package biz
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+)
+
+// Field names a user attribute that list queries may reference.
+type Field string
+
+const (
+	FieldID        Field = "id"
+	FieldUsername  Field = "username"
+	FieldEmail     Field = "email"
+	FieldPhone     Field = "phone"
+	FieldAvatar    Field = "avatar"
+	FieldCreatedAt Field = "created_at"
+	FieldUpdatedAt Field = "updated_at"
+	FieldDeletedAt Field = "deleted_at"
+	// filtering on email_verified_at:is_null lists unverified users
+	FieldEmailVerifiedAt Field = "email_verified_at"
+	FieldPhoneVerifiedAt Field = "phone_verified_at"
+)
+
+// selectableFields is the allow-list of fields a list query may project.
+var selectableFields = map[Field]bool{
+	FieldID:              true,
+	FieldUsername:        true,
+	FieldEmail:           true,
+	FieldPhone:           true,
+	FieldDeletedAt:       true,
+	FieldEmailVerifiedAt: true,
+	FieldPhoneVerifiedAt: true,
+}
+
+// ParseFields validates the fields requested by a ListUsers call. Entries may
+// also be comma-separated lists. FieldID is always part of the result so rows
+// stay addressable; an empty request yields nil, meaning all fields.
+func ParseFields(raw []string) ([]Field, error) {
+	var fields []Field
+	seen := map[Field]bool{}
+	for _, entry := range raw {
+		for _, name := range strings.Split(entry, ",") {
+			field := Field(strings.TrimSpace(name))
+			if field == "" || seen[field] {
+				continue
+			}
+			if !selectableFields[field] {
+				return nil, errors.BadRequest("users.list", fmt.Sprintf("unknown field %q", field))
+			}
+			seen[field] = true
+			fields = append(fields, field)
+		}
+	}
+	if len(fields) > 0 && !seen[FieldID] {
+		fields = append([]Field{FieldID}, fields...)
+	}
+	return fields, nil
+}
+
+// SortableFields is the allow-list of fields list results may be ordered by.
+// Nullable fields are left out since NULLs cannot be compared in the keyset
+// of a page token.
+var SortableFields = map[Field]bool{
+	FieldID:        true,
+	FieldUsername:  true,
+	FieldEmail:     true,
+	FieldCreatedAt: true,
+	FieldUpdatedAt: true,
+}
+
+// ParseSort parses the sort_by of a ListUsers request, a comma-separated
+// list of "field [asc|desc]" terms such as "created_at desc,username asc".
+// Terms without a direction use sortOrder, or ascending when it is empty.
+func ParseSort(sortBy, sortOrder string) (SortParams, error) {
+	defaultDesc, err := parseSortDirection(sortOrder)
+	if err != nil {
+		return nil, err
+	}
+	var sp SortParams
+	seen := map[Field]bool{}
+	for _, term := range strings.Split(sortBy, ",") {
+		parts := strings.Fields(term)
+		if len(parts) == 0 {
+			continue
+		}
+		if len(parts) > 2 {
+			return nil, errors.BadRequest("users.list", fmt.Sprintf("invalid sort term %q", strings.TrimSpace(term)))
+		}
+		key := SortKey{Field: Field(parts[0]), Desc: defaultDesc}
+		if !SortableFields[key.Field] {
+			return nil, errors.BadRequest("users.list", fmt.Sprintf("field %q is not sortable", key.Field))
+		}
+		if seen[key.Field] {
+			return nil, errors.BadRequest("users.list", fmt.Sprintf("field %q is sorted on more than once", key.Field))
+		}
+		seen[key.Field] = true
+		if len(parts) == 2 {
+			if key.Desc, err = parseSortDirection(parts[1]); err != nil {
+				return nil, err
+			}
+		}
+		sp = append(sp, key)
+	}
+	return sp, nil
+}
+
+func parseSortDirection(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "", "asc":
+		return false, nil
+	case "desc":
+		return true, nil
+	}
+	return false, errors.BadRequest("users.list", fmt.Sprintf("invalid sort order %q, expected asc or desc", s))
+}
+
+// FilterOp is a comparison applied by a Filter.
+type FilterOp string
+
+const (
+	FilterEq         FilterOp = "eq"
+	FilterNeq        FilterOp = "neq"
+	FilterContains   FilterOp = "contains"
+	FilterStartsWith FilterOp = "starts_with"
+	FilterEndsWith   FilterOp = "ends_with"
+	FilterGt         FilterOp = "gt"
+	FilterGte        FilterOp = "gte"
+	FilterLt         FilterOp = "lt"
+	FilterLte        FilterOp = "lte"
+	FilterIsNull     FilterOp = "is_null"
+)
+
+// Filter is a single parsed condition of a list query. Value holds a
+// string, uuid.UUID, time.Time or, for FilterIsNull, a bool.
+type Filter struct {
+	Field Field
+	Op    FilterOp
+	Value interface{}
+}
+
+type fieldKind int
+
+const (
+	kindString fieldKind = iota
+	kindUUID
+	kindTime
+)
+
+type filterableField struct {
+	kind     fieldKind
+	nullable bool
+}
+
+// filterableFields is the allow-list of fields accepted in filters.
+var filterableFields = map[Field]filterableField{
+	FieldID:              {kind: kindUUID},
+	FieldUsername:        {kind: kindString},
+	FieldEmail:           {kind: kindString},
+	FieldPhone:           {kind: kindString, nullable: true},
+	FieldAvatar:          {kind: kindString, nullable: true},
+	FieldCreatedAt:       {kind: kindTime},
+	FieldUpdatedAt:       {kind: kindTime},
+	FieldDeletedAt:       {kind: kindTime, nullable: true},
+	FieldEmailVerifiedAt: {kind: kindTime, nullable: true},
+	FieldPhoneVerifiedAt: {kind: kindTime, nullable: true},
+}
+
+// kindOps lists the operators each kind of field supports.
+var kindOps = map[fieldKind][]FilterOp{
+	kindString: {FilterEq, FilterNeq, FilterContains, FilterStartsWith, FilterEndsWith},
+	kindUUID:   {FilterEq, FilterNeq},
+	kindTime:   {FilterEq, FilterNeq, FilterGt, FilterGte, FilterLt, FilterLte},
+}
+
+// ParseFilters turns the filters of a ListUsers request into Filters. Keys
+// have the form "field:op" and default to "eq" when the op is omitted, e.g.
+// "email:ends_with" => "@acme.com", "created_at:gte" => "2025-01-01" or
+// "phone:is_null" => "true". Unknown fields, operators or malformed values
+// are reported as BadRequest.
+func ParseFilters(raw map[string]string) ([]Filter, error) {
+	keys := make([]string, 0, len(raw))
+	for k := range raw {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	filters := make([]Filter, 0, len(keys))
+	for _, key := range keys {
+		f, err := parseFilter(key, raw[key])
+		if err != nil {
+			return nil, err
+		}
+		filters = append(filters, f)
+	}
+	return filters, nil
+}
+
+func parseFilter(key, value string) (Filter, error) {
+	name, op, found := strings.Cut(key, ":")
+	if !found {
+		op = string(FilterEq)
+	}
+	field := Field(strings.TrimSpace(name))
+	spec, ok := filterableFields[field]
+	if !ok {
+		return Filter{}, errors.BadRequest("users.list", fmt.Sprintf("filter %q: unknown field %q", key, field))
+	}
+	f := Filter{Field: field, Op: FilterOp(strings.TrimSpace(op))}
+
+	if f.Op == FilterIsNull {
+		if !spec.nullable {
+			return Filter{}, errors.BadRequest("users.list", fmt.Sprintf("filter %q: field %q is never null", key, field))
+		}
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return Filter{}, errors.BadRequest("users.list", fmt.Sprintf("filter %q: value must be true or false", key))
+		}
+		f.Value = b
+		return f, nil
+	}
+	if !supportsOp(spec.kind, f.Op) {
+		return Filter{}, errors.BadRequest("users.list", fmt.Sprintf("filter %q: unsupported operator %q for field %q", key, f.Op, field))
+	}
+
+	switch spec.kind {
+	case kindUUID:
+		id, err := uuid.Parse(value)
+		if err != nil {
+			return Filter{}, errors.BadRequest("users.list", fmt.Sprintf("filter %q: value must be a UUID", key))
+		}
+		f.Value = id
+	case kindTime:
+		t, err := parseFilterTime(value)
+		if err != nil {
+			return Filter{}, errors.BadRequest("users.list", fmt.Sprintf("filter %q: value must be an RFC 3339 timestamp or a YYYY-MM-DD date", key))
+		}
+		f.Value = t
+	default:
+		f.Value = value
+	}
+	return f, nil
+}
+
+func supportsOp(kind fieldKind, op FilterOp) bool {
+	for _, o := range kindOps[kind] {
+		if o == op {
+			return true
+		}
+	}
+	return false
+}
+
+func parseFilterTime(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Parse(time.DateOnly, s)
+}
@@ -0,0 +1,138 @@
+package biz
+
+import (
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseFilters(t *testing.T) {
+	id := uuid.MustParse("6f1c1b8e-3c4f-4a8e-9d1c-2b7e5f0a9c31")
+	tests := []struct {
+		name string
+		raw  map[string]string
+		want []Filter
+	}{
+		{"none", nil, []Filter{}},
+		{"op defaults to eq", map[string]string{"username": "alice"},
+			[]Filter{{FieldUsername, FilterEq, "alice"}}},
+		{"string op", map[string]string{"email:ends_with": "@acme.com"},
+			[]Filter{{FieldEmail, FilterEndsWith, "@acme.com"}}},
+		{"spaces around field and op", map[string]string{" email : contains ": "acme"},
+			[]Filter{{FieldEmail, FilterContains, "acme"}}},
+		{"uuid", map[string]string{"id:neq": id.String()},
+			[]Filter{{FieldID, FilterNeq, id}}},
+		{"date", map[string]string{"created_at:gte": "2025-01-01"},
+			[]Filter{{FieldCreatedAt, FilterGte, time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)}}},
+		{"timestamp", map[string]string{"updated_at:lt": "2025-01-01T12:30:00Z"},
+			[]Filter{{FieldUpdatedAt, FilterLt, time.Date(2025, 1, 1, 12, 30, 0, 0, time.UTC)}}},
+		{"is_null", map[string]string{"email_verified_at:is_null": "true", "phone:is_null": "false"},
+			[]Filter{{FieldEmailVerifiedAt, FilterIsNull, true}, {FieldPhone, FilterIsNull, false}}},
+		{"sorted by key", map[string]string{"username:starts_with": "a", "email": "a@acme.com"},
+			[]Filter{{FieldEmail, FilterEq, "a@acme.com"}, {FieldUsername, FilterStartsWith, "a"}}},
+	}
+	for _, tt := range tests {
+		got, err := ParseFilters(tt.raw)
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: ParseFilters(%v) = %v, want %v", tt.name, tt.raw, got, tt.want)
+		}
+	}
+}
+
+func TestParseFiltersRejections(t *testing.T) {
+	tests := []struct {
+		name string
+		raw  map[string]string
+	}{
+		{"unknown field", map[string]string{"password": "x"}},
+		{"unknown op", map[string]string{"username:like": "a%"}},
+		{"op of another kind", map[string]string{"username:gt": "a"}},
+		{"substring of a uuid", map[string]string{"id:contains": "6f1c"}},
+		{"malformed uuid", map[string]string{"id": "42"}},
+		{"malformed time", map[string]string{"created_at:gt": "yesterday"}},
+		{"is_null on a required field", map[string]string{"email:is_null": "true"}},
+		{"is_null with a non-bool", map[string]string{"deleted_at:is_null": "maybe"}},
+		{"one bad among good", map[string]string{"username": "alice", "avatar:gte": "x"}},
+	}
+	for _, tt := range tests {
+		if _, err := ParseFilters(tt.raw); !errors.IsBadRequest(err) {
+			t.Errorf("%s: ParseFilters(%v) err = %v, want BadRequest", tt.name, tt.raw, err)
+		}
+	}
+}
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		sortBy, sortOrder string
+		want              SortParams
+		wantErr           bool
+	}{
+		{sortBy: "", want: nil},
+		{sortBy: "username", want: SortParams{{FieldUsername, false}}},
+		{sortBy: "username", sortOrder: "DESC", want: SortParams{{FieldUsername, true}}},
+		{sortBy: "created_at desc,username asc", sortOrder: "desc",
+			want: SortParams{{FieldCreatedAt, true}, {FieldUsername, false}}},
+		{sortBy: " email , id desc ,", want: SortParams{{FieldEmail, false}, {FieldID, true}}},
+		{sortBy: "phone", wantErr: true},
+		{sortBy: "deleted_at", wantErr: true},
+		{sortBy: "username; drop table users", wantErr: true},
+		{sortBy: "username upward", wantErr: true},
+		{sortBy: "username asc nulls", wantErr: true},
+		{sortBy: "username,username desc", wantErr: true},
+		{sortBy: "username", sortOrder: "sideways", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseSort(tt.sortBy, tt.sortOrder)
+		if tt.wantErr {
+			if !errors.IsBadRequest(err) {
+				t.Errorf("ParseSort(%q, %q) err = %v, want BadRequest", tt.sortBy, tt.sortOrder, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSort(%q, %q): %v", tt.sortBy, tt.sortOrder, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSort(%q, %q) = %v, want %v", tt.sortBy, tt.sortOrder, got, tt.want)
+		}
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		raw     []string
+		want    []Field
+		wantErr bool
+	}{
+		{raw: nil, want: nil},
+		{raw: []string{"", " , "}, want: nil},
+		{raw: []string{"username"}, want: []Field{FieldID, FieldUsername}},
+		{raw: []string{"email, username", "email"}, want: []Field{FieldID, FieldEmail, FieldUsername}},
+		{raw: []string{"username", "id"}, want: []Field{FieldUsername, FieldID}},
+		{raw: []string{"password_hash"}, wantErr: true},
+		{raw: []string{"username,avatar"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseFields(tt.raw)
+		if tt.wantErr {
+			if !errors.IsBadRequest(err) {
+				t.Errorf("ParseFields(%q) err = %v, want BadRequest", tt.raw, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseFields(%q): %v", tt.raw, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseFields(%q) = %v, want %v", tt.raw, got, tt.want)
+		}
+	}
+}
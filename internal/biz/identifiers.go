@@ -0,0 +1,55 @@
+package biz
+
+import (
+	"strings"
+	"unicode"
+)
+
+// LookupKey identifies a user by one of its unique attributes: FieldUsername,
+// FieldEmail or FieldPhone.
+type LookupKey struct {
+	Field Field
+	Value string
+}
+
+// NormalizeUsername trims surrounding space. Usernames keep their case for
+// display but are compared case-insensitively.
+func NormalizeUsername(s string) string {
+	return strings.TrimSpace(s)
+}
+
+// NormalizeEmail trims surrounding space and lowercases the address.
+func NormalizeEmail(s string) string {
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// NormalizePhone drops the separators people commonly type in phone numbers,
+// keeping only digits and a leading plus sign.
+func NormalizePhone(s string) string {
+	var b strings.Builder
+	for i, r := range strings.TrimSpace(s) {
+		switch {
+		case unicode.IsDigit(r):
+			b.WriteRune(r)
+		case r == '+' && i == 0:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// normalizeUser normalizes the identifiers set on u in place.
+func normalizeUser(u *Users) {
+	if u.Username != nil {
+		v := NormalizeUsername(*u.Username)
+		u.Username = &v
+	}
+	if u.Email != nil {
+		v := NormalizeEmail(*u.Email)
+		u.Email = &v
+	}
+	if u.Phone != nil {
+		v := NormalizePhone(*u.Phone)
+		u.Phone = &v
+	}
+}
@@ -0,0 +1,332 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+	"strconv"
+	"time"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+const (
+	defaultLockoutWindow           = time.Hour
+	defaultAccountLockoutThreshold = 5
+	defaultIPLockoutThreshold      = 20
+	defaultLockoutBaseDelay        = 30 * time.Second
+	defaultLockoutMaxDelay         = 15 * time.Minute
+)
+
+// Credential checks guarded by the AttemptLimiter; failures of one do not
+// count against another.
+const (
+	ScopeLogin        = "login"
+	ScopeMFA          = "mfa"
+	ScopePasswordless = "passwordless"
+)
+
+// ScopePasswordReset counts the password reset mails sent to an account, see
+// AttemptLimiter.Allow.
+const ScopePasswordReset = "password-reset"
+
+var lockoutScopes = []string{ScopeLogin, ScopeMFA, ScopePasswordless, ScopePasswordReset}
+
+// Kinds of attempt counters.
+const (
+	lockoutAccount = "account"
+	lockoutIP      = "ip"
+)
+
+type AttemptStore interface {
+	// Fail counts a failed attempt against key, returning the failures
+	// counted since the last time none were for ttl.
+	Fail(ctx context.Context, key string, ttl time.Duration) (int, error)
+	// Lock locks key until the given time.
+	Lock(ctx context.Context, key string, until time.Time) error
+	// LockedUntil returns when the lock of key ends, the zero time when it
+	// is not locked.
+	LockedUntil(ctx context.Context, key string) (time.Time, error)
+	// Reset forgets the failures and locks of keys.
+	Reset(ctx context.Context, keys ...string) error
+}
+
+// lockoutPolicy locks a counter for delay once it reaches threshold
+// failures, doubling the delay with every further failure up to maxDelay.
+type lockoutPolicy struct {
+	threshold int
+	delay     time.Duration
+	maxDelay  time.Duration
+}
+
+func newLockoutPolicy(c *conf.Biz_Lockout_Policy, threshold int) lockoutPolicy {
+	p := lockoutPolicy{
+		threshold: int(c.GetThreshold()),
+		delay:     c.GetBaseDelay().AsDuration(),
+		maxDelay:  c.GetMaxDelay().AsDuration(),
+	}
+	if p.threshold <= 0 {
+		p.threshold = threshold
+	}
+	if p.delay <= 0 {
+		p.delay = defaultLockoutBaseDelay
+	}
+	if p.maxDelay <= 0 {
+		p.maxDelay = defaultLockoutMaxDelay
+	}
+	if p.maxDelay < p.delay {
+		p.maxDelay = p.delay
+	}
+	return p
+}
+
+// lockFor returns how long a counter is locked after its nth failure, zero
+// below the threshold.
+func (p lockoutPolicy) lockFor(n int) time.Duration {
+	if n < p.threshold {
+		return 0
+	}
+	d := p.delay
+	for i := p.threshold; i < n && d < p.maxDelay; i++ {
+		d *= 2
+	}
+	return min(d, p.maxDelay)
+}
+
+// AttemptLimiter slows down guessing of credentials by counting failed
+// attempts per account and per client IP, and locking either out for a
+// while once it failed too often. Counters are shared through the store, so
+// that instances enforce them together.
+//
+// Store errors are logged and the attempt let through: an outage of the
+// store must not lock everybody out.
+type AttemptLimiter struct {
+	store    AttemptStore
+	users    UsersRepo
+	mfa      MFARepo
+	account  lockoutPolicy
+	ip       lockoutPolicy
+	window   time.Duration
+	lockouts metric.Int64Counter
+	rejected metric.Int64Counter
+	log      *log.Helper
+}
+
+// NewAttemptLimiter new an AttemptLimiter.
+func NewAttemptLimiter(store AttemptStore, users UsersRepo, mfa MFARepo, c *conf.Biz, meter metric.Meter, logger log.Logger) (*AttemptLimiter, error) {
+	lc := c.GetLockout()
+	window := lc.GetWindow().AsDuration()
+	if window <= 0 {
+		window = defaultLockoutWindow
+	}
+	lockouts, err := meter.Int64Counter("users.lockouts",
+		metric.WithDescription("Accounts and client IPs locked out after failed attempts"))
+	if err != nil {
+		return nil, err
+	}
+	rejected, err := meter.Int64Counter("users.lockout.rejections",
+		metric.WithDescription("Attempts rejected because of a lockout"))
+	if err != nil {
+		return nil, err
+	}
+	return &AttemptLimiter{
+		store:    store,
+		users:    users,
+		mfa:      mfa,
+		account:  newLockoutPolicy(lc.GetAccount(), defaultAccountLockoutThreshold),
+		ip:       newLockoutPolicy(lc.GetIp(), defaultIPLockoutThreshold),
+		window:   window,
+		lockouts: lockouts,
+		rejected: rejected,
+		log:      log.NewHelper(logger),
+	}, nil
+}
+
+// attemptCounter is the counter of one account or client IP for a scope.
+type attemptCounter struct {
+	scope  string
+	kind   string
+	key    string
+	policy lockoutPolicy
+}
+
+// counters returns the counters of an account and a client IP for scope,
+// skipping the empty ones.
+func (l *AttemptLimiter) counters(scope string, account uuid.UUID, ip string) []attemptCounter {
+	var res []attemptCounter
+	if account != uuid.Nil {
+		res = append(res, attemptCounter{scope, lockoutAccount, accountAttemptsKey(scope, account), l.account})
+	}
+	if ip != "" {
+		res = append(res, attemptCounter{scope, lockoutIP, scope + ":ip:" + ip, l.ip})
+	}
+	return res
+}
+
+func accountAttemptsKey(scope string, account uuid.UUID) string {
+	return scope + ":account:" + account.String()
+}
+
+// Check returns a TooManyRequests error when account or ip is locked out of
+// scope. account may be nil and ip empty.
+func (l *AttemptLimiter) Check(ctx context.Context, scope string, account uuid.UUID, ip string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Biz CheckAttempt")
+	defer span.End()
+	wait := time.Until(l.lockedUntil(ctx, l.counters(scope, account, ip)))
+	if wait <= 0 {
+		return nil
+	}
+	wait = wait.Truncate(time.Second) + time.Second
+	err := errors.New(429, "auth.locked", fmt.Sprintf("too many failed attempts, retry in %s", wait)).
+		WithMetadata(map[string]string{"retry_after": strconv.Itoa(int(wait.Seconds()))})
+	span.AddEvent(err.Error())
+	return err
+}
+
+// Locked reports whether account is locked out of scope, for checks that
+// must not tell a locked account from a wrong credential.
+func (l *AttemptLimiter) Locked(ctx context.Context, scope string, account uuid.UUID) bool {
+	_, span := otel.Tracer("users").Start(ctx, "Biz LockedAttempt")
+	defer span.End()
+	return time.Now().Before(l.lockedUntil(ctx, l.counters(scope, account, "")))
+}
+
+// lockedUntil returns when the last lock of counters ends.
+func (l *AttemptLimiter) lockedUntil(ctx context.Context, counters []attemptCounter) time.Time {
+	var until time.Time
+	for _, c := range counters {
+		t, err := l.store.LockedUntil(ctx, c.key)
+		if err != nil {
+			l.log.WithContext(ctx).Errorf("checking lockout of %s: %v", c.key, err)
+			continue
+		}
+		if t.After(until) {
+			until = t
+		}
+		if time.Now().Before(t) {
+			l.rejected.Add(ctx, 1, metric.WithAttributes(
+				attribute.String("scope", c.scope),
+				attribute.String("kind", c.kind),
+			))
+		}
+	}
+	return until
+}
+
+// Fail counts a failed attempt of account and ip at scope, locking them out
+// when they failed too often.
+func (l *AttemptLimiter) Fail(ctx context.Context, scope string, account uuid.UUID, ip string) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz FailAttempt")
+	defer span.End()
+	for _, c := range l.counters(scope, account, ip) {
+		// counters outlive the longest lockout, so that failing right after
+		// one doubles it
+		n, err := l.store.Fail(ctx, c.key, max(l.window, c.policy.maxDelay))
+		if err != nil {
+			span.AddEvent(err.Error())
+			l.log.WithContext(ctx).Errorf("counting failed attempt of %s: %v", c.key, err)
+			continue
+		}
+		d := c.policy.lockFor(n)
+		if d == 0 {
+			continue
+		}
+		if err := l.store.Lock(ctx, c.key, time.Now().Add(d)); err != nil {
+			span.AddEvent(err.Error())
+			l.log.WithContext(ctx).Errorf("locking %s: %v", c.key, err)
+			continue
+		}
+		l.lockouts.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("scope", c.scope),
+			attribute.String("kind", c.kind),
+		))
+		l.log.WithContext(ctx).Warnf("%s locked out for %s after %d failed attempts", c.key, d, n)
+	}
+}
+
+// Allow counts an action of account at scope, such as sending it a mail, and
+// reports whether it may go ahead: after limit of them within window the
+// account is held back until window passed, however often it tries
+// meanwhile.
+func (l *AttemptLimiter) Allow(ctx context.Context, scope string, account uuid.UUID, limit int, window time.Duration) bool {
+	_, span := otel.Tracer("users").Start(ctx, "Biz AllowAttempt")
+	defer span.End()
+	key := accountAttemptsKey(scope, account)
+	until, err := l.store.LockedUntil(ctx, key)
+	if err != nil {
+		span.AddEvent(err.Error())
+		l.log.WithContext(ctx).Errorf("checking limit of %s: %v", key, err)
+		return true
+	}
+	if time.Now().Before(until) {
+		l.rejected.Add(ctx, 1, metric.WithAttributes(
+			attribute.String("scope", scope),
+			attribute.String("kind", lockoutAccount),
+		))
+		return false
+	}
+	n, err := l.store.Fail(ctx, key, window)
+	if err != nil {
+		span.AddEvent(err.Error())
+		l.log.WithContext(ctx).Errorf("counting %s: %v", key, err)
+		return true
+	}
+	if n > limit {
+		return false
+	}
+	if n == limit {
+		// the count expires along with the lock
+		if err := l.store.Lock(ctx, key, time.Now().Add(window)); err != nil {
+			span.AddEvent(err.Error())
+			l.log.WithContext(ctx).Errorf("locking %s: %v", key, err)
+		}
+	}
+	return true
+}
+
+// Succeed forgets the failed attempts of account at scope. Client IPs keep
+// theirs, a success of one account says nothing about the others tried from
+// the same IP.
+func (l *AttemptLimiter) Succeed(ctx context.Context, scope string, account uuid.UUID) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz SucceedAttempt")
+	defer span.End()
+	if err := l.store.Reset(ctx, accountAttemptsKey(scope, account)); err != nil {
+		span.AddEvent(err.Error())
+		l.log.WithContext(ctx).Errorf("resetting failed attempts of %s: %v", account, err)
+	}
+}
+
+// Unlock lifts the lockouts of an account in every scope and forgets its
+// failed attempts, including the TOTP attempts the MFA repository counts.
+func (l *AttemptLimiter) Unlock(ctx context.Context, id string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Biz Unlock")
+	defer span.End()
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		err = errors.BadRequest("users.unlock", "invalid user ID")
+		span.AddEvent(err.Error())
+		return err
+	}
+	if _, err := l.users.FindByID(ctx, uid, ExcludeDeleted); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	keys := make([]string, len(lockoutScopes))
+	for i, scope := range lockoutScopes {
+		keys[i] = accountAttemptsKey(scope, uid)
+	}
+	if err := l.store.Reset(ctx, keys...); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	if err := l.mfa.ResetTOTPAttempts(ctx, uid); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
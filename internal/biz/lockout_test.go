@@ -0,0 +1,238 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"go.opentelemetry.io/otel/metric/noop"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+func TestLockoutPolicy(t *testing.T) {
+	p := newLockoutPolicy(&conf.Biz_Lockout_Policy{
+		Threshold: 3,
+		BaseDelay: durationpb.New(10 * time.Second),
+		MaxDelay:  durationpb.New(time.Minute),
+	}, 5)
+	tests := []struct {
+		failures int
+		want     time.Duration
+	}{
+		{0, 0},
+		{2, 0},
+		{3, 10 * time.Second},
+		{4, 20 * time.Second},
+		{5, 40 * time.Second},
+		{6, time.Minute},
+		{100, time.Minute},
+	}
+	for _, tt := range tests {
+		if got := p.lockFor(tt.failures); got != tt.want {
+			t.Errorf("lockFor(%d) = %s, want %s", tt.failures, got, tt.want)
+		}
+	}
+}
+
+func TestNewLockoutPolicy(t *testing.T) {
+	tests := []struct {
+		c    *conf.Biz_Lockout_Policy
+		want lockoutPolicy
+	}{
+		{nil, lockoutPolicy{5, defaultLockoutBaseDelay, defaultLockoutMaxDelay}},
+		{&conf.Biz_Lockout_Policy{Threshold: 2}, lockoutPolicy{2, defaultLockoutBaseDelay, defaultLockoutMaxDelay}},
+		// the longest lockout is never shorter than the first
+		{&conf.Biz_Lockout_Policy{BaseDelay: durationpb.New(time.Hour), MaxDelay: durationpb.New(time.Minute)},
+			lockoutPolicy{5, time.Hour, time.Hour}},
+	}
+	for _, tt := range tests {
+		if got := newLockoutPolicy(tt.c, 5); got != tt.want {
+			t.Errorf("newLockoutPolicy(%v) = %+v, want %+v", tt.c, got, tt.want)
+		}
+	}
+}
+
+func newTestLimiter(t *testing.T, store AttemptStore, users ...*Users) (*AttemptLimiter, *memMFA) {
+	t.Helper()
+	mfa := &memMFA{totp: make(map[uuid.UUID]*TOTP), attempts: make(map[uuid.UUID]int)}
+	l, err := NewAttemptLimiter(store, newMemUsers(users...), mfa, &conf.Biz{}, noop.NewMeterProvider().Meter("test"), log.NewStdLogger(testWriter{t}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l, mfa
+}
+
+func TestAttemptLimiter(t *testing.T) {
+	ctx := context.Background()
+	store := newMemAttempts()
+	l, _ := newTestLimiter(t, store)
+	account := uuid.New()
+	key := accountAttemptsKey(ScopeLogin, account)
+
+	for i := 1; i < defaultAccountLockoutThreshold; i++ {
+		l.Fail(ctx, ScopeLogin, account, "")
+		if err := l.Check(ctx, ScopeLogin, account, ""); err != nil {
+			t.Fatalf("locked after %d failures: %v", i, err)
+		}
+	}
+	// every failure from the threshold on doubles the lockout
+	for i, want := range []time.Duration{defaultLockoutBaseDelay, 2 * defaultLockoutBaseDelay, 4 * defaultLockoutBaseDelay} {
+		l.Fail(ctx, ScopeLogin, account, "")
+		lock := time.Until(store.locks[key])
+		if lock <= want-time.Second || lock > want {
+			t.Errorf("failure %d locks for %s, want %s", defaultAccountLockoutThreshold+i, lock, want)
+		}
+		err := l.Check(ctx, ScopeLogin, account, "")
+		if errors.Code(err) != 429 || errors.Reason(err) != "auth.locked" {
+			t.Fatalf("Check err = %v, want auth.locked", err)
+		}
+		if retry := errors.FromError(err).Metadata["retry_after"]; retry != fmt.Sprint(int(want.Seconds())) {
+			t.Errorf("retry_after %s, want %d", retry, int(want.Seconds()))
+		}
+		if !l.Locked(ctx, ScopeLogin, account) {
+			t.Error("Locked = false while locked")
+		}
+	}
+
+	// scopes and accounts are counted apart
+	if err := l.Check(ctx, ScopeMFA, account, ""); err != nil {
+		t.Errorf("another scope: %v", err)
+	}
+	if err := l.Check(ctx, ScopeLogin, uuid.New(), ""); err != nil {
+		t.Errorf("another account: %v", err)
+	}
+
+	l.Succeed(ctx, ScopeLogin, account)
+	if err := l.Check(ctx, ScopeLogin, account, ""); err != nil || l.Locked(ctx, ScopeLogin, account) {
+		t.Errorf("still locked after a success: %v", err)
+	}
+
+	// a success of an account leaves the failures of its IP
+	ip := "203.0.113.5"
+	for i := 0; i < defaultIPLockoutThreshold; i++ {
+		l.Fail(ctx, ScopeLogin, uuid.Nil, ip)
+	}
+	l.Succeed(ctx, ScopeLogin, account)
+	if err := l.Check(ctx, ScopeLogin, account, ip); errors.Code(err) != 429 {
+		t.Errorf("locked IP err = %v, want 429", err)
+	}
+	if l.Locked(ctx, ScopeLogin, account) {
+		t.Error("the lock of an IP locks the accounts tried from it")
+	}
+}
+
+func TestUnlock(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin")}
+	uid := uuid.MustParse(user.ID)
+	store := newMemAttempts()
+	l, mfa := newTestLimiter(t, store, user)
+	for _, scope := range lockoutScopes {
+		for i := 0; i < defaultAccountLockoutThreshold; i++ {
+			l.Fail(ctx, scope, uid, "203.0.113.5")
+		}
+	}
+	for i := 0; i <= totpMaxAttempts; i++ {
+		if _, err := mfa.UseTOTPAttempt(ctx, uid, totpMaxAttempts, totpAttemptWindow); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		id   string
+		want func(error) bool
+	}{
+		{"not a uuid", errors.IsBadRequest},
+		{uuid.NewString(), errors.IsNotFound},
+	}
+	for _, tt := range tests {
+		if err := l.Unlock(ctx, tt.id); !tt.want(err) {
+			t.Errorf("Unlock(%q) err = %v", tt.id, err)
+		}
+	}
+
+	if err := l.Unlock(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	for _, scope := range lockoutScopes {
+		if l.Locked(ctx, scope, uid) {
+			t.Errorf("still locked out of %s", scope)
+		}
+		if n := store.failures[accountAttemptsKey(scope, uid)]; n != 0 {
+			t.Errorf("%d failures left in %s", n, scope)
+		}
+	}
+	if ok, _ := mfa.UseTOTPAttempt(ctx, uid, totpMaxAttempts, totpAttemptWindow); !ok {
+		t.Error("TOTP attempts were not reset")
+	}
+	// unlocking an account does not unlock the IPs it was tried from
+	if err := l.Check(ctx, ScopeLogin, uuid.Nil, "203.0.113.5"); err != nil {
+		t.Errorf("IP unlocked along with the account: %v", err)
+	}
+}
+
+func TestAttemptLimiterAllow(t *testing.T) {
+	ctx := context.Background()
+	store := newMemAttempts()
+	l, _ := newTestLimiter(t, store)
+	account := uuid.New()
+	for i := 1; i <= 3; i++ {
+		if !l.Allow(ctx, ScopePasswordReset, account, 3, time.Hour) {
+			t.Fatalf("action %d of 3 held back", i)
+		}
+	}
+	for i := 0; i < 5; i++ {
+		if l.Allow(ctx, ScopePasswordReset, account, 3, time.Hour) {
+			t.Fatal("action beyond the limit allowed")
+		}
+	}
+	// held back actions are not counted, so they cannot stretch the hold
+	if n := store.failures[accountAttemptsKey(ScopePasswordReset, account)]; n != 3 {
+		t.Errorf("%d actions counted, want 3", n)
+	}
+	if !l.Allow(ctx, ScopePasswordReset, uuid.New(), 3, time.Hour) {
+		t.Error("another account held back")
+	}
+}
+
+// brokenAttempts fails every call.
+type brokenAttempts struct{}
+
+func (brokenAttempts) Fail(context.Context, string, time.Duration) (int, error) {
+	return 0, fmt.Errorf("store down")
+}
+
+func (brokenAttempts) Lock(context.Context, string, time.Time) error {
+	return fmt.Errorf("store down")
+}
+
+func (brokenAttempts) LockedUntil(context.Context, string) (time.Time, error) {
+	return time.Time{}, fmt.Errorf("store down")
+}
+
+func (brokenAttempts) Reset(context.Context, ...string) error {
+	return fmt.Errorf("store down")
+}
+
+func TestAttemptLimiterStoreDown(t *testing.T) {
+	ctx := context.Background()
+	l, _ := newTestLimiter(t, brokenAttempts{})
+	account := uuid.New()
+	for i := 0; i < 2*defaultIPLockoutThreshold; i++ {
+		l.Fail(ctx, ScopeLogin, account, "203.0.113.5")
+	}
+	if err := l.Check(ctx, ScopeLogin, account, "203.0.113.5"); err != nil {
+		t.Errorf("Check with the store down: %v", err)
+	}
+	if l.Locked(ctx, ScopeLogin, account) {
+		t.Error("Locked with the store down")
+	}
+	if !l.Allow(ctx, ScopePasswordReset, account, 1, time.Hour) {
+		t.Error("Allow with the store down held back")
+	}
+}
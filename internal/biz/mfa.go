@@ -0,0 +1,369 @@
+package biz
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"strconv"
+	"strings"
+	"time"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+const (
+	totpSecretLength   = 20
+	recoveryCodeCount  = 10
+	recoveryCodeLength = 10
+	// totpMaxAttempts codes may be tried per user every totpAttemptWindow,
+	// far too few to guess one of the million codes
+	totpMaxAttempts   = 5
+	totpAttemptWindow = 15 * time.Minute
+)
+
+// TOTP is the second factor of a user. Secret is sealed, and ConfirmedAt
+// stays nil until the user proves their authenticator works.
+type TOTP struct {
+	UserID      uuid.UUID
+	Secret      []byte
+	ConfirmedAt *time.Time
+	LastStep    int64
+}
+
+type MFARepo interface {
+	// FindTOTP returns the TOTP of a user, or a NotFound error.
+	FindTOTP(context.Context, uuid.UUID) (*TOTP, error)
+	// SaveTOTP stores a pending TOTP, replacing any previous one.
+	SaveTOTP(context.Context, *TOTP) error
+	// ConfirmTOTP enables a pending TOTP along with its recovery codes.
+	ConfirmTOTP(ctx context.Context, id uuid.UUID, step int64, recoveryHashes []string) error
+	// UseTOTPStep records the step of an accepted code, reporting false when
+	// that step or a later one was already used.
+	UseTOTPStep(context.Context, uuid.UUID, int64) (bool, error)
+	// UseTOTPAttempt counts a verification attempt of a user, reporting false
+	// when limit attempts were already made within window.
+	UseTOTPAttempt(ctx context.Context, id uuid.UUID, limit int, window time.Duration) (bool, error)
+	// ResetTOTPAttempts forgets the verification attempts of a user.
+	ResetTOTPAttempts(context.Context, uuid.UUID) error
+	// UseRecoveryCode consumes an unused recovery code, reporting false when
+	// there is none with that hash.
+	UseRecoveryCode(ctx context.Context, id uuid.UUID, hash string) (bool, error)
+	// CountRecoveryCodes returns how many unused recovery codes a user has.
+	CountRecoveryCodes(context.Context, uuid.UUID) (int, error)
+	// ReplaceRecoveryCodes drops the recovery codes of a user for new ones.
+	ReplaceRecoveryCodes(ctx context.Context, id uuid.UUID, hashes []string) error
+	// DeleteTOTP removes the TOTP and recovery codes of a user.
+	DeleteTOTP(context.Context, uuid.UUID) error
+}
+
+// MFAVerification is the outcome of VerifyTOTP.
+type MFAVerification struct {
+	Valid                  bool
+	RecoveryCodeUsed       bool
+	RecoveryCodesRemaining int
+}
+
+type MFAUsecase struct {
+	repo     MFARepo
+	users    UsersRepo
+	attempts *AttemptLimiter
+	sealer   *sealer
+	issuer   string
+	log      *log.Helper
+}
+
+// NewMFAUsecase new an MFA usecase. TOTP stays unavailable until an
+// encryption key is configured.
+func NewMFAUsecase(repo MFARepo, users UsersRepo, attempts *AttemptLimiter, c *conf.Biz, logger log.Logger) (*MFAUsecase, error) {
+	helper := log.NewHelper(logger)
+	uc := &MFAUsecase{
+		repo:     repo,
+		users:    users,
+		attempts: attempts,
+		issuer:   c.GetMfa().GetIssuer(),
+		log:      helper,
+	}
+	if key := c.GetMfa().GetEncryptionKey(); key != "" {
+		s, err := newSealer(key)
+		if err != nil {
+			return nil, err
+		}
+		uc.sealer = s
+	} else {
+		helper.Warn("no MFA encryption key configured, TOTP is disabled")
+	}
+	return uc, nil
+}
+
+func (uc *MFAUsecase) available() error {
+	if uc.sealer == nil {
+		return errors.ServiceUnavailable("users.mfa", "TOTP is not configured")
+	}
+	return nil
+}
+
+// EnrollTOTP starts the enrollment of a user, returning the base32 secret
+// and the provisioning URI to show as a QR code. It replaces any pending
+// enrollment but refuses to replace a confirmed one.
+func (uc *MFAUsecase) EnrollTOTP(ctx context.Context, id string) (string, string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz EnrollTOTP")
+	defer span.End()
+	if err := uc.available(); err != nil {
+		span.AddEvent(err.Error())
+		return "", "", err
+	}
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", "", err
+	}
+	user, err := uc.users.FindByID(ctx, uid, ExcludeDeleted)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", "", err
+	}
+	existing, err := uc.repo.FindTOTP(ctx, uid)
+	if err != nil && !errors.IsNotFound(err) {
+		span.AddEvent(err.Error())
+		return "", "", err
+	}
+	if existing != nil && existing.ConfirmedAt != nil {
+		err := errors.Conflict("users.mfa", "TOTP is already enabled")
+		span.AddEvent(err.Error())
+		return "", "", err
+	}
+	secret := make([]byte, totpSecretLength)
+	if _, err := rand.Read(secret); err != nil {
+		span.AddEvent(err.Error())
+		return "", "", err
+	}
+	sealed, err := uc.sealer.seal(secret, uid[:])
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", "", err
+	}
+	if err := uc.repo.SaveTOTP(ctx, &TOTP{UserID: uid, Secret: sealed}); err != nil {
+		span.AddEvent(err.Error())
+		return "", "", err
+	}
+	return totpEncoding.EncodeToString(secret), totpURI(uc.issuer, *user.Username, secret), nil
+}
+
+// ConfirmTOTP enables a pending enrollment given a first code, and returns
+// the recovery codes of the user. They are not stored in clear, so this is
+// the only time they can be shown.
+func (uc *MFAUsecase) ConfirmTOTP(ctx context.Context, id, code string) ([]string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz ConfirmTOTP")
+	defer span.End()
+	if err := uc.available(); err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	totp, secret, err := uc.findTOTP(ctx, uid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if totp.ConfirmedAt != nil {
+		err := errors.Conflict("users.mfa", "TOTP is already enabled")
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	step, ok := totpMatch(secret, normalizeCode(code), time.Now(), totp.LastStep)
+	if !ok {
+		err := errors.BadRequest("users.mfa", "invalid code")
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes(uid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if err := uc.repo.ConfirmTOTP(ctx, uid, step, hashes); err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return codes, nil
+}
+
+// VerifyTOTP checks a TOTP code, or a recovery code which it then consumes,
+// as the second step of a login. A code is accepted only once, each user
+// gets a few attempts per window wherever they come from, and users failing
+// too often are locked out for a while.
+func (uc *MFAUsecase) VerifyTOTP(ctx context.Context, id, code string) (*MFAVerification, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz VerifyTOTP")
+	defer span.End()
+	if err := uc.available(); err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	totp, secret, err := uc.findTOTP(ctx, uid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if totp.ConfirmedAt == nil {
+		err := errors.NotFound("users.mfa", "TOTP is not enabled")
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if err := uc.attempts.Check(ctx, ScopeMFA, uid, ""); err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	// the attempt is counted before the code is compared, so that it holds
+	// when the lockout store is down
+	ok, err := uc.repo.UseTOTPAttempt(ctx, uid, totpMaxAttempts, totpAttemptWindow)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if !ok {
+		err := errors.New(429, "users.mfa", "too many verification attempts, retry later").
+			WithMetadata(map[string]string{"retry_after": strconv.Itoa(int(totpAttemptWindow.Seconds()))})
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	res := &MFAVerification{}
+	code = normalizeCode(code)
+	if len(code) == totpDigits {
+		if step, ok := totpMatch(secret, code, time.Now(), totp.LastStep); ok {
+			// a concurrent verification may have taken the step meanwhile
+			res.Valid, err = uc.repo.UseTOTPStep(ctx, uid, step)
+		}
+	} else {
+		res.Valid, err = uc.repo.UseRecoveryCode(ctx, uid, hashRecoveryCode(uid, code))
+		res.RecoveryCodeUsed = res.Valid
+	}
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if !res.Valid {
+		uc.attempts.Fail(ctx, ScopeMFA, uid, "")
+		return res, nil
+	}
+	uc.attempts.Succeed(ctx, ScopeMFA, uid)
+	if err := uc.repo.ResetTOTPAttempts(ctx, uid); err != nil {
+		uc.log.WithContext(ctx).Errorf("resetting TOTP attempts of user %s: %v", uid, err)
+	}
+	if res.RecoveryCodeUsed {
+		res.RecoveryCodesRemaining, err = uc.repo.CountRecoveryCodes(ctx, uid)
+		if err != nil {
+			span.AddEvent(err.Error())
+			return nil, err
+		}
+		uc.log.WithContext(ctx).Infof("user %s used a recovery code, %d left", uid, res.RecoveryCodesRemaining)
+	}
+	return res, nil
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of a user with TOTP
+// enabled.
+func (uc *MFAUsecase) RegenerateRecoveryCodes(ctx context.Context, id string) ([]string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz RegenerateRecoveryCodes")
+	defer span.End()
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	totp, err := uc.repo.FindTOTP(ctx, uid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if totp.ConfirmedAt == nil {
+		err := errors.NotFound("users.mfa", "TOTP is not enabled")
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	codes, hashes, err := newRecoveryCodes(uid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if err := uc.repo.ReplaceRecoveryCodes(ctx, uid, hashes); err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return codes, nil
+}
+
+// ResetTOTP removes the second factor of a user, e.g. after they lost their
+// device and recovery codes, so that they can enroll again.
+func (uc *MFAUsecase) ResetTOTP(ctx context.Context, id string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Biz ResetTOTP")
+	defer span.End()
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	if err := uc.repo.DeleteTOTP(ctx, uid); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	uc.log.WithContext(ctx).Infof("reset TOTP of user %s", uid)
+	return nil
+}
+
+func (uc *MFAUsecase) findTOTP(ctx context.Context, uid uuid.UUID) (*TOTP, []byte, error) {
+	totp, err := uc.repo.FindTOTP(ctx, uid)
+	if err != nil {
+		return nil, nil, err
+	}
+	secret, err := uc.sealer.open(totp.Secret, uid[:])
+	if err != nil {
+		uc.log.WithContext(ctx).Errorf("unreadable TOTP secret for user %s: %v", uid, err)
+		return nil, nil, errors.InternalServer("users.mfa", "unreadable TOTP secret")
+	}
+	return totp, secret, nil
+}
+
+// normalizeCode drops the spaces and dashes codes are often typed with.
+func normalizeCode(code string) string {
+	return strings.ToLower(strings.NewReplacer(" ", "", "-", "").Replace(strings.TrimSpace(code)))
+}
+
+// newRecoveryCodes returns codes formatted like "abcde-fghij" along with
+// the hashes to store.
+func newRecoveryCodes(uid uuid.UUID) ([]string, []string, error) {
+	enc := base32.StdEncoding.WithPadding(base32.NoPadding)
+	codes := make([]string, recoveryCodeCount)
+	hashes := make([]string, recoveryCodeCount)
+	buf := make([]byte, recoveryCodeLength*5/8)
+	for i := range codes {
+		if _, err := rand.Read(buf); err != nil {
+			return nil, nil, err
+		}
+		code := strings.ToLower(enc.EncodeToString(buf))
+		codes[i] = code[:recoveryCodeLength/2] + "-" + code[recoveryCodeLength/2:]
+		hashes[i] = hashRecoveryCode(uid, code)
+	}
+	return codes, hashes, nil
+}
+
+// hashRecoveryCode salts with the user id, so that equal codes of different
+// users do not share a hash.
+func hashRecoveryCode(uid uuid.UUID, code string) string {
+	sum := sha256.Sum256([]byte(uid.String() + ":" + code))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,118 @@
+package biz
+
+import (
+	"context"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"time"
+)
+
+// MFALoginTTL is how long a user has to prove their second factor once the
+// first one checked out.
+const MFALoginTTL = 5 * time.Minute
+
+// mfaRequired reports whether a user enabled a second factor, which every
+// login of theirs must then prove before a session starts.
+func (uc *AuthUsecase) mfaRequired(ctx context.Context, uid uuid.UUID) (bool, error) {
+	totp, err := uc.mfa.repo.FindTOTP(ctx, uid)
+	if errors.IsNotFound(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return totp.ConfirmedAt != nil, nil
+}
+
+// verifyMFA checks the TOTP or recovery code of a user.
+func (uc *AuthUsecase) verifyMFA(ctx context.Context, uid uuid.UUID, code string) error {
+	res, err := uc.mfa.VerifyTOTP(ctx, uid.String(), code)
+	if err != nil {
+		return err
+	}
+	if !res.Valid {
+		return errors.Unauthorized("auth.mfa", "invalid code")
+	}
+	return nil
+}
+
+// startLogin starts a session from client for a user whose first factor
+// checked out, and issues its tokens. Users with a second factor get no
+// tokens but an MFA token, to complete the login with CompleteMFALogin.
+func (uc *AuthUsecase) startLogin(ctx context.Context, uid uuid.UUID, client Client) (*TokenPair, string, error) {
+	required, err := uc.mfaRequired(ctx, uid)
+	if err != nil {
+		return nil, "", err
+	}
+	if required {
+		now := time.Now()
+		token, err := uc.signer.sign(typeMFAToken, jwt.RegisteredClaims{
+			ID:        uuid.NewString(),
+			Issuer:    uc.issuer,
+			Subject:   uid.String(),
+			IssuedAt:  jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(now.Add(MFALoginTTL)),
+		})
+		if err != nil {
+			return nil, "", err
+		}
+		return nil, token, nil
+	}
+	session, err := uc.sessions.start(ctx, uid, client, "", "")
+	if err != nil {
+		return nil, "", err
+	}
+	res, err := uc.issue(ctx, uid, session.ID, nil, "")
+	if err != nil {
+		return nil, "", err
+	}
+	return res, "", nil
+}
+
+// CompleteMFALogin checks the second factor of a login startLogin returned
+// an MFA token for, and starts a new session from client like Login.
+func (uc *AuthUsecase) CompleteMFALogin(ctx context.Context, token, code string, client Client) (*TokenPair, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz CompleteMFALogin")
+	defer span.End()
+	invalid := errors.Unauthorized("auth.mfa", "invalid or expired login, please start again")
+	var claims jwt.RegisteredClaims
+	t, err := jwt.ParseWithClaims(token, &claims, uc.signer.keyfunc,
+		jwt.WithValidMethods([]string{
+			jwt.SigningMethodEdDSA.Alg(),
+			jwt.SigningMethodES256.Alg(),
+			jwt.SigningMethodRS256.Alg(),
+		}),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		span.AddEvent(invalid.Error())
+		return nil, invalid
+	}
+	// MFA tokens are signed with the configured keys, never another issuer's
+	if typ, _ := t.Header["typ"].(string); !uc.signer.signedHere(t) || typ != typeMFAToken {
+		span.AddEvent(invalid.Error())
+		return nil, invalid
+	}
+	uid, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		span.AddEvent(invalid.Error())
+		return nil, invalid
+	}
+	if err := uc.verifyMFA(ctx, uid, code); err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	session, err := uc.sessions.start(ctx, uid, client, "", "")
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	res, err := uc.issue(ctx, uid, session.ID, nil, "")
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
@@ -0,0 +1,88 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"testing"
+	"time"
+	"users/internal/conf"
+)
+
+func TestStartLoginWaitsForMFA(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("dave")}
+	auth := newTestAuth(t, &conf.Auth{Keys: []*conf.Auth_Key{testSigningKey(t)}}, user)
+	uid := uuid.MustParse(user.ID)
+
+	pair, mfaToken, err := auth.startLogin(ctx, uid, Client{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair == nil || mfaToken != "" {
+		t.Fatalf("login without a second factor = %v, %q, want tokens", pair, mfaToken)
+	}
+
+	secret := auth.enableTOTP(t, uid)
+	sessions := len(auth.sessions.sessions)
+	pair, mfaToken, err = auth.startLogin(ctx, uid, Client{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair != nil || mfaToken == "" {
+		t.Fatalf("login with a second factor = %v, %q, want an MFA token", pair, mfaToken)
+	}
+	if len(auth.sessions.sessions) != sessions {
+		t.Error("a session started before the second factor was verified")
+	}
+	if _, err := auth.Authenticate(ctx, mfaToken); err == nil {
+		t.Error("the MFA token authenticates as an access token")
+	}
+
+	code := totpCode(secret, totpStep(time.Now()))
+	wrong := fmt.Sprintf("%06d", (mustAtoi(t, code)+1)%1000000)
+	if _, err := auth.CompleteMFALogin(ctx, mfaToken, wrong, Client{}); !errors.IsUnauthorized(err) {
+		t.Errorf("wrong code err = %v, want Unauthorized", err)
+	}
+	access, _, err := auth.startLoginWithoutMFA(ctx, uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := auth.CompleteMFALogin(ctx, access, code, Client{}); errors.Reason(err) != "auth.mfa" {
+		t.Errorf("access token as MFA token err = %v, want auth.mfa", err)
+	}
+	if len(auth.sessions.sessions) != sessions+1 {
+		t.Error("a session started without the second factor")
+	}
+
+	pair, err = auth.CompleteMFALogin(ctx, mfaToken, code, Client{Device: "phone"})
+	if err != nil {
+		t.Fatalf("CompleteMFALogin: %v", err)
+	}
+	p, err := auth.Authenticate(ctx, pair.AccessToken)
+	if err != nil {
+		t.Fatalf("Authenticate: %v", err)
+	}
+	if p.UserID != user.ID {
+		t.Errorf("principal of %s, want %s", p.UserID, user.ID)
+	}
+	// the code was used up
+	if _, err := auth.CompleteMFALogin(ctx, mfaToken, code, Client{}); !errors.IsUnauthorized(err) {
+		t.Errorf("replayed code err = %v, want Unauthorized", err)
+	}
+}
+
+// startLoginWithoutMFA issues an access token to a user as if they had no
+// second factor.
+func (ta *testAuth) startLoginWithoutMFA(ctx context.Context, uid uuid.UUID) (string, *Session, error) {
+	session, err := ta.sessions.SaveSession(ctx, &Session{ID: uuid.New(), UserID: uid, ExpiresAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		return "", nil, err
+	}
+	pair, err := ta.issue(ctx, uid, session.ID, nil, "")
+	if err != nil {
+		return "", nil, err
+	}
+	return pair.AccessToken, session, nil
+}
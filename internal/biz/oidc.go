@@ -0,0 +1,836 @@
+package biz
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// The paths of the OpenID Connect endpoints, relative to the issuer.
+const (
+	OIDCDiscoveryPath = "/.well-known/openid-configuration"
+	OIDCAuthorizePath = "/oauth2/authorize"
+	OIDCTokenPath     = "/oauth2/token"
+	OIDCUserInfoPath  = "/oauth2/userinfo"
+	OIDCJWKSPath      = "/.well-known/jwks.json"
+)
+
+const (
+	defaultAuthorizationCodeTTL = time.Minute
+	// consentFormTTL bounds how long a consent screen can be left open.
+	consentFormTTL = 10 * time.Minute
+
+	purposeOIDCLogin   = "oidc-login"
+	purposeOIDCConsent = "oidc-consent"
+	purposeOIDCMFA     = "oidc-mfa"
+)
+
+// oidcScopes are the scopes clients can be granted, in display order.
+// Requested scopes missing from it are ignored.
+var oidcScopes = []string{"openid", "profile", "email", "phone"}
+
+// OIDCClient is an application registered to log users in through OpenID
+// Connect.
+type OIDCClient struct {
+	ID   uuid.UUID
+	Name string
+	// SecretHash is empty for public clients, such as mobile apps, which
+	// only prove themselves with PKCE.
+	SecretHash   string
+	RedirectURIs []string
+	// Trusted clients are first-party and skip the consent screen.
+	Trusted   bool
+	CreatedAt time.Time
+}
+
+func (c *OIDCClient) Public() bool {
+	return c.SecretHash == ""
+}
+
+// Consent records the scopes a user granted a client.
+type Consent struct {
+	UserID   uuid.UUID
+	ClientID uuid.UUID
+	Scope    string
+}
+
+// AuthorizationCode is an issued, not yet exchanged, authorization code.
+// Only its hash is stored.
+type AuthorizationCode struct {
+	Hash          string
+	ClientID      uuid.UUID
+	UserID        uuid.UUID
+	RedirectURI   string
+	Scope         string
+	Nonce         string
+	CodeChallenge string
+	AuthTime      time.Time
+	ExpiresAt     time.Time
+}
+
+type OIDCRepo interface {
+	SaveClient(context.Context, *OIDCClient) (*OIDCClient, error)
+	// FindClient returns a client or a NotFound error.
+	FindClient(context.Context, uuid.UUID) (*OIDCClient, error)
+	// ListClients returns every client, oldest first.
+	ListClients(context.Context) ([]OIDCClient, error)
+	// DeleteClient deletes a client along with its consents and codes,
+	// returning a NotFound error for unknown clients.
+	DeleteClient(context.Context, uuid.UUID) error
+	// FindConsent returns what a user granted a client, or a NotFound error.
+	FindConsent(ctx context.Context, userID, clientID uuid.UUID) (*Consent, error)
+	// SaveConsent replaces what a user granted a client.
+	SaveConsent(context.Context, *Consent) error
+	SaveAuthorizationCode(context.Context, *AuthorizationCode) error
+	// UseAuthorizationCode deletes and returns the code with the given hash,
+	// or returns a NotFound error, so that each code is exchanged once.
+	UseAuthorizationCode(context.Context, string) (*AuthorizationCode, error)
+}
+
+// OIDCDiscovery is the OpenID Provider metadata served at
+// OIDCDiscoveryPath.
+type OIDCDiscovery struct {
+	Issuer                            string   `json:"issuer"`
+	AuthorizationEndpoint             string   `json:"authorization_endpoint"`
+	TokenEndpoint                     string   `json:"token_endpoint"`
+	UserInfoEndpoint                  string   `json:"userinfo_endpoint"`
+	JWKSURI                           string   `json:"jwks_uri"`
+	ScopesSupported                   []string `json:"scopes_supported"`
+	ResponseTypesSupported            []string `json:"response_types_supported"`
+	GrantTypesSupported               []string `json:"grant_types_supported"`
+	SubjectTypesSupported             []string `json:"subject_types_supported"`
+	IDTokenSigningAlgValuesSupported  []string `json:"id_token_signing_alg_values_supported"`
+	TokenEndpointAuthMethodsSupported []string `json:"token_endpoint_auth_methods_supported"`
+	CodeChallengeMethodsSupported     []string `json:"code_challenge_methods_supported"`
+	ClaimsSupported                   []string `json:"claims_supported"`
+}
+
+// AuthorizeRequest is a validated authorization request. Errors about it
+// can be reported to its client through RedirectError.
+type AuthorizeRequest struct {
+	Client        *OIDCClient
+	RedirectURI   string
+	Scope         string
+	State         string
+	Nonce         string
+	CodeChallenge string
+	// Prompt holds the space separated "none", "login" and "consent"
+	// values the client asked for.
+	Prompt string
+}
+
+// Prompts reports whether the client asked for the given prompt value.
+func (r *AuthorizeRequest) Prompts(value string) bool {
+	for _, p := range strings.Fields(r.Prompt) {
+		if p == value {
+			return true
+		}
+	}
+	return false
+}
+
+// RedirectError returns where to send the browser to report err to the
+// client. The OAuth error code is the reason of err.
+func (r *AuthorizeRequest) RedirectError(err error) string {
+	e := errors.FromError(err)
+	code := e.Reason
+	if e.Code >= 500 || code == "" {
+		code = "server_error"
+	}
+	params := url.Values{"error": {code}}
+	if e.Code < 500 && e.Message != "" {
+		params.Set("error_description", e.Message)
+	}
+	return r.redirect(params)
+}
+
+func (r *AuthorizeRequest) redirect(params url.Values) string {
+	if r.State != "" {
+		params.Set("state", r.State)
+	}
+	u, _ := url.Parse(r.RedirectURI)
+	q := u.Query()
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// TokenRequest is a request to the token endpoint.
+type TokenRequest struct {
+	GrantType    string
+	Code         string
+	RedirectURI  string
+	CodeVerifier string
+	RefreshToken string
+	ClientID     string
+	ClientSecret string
+}
+
+// OIDCTokens is what the token endpoint hands out. IDToken is only set for
+// the authorization code grant.
+type OIDCTokens struct {
+	TokenPair
+	IDToken string
+	Scope   string
+}
+
+// OIDCUsecase is an OpenID Connect provider on top of the users store. It
+// supports the authorization code flow with PKCE, and issues the same
+// access and refresh tokens as AuthUsecase, in a session of their own per
+// client. Browsers log in to the provider itself with a signed cookie naming
+// a session.
+type OIDCUsecase struct {
+	auth    *AuthUsecase
+	users   UsersRepo
+	repo    OIDCRepo
+	cookies *signedTokens
+	issuer  string
+	codeTTL time.Duration
+	log     *log.Helper
+}
+
+// NewOIDCUsecase new an OIDC usecase.
+func NewOIDCUsecase(auth *AuthUsecase, users UsersRepo, repo OIDCRepo, c *conf.Auth, logger log.Logger) (*OIDCUsecase, error) {
+	helper := log.NewHelper(logger)
+	oc := c.GetOidc()
+	cookies, err := newSignedTokens(oc.GetCookieSecret())
+	if err != nil {
+		return nil, err
+	}
+	issuer := strings.TrimSuffix(c.GetIssuer(), "/")
+	if !strings.HasPrefix(issuer, "https://") && !strings.HasPrefix(issuer, "http://") {
+		issuer = ""
+	} else if oc.GetCookieSecret() == "" {
+		helper.Warn("no OIDC cookie secret configured, browsers will be logged out on restart")
+	}
+	codeTTL := oc.GetCodeTtl().AsDuration()
+	if codeTTL <= 0 {
+		codeTTL = defaultAuthorizationCodeTTL
+	}
+	return &OIDCUsecase{
+		auth:    auth,
+		users:   users,
+		repo:    repo,
+		cookies: cookies,
+		issuer:  issuer,
+		codeTTL: codeTTL,
+		log:     helper,
+	}, nil
+}
+
+// Enabled reports whether the provider is configured, which takes an
+// http(s) issuer.
+func (uc *OIDCUsecase) Enabled() bool {
+	return uc.issuer != ""
+}
+
+// SecureCookies reports whether the login cookie must be sent over HTTPS
+// only.
+func (uc *OIDCUsecase) SecureCookies() bool {
+	return strings.HasPrefix(uc.issuer, "https://")
+}
+
+func (uc *OIDCUsecase) Discovery() *OIDCDiscovery {
+	return &OIDCDiscovery{
+		Issuer:                            uc.issuer,
+		AuthorizationEndpoint:             uc.issuer + OIDCAuthorizePath,
+		TokenEndpoint:                     uc.issuer + OIDCTokenPath,
+		UserInfoEndpoint:                  uc.issuer + OIDCUserInfoPath,
+		JWKSURI:                           uc.issuer + OIDCJWKSPath,
+		ScopesSupported:                   oidcScopes,
+		ResponseTypesSupported:            []string{"code"},
+		GrantTypesSupported:               []string{"authorization_code", "refresh_token"},
+		SubjectTypesSupported:             []string{"public"},
+		IDTokenSigningAlgValuesSupported:  []string{uc.auth.signer.keys[0].method.Alg()},
+		TokenEndpointAuthMethodsSupported: []string{"client_secret_basic", "client_secret_post", "none"},
+		CodeChallengeMethodsSupported:     []string{"S256"},
+		ClaimsSupported: []string{
+			"sub", "iss", "aud", "exp", "iat", "auth_time", "nonce", "sid",
+			"preferred_username", "picture", "updated_at",
+			"email", "email_verified", "phone_number", "phone_number_verified",
+		},
+	}
+}
+
+// RegisterClient registers a client and returns it along with its secret,
+// which is empty for public clients.
+func (uc *OIDCUsecase) RegisterClient(ctx context.Context, name string, redirectURIs []string, public, trusted bool) (*OIDCClient, string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz RegisterClient")
+	defer span.End()
+	name = strings.TrimSpace(name)
+	if name == "" {
+		err := errors.BadRequest("oidc.client", "name is required")
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	if len(redirectURIs) == 0 {
+		err := errors.BadRequest("oidc.client", "at least one redirect URI is required")
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	for _, uri := range redirectURIs {
+		if err := checkRedirectURI(uri); err != nil {
+			span.AddEvent(err.Error())
+			return nil, "", err
+		}
+	}
+	client := &OIDCClient{
+		Name:         name,
+		RedirectURIs: redirectURIs,
+		Trusted:      trusted,
+	}
+	var secret string
+	if !public {
+		var err error
+		secret, err = randomToken()
+		if err != nil {
+			span.AddEvent(err.Error())
+			return nil, "", err
+		}
+		client.SecretHash = sha256Hex(secret)
+	}
+	res, err := uc.repo.SaveClient(ctx, client)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	return res, secret, nil
+}
+
+func (uc *OIDCUsecase) ListClients(ctx context.Context) ([]OIDCClient, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz ListClients")
+	defer span.End()
+	res, err := uc.repo.ListClients(ctx)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+// DeleteClient deletes a client. Tokens it already holds keep working until
+// they expire, but can no longer be refreshed.
+func (uc *OIDCUsecase) DeleteClient(ctx context.Context, id string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Biz DeleteClient")
+	defer span.End()
+	cid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	if err := uc.repo.DeleteClient(ctx, cid); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
+
+// ParseAuthorizeRequest validates the parameters of an authorization
+// request. The request is returned along with errors that can be reported
+// to the client, and is nil when the client or its redirect URI cannot be
+// trusted.
+func (uc *OIDCUsecase) ParseAuthorizeRequest(ctx context.Context, params url.Values) (*AuthorizeRequest, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz ParseAuthorizeRequest")
+	defer span.End()
+	cid, err := uuid.Parse(params.Get("client_id"))
+	if err != nil {
+		err := errors.BadRequest("invalid_request", "unknown client")
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	client, err := uc.repo.FindClient(ctx, cid)
+	if errors.IsNotFound(err) {
+		err = errors.BadRequest("invalid_request", "unknown client")
+	}
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	redirectURI := params.Get("redirect_uri")
+	registered := false
+	for _, uri := range client.RedirectURIs {
+		registered = registered || uri == redirectURI
+	}
+	if !registered {
+		err := errors.BadRequest("invalid_request", "redirect_uri is not registered for the client")
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	req := &AuthorizeRequest{
+		Client:        client,
+		RedirectURI:   redirectURI,
+		Scope:         normalizeScope(params.Get("scope")),
+		State:         params.Get("state"),
+		Nonce:         params.Get("nonce"),
+		CodeChallenge: params.Get("code_challenge"),
+		Prompt:        params.Get("prompt"),
+	}
+	switch {
+	case params.Get("response_type") != "code":
+		err = errors.BadRequest("unsupported_response_type", "only the code response type is supported")
+	case !hasScope(req.Scope, "openid"):
+		err = errors.BadRequest("invalid_scope", "the openid scope is required")
+	case params.Get("code_challenge_method") != "S256" || !validCodeChallenge(req.CodeChallenge):
+		err = errors.BadRequest("invalid_request", "PKCE with the S256 method is required")
+	case req.Prompts("none") && len(strings.Fields(req.Prompt)) > 1:
+		err = errors.BadRequest("invalid_request", "prompt none cannot be combined")
+	}
+	if err != nil {
+		span.AddEvent(err.Error())
+		return req, err
+	}
+	return req, nil
+}
+
+// BrowserAuth is the outcome of logging a browser in: the session and the
+// value of its login cookie, or when the user has a second factor to prove,
+// only the value of the cookie holding the login until VerifyBrowserMFA.
+type BrowserAuth struct {
+	Session    *Session
+	Cookie     string
+	PendingMFA string
+}
+
+// BrowserLogin checks the credentials entered on the login page, login
+// being a username or an email, and logs the browser in.
+func (uc *OIDCUsecase) BrowserLogin(ctx context.Context, login, password string, client Client) (*BrowserAuth, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz BrowserLogin")
+	defer span.End()
+	key := LookupKey{Field: FieldUsername, Value: login}
+	if strings.Contains(login, "@") {
+		key = LookupKey{Field: FieldEmail, Value: login}
+	}
+	uid, err := uc.auth.checkCredentials(ctx, key, password, client.IP)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	res, err := uc.loginBrowser(ctx, uid, client)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+// VerifyBrowserMFA checks the TOTP or recovery code entered for a login
+// held by the pending cookie, and only then starts its session.
+func (uc *OIDCUsecase) VerifyBrowserMFA(ctx context.Context, pending, code string, client Client) (*BrowserAuth, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz VerifyBrowserMFA")
+	defer span.End()
+	uid, ok := uc.pendingMFAUser(pending)
+	if !ok {
+		err := errors.Unauthorized("oidc.mfa", "the login expired, please log in again")
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if err := uc.auth.verifyMFA(ctx, uid, code); err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	res, err := uc.startBrowserSession(ctx, uid, client)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+// HasPendingMFA reports whether the pending cookie holds a login still
+// waiting for its second factor.
+func (uc *OIDCUsecase) HasPendingMFA(pending string) bool {
+	_, ok := uc.pendingMFAUser(pending)
+	return ok
+}
+
+func (uc *OIDCUsecase) pendingMFAUser(pending string) (uuid.UUID, bool) {
+	claims, err := uc.cookies.verify("oidc.mfa", purposeOIDCMFA, pending)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	uid, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, false
+	}
+	return uid, true
+}
+
+// loginBrowser logs in a browser that proved to be userID with a first
+// factor: a session starts unless the user has a second factor, which the
+// login then waits for.
+func (uc *OIDCUsecase) loginBrowser(ctx context.Context, userID uuid.UUID, client Client) (*BrowserAuth, error) {
+	required, err := uc.auth.mfaRequired(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !required {
+		return uc.startBrowserSession(ctx, userID, client)
+	}
+	pending, err := uc.cookies.sign(purposeOIDCMFA, tokenClaims{
+		Subject: userID.String(),
+		Expires: time.Now().Add(MFALoginTTL).Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &BrowserAuth{PendingMFA: pending}, nil
+}
+
+// startBrowserSession starts a session for a browser that proved to be
+// userID, returning it along with the value of its cookie.
+func (uc *OIDCUsecase) startBrowserSession(ctx context.Context, userID uuid.UUID, client Client) (*BrowserAuth, error) {
+	session, err := uc.auth.sessions.start(ctx, userID, client, "", "")
+	if err != nil {
+		return nil, err
+	}
+	cookie, err := uc.cookies.sign(purposeOIDCLogin, tokenClaims{
+		Subject: session.ID.String(),
+		Expires: session.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &BrowserAuth{Session: session, Cookie: cookie}, nil
+}
+
+// BrowserSession returns the active session named by a login cookie, or nil.
+func (uc *OIDCUsecase) BrowserSession(ctx context.Context, cookie string) (*Session, error) {
+	claims, err := uc.cookies.verify("oidc.login", purposeOIDCLogin, cookie)
+	if err != nil {
+		return nil, nil
+	}
+	return uc.auth.sessions.ValidateSession(ctx, claims.Subject)
+}
+
+// NeedsConsent reports whether the user of session has yet to grant the
+// requested scopes to the client.
+func (uc *OIDCUsecase) NeedsConsent(ctx context.Context, session *Session, req *AuthorizeRequest) (bool, error) {
+	if req.Client.Trusted {
+		return false, nil
+	}
+	consent, err := uc.repo.FindConsent(ctx, session.UserID, req.Client.ID)
+	if errors.IsNotFound(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	for _, s := range strings.Fields(req.Scope) {
+		if !hasScope(consent.Scope, s) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ConsentToken returns the token the consent form posts back, which ties
+// the answer to the session and the request it was asked for.
+func (uc *OIDCUsecase) ConsentToken(session *Session, req *AuthorizeRequest) (string, error) {
+	return uc.cookies.sign(purposeOIDCConsent, tokenClaims{
+		Subject: session.ID.String(),
+		Binding: req.Client.ID.String() + " " + req.Scope,
+		Expires: time.Now().Add(consentFormTTL).Unix(),
+	})
+}
+
+// Consent records the answer of the consent form. A denial is returned as
+// an access_denied error for the client.
+func (uc *OIDCUsecase) Consent(ctx context.Context, session *Session, req *AuthorizeRequest, token string, allow bool) error {
+	_, span := otel.Tracer("users").Start(ctx, "Biz Consent")
+	defer span.End()
+	claims, err := uc.cookies.verify("invalid_request", purposeOIDCConsent, token)
+	if err == nil && (claims.Subject != session.ID.String() || claims.Binding != req.Client.ID.String()+" "+req.Scope) {
+		err = errors.BadRequest("invalid_request", "invalid or expired token")
+	}
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	if !allow {
+		err := errors.Forbidden("access_denied", "the user denied access")
+		span.AddEvent(err.Error())
+		return err
+	}
+	err = uc.repo.SaveConsent(ctx, &Consent{
+		UserID:   session.UserID,
+		ClientID: req.Client.ID,
+		Scope:    req.Scope,
+	})
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
+
+// IssueCode issues an authorization code to the user of session and
+// returns where to send the browser with it.
+func (uc *OIDCUsecase) IssueCode(ctx context.Context, session *Session, req *AuthorizeRequest) (string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz IssueCode")
+	defer span.End()
+	code, err := randomToken()
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", err
+	}
+	err = uc.repo.SaveAuthorizationCode(ctx, &AuthorizationCode{
+		Hash:          sha256Hex(code),
+		ClientID:      req.Client.ID,
+		UserID:        session.UserID,
+		RedirectURI:   req.RedirectURI,
+		Scope:         req.Scope,
+		Nonce:         req.Nonce,
+		CodeChallenge: req.CodeChallenge,
+		AuthTime:      session.CreatedAt,
+		ExpiresAt:     time.Now().Add(uc.codeTTL),
+	})
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", err
+	}
+	return req.redirect(url.Values{"code": {code}}), nil
+}
+
+// Token serves the token endpoint. Errors carry the OAuth error code as
+// their reason.
+func (uc *OIDCUsecase) Token(ctx context.Context, req *TokenRequest, from Client) (*OIDCTokens, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz Token")
+	defer span.End()
+	client, err := uc.authenticateClient(ctx, req.ClientID, req.ClientSecret)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	var res *OIDCTokens
+	switch req.GrantType {
+	case "authorization_code":
+		res, err = uc.exchangeCode(ctx, client, req, from)
+	case "refresh_token":
+		var pair *TokenPair
+		pair, err = uc.auth.refresh(ctx, req.RefreshToken, client.ID.String())
+		if errors.Reason(err) == "auth.refresh" {
+			err = errors.BadRequest("invalid_grant", "invalid refresh token")
+		}
+		if err == nil {
+			res = &OIDCTokens{TokenPair: *pair}
+		}
+	default:
+		err = errors.BadRequest("unsupported_grant_type", "only the authorization_code and refresh_token grants are supported")
+	}
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+func (uc *OIDCUsecase) authenticateClient(ctx context.Context, id, secret string) (*OIDCClient, error) {
+	invalid := errors.Unauthorized("invalid_client", "client authentication failed")
+	cid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, invalid
+	}
+	client, err := uc.repo.FindClient(ctx, cid)
+	if errors.IsNotFound(err) {
+		return nil, invalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if client.Public() {
+		if secret != "" {
+			return nil, invalid
+		}
+		return client, nil
+	}
+	if subtle.ConstantTimeCompare([]byte(sha256Hex(secret)), []byte(client.SecretHash)) != 1 {
+		return nil, invalid
+	}
+	return client, nil
+}
+
+func (uc *OIDCUsecase) exchangeCode(ctx context.Context, client *OIDCClient, req *TokenRequest, from Client) (*OIDCTokens, error) {
+	invalid := errors.BadRequest("invalid_grant", "invalid authorization code")
+	code, err := uc.repo.UseAuthorizationCode(ctx, sha256Hex(req.Code))
+	if errors.IsNotFound(err) {
+		return nil, invalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if code.ClientID != client.ID || code.RedirectURI != req.RedirectURI || time.Now().After(code.ExpiresAt) {
+		return nil, invalid
+	}
+	sum := sha256.Sum256([]byte(req.CodeVerifier))
+	challenge := base64.RawURLEncoding.EncodeToString(sum[:])
+	if subtle.ConstantTimeCompare([]byte(challenge), []byte(code.CodeChallenge)) != 1 {
+		return nil, errors.BadRequest("invalid_grant", "code_verifier does not match the code challenge")
+	}
+	user, err := uc.users.FindByID(ctx, code.UserID, ExcludeDeleted)
+	if errors.IsNotFound(err) {
+		return nil, invalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	from.Device = client.Name
+	session, err := uc.auth.sessions.start(ctx, code.UserID, from, client.ID.String(), code.Scope)
+	if err != nil {
+		return nil, err
+	}
+	pair, err := uc.auth.issue(ctx, code.UserID, session.ID, nil, code.Scope)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	claims := jwt.MapClaims{}
+	for k, v := range userClaims(user, code.Scope) {
+		claims[k] = v
+	}
+	claims["iss"] = uc.issuer
+	claims["aud"] = client.ID.String()
+	claims["iat"] = now.Unix()
+	claims["exp"] = pair.AccessExpiresAt.Unix()
+	claims["auth_time"] = code.AuthTime.Unix()
+	claims["sid"] = session.ID.String()
+	if code.Nonce != "" {
+		claims["nonce"] = code.Nonce
+	}
+	idToken, err := uc.auth.signer.sign(typeIDToken, claims)
+	if err != nil {
+		return nil, err
+	}
+	return &OIDCTokens{TokenPair: *pair, IDToken: idToken, Scope: code.Scope}, nil
+}
+
+// UserInfo returns the claims about the user an access token was issued to
+// that its scope allows.
+func (uc *OIDCUsecase) UserInfo(ctx context.Context, token string) (map[string]interface{}, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz UserInfo")
+	defer span.End()
+	claims, err := uc.auth.parseAccessToken(ctx, token)
+	if err != nil {
+		err := errors.Unauthorized("invalid_token", errors.FromError(err).Message)
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if !hasScope(claims.Scope, "openid") {
+		err := errors.Forbidden("insufficient_scope", "the access token was not issued for OpenID Connect")
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	uid, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, errors.Unauthorized("invalid_token", "invalid access token")
+	}
+	user, err := uc.users.FindByID(ctx, uid, ExcludeDeleted)
+	if errors.IsNotFound(err) {
+		err = errors.Unauthorized("invalid_token", "invalid access token")
+	}
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return userClaims(user, claims.Scope), nil
+}
+
+// userClaims maps a user to the standard claims scope grants.
+func userClaims(u *Users, scope string) map[string]interface{} {
+	claims := map[string]interface{}{"sub": u.ID}
+	if hasScope(scope, "profile") {
+		if u.Username != nil {
+			claims["preferred_username"] = *u.Username
+		}
+		if u.Avatar != nil && *u.Avatar != "" {
+			claims["picture"] = *u.Avatar
+		}
+		if u.UpdatedAt != nil {
+			claims["updated_at"] = u.UpdatedAt.Unix()
+		}
+	}
+	if hasScope(scope, "email") && u.Email != nil {
+		claims["email"] = *u.Email
+		claims["email_verified"] = u.EmailVerifiedAt != nil
+	}
+	if hasScope(scope, "phone") && u.Phone != nil && *u.Phone != "" {
+		claims["phone_number"] = *u.Phone
+		claims["phone_number_verified"] = u.PhoneVerifiedAt != nil
+	}
+	return claims
+}
+
+// normalizeScope keeps the supported scopes of a request, in the order of
+// oidcScopes.
+func normalizeScope(scope string) string {
+	var res []string
+	for _, s := range oidcScopes {
+		if hasScope(scope, s) {
+			res = append(res, s)
+		}
+	}
+	return strings.Join(res, " ")
+}
+
+func hasScope(scope, s string) bool {
+	for _, f := range strings.Fields(scope) {
+		if f == s {
+			return true
+		}
+	}
+	return false
+}
+
+// validCodeChallenge checks the form of an S256 code challenge, the
+// base64url encoding of a SHA-256 hash.
+func validCodeChallenge(c string) bool {
+	b, err := base64.RawURLEncoding.DecodeString(c)
+	return err == nil && len(b) == sha256.Size
+}
+
+// checkRedirectURI accepts absolute URIs without fragment. Plain http is only
+// allowed on loopback addresses, for native apps and development.
+func checkRedirectURI(uri string) error {
+	u, err := url.Parse(uri)
+	if err != nil || !u.IsAbs() || u.Fragment != "" {
+		return errors.BadRequest("oidc.client", fmt.Sprintf("invalid redirect URI %q", uri))
+	}
+	if u.Scheme == "http" {
+		host := u.Hostname()
+		ip := net.ParseIP(host)
+		if host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			return errors.BadRequest("oidc.client", fmt.Sprintf("redirect URI %q must use https", uri))
+		}
+	}
+	return nil
+}
+
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,407 @@
+package biz
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"net/url"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const testIssuer = "https://id.example.com"
+
+type memOIDC struct {
+	OIDCRepo
+	mu      sync.Mutex
+	clients map[uuid.UUID]*OIDCClient
+	codes   map[string]*AuthorizationCode
+}
+
+func (r *memOIDC) SaveClient(_ context.Context, c *OIDCClient) (*OIDCClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved := *c
+	saved.ID = uuid.New()
+	r.clients[saved.ID] = &saved
+	return &saved, nil
+}
+
+func (r *memOIDC) FindClient(_ context.Context, id uuid.UUID) (*OIDCClient, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.clients[id]
+	if !ok {
+		return nil, errors.NotFound("oidc.client", "client not found")
+	}
+	return c, nil
+}
+
+func (r *memOIDC) SaveAuthorizationCode(_ context.Context, code *AuthorizationCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.codes[code.Hash] = code
+	return nil
+}
+
+func (r *memOIDC) UseAuthorizationCode(_ context.Context, hash string) (*AuthorizationCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	code, ok := r.codes[hash]
+	if !ok {
+		return nil, errors.NotFound("oidc.code", "code not found")
+	}
+	delete(r.codes, hash)
+	return code, nil
+}
+
+// testSigningKey returns a configured ES256 signing key.
+func testSigningKey(t *testing.T) *conf.Auth_Key {
+	t.Helper()
+	priv, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(priv)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &conf.Auth_Key{
+		Id:         "test-key",
+		PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+	}
+}
+
+func newTestOIDC(t *testing.T, users ...*Users) (*OIDCUsecase, *testAuth, *memOIDC) {
+	t.Helper()
+	auth := newTestAuth(t, &conf.Auth{
+		Issuer: testIssuer,
+		Keys:   []*conf.Auth_Key{testSigningKey(t)},
+		Oidc:   &conf.Auth_Oidc{CookieSecret: "test cookie secret"},
+	}, users...)
+	repo := &memOIDC{clients: make(map[uuid.UUID]*OIDCClient), codes: make(map[string]*AuthorizationCode)}
+	uc, err := NewOIDCUsecase(auth.AuthUsecase, auth.users, repo, &conf.Auth{
+		Issuer: testIssuer,
+		Oidc:   &conf.Auth_Oidc{CookieSecret: "test cookie secret"},
+	}, log.NewStdLogger(testWriter{t}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uc, auth, repo
+}
+
+// authorize runs an authorization request of client for the user of session
+// and returns the code it redirected with.
+func authorize(t *testing.T, uc *OIDCUsecase, client *OIDCClient, session *Session, verifier, nonce string) string {
+	t.Helper()
+	ctx := context.Background()
+	sum := sha256.Sum256([]byte(verifier))
+	req, err := uc.ParseAuthorizeRequest(ctx, url.Values{
+		"client_id":             {client.ID.String()},
+		"redirect_uri":          {client.RedirectURIs[0]},
+		"response_type":         {"code"},
+		"scope":                 {"openid email profile"},
+		"state":                 {"xyz"},
+		"nonce":                 {nonce},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+	})
+	if err != nil {
+		t.Fatalf("ParseAuthorizeRequest: %v", err)
+	}
+	location, err := uc.IssueCode(ctx, session, req)
+	if err != nil {
+		t.Fatalf("IssueCode: %v", err)
+	}
+	u, err := url.Parse(location)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := u.Query().Get("state"); got != "xyz" {
+		t.Errorf("state = %q, want xyz", got)
+	}
+	code := u.Query().Get("code")
+	if code == "" {
+		t.Fatalf("no code in %s", location)
+	}
+	return code
+}
+
+// verifyWithJWKS verifies a token the way a client does, with the keys
+// published in the JWKS document.
+func verifyWithJWKS(t *testing.T, set JWKS, token string, claims jwt.Claims, opts ...jwt.ParserOption) (*jwt.Token, error) {
+	t.Helper()
+	// through JSON, as clients get it
+	data, err := json.Marshal(set)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var published JWKS
+	if err := json.Unmarshal(data, &published); err != nil {
+		t.Fatal(err)
+	}
+	return jwt.ParseWithClaims(token, claims, func(tok *jwt.Token) (interface{}, error) {
+		for _, k := range published.Keys {
+			if k.Kid == tok.Header["kid"] && k.Alg == tok.Method.Alg() {
+				return k.publicKey()
+			}
+		}
+		return nil, errors.New(401, "test", "no such key")
+	}, opts...)
+}
+
+func TestOIDCCodeExchange(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{
+		ID:              uuid.NewString(),
+		Username:        ptr("alice"),
+		Email:           ptr("alice@example.com"),
+		EmailVerifiedAt: ptr(time.Now()),
+	}
+	uc, auth, _ := newTestOIDC(t, user)
+	client, secret, err := uc.RegisterClient(ctx, "app", []string{"https://app.example.com/cb"}, false, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session, err := auth.sessions.SaveSession(ctx, &Session{
+		ID:        uuid.New(),
+		UserID:    uuid.MustParse(user.ID),
+		CreatedAt: time.Now().Add(-time.Minute),
+		ExpiresAt: time.Now().Add(time.Hour),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	verifier := "a-verifier-long-enough-for-pkce-0123456789-abcdef"
+	code := authorize(t, uc, client, session, verifier, "n-0S6_WzA2Mj")
+
+	res, err := uc.Token(ctx, &TokenRequest{
+		GrantType:    "authorization_code",
+		Code:         code,
+		RedirectURI:  client.RedirectURIs[0],
+		CodeVerifier: verifier,
+		ClientID:     client.ID.String(),
+		ClientSecret: secret,
+	}, Client{})
+	if err != nil {
+		t.Fatalf("Token: %v", err)
+	}
+	if res.Scope != "openid profile email" {
+		t.Errorf("scope = %q", res.Scope)
+	}
+	if res.AccessToken == "" || res.RefreshToken == "" || res.IDToken == "" {
+		t.Fatalf("incomplete tokens %+v", res)
+	}
+
+	claims := jwt.MapClaims{}
+	tok, err := verifyWithJWKS(t, uc.auth.JWKS(), res.IDToken, claims,
+		jwt.WithValidMethods([]string{jwt.SigningMethodES256.Alg()}),
+		jwt.WithIssuer(testIssuer),
+		jwt.WithAudience(client.ID.String()),
+		jwt.WithExpirationRequired(),
+	)
+	if err != nil {
+		t.Fatalf("ID token does not verify against the JWKS: %v", err)
+	}
+	if typ := tok.Header["typ"]; typ != typeIDToken {
+		t.Errorf("typ = %v, want %s", typ, typeIDToken)
+	}
+	want := map[string]interface{}{
+		"sub":                user.ID,
+		"nonce":              "n-0S6_WzA2Mj",
+		"preferred_username": "alice",
+		"email":              "alice@example.com",
+		"email_verified":     true,
+		"auth_time":          float64(session.CreatedAt.Unix()),
+	}
+	for k, v := range want {
+		if claims[k] != v {
+			t.Errorf("claim %s = %v, want %v", k, claims[k], v)
+		}
+	}
+	if _, ok := claims["phone_number"]; ok {
+		t.Error("phone_number claim without the phone scope")
+	}
+
+	// the access token is the client's, good for userinfo only
+	info, err := uc.UserInfo(ctx, res.AccessToken)
+	if err != nil {
+		t.Fatalf("UserInfo: %v", err)
+	}
+	if info["sub"] != user.ID {
+		t.Errorf("userinfo sub = %v", info["sub"])
+	}
+	if _, err := auth.Authenticate(ctx, res.AccessToken); err == nil {
+		t.Error("the access token of a client authenticates on the API")
+	}
+	if _, err := auth.Authenticate(ctx, res.IDToken); err == nil {
+		t.Error("an ID token authenticates as an access token")
+	}
+}
+
+func TestOIDCCodeExchangeRejections(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("bob")}
+	uc, _, _ := newTestOIDC(t, user)
+	client, _, err := uc.RegisterClient(ctx, "mobile", []string{"http://127.0.0.1:8080/cb"}, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, _, err := uc.RegisterClient(ctx, "other", []string{"http://127.0.0.1:9090/cb"}, true, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	session := &Session{ID: uuid.New(), UserID: uuid.MustParse(user.ID), CreatedAt: time.Now()}
+	verifier := "another-verifier-long-enough-for-pkce-0123456789"
+	exchange := func(code string, mod func(*TokenRequest)) error {
+		req := &TokenRequest{
+			GrantType:    "authorization_code",
+			Code:         code,
+			RedirectURI:  client.RedirectURIs[0],
+			CodeVerifier: verifier,
+			ClientID:     client.ID.String(),
+		}
+		if mod != nil {
+			mod(req)
+		}
+		_, err := uc.Token(ctx, req, Client{})
+		return err
+	}
+
+	tests := []struct {
+		name   string
+		mod    func(*TokenRequest)
+		reason string
+	}{
+		{"wrong verifier", func(r *TokenRequest) { r.CodeVerifier = "not-the-verifier-of-the-challenge-0123456789" }, "invalid_grant"},
+		{"no verifier", func(r *TokenRequest) { r.CodeVerifier = "" }, "invalid_grant"},
+		{"other redirect URI", func(r *TokenRequest) { r.RedirectURI = "http://127.0.0.1:8080/other" }, "invalid_grant"},
+		{"other client", func(r *TokenRequest) { r.ClientID = other.ID.String() }, "invalid_grant"},
+		{"secret for a public client", func(r *TokenRequest) { r.ClientSecret = "guess" }, "invalid_client"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code := authorize(t, uc, client, session, verifier, "")
+			if err := exchange(code, tt.mod); errors.Reason(err) != tt.reason {
+				t.Errorf("err = %v, want reason %s", err, tt.reason)
+			}
+		})
+	}
+
+	t.Run("code reuse", func(t *testing.T) {
+		code := authorize(t, uc, client, session, verifier, "")
+		if err := exchange(code, nil); err != nil {
+			t.Fatalf("first exchange: %v", err)
+		}
+		if err := exchange(code, nil); errors.Reason(err) != "invalid_grant" {
+			t.Errorf("second exchange err = %v, want invalid_grant", err)
+		}
+	})
+
+	t.Run("plain PKCE", func(t *testing.T) {
+		_, err := uc.ParseAuthorizeRequest(ctx, url.Values{
+			"client_id":             {client.ID.String()},
+			"redirect_uri":          {client.RedirectURIs[0]},
+			"response_type":         {"code"},
+			"scope":                 {"openid"},
+			"code_challenge":        {verifier},
+			"code_challenge_method": {"plain"},
+		})
+		if errors.Reason(err) != "invalid_request" {
+			t.Errorf("err = %v, want invalid_request", err)
+		}
+	})
+}
+
+func TestOIDCIDTokenForeignKey(t *testing.T) {
+	uc, _, _ := newTestOIDC(t)
+	// an ID token signed by another provider's key must not verify
+	other, _, _ := newTestOIDC(t)
+	token, err := other.auth.signer.sign(typeIDToken, jwt.MapClaims{"iss": testIssuer, "sub": "x", "exp": time.Now().Add(time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := verifyWithJWKS(t, uc.auth.JWKS(), token, jwt.MapClaims{}); err == nil {
+		t.Error("token of another key verified")
+	}
+}
+
+func TestOIDCBrowserLoginWaitsForMFA(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("carol")}
+	uc, auth, _ := newTestOIDC(t, user)
+	uid := uuid.MustParse(user.ID)
+
+	res, err := uc.loginBrowser(ctx, uid, Client{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Session == nil || res.Cookie == "" || res.PendingMFA != "" {
+		t.Fatalf("login without a second factor = %+v, want a session", res)
+	}
+
+	secret := auth.enableTOTP(t, uid)
+	sessions := len(auth.sessions.sessions)
+	res, err = uc.loginBrowser(ctx, uid, Client{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.Session != nil || res.Cookie != "" || res.PendingMFA == "" {
+		t.Fatalf("login with a second factor = %+v, want it pending", res)
+	}
+	if len(auth.sessions.sessions) != sessions {
+		t.Error("a session started before the second factor was verified")
+	}
+	if !uc.HasPendingMFA(res.PendingMFA) {
+		t.Error("pending login not recognized")
+	}
+	if session, _ := uc.BrowserSession(ctx, res.PendingMFA); session != nil {
+		t.Error("the pending cookie works as a login cookie")
+	}
+
+	code := totpCode(secret, totpStep(time.Now()))
+	wrong := fmt.Sprintf("%06d", (mustAtoi(t, code)+1)%1000000)
+	if _, err := uc.VerifyBrowserMFA(ctx, res.PendingMFA, wrong, Client{}); !errors.IsUnauthorized(err) {
+		t.Errorf("wrong code err = %v, want Unauthorized", err)
+	}
+	if _, err := uc.VerifyBrowserMFA(ctx, "forged", code, Client{}); errors.Reason(err) != "oidc.mfa" {
+		t.Errorf("forged pending cookie err = %v, want oidc.mfa", err)
+	}
+	if len(auth.sessions.sessions) != sessions {
+		t.Error("a session started without the second factor")
+	}
+	done, err := uc.VerifyBrowserMFA(ctx, res.PendingMFA, code, Client{})
+	if err != nil {
+		t.Fatalf("VerifyBrowserMFA: %v", err)
+	}
+	if done.Session == nil || done.Session.UserID != uid || done.Cookie == "" {
+		t.Fatalf("verified login = %+v, want a session of the user", done)
+	}
+	if session, _ := uc.BrowserSession(ctx, done.Cookie); session == nil || session.ID != done.Session.ID {
+		t.Error("login cookie does not name the session")
+	}
+}
+
+func mustAtoi(t *testing.T, s string) int {
+	t.Helper()
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return n
+}
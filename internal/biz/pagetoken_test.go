@@ -0,0 +1,118 @@
+package biz
+
+import (
+	"encoding/base64"
+	"github.com/go-kratos/kratos/v2/errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPageTokenRoundTrip(t *testing.T) {
+	codec, err := newPageTokenCodec("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []Keyset{
+		{"6f1c1b8e-3c4f-4a8e-9d1c-2b7e5f0a9c31"},
+		{"alice", "6f1c1b8e-3c4f-4a8e-9d1c-2b7e5f0a9c31"},
+		{"2025-01-01T00:00:00Z", "a@acme.com", "6f1c1b8e-3c4f-4a8e-9d1c-2b7e5f0a9c31"},
+		// numbers come back as JSON numbers do
+		{float64(42), "6f1c1b8e-3c4f-4a8e-9d1c-2b7e5f0a9c31"},
+	}
+	for _, ks := range tests {
+		token, err := codec.encode("fp", ks)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got, err := codec.decode(token, "fp")
+		if err != nil {
+			t.Errorf("decode(encode(%v)): %v", ks, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, ks) {
+			t.Errorf("decode(encode(%v)) = %v", ks, got)
+		}
+	}
+}
+
+func TestPageTokenRejections(t *testing.T) {
+	codec, err := newPageTokenCodec("secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := newPageTokenCodec("other secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	token, err := codec.encode("fp", Keyset{"alice", "6f1c1b8e-3c4f-4a8e-9d1c-2b7e5f0a9c31"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, sig, _ := strings.Cut(token, ".")
+	forged := base64.RawURLEncoding.EncodeToString([]byte(`{"q":"fp","k":["bob","6f1c1b8e-3c4f-4a8e-9d1c-2b7e5f0a9c31"]}`))
+	signedBy := func(c *pageTokenCodec, body string) string {
+		return body + "." + base64.RawURLEncoding.EncodeToString(c.sign(body))
+	}
+	flip := func(s string) string {
+		if s[0] == 'A' {
+			return "B" + s[1:]
+		}
+		return "A" + s[1:]
+	}
+	empty := base64.RawURLEncoding.EncodeToString([]byte(`{"q":"fp","k":[]}`))
+	garbage := base64.RawURLEncoding.EncodeToString([]byte(`not json`))
+	tests := []struct {
+		name, token, fingerprint string
+	}{
+		{"empty", "", "fp"},
+		{"no signature", body, "fp"},
+		{"forged body", forged + "." + sig, "fp"},
+		{"altered signature", body + "." + flip(sig), "fp"},
+		{"malformed signature", body + ".!!", "fp"},
+		{"signed with another key", signedBy(other, body), "fp"},
+		{"empty keyset", signedBy(codec, empty), "fp"},
+		{"malformed payload", signedBy(codec, garbage), "fp"},
+		{"wrong fingerprint", token, "other fp"},
+	}
+	for _, tt := range tests {
+		if _, err := codec.decode(tt.token, tt.fingerprint); !errors.IsBadRequest(err) {
+			t.Errorf("%s: decode err = %v, want BadRequest", tt.name, err)
+		}
+	}
+}
+
+func TestListFingerprint(t *testing.T) {
+	base := func() (PaginationParams, SortParams, QueryParams) {
+		return PaginationParams{Page: 1, PageSize: 10},
+			SortParams{{FieldUsername, false}},
+			QueryParams{Search: "ali", Filters: []Filter{{FieldEmail, FilterEndsWith, "@acme.com"}}}
+	}
+	pp, sp, qp := base()
+	want := listFingerprint(pp, sp, qp)
+
+	// the page and its size do not change the rows that follow a keyset
+	pp.Page, pp.PageSize, pp.PageToken, pp.EstimateTotal = 3, 50, "token", true
+	qp.Fields = []Field{FieldID, FieldEmail}
+	if got := listFingerprint(pp, sp, qp); got != want {
+		t.Errorf("fingerprint changed with the page, size or fields")
+	}
+
+	changes := map[string]func(*PaginationParams, *SortParams, *QueryParams){
+		"reverse":   func(pp *PaginationParams, _ *SortParams, _ *QueryParams) { pp.Reverse = true },
+		"direction": func(_ *PaginationParams, sp *SortParams, _ *QueryParams) { (*sp)[0].Desc = true },
+		"sort key": func(_ *PaginationParams, sp *SortParams, _ *QueryParams) {
+			*sp = append(*sp, SortKey{FieldCreatedAt, false})
+		},
+		"search":  func(_ *PaginationParams, _ *SortParams, qp *QueryParams) { qp.Search = "bob" },
+		"filter":  func(_ *PaginationParams, _ *SortParams, qp *QueryParams) { qp.Filters[0].Value = "@example.com" },
+		"deleted": func(_ *PaginationParams, _ *SortParams, qp *QueryParams) { qp.Deleted = OnlyDeleted },
+	}
+	for name, change := range changes {
+		pp, sp, qp := base()
+		change(&pp, &sp, &qp)
+		if got := listFingerprint(pp, sp, qp); got == want {
+			t.Errorf("fingerprint did not change with the %s", name)
+		}
+	}
+}
@@ -0,0 +1,602 @@
+package biz
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"net/url"
+	"strings"
+	"time"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+const (
+	defaultPasskeyName     = "Passkey"
+	maxPasskeyNameLength   = 64
+	defaultWebAuthnTimeout = 5 * time.Minute
+)
+
+// Purposes of WebAuthn challenges.
+const (
+	ceremonyRegistration = "registration"
+	ceremonyLogin        = "login"
+)
+
+// Passkey is a WebAuthn credential a user logs in with. PublicKey is in
+// COSE_Key form.
+type Passkey struct {
+	ID             uuid.UUID
+	UserID         uuid.UUID
+	Name           string
+	CredentialID   []byte
+	PublicKey      []byte
+	SignCount      uint32
+	Transports     []string
+	AAGUID         uuid.UUID
+	BackupEligible bool
+	BackedUp       bool
+	CreatedAt      time.Time
+	LastUsedAt     *time.Time
+}
+
+// WebAuthnChallenge is a ceremony waiting to be finished, by hash of its
+// challenge. UserID is nil for logins that may use any passkey.
+type WebAuthnChallenge struct {
+	Hash      string
+	Purpose   string
+	UserID    *uuid.UUID
+	ExpiresAt time.Time
+}
+
+type PasskeysRepo interface {
+	// SavePasskey stores a passkey, returning a Conflict error when its
+	// credential ID is registered already.
+	SavePasskey(context.Context, *Passkey) (*Passkey, error)
+	// FindPasskey returns the passkey with a credential ID, or a NotFound
+	// error.
+	FindPasskey(ctx context.Context, credentialID []byte) (*Passkey, error)
+	// ListPasskeys returns the passkeys of a user, oldest first.
+	ListPasskeys(context.Context, uuid.UUID) ([]Passkey, error)
+	// RenamePasskey renames a passkey of a user, returning a NotFound error
+	// when there is none.
+	RenamePasskey(ctx context.Context, userID, id uuid.UUID, name string) (*Passkey, error)
+	// DeletePasskey deletes a passkey of a user, returning a NotFound error
+	// when there is none.
+	DeletePasskey(ctx context.Context, userID, id uuid.UUID) error
+	// UsePasskey records a login with a passkey along with the state its
+	// authenticator reported.
+	UsePasskey(ctx context.Context, id uuid.UUID, signCount uint32, backedUp bool, at time.Time) error
+	// SaveChallenge stores a challenge, dropping expired ones.
+	SaveChallenge(context.Context, *WebAuthnChallenge) error
+	// UseChallenge deletes and returns the challenge with the given hash,
+	// or returns a NotFound error.
+	UseChallenge(ctx context.Context, hash string) (*WebAuthnChallenge, error)
+}
+
+// PasskeysUsecase registers passkeys and logs users in with them, running
+// the server side of the WebAuthn ceremonies. Passkeys must verify the user
+// (PIN or biometrics), so they stand in for a password and a second factor
+// at once.
+type PasskeysUsecase struct {
+	auth    *AuthUsecase
+	users   *UsersUsecase
+	creds   *CredentialsUsecase
+	repo    PasskeysRepo
+	rpID    string
+	rpName  string
+	rpHash  [32]byte
+	origins map[string]bool
+	timeout time.Duration
+	log     *log.Helper
+}
+
+// NewPasskeysUsecase new a passkeys usecase. Passkeys stay unavailable until
+// a relying party ID and its origins are configured.
+func NewPasskeysUsecase(auth *AuthUsecase, users *UsersUsecase, creds *CredentialsUsecase, repo PasskeysRepo, c *conf.Auth, logger log.Logger) (*PasskeysUsecase, error) {
+	helper := log.NewHelper(logger)
+	wc := c.GetWebauthn()
+	uc := &PasskeysUsecase{
+		auth:    auth,
+		users:   users,
+		creds:   creds,
+		repo:    repo,
+		rpID:    wc.GetRpId(),
+		rpName:  wc.GetRpName(),
+		origins: make(map[string]bool),
+		timeout: wc.GetTimeout().AsDuration(),
+		log:     helper,
+	}
+	if uc.rpName == "" {
+		uc.rpName = uc.rpID
+	}
+	if uc.timeout <= 0 {
+		uc.timeout = defaultWebAuthnTimeout
+	}
+	for _, o := range wc.GetOrigins() {
+		if uc.rpID == "" {
+			break
+		}
+		u, err := url.Parse(o)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" || strings.TrimSuffix(u.Path, "/") != "" {
+			return nil, fmt.Errorf("webauthn origin %q must be a scheme and host", o)
+		}
+		host := u.Hostname()
+		if host != uc.rpID && !strings.HasSuffix(host, "."+uc.rpID) {
+			return nil, fmt.Errorf("webauthn origin %q is not within relying party ID %q", o, uc.rpID)
+		}
+		uc.origins[u.Scheme+"://"+u.Host] = true
+	}
+	if uc.rpID == "" || len(uc.origins) == 0 {
+		uc.rpID = ""
+		helper.Warn("no WebAuthn relying party configured, passkeys are disabled")
+	}
+	uc.rpHash = sha256.Sum256([]byte(uc.rpID))
+	return uc, nil
+}
+
+func (uc *PasskeysUsecase) available() error {
+	if uc.rpID == "" {
+		return errors.ServiceUnavailable("users.passkeys", "passkeys are not configured")
+	}
+	return nil
+}
+
+// BeginRegistration starts registering a passkey for a user and returns the
+// creation options for the browser.
+func (uc *PasskeysUsecase) BeginRegistration(ctx context.Context, userID string) (string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz BeginRegistration")
+	defer span.End()
+	if err := uc.available(); err != nil {
+		span.AddEvent(err.Error())
+		return "", err
+	}
+	user, err := uc.users.GetByID(ctx, userID, ExcludeDeleted)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", err
+	}
+	uid, err := uuid.Parse(user.ID)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", err
+	}
+	existing, err := uc.repo.ListPasskeys(ctx, uid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", err
+	}
+	challenge, err := uc.newChallenge(ctx, ceremonyRegistration, &uid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", err
+	}
+
+	opts := creationOptions{
+		Challenge:          challenge,
+		Timeout:            uc.timeout.Milliseconds(),
+		ExcludeCredentials: descriptors(existing),
+		Attestation:        "none",
+	}
+	opts.RP.ID = uc.rpID
+	opts.RP.Name = uc.rpName
+	opts.User.ID = base64.RawURLEncoding.EncodeToString(uid[:])
+	if user.Username != nil {
+		opts.User.Name = *user.Username
+	}
+	if opts.User.Name == "" && user.Email != nil {
+		opts.User.Name = *user.Email
+	}
+	opts.User.DisplayName = opts.User.Name
+	for _, alg := range passkeyAlgorithms {
+		opts.PubKeyCredParams = append(opts.PubKeyCredParams, credentialParameter{Type: "public-key", Alg: alg})
+	}
+	opts.AuthenticatorSelection.ResidentKey = "required"
+	opts.AuthenticatorSelection.RequireResidentKey = true
+	opts.AuthenticatorSelection.UserVerification = "required"
+	res, err := json.Marshal(opts)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", err
+	}
+	return string(res), nil
+}
+
+// FinishRegistration checks the credential the authenticator created for
+// a registration BeginRegistration started, and stores it.
+func (uc *PasskeysUsecase) FinishRegistration(ctx context.Context, userID, credential, name string) (*Passkey, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz FinishRegistration")
+	defer span.End()
+	res, err := uc.finishRegistration(ctx, userID, credential, name)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+func (uc *PasskeysUsecase) finishRegistration(ctx context.Context, userID, credential, name string) (*Passkey, error) {
+	if err := uc.available(); err != nil {
+		return nil, err
+	}
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		return nil, err
+	}
+	name, err = passkeyName(name)
+	if err != nil {
+		return nil, err
+	}
+	invalid := func(cause error) error {
+		return errors.BadRequest("users.passkeys", "invalid passkey registration").WithCause(cause)
+	}
+	cred, err := parseCredential(credential)
+	if err != nil {
+		return nil, invalid(err)
+	}
+	challenge, err := uc.useChallenge(ctx, cred, "webauthn.create")
+	if err != nil {
+		return nil, err
+	}
+	if challenge.Purpose != ceremonyRegistration || challenge.UserID == nil || *challenge.UserID != uid {
+		return nil, invalid(fmt.Errorf("challenge was issued for another ceremony"))
+	}
+	attestation, err := decodeBase64URL(cred.Response.AttestationObject)
+	if err != nil {
+		return nil, invalid(err)
+	}
+	authData, err := parseAttestationObject(attestation)
+	if err != nil {
+		return nil, invalid(err)
+	}
+	if err := uc.checkAuthenticatorData(authData); err != nil {
+		return nil, invalid(err)
+	}
+	rawID, err := decodeBase64URL(cred.RawID)
+	if err != nil || authData.CredentialID == nil || !bytes.Equal(rawID, authData.CredentialID) {
+		return nil, invalid(fmt.Errorf("credential ID mismatch"))
+	}
+	if _, err := parseCOSEKey(authData.PublicKey); err != nil {
+		return nil, errors.BadRequest("users.passkeys", "unsupported passkey algorithm").WithCause(err)
+	}
+	if _, err := uc.users.GetByID(ctx, userID, ExcludeDeleted); err != nil {
+		return nil, err
+	}
+	return uc.repo.SavePasskey(ctx, &Passkey{
+		UserID:         uid,
+		Name:           name,
+		CredentialID:   authData.CredentialID,
+		PublicKey:      authData.PublicKey,
+		SignCount:      authData.SignCount,
+		Transports:     cred.Response.Transports,
+		AAGUID:         authData.AAGUID,
+		BackupEligible: authData.Flags&authDataBackupEligible != 0,
+		BackedUp:       authData.Flags&authDataBackedUp != 0,
+	})
+}
+
+// BeginLogin starts a passkey login and returns the request options for the
+// browser. With a key, only the passkeys of that user are accepted;
+// without, any passkey the authenticator holds for the relying party.
+func (uc *PasskeysUsecase) BeginLogin(ctx context.Context, key *LookupKey) (string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz BeginLogin")
+	defer span.End()
+	if err := uc.available(); err != nil {
+		span.AddEvent(err.Error())
+		return "", err
+	}
+	var uid *uuid.UUID
+	var allowed []Passkey
+	if key != nil {
+		user, err := uc.users.LookupUser(ctx, *key)
+		switch {
+		case errors.IsNotFound(err):
+			// bind the login to nobody rather than telling that the user
+			// does not exist
+			id := uuid.New()
+			uid = &id
+		case err != nil:
+			span.AddEvent(err.Error())
+			return "", err
+		default:
+			id, err := uuid.Parse(user.ID)
+			if err != nil {
+				span.AddEvent(err.Error())
+				return "", err
+			}
+			uid = &id
+			if allowed, err = uc.repo.ListPasskeys(ctx, id); err != nil {
+				span.AddEvent(err.Error())
+				return "", err
+			}
+		}
+	}
+	challenge, err := uc.newChallenge(ctx, ceremonyLogin, uid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", err
+	}
+	res, err := json.Marshal(requestOptions{
+		Challenge:        challenge,
+		Timeout:          uc.timeout.Milliseconds(),
+		RPID:             uc.rpID,
+		AllowCredentials: descriptors(allowed),
+		UserVerification: "required",
+	})
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", err
+	}
+	return string(res), nil
+}
+
+// FinishLogin checks the assertion of a passkey for a login BeginLogin
+// started, and starts a new session from client like Login. Users with a
+// second factor get an MFA token instead, see CompleteMFALogin.
+func (uc *PasskeysUsecase) FinishLogin(ctx context.Context, credential string, client Client) (*TokenPair, string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz FinishLogin")
+	defer span.End()
+	uid, err := uc.checkAssertion(ctx, credential)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	res, mfaToken, err := uc.auth.startLogin(ctx, uid, client)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	return res, mfaToken, nil
+}
+
+// checkAssertion returns the user whose passkey signed the assertion.
+func (uc *PasskeysUsecase) checkAssertion(ctx context.Context, credential string) (uuid.UUID, error) {
+	if err := uc.available(); err != nil {
+		return uuid.Nil, err
+	}
+	invalid := func(cause error) error {
+		return errors.Unauthorized("auth.passkey", "invalid passkey").WithCause(cause)
+	}
+	cred, err := parseCredential(credential)
+	if err != nil {
+		return uuid.Nil, invalid(err)
+	}
+	challenge, err := uc.useChallenge(ctx, cred, "webauthn.get")
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if challenge.Purpose != ceremonyLogin {
+		return uuid.Nil, invalid(fmt.Errorf("challenge was issued for another ceremony"))
+	}
+	rawID, err := decodeBase64URL(cred.RawID)
+	if err != nil {
+		return uuid.Nil, invalid(err)
+	}
+	passkey, err := uc.repo.FindPasskey(ctx, rawID)
+	if errors.IsNotFound(err) {
+		return uuid.Nil, invalid(err)
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if challenge.UserID != nil && *challenge.UserID != passkey.UserID {
+		return uuid.Nil, invalid(fmt.Errorf("passkey of another user"))
+	}
+	if cred.Response.UserHandle != "" {
+		handle, err := decodeBase64URL(cred.Response.UserHandle)
+		if err != nil || !bytes.Equal(handle, passkey.UserID[:]) {
+			return uuid.Nil, invalid(fmt.Errorf("user handle mismatch"))
+		}
+	}
+
+	rawAuthData, err := decodeBase64URL(cred.Response.AuthenticatorData)
+	if err != nil {
+		return uuid.Nil, invalid(err)
+	}
+	authData, err := parseAuthenticatorData(rawAuthData)
+	if err != nil {
+		return uuid.Nil, invalid(err)
+	}
+	if err := uc.checkAuthenticatorData(authData); err != nil {
+		return uuid.Nil, invalid(err)
+	}
+	key, err := parseCOSEKey(passkey.PublicKey)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	clientData, err := decodeBase64URL(cred.Response.ClientDataJSON)
+	if err != nil {
+		return uuid.Nil, invalid(err)
+	}
+	sig, err := decodeBase64URL(cred.Response.Signature)
+	if err != nil || !key.verify(rawAuthData, clientData, sig) {
+		return uuid.Nil, invalid(fmt.Errorf("bad signature"))
+	}
+	// authenticators that count signatures must count up; a count that did
+	// not is a sign of a cloned authenticator
+	if (authData.SignCount != 0 || passkey.SignCount != 0) && authData.SignCount <= passkey.SignCount {
+		uc.log.WithContext(ctx).Warnf("passkey %s of user %s sign count went from %d to %d, it may have been cloned", passkey.ID, passkey.UserID, passkey.SignCount, authData.SignCount)
+		return uuid.Nil, invalid(fmt.Errorf("sign count did not increase"))
+	}
+	if err := uc.repo.UsePasskey(ctx, passkey.ID, authData.SignCount, authData.Flags&authDataBackedUp != 0, time.Now()); err != nil {
+		return uuid.Nil, err
+	}
+	_, err = uc.users.GetByID(ctx, passkey.UserID.String(), ExcludeDeleted)
+	if errors.IsNotFound(err) {
+		return uuid.Nil, invalid(err)
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return passkey.UserID, nil
+}
+
+// ListPasskeys returns the passkeys of a user.
+func (uc *PasskeysUsecase) ListPasskeys(ctx context.Context, userID string) ([]Passkey, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz ListPasskeys")
+	defer span.End()
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	res, err := uc.repo.ListPasskeys(ctx, uid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+// RenamePasskey renames a passkey of a user.
+func (uc *PasskeysUsecase) RenamePasskey(ctx context.Context, userID, id, name string) (*Passkey, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz RenamePasskey")
+	defer span.End()
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	pid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if name, err = passkeyName(name); err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	res, err := uc.repo.RenamePasskey(ctx, uid, pid, name)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+// DeletePasskey deletes a passkey of a user, who must keep a password or
+// another passkey to log in with.
+func (uc *PasskeysUsecase) DeletePasskey(ctx context.Context, userID, id string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Biz DeletePasskey")
+	defer span.End()
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	pid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	passkeys, err := uc.repo.ListPasskeys(ctx, uid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	if len(passkeys) == 1 && passkeys[0].ID == pid {
+		hash, err := uc.creds.currentHash(ctx, uid)
+		if err != nil {
+			span.AddEvent(err.Error())
+			return err
+		}
+		if hash == "" {
+			err := errors.BadRequest("users.passkeys", "set a password before deleting the last passkey")
+			span.AddEvent(err.Error())
+			return err
+		}
+	}
+	if err := uc.repo.DeletePasskey(ctx, uid, pid); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (uc *PasskeysUsecase) newChallenge(ctx context.Context, purpose string, userID *uuid.UUID) (string, error) {
+	challenge, err := randomToken()
+	if err != nil {
+		return "", err
+	}
+	err = uc.repo.SaveChallenge(ctx, &WebAuthnChallenge{
+		Hash:      sha256Hex(challenge),
+		Purpose:   purpose,
+		UserID:    userID,
+		ExpiresAt: time.Now().Add(uc.timeout),
+	})
+	if err != nil {
+		return "", err
+	}
+	return challenge, nil
+}
+
+// useChallenge checks the client data of a ceremony of type typ and
+// consumes its challenge, so that every ceremony finishes at most once.
+func (uc *PasskeysUsecase) useChallenge(ctx context.Context, cred *publicKeyCredential, typ string) (*WebAuthnChallenge, error) {
+	invalid := errors.BadRequest("users.passkeys", "the ceremony expired or is unknown, please try again")
+	raw, err := decodeBase64URL(cred.Response.ClientDataJSON)
+	if err != nil {
+		return nil, invalid.WithCause(err)
+	}
+	clientData, err := parseClientData(raw)
+	if err != nil {
+		return nil, invalid.WithCause(err)
+	}
+	if clientData.Type != typ || clientData.CrossOrigin || !uc.origins[clientData.Origin] {
+		return nil, errors.BadRequest("users.passkeys", "ceremony from an unexpected origin")
+	}
+	challenge, err := uc.repo.UseChallenge(ctx, sha256Hex(clientData.Challenge))
+	if errors.IsNotFound(err) {
+		return nil, invalid
+	}
+	if err != nil {
+		return nil, err
+	}
+	if time.Now().After(challenge.ExpiresAt) {
+		return nil, invalid
+	}
+	return challenge, nil
+}
+
+// checkAuthenticatorData checks that the authenticator data is for this
+// relying party and that the authenticator verified the user.
+func (uc *PasskeysUsecase) checkAuthenticatorData(d *authenticatorData) error {
+	if subtle.ConstantTimeCompare(d.RPIDHash, uc.rpHash[:]) != 1 {
+		return fmt.Errorf("relying party ID mismatch")
+	}
+	if d.Flags&authDataUserPresent == 0 || d.Flags&authDataUserVerified == 0 {
+		return fmt.Errorf("user not verified")
+	}
+	return nil
+}
+
+func descriptors(passkeys []Passkey) []credentialDescriptor {
+	res := make([]credentialDescriptor, len(passkeys))
+	for i, p := range passkeys {
+		res[i] = credentialDescriptor{
+			Type:       "public-key",
+			ID:         base64.RawURLEncoding.EncodeToString(p.CredentialID),
+			Transports: p.Transports,
+		}
+	}
+	return res
+}
+
+func passkeyName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return defaultPasskeyName, nil
+	}
+	if len(name) > maxPasskeyNameLength {
+		return "", errors.BadRequest("users.passkeys", fmt.Sprintf("name must be at most %d characters", maxPasskeyNameLength))
+	}
+	return name, nil
+}
@@ -0,0 +1,515 @@
+package biz
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"sync"
+	"testing"
+	"time"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+const (
+	testRPID   = "example.com"
+	testOrigin = "https://login.example.com"
+)
+
+type memPasskeys struct {
+	PasskeysRepo
+	mu         sync.Mutex
+	passkeys   []*Passkey
+	challenges map[string]*WebAuthnChallenge
+}
+
+func (r *memPasskeys) SavePasskey(_ context.Context, p *Passkey) (*Passkey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.passkeys {
+		if bytes.Equal(e.CredentialID, p.CredentialID) {
+			return nil, errors.Conflict("users.passkeys", "passkey is registered already")
+		}
+	}
+	saved := *p
+	saved.ID = uuid.New()
+	saved.CreatedAt = time.Now()
+	r.passkeys = append(r.passkeys, &saved)
+	return &saved, nil
+}
+
+func (r *memPasskeys) FindPasskey(_ context.Context, credentialID []byte) (*Passkey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.passkeys {
+		if bytes.Equal(e.CredentialID, credentialID) {
+			found := *e
+			return &found, nil
+		}
+	}
+	return nil, errors.NotFound("users.passkeys", "passkey not found")
+}
+
+func (r *memPasskeys) ListPasskeys(_ context.Context, userID uuid.UUID) ([]Passkey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var res []Passkey
+	for _, e := range r.passkeys {
+		if e.UserID == userID {
+			res = append(res, *e)
+		}
+	}
+	return res, nil
+}
+
+func (r *memPasskeys) UsePasskey(_ context.Context, id uuid.UUID, signCount uint32, backedUp bool, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, e := range r.passkeys {
+		if e.ID == id {
+			e.SignCount, e.BackedUp, e.LastUsedAt = signCount, backedUp, &at
+		}
+	}
+	return nil
+}
+
+func (r *memPasskeys) SaveChallenge(_ context.Context, c *WebAuthnChallenge) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.challenges[c.Hash] = c
+	return nil
+}
+
+func (r *memPasskeys) UseChallenge(_ context.Context, hash string) (*WebAuthnChallenge, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.challenges[hash]
+	if !ok {
+		return nil, errors.NotFound("users.passkeys", "challenge not found")
+	}
+	delete(r.challenges, hash)
+	return c, nil
+}
+
+// testAuthenticator is a platform authenticator holding one ES256 passkey.
+type testAuthenticator struct {
+	t         *testing.T
+	key       *ecdsa.PrivateKey
+	id        []byte
+	userID    []byte
+	origin    string
+	signCount uint32
+}
+
+func newTestAuthenticator(t *testing.T) *testAuthenticator {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		t.Fatal(err)
+	}
+	return &testAuthenticator{t: t, key: key, id: id, origin: testOrigin}
+}
+
+// authData returns authenticator data for the relying party, with the
+// attested credential when attested.
+func (a *testAuthenticator) authData(attested bool) []byte {
+	rpHash := sha256.Sum256([]byte(testRPID))
+	flags := byte(authDataUserPresent | authDataUserVerified)
+	if attested {
+		flags |= authDataAttested
+	}
+	b := append(rpHash[:], flags)
+	b = binary.BigEndian.AppendUint32(b, a.signCount)
+	if !attested {
+		return b
+	}
+	b = append(b, make([]byte, 16)...) // AAGUID
+	b = binary.BigEndian.AppendUint16(b, uint16(len(a.id)))
+	b = append(b, a.id...)
+	pub := a.key.PublicKey
+	return append(b, cborMap(
+		cborInt(1), cborInt(2), // kty: EC2
+		cborInt(3), cborInt(coseES256),
+		cborInt(-1), cborInt(1), // crv: P-256
+		cborInt(-2), cborBytes(pub.X.FillBytes(make([]byte, 32))),
+		cborInt(-3), cborBytes(pub.Y.FillBytes(make([]byte, 32))),
+	)...)
+}
+
+func (a *testAuthenticator) clientData(typ, challenge string) []byte {
+	b, err := json.Marshal(collectedClientData{Type: typ, Challenge: challenge, Origin: a.origin})
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return b
+}
+
+// create answers creation options with a new credential.
+func (a *testAuthenticator) create(options string) string {
+	a.t.Helper()
+	var opts creationOptions
+	if err := json.Unmarshal([]byte(options), &opts); err != nil {
+		a.t.Fatal(err)
+	}
+	if opts.RP.ID != testRPID || opts.AuthenticatorSelection.UserVerification != "required" {
+		a.t.Fatalf("unexpected creation options %s", options)
+	}
+	var err error
+	if a.userID, err = decodeBase64URL(opts.User.ID); err != nil {
+		a.t.Fatal(err)
+	}
+	attestation := cborMap(
+		cborText("fmt"), cborText("none"),
+		cborText("attStmt"), cborMap(),
+		cborText("authData"), cborBytes(a.authData(true)),
+	)
+	var cred publicKeyCredential
+	cred.Type = "public-key"
+	cred.ID = base64.RawURLEncoding.EncodeToString(a.id)
+	cred.RawID = cred.ID
+	cred.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(a.clientData("webauthn.create", opts.Challenge))
+	cred.Response.AttestationObject = base64.RawURLEncoding.EncodeToString(attestation)
+	cred.Response.Transports = []string{"internal"}
+	return a.marshal(cred)
+}
+
+// get answers request options with an assertion, counting a signature.
+func (a *testAuthenticator) get(options string) string {
+	a.t.Helper()
+	var opts requestOptions
+	if err := json.Unmarshal([]byte(options), &opts); err != nil {
+		a.t.Fatal(err)
+	}
+	if opts.RPID != testRPID {
+		a.t.Fatalf("unexpected request options %s", options)
+	}
+	a.signCount++
+	authData := a.authData(false)
+	clientData := a.clientData("webauthn.get", opts.Challenge)
+	clientHash := sha256.Sum256(clientData)
+	digest := sha256.Sum256(append(append([]byte{}, authData...), clientHash[:]...))
+	sig, err := ecdsa.SignASN1(rand.Reader, a.key, digest[:])
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	var cred publicKeyCredential
+	cred.Type = "public-key"
+	cred.ID = base64.RawURLEncoding.EncodeToString(a.id)
+	cred.RawID = cred.ID
+	cred.Response.ClientDataJSON = base64.RawURLEncoding.EncodeToString(clientData)
+	cred.Response.AuthenticatorData = base64.RawURLEncoding.EncodeToString(authData)
+	cred.Response.Signature = base64.RawURLEncoding.EncodeToString(sig)
+	cred.Response.UserHandle = base64.RawURLEncoding.EncodeToString(a.userID)
+	return a.marshal(cred)
+}
+
+func (a *testAuthenticator) marshal(cred publicKeyCredential) string {
+	b, err := json.Marshal(cred)
+	if err != nil {
+		a.t.Fatal(err)
+	}
+	return string(b)
+}
+
+// The CBOR encoders below cover what authenticators send.
+
+func cborHead(major byte, n uint64) []byte {
+	switch {
+	case n < 24:
+		return []byte{major<<5 | byte(n)}
+	case n <= 0xff:
+		return []byte{major<<5 | 24, byte(n)}
+	case n <= 0xffff:
+		return binary.BigEndian.AppendUint16([]byte{major<<5 | 25}, uint16(n))
+	}
+	return binary.BigEndian.AppendUint32([]byte{major<<5 | 26}, uint32(n))
+}
+
+func cborInt(v int64) []byte {
+	if v < 0 {
+		return cborHead(1, uint64(-1-v))
+	}
+	return cborHead(0, uint64(v))
+}
+
+func cborBytes(b []byte) []byte {
+	return append(cborHead(2, uint64(len(b))), b...)
+}
+
+func cborText(s string) []byte {
+	return append(cborHead(3, uint64(len(s))), s...)
+}
+
+// cborMap encodes alternating keys and values.
+func cborMap(items ...[]byte) []byte {
+	b := cborHead(5, uint64(len(items)/2))
+	for _, item := range items {
+		b = append(b, item...)
+	}
+	return b
+}
+
+func newTestPasskeys(t *testing.T, users ...*Users) (*PasskeysUsecase, *testAuth, *memPasskeys) {
+	t.Helper()
+	auth := newTestAuth(t, &conf.Auth{Keys: []*conf.Auth_Key{testSigningKey(t)}}, users...)
+	logger := log.NewStdLogger(testWriter{t})
+	usersUC, err := NewUsersUsecase(auth.users, &conf.Biz{}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	repo := &memPasskeys{challenges: make(map[string]*WebAuthnChallenge)}
+	uc, err := NewPasskeysUsecase(auth.AuthUsecase, usersUC, nil, repo, &conf.Auth{
+		Webauthn: &conf.Auth_Webauthn{RpId: testRPID, Origins: []string{testOrigin}},
+	}, logger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uc, auth, repo
+}
+
+// registerPasskey registers the passkey of authenticator for a user.
+func registerPasskey(t *testing.T, uc *PasskeysUsecase, authenticator *testAuthenticator, userID string) *Passkey {
+	t.Helper()
+	ctx := context.Background()
+	options, err := uc.BeginRegistration(ctx, userID)
+	if err != nil {
+		t.Fatalf("BeginRegistration: %v", err)
+	}
+	passkey, err := uc.FinishRegistration(ctx, userID, authenticator.create(options), "Laptop")
+	if err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+	return passkey
+}
+
+func TestPasskeyRegistration(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("frank")}
+	uc, _, repo := newTestPasskeys(t, user)
+	authenticator := newTestAuthenticator(t)
+
+	passkey := registerPasskey(t, uc, authenticator, user.ID)
+	if passkey.UserID.String() != user.ID || !bytes.Equal(passkey.CredentialID, authenticator.id) || passkey.Name != "Laptop" {
+		t.Errorf("passkey = %+v", passkey)
+	}
+	if !bytes.Equal(authenticator.userID, passkey.UserID[:]) {
+		t.Errorf("user handle %x, want the user ID", authenticator.userID)
+	}
+	key, err := parseCOSEKey(passkey.PublicKey)
+	if err != nil || !key.key.(*ecdsa.PublicKey).Equal(&authenticator.key.PublicKey) {
+		t.Errorf("stored public key = %v, %v, want the one of the authenticator", key, err)
+	}
+
+	// the ceremony finishes once
+	options, err := uc.BeginRegistration(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var opts creationOptions
+	if err := json.Unmarshal([]byte(options), &opts); err != nil {
+		t.Fatal(err)
+	}
+	if len(opts.ExcludeCredentials) != 1 || opts.ExcludeCredentials[0].ID != base64.RawURLEncoding.EncodeToString(authenticator.id) {
+		t.Errorf("excludeCredentials = %+v, want the registered passkey", opts.ExcludeCredentials)
+	}
+	second := newTestAuthenticator(t)
+	credential := second.create(options)
+	if _, err := uc.FinishRegistration(ctx, user.ID, credential, ""); err != nil {
+		t.Fatalf("FinishRegistration: %v", err)
+	}
+	if _, err := uc.FinishRegistration(ctx, user.ID, credential, ""); !errors.IsBadRequest(err) {
+		t.Errorf("replayed registration err = %v, want BadRequest", err)
+	}
+	if len(repo.passkeys) != 2 {
+		t.Errorf("%d passkeys, want 2", len(repo.passkeys))
+	}
+}
+
+func TestPasskeyRegistrationRejections(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("frank")}
+	other := &Users{ID: uuid.NewString(), Username: ptr("grace")}
+	tests := []struct {
+		name   string
+		finish func(uc *PasskeysUsecase, a *testAuthenticator, options string) error
+	}{
+		{"other origin", func(uc *PasskeysUsecase, a *testAuthenticator, options string) error {
+			a.origin = "https://evil.example.net"
+			_, err := uc.FinishRegistration(ctx, user.ID, a.create(options), "")
+			return err
+		}},
+		{"other user", func(uc *PasskeysUsecase, a *testAuthenticator, options string) error {
+			_, err := uc.FinishRegistration(ctx, other.ID, a.create(options), "")
+			return err
+		}},
+		{"unknown challenge", func(uc *PasskeysUsecase, a *testAuthenticator, options string) error {
+			var opts creationOptions
+			if err := json.Unmarshal([]byte(options), &opts); err != nil {
+				return err
+			}
+			opts.Challenge = base64.RawURLEncoding.EncodeToString([]byte("forged"))
+			forged, err := json.Marshal(opts)
+			if err != nil {
+				return err
+			}
+			_, err = uc.FinishRegistration(ctx, user.ID, a.create(string(forged)), "")
+			return err
+		}},
+		{"malformed attestation", func(uc *PasskeysUsecase, a *testAuthenticator, options string) error {
+			var cred publicKeyCredential
+			if err := json.Unmarshal([]byte(a.create(options)), &cred); err != nil {
+				return err
+			}
+			cred.Response.AttestationObject = base64.RawURLEncoding.EncodeToString(cborMap(cborText("fmt"), cborText("none")))
+			_, err := uc.FinishRegistration(ctx, user.ID, a.marshal(cred), "")
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			uc, _, repo := newTestPasskeys(t, user, other)
+			options, err := uc.BeginRegistration(ctx, user.ID)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := tt.finish(uc, newTestAuthenticator(t), options); !errors.IsBadRequest(err) {
+				t.Errorf("err = %v, want BadRequest", err)
+			}
+			if len(repo.passkeys) != 0 {
+				t.Errorf("a rejected registration stored %+v", repo.passkeys)
+			}
+		})
+	}
+}
+
+func TestPasskeyLogin(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("frank")}
+	uc, auth, repo := newTestPasskeys(t, user)
+	authenticator := newTestAuthenticator(t)
+	registerPasskey(t, uc, authenticator, user.ID)
+
+	options, err := uc.BeginLogin(ctx, &LookupKey{Field: FieldUsername, Value: "frank"})
+	if err != nil {
+		t.Fatalf("BeginLogin: %v", err)
+	}
+	pair, mfaToken, err := uc.FinishLogin(ctx, authenticator.get(options), Client{})
+	if err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if pair == nil || mfaToken != "" {
+		t.Fatalf("FinishLogin = %v, %q, want tokens", pair, mfaToken)
+	}
+	if p, err := auth.Authenticate(ctx, pair.AccessToken); err != nil || p.UserID != user.ID {
+		t.Errorf("Authenticate = %v, %v, want %s", p, err, user.ID)
+	}
+	if repo.passkeys[0].SignCount != 1 || repo.passkeys[0].LastUsedAt == nil {
+		t.Errorf("passkey after login = %+v, want sign count 1", repo.passkeys[0])
+	}
+
+	// a discoverable login, without naming the user
+	options, err = uc.BeginLogin(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertion := authenticator.get(options)
+	if _, _, err := uc.FinishLogin(ctx, assertion, Client{}); err != nil {
+		t.Fatalf("discoverable FinishLogin: %v", err)
+	}
+	if _, _, err := uc.FinishLogin(ctx, assertion, Client{}); !errors.IsBadRequest(err) {
+		t.Errorf("replayed assertion err = %v, want BadRequest", err)
+	}
+
+	// a clone of the authenticator is behind on the count
+	options, err = uc.BeginLogin(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	authenticator.signCount = 1
+	if _, _, err := uc.FinishLogin(ctx, authenticator.get(options), Client{}); !errors.IsUnauthorized(err) {
+		t.Errorf("sign count going back err = %v, want Unauthorized", err)
+	}
+	if repo.passkeys[0].SignCount != 2 {
+		t.Errorf("sign count = %d after a rejected login, want 2", repo.passkeys[0].SignCount)
+	}
+}
+
+func TestPasskeyLoginRejections(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("frank")}
+	other := &Users{ID: uuid.NewString(), Username: ptr("grace")}
+	uc, _, _ := newTestPasskeys(t, user, other)
+	authenticator := newTestAuthenticator(t)
+	registerPasskey(t, uc, authenticator, user.ID)
+
+	// a login bound to another user
+	options, err := uc.BeginLogin(ctx, &LookupKey{Field: FieldUsername, Value: "grace"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := uc.FinishLogin(ctx, authenticator.get(options), Client{}); errors.Reason(err) != "auth.passkey" {
+		t.Errorf("passkey of another user err = %v, want auth.passkey", err)
+	}
+
+	// a registration challenge
+	options, err = uc.BeginRegistration(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var creation creationOptions
+	if err := json.Unmarshal([]byte(options), &creation); err != nil {
+		t.Fatal(err)
+	}
+	request, err := json.Marshal(requestOptions{Challenge: creation.Challenge, RPID: testRPID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := uc.FinishLogin(ctx, authenticator.get(string(request)), Client{}); errors.Reason(err) != "auth.passkey" {
+		t.Errorf("registration challenge err = %v, want auth.passkey", err)
+	}
+
+	// a signature by another key
+	options, err = uc.BeginLogin(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	impostor := newTestAuthenticator(t)
+	impostor.id, impostor.userID, impostor.signCount = authenticator.id, authenticator.userID, 100
+	if _, _, err := uc.FinishLogin(ctx, impostor.get(options), Client{}); !errors.IsUnauthorized(err) {
+		t.Errorf("signature by another key err = %v, want Unauthorized", err)
+	}
+}
+
+func TestPasskeyLoginWaitsForMFA(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("frank")}
+	uc, auth, _ := newTestPasskeys(t, user)
+	authenticator := newTestAuthenticator(t)
+	registerPasskey(t, uc, authenticator, user.ID)
+	auth.enableTOTP(t, uuid.MustParse(user.ID))
+
+	options, err := uc.BeginLogin(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair, mfaToken, err := uc.FinishLogin(ctx, authenticator.get(options), Client{})
+	if err != nil {
+		t.Fatalf("FinishLogin: %v", err)
+	}
+	if pair != nil || mfaToken == "" {
+		t.Errorf("FinishLogin = %v, %q, want an MFA token", pair, mfaToken)
+	}
+}
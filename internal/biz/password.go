@@ -0,0 +1,120 @@
+package biz
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+	"users/internal/conf"
+
+	"golang.org/x/crypto/argon2"
+)
+
+// argon2Params are the Argon2id cost parameters, encoded alongside every hash
+// so that older hashes keep verifying after the configuration changes.
+type argon2Params struct {
+	Time       uint32
+	Memory     uint32 // KiB
+	Threads    uint8
+	KeyLength  uint32
+	SaltLength uint32
+}
+
+// defaultArgon2Params follow the OWASP recommendation for Argon2id.
+var defaultArgon2Params = argon2Params{
+	Time:       3,
+	Memory:     64 * 1024,
+	Threads:    2,
+	KeyLength:  32,
+	SaltLength: 16,
+}
+
+func newArgon2Params(c *conf.Biz_Password) argon2Params {
+	p := defaultArgon2Params
+	if v := c.GetTime(); v > 0 {
+		p.Time = v
+	}
+	if v := c.GetMemoryKib(); v > 0 {
+		p.Memory = v
+	}
+	if v := c.GetThreads(); v > 0 && v <= 255 {
+		p.Threads = uint8(v)
+	}
+	if v := c.GetKeyLength(); v > 0 {
+		p.KeyLength = v
+	}
+	if v := c.GetSaltLength(); v > 0 {
+		p.SaltLength = v
+	}
+	return p
+}
+
+// hashPassword returns the PHC string of password, e.g.
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>
+func hashPassword(password string, p argon2Params) (string, error) {
+	salt := make([]byte, p.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	key := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLength)
+	return encodeHash(p, salt, key), nil
+}
+
+func encodeHash(p argon2Params, salt, key []byte) string {
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Time, p.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	)
+}
+
+// dummyHash returns a hash with the parameters p that no password is
+// expected to match. Verifying passwords of users who have none against it
+// costs as much as verifying a real one, so timing does not tell them apart.
+func dummyHash(p argon2Params) string {
+	return encodeHash(p, make([]byte, p.SaltLength), make([]byte, p.KeyLength))
+}
+
+// decodeHash parses a PHC string produced by hashPassword.
+func decodeHash(encoded string) (p argon2Params, salt, key []byte, err error) {
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, fmt.Errorf("unsupported password hash format")
+	}
+	var version int
+	if _, err = fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return p, nil, nil, fmt.Errorf("malformed password hash version: %w", err)
+	}
+	if version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2 version %d", version)
+	}
+	if _, err = fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Time, &p.Threads); err != nil {
+		return p, nil, nil, fmt.Errorf("malformed password hash parameters: %w", err)
+	}
+	if salt, err = base64.RawStdEncoding.DecodeString(parts[4]); err != nil {
+		return p, nil, nil, fmt.Errorf("malformed password hash salt: %w", err)
+	}
+	if key, err = base64.RawStdEncoding.DecodeString(parts[5]); err != nil {
+		return p, nil, nil, fmt.Errorf("malformed password hash key: %w", err)
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	return p, salt, key, nil
+}
+
+// verifyPassword reports whether password matches the encoded hash, and
+// whether the hash was made with weaker parameters than want.
+func verifyPassword(password, encoded string, want argon2Params) (ok, rehash bool, err error) {
+	p, salt, key, err := decodeHash(encoded)
+	if err != nil {
+		return false, false, err
+	}
+	other := argon2.IDKey([]byte(password), salt, p.Time, p.Memory, p.Threads, p.KeyLength)
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return false, false, nil
+	}
+	rehash = p.Time < want.Time || p.Memory < want.Memory || p.Threads < want.Threads ||
+		p.KeyLength < want.KeyLength || p.SaltLength < want.SaltLength
+	return true, rehash, nil
+}
@@ -0,0 +1,153 @@
+package biz
+
+import (
+	"encoding/base64"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"users/internal/conf"
+)
+
+// cheapArgon2Params keep hashing fast in tests.
+var cheapArgon2Params = argon2Params{Time: 1, Memory: 64, Threads: 1, KeyLength: 32, SaltLength: 16}
+
+func TestHashPassword(t *testing.T) {
+	encoded, err := hashPassword("correct horse", cheapArgon2Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	format := regexp.MustCompile(`^\$argon2id\$v=19\$m=64,t=1,p=1\$[A-Za-z0-9+/]{22}\$[A-Za-z0-9+/]{43}$`)
+	if !format.MatchString(encoded) {
+		t.Errorf("hash %q is not a PHC string of the parameters", encoded)
+	}
+	p, salt, key, err := decodeHash(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != cheapArgon2Params || len(salt) != 16 || len(key) != 32 {
+		t.Errorf("decoded %+v with %d byte salt and %d byte key, want %+v", p, len(salt), len(key), cheapArgon2Params)
+	}
+	again, err := hashPassword("correct horse", cheapArgon2Params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again == encoded {
+		t.Error("two hashes of a password share their salt")
+	}
+
+	tests := []struct {
+		password string
+		ok       bool
+	}{
+		{"correct horse", true},
+		{"Correct horse", false},
+		{"correct horse ", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		ok, rehash, err := verifyPassword(tt.password, encoded, cheapArgon2Params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ok != tt.ok || rehash {
+			t.Errorf("verifyPassword(%q) = %t, %t, want %t, false", tt.password, ok, rehash, tt.ok)
+		}
+	}
+}
+
+func TestVerifyPasswordRehash(t *testing.T) {
+	stored := cheapArgon2Params
+	encoded, err := hashPassword("correct horse", stored)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name   string
+		want   func(*argon2Params)
+		rehash bool
+	}{
+		{"same", func(*argon2Params) {}, false},
+		{"weaker", func(p *argon2Params) { p.Memory = 32 }, false},
+		{"more time", func(p *argon2Params) { p.Time = 2 }, true},
+		{"more memory", func(p *argon2Params) { p.Memory = 128 }, true},
+		{"more threads", func(p *argon2Params) { p.Threads = 2 }, true},
+		{"longer key", func(p *argon2Params) { p.KeyLength = 64 }, true},
+		{"longer salt", func(p *argon2Params) { p.SaltLength = 32 }, true},
+	}
+	for _, tt := range tests {
+		want := stored
+		tt.want(&want)
+		ok, rehash, err := verifyPassword("correct horse", encoded, want)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok || rehash != tt.rehash {
+			t.Errorf("%s: verifyPassword = %t, %t, want true, %t", tt.name, ok, rehash, tt.rehash)
+		}
+		// wrong passwords are never worth a rehash
+		if ok, rehash, _ := verifyPassword("wrong", encoded, want); ok || rehash {
+			t.Errorf("%s: wrong password = %t, %t", tt.name, ok, rehash)
+		}
+	}
+}
+
+func TestDecodeHashRejections(t *testing.T) {
+	salt := base64.RawStdEncoding.EncodeToString([]byte("0123456789abcdef"))
+	key := base64.RawStdEncoding.EncodeToString([]byte("0123456789abcdef0123456789abcdef"))
+	tests := []string{
+		"",
+		"plain text",
+		"$2a$10$N9qo8uLOickgx2ZMRZoMyeIjZAgcfl7p92ldGxad68LJZdL17lhWy",
+		fmt.Sprintf("$argon2i$v=19$m=64,t=1,p=1$%s$%s", salt, key),
+		fmt.Sprintf("$argon2id$v=16$m=64,t=1,p=1$%s$%s", salt, key),
+		fmt.Sprintf("$argon2id$v=x$m=64,t=1,p=1$%s$%s", salt, key),
+		fmt.Sprintf("$argon2id$v=19$m=64,p=1$%s$%s", salt, key),
+		fmt.Sprintf("$argon2id$v=19$m=64,t=1,p=1$%s$%s", "!!", key),
+		fmt.Sprintf("$argon2id$v=19$m=64,t=1,p=1$%s$%s", salt, "!!"),
+		fmt.Sprintf("$argon2id$v=19$m=64,t=1,p=1$%s", salt),
+	}
+	for _, encoded := range tests {
+		if _, _, _, err := decodeHash(encoded); err == nil {
+			t.Errorf("decodeHash(%q) succeeded", encoded)
+		}
+		if _, _, err := verifyPassword("x", encoded, cheapArgon2Params); err == nil {
+			t.Errorf("verifyPassword against %q succeeded", encoded)
+		}
+	}
+}
+
+func TestDummyHash(t *testing.T) {
+	encoded := dummyHash(cheapArgon2Params)
+	p, _, _, err := decodeHash(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p != cheapArgon2Params {
+		t.Errorf("dummy hash made with %+v, want %+v", p, cheapArgon2Params)
+	}
+	for _, password := range []string{"", "password", strings.Repeat("a", 64)} {
+		if ok, _, _ := verifyPassword(password, encoded, cheapArgon2Params); ok {
+			t.Errorf("%q matches the dummy hash", password)
+		}
+	}
+}
+
+func TestNewArgon2Params(t *testing.T) {
+	tests := []struct {
+		c    *conf.Biz_Password
+		want argon2Params
+	}{
+		{nil, defaultArgon2Params},
+		{&conf.Biz_Password{}, defaultArgon2Params},
+		{&conf.Biz_Password{Time: 4, MemoryKib: 128 * 1024, Threads: 8, KeyLength: 64, SaltLength: 32},
+			argon2Params{Time: 4, Memory: 128 * 1024, Threads: 8, KeyLength: 64, SaltLength: 32}},
+		// threads beyond what argon2 takes are ignored
+		{&conf.Biz_Password{Threads: 256}, defaultArgon2Params},
+	}
+	for _, tt := range tests {
+		if got := newArgon2Params(tt.c); got != tt.want {
+			t.Errorf("newArgon2Params(%v) = %+v, want %+v", tt.c, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,326 @@
+package biz
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"time"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+const (
+	defaultPasswordlessTTL         = 15 * time.Minute
+	defaultPasswordlessMaxAttempts = 5
+	defaultPasswordlessHourlyLimit = 5
+	passwordlessCodeDigits         = 6
+
+	purposePasswordless = "passwordless-login"
+)
+
+// Channels passwordless logins are sent through.
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+// PasswordlessLogin is a pending passwordless login. Only a keyed hash of
+// its code or link token is stored.
+type PasswordlessLogin struct {
+	ID          uuid.UUID
+	UserID      uuid.UUID
+	Channel     string
+	Destination string
+	Link        bool
+	Hash        string
+	ExpiresAt   time.Time
+	CreatedAt   time.Time
+}
+
+type PasswordlessRepo interface {
+	// SavePasswordlessLogin stores a pending login, dropping ones that
+	// expired long ago.
+	SavePasswordlessLogin(context.Context, *PasswordlessLogin) error
+	// CountPasswordlessLogins returns how many logins were started for a
+	// user since a time and are still stored.
+	CountPasswordlessLogins(ctx context.Context, userID uuid.UUID, since time.Time) (int, error)
+	// FindPasswordlessLogin returns a pending login, or a NotFound error.
+	FindPasswordlessLogin(context.Context, uuid.UUID) (*PasswordlessLogin, error)
+	// UsePasswordlessAttempt counts an attempt against a pending login,
+	// reporting false when max attempts were already made.
+	UsePasswordlessAttempt(ctx context.Context, id uuid.UUID, max int) (bool, error)
+	// ConsumePasswordlessLogins deletes the pending logins of a user,
+	// reporting whether id was one of them.
+	ConsumePasswordlessLogins(ctx context.Context, userID, id uuid.UUID) (bool, error)
+}
+
+// PasswordlessUsecase logs users in with a one-time code sent by email or
+// SMS, or with a magic link sent by email. Each login is bound to the device
+// that started it through a login token, so a code or link sent to a user is
+// worthless to anyone else.
+type PasswordlessUsecase struct {
+	auth        *AuthUsecase
+	users       *UsersUsecase
+	repo        PasswordlessRepo
+	mailer      Mailer
+	sms         SMSSender
+	tokens      *signedTokens
+	ttl         time.Duration
+	maxAttempts int
+	hourlyLimit int
+	link        string
+	log         *log.Helper
+}
+
+// NewPasswordlessUsecase new a Passwordless usecase.
+func NewPasswordlessUsecase(auth *AuthUsecase, users *UsersUsecase, repo PasswordlessRepo, mailer Mailer, sms SMSSender, c *conf.Biz, logger log.Logger) (*PasswordlessUsecase, error) {
+	helper := log.NewHelper(logger)
+	secret := c.GetVerification().GetSecret()
+	if secret == "" {
+		helper.Warn("no verification secret configured, passwordless logins will not survive restarts")
+	}
+	tokens, err := newSignedTokens(secret)
+	if err != nil {
+		return nil, err
+	}
+	pc := c.GetPasswordless()
+	ttl := pc.GetTtl().AsDuration()
+	if ttl <= 0 {
+		ttl = defaultPasswordlessTTL
+	}
+	maxAttempts := int(pc.GetMaxAttempts())
+	if maxAttempts <= 0 {
+		maxAttempts = defaultPasswordlessMaxAttempts
+	}
+	hourlyLimit := int(pc.GetHourlyLimit())
+	if hourlyLimit <= 0 {
+		hourlyLimit = defaultPasswordlessHourlyLimit
+	}
+	return &PasswordlessUsecase{
+		auth:        auth,
+		users:       users,
+		repo:        repo,
+		mailer:      mailer,
+		sms:         sms,
+		tokens:      tokens,
+		ttl:         ttl,
+		maxAttempts: maxAttempts,
+		hourlyLimit: hourlyLimit,
+		link:        pc.GetLinkUrl(),
+		log:         helper,
+	}, nil
+}
+
+// StartLogin sends a code, or a magic link when link is set, to the user
+// holding key, which must be an email or phone, and returns the login token
+// completing the login requires. The outcome is the same whether or not
+// such a user exists, and the code is sent in the background so that timing
+// does not tell either.
+func (uc *PasswordlessUsecase) StartLogin(ctx context.Context, key LookupKey, link bool) (string, time.Duration, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz StartLogin")
+	defer span.End()
+	var err error
+	switch {
+	case key.Field != FieldEmail && key.Field != FieldPhone:
+		err = errors.BadRequest("auth.passwordless", "an email or phone is required")
+	case link && key.Field != FieldEmail:
+		err = errors.BadRequest("auth.passwordless", "magic links can only be sent to emails")
+	}
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", 0, err
+	}
+	user, err := uc.users.LookupUser(ctx, key)
+	if err != nil && !errors.IsNotFound(err) {
+		span.AddEvent(err.Error())
+		return "", 0, err
+	}
+	login := &PasswordlessLogin{
+		ID:        uuid.New(),
+		Link:      link,
+		ExpiresAt: time.Now().Add(uc.ttl),
+	}
+	token, err := uc.tokens.sign(purposePasswordless, tokenClaims{
+		Subject: login.ID.String(),
+		Expires: login.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", 0, err
+	}
+	if user != nil {
+		go uc.send(context.WithoutCancel(ctx), user, key.Field, login)
+	}
+	return token, uc.ttl, nil
+}
+
+func (uc *PasswordlessUsecase) send(ctx context.Context, user *Users, field Field, login *PasswordlessLogin) {
+	var err error
+	if login.UserID, err = uuid.Parse(user.ID); err != nil {
+		uc.log.WithContext(ctx).Errorf("passwordless login for user %s: %v", user.ID, err)
+		return
+	}
+	sent, err := uc.repo.CountPasswordlessLogins(ctx, login.UserID, time.Now().Add(-time.Hour))
+	if err != nil {
+		uc.log.WithContext(ctx).Errorf("passwordless login for user %s: %v", user.ID, err)
+		return
+	}
+	if sent >= uc.hourlyLimit {
+		uc.log.WithContext(ctx).Warnf("passwordless login for user %s: hourly limit of %d reached", user.ID, uc.hourlyLimit)
+		return
+	}
+	login.Channel, login.Destination = ChannelEmail, *user.Email
+	if field == FieldPhone {
+		login.Channel, login.Destination = ChannelSMS, *user.Phone
+	}
+	var secret string
+	if login.Link {
+		secret, err = randomToken()
+	} else {
+		secret, err = newNumericCode(passwordlessCodeDigits)
+	}
+	if err != nil {
+		uc.log.WithContext(ctx).Errorf("passwordless login for user %s: %v", user.ID, err)
+		return
+	}
+	login.Hash = uc.secretDigest(login.ID, secret)
+	if err := uc.repo.SavePasswordlessLogin(ctx, login); err != nil {
+		uc.log.WithContext(ctx).Errorf("passwordless login for user %s: %v", user.ID, err)
+		return
+	}
+
+	if login.Channel == ChannelSMS {
+		body := fmt.Sprintf("Your login code is %s. It expires in %s.", secret, uc.ttl)
+		err = uc.sms.Send(ctx, login.Destination, body)
+	} else {
+		mail := &Mail{To: login.Destination}
+		body := fmt.Sprintf("Hello %s,\n\n", *user.Username)
+		if login.Link {
+			mail.Subject = "Your login link"
+			body += "Log in"
+			if uc.link != "" {
+				body += " by opening this link:\n\n" + uc.link + secret
+			} else {
+				body += " with this token:\n\n" + secret
+			}
+		} else {
+			mail.Subject = "Your login code"
+			body += "Your login code is:\n\n" + secret
+		}
+		body += fmt.Sprintf("\n\nIt works once, on the device you started logging in from, and expires in %s. If you did not try to log in, ignore this email.\n", uc.ttl)
+		mail.Body = body
+		err = uc.mailer.Send(ctx, mail)
+	}
+	if err != nil {
+		uc.log.WithContext(ctx).Errorf("passwordless login for user %s: %v", user.ID, err)
+	}
+}
+
+// CompleteLogin checks the code or link token sent for the login of a login
+// token and starts a new session from client like Login. Every attempt
+// counts, and a login stops working after the maximum number of attempts or
+// once any login of the user completed. Failures also count towards the
+// lockouts of the user and client IP. Users with a second factor get an MFA
+// token instead of tokens, see CompleteMFALogin.
+func (uc *PasswordlessUsecase) CompleteLogin(ctx context.Context, token, code, linkToken string, client Client) (*TokenPair, string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz CompleteLogin")
+	defer span.End()
+	uid, err := uc.complete(ctx, token, code, linkToken, client.IP)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	res, mfaToken, err := uc.auth.startLogin(ctx, uid, client)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, "", err
+	}
+	return res, mfaToken, nil
+}
+
+// complete returns the user of a login once its code or link token checks
+// out.
+func (uc *PasswordlessUsecase) complete(ctx context.Context, token, code, linkToken, ip string) (uuid.UUID, error) {
+	claims, err := uc.tokens.verify("auth.passwordless", purposePasswordless, token)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	invalid := errors.Unauthorized("auth.passwordless", "invalid or expired login, please start again")
+	id, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		return uuid.Nil, invalid
+	}
+	login, err := uc.repo.FindPasswordlessLogin(ctx, id)
+	if errors.IsNotFound(err) {
+		return uuid.Nil, invalid
+	}
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if time.Now().After(login.ExpiresAt) {
+		return uuid.Nil, invalid
+	}
+	if err := uc.auth.attempts.Check(ctx, ScopePasswordless, login.UserID, ip); err != nil {
+		return uuid.Nil, err
+	}
+	secret := linkToken
+	if !login.Link {
+		secret = normalizeCode(code)
+	}
+	if secret == "" {
+		if login.Link {
+			return uuid.Nil, errors.BadRequest("auth.passwordless", "the link token is required")
+		}
+		return uuid.Nil, errors.BadRequest("auth.passwordless", "the code is required")
+	}
+	// the attempt is counted before the comparison, so that concurrent
+	// guesses cannot exceed the limit
+	ok, err := uc.repo.UsePasswordlessAttempt(ctx, id, uc.maxAttempts)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if !ok {
+		return uuid.Nil, errors.Unauthorized("auth.passwordless", "too many attempts, please start again")
+	}
+	want := uc.secretDigest(id, secret)
+	if subtle.ConstantTimeCompare([]byte(want), []byte(login.Hash)) != 1 {
+		uc.auth.attempts.Fail(ctx, ScopePasswordless, login.UserID, ip)
+		// a wrong secret gets the same error as a login that does not
+		// exist, so that neither tells the other apart
+		return uuid.Nil, invalid
+	}
+	// deleting the logins of the user makes the code single-use, and the
+	// other codes sent meanwhile useless
+	if ok, err = uc.repo.ConsumePasswordlessLogins(ctx, login.UserID, id); err != nil {
+		return uuid.Nil, err
+	}
+	if !ok {
+		// a concurrent attempt won
+		return uuid.Nil, invalid
+	}
+	uc.auth.attempts.Succeed(ctx, ScopePasswordless, login.UserID)
+	if _, err := uc.users.GetByID(ctx, login.UserID.String(), ExcludeDeleted); err != nil {
+		if errors.IsNotFound(err) {
+			err = invalid
+		}
+		return uuid.Nil, err
+	}
+	// receiving the code proves the user owns where it was sent
+	if login.Channel == ChannelSMS {
+		_, err = uc.users.repo.MarkPhoneVerified(ctx, login.UserID, login.Destination)
+	} else {
+		_, err = uc.users.repo.MarkEmailVerified(ctx, login.UserID, login.Destination)
+	}
+	if err != nil && !errors.IsNotFound(err) {
+		uc.log.WithContext(ctx).Warnf("failed marking %s of user %s verified: %v", login.Channel, login.UserID, err)
+	}
+	return login.UserID, nil
+}
+
+func (uc *PasswordlessUsecase) secretDigest(id uuid.UUID, secret string) string {
+	return uc.tokens.digest(purposePasswordless, id.String()+":"+secret)
+}
@@ -0,0 +1,289 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"sync"
+	"testing"
+	"time"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+type memPasswordless struct {
+	PasswordlessRepo
+	mu       sync.Mutex
+	logins   map[uuid.UUID]*PasswordlessLogin
+	attempts map[uuid.UUID]int
+}
+
+func (r *memPasswordless) SavePasswordlessLogin(_ context.Context, l *PasswordlessLogin) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved := *l
+	saved.CreatedAt = time.Now()
+	r.logins[l.ID] = &saved
+	return nil
+}
+
+func (r *memPasswordless) CountPasswordlessLogins(_ context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	n := 0
+	for _, l := range r.logins {
+		if l.UserID == userID && !l.CreatedAt.Before(since) {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *memPasswordless) FindPasswordlessLogin(_ context.Context, id uuid.UUID) (*PasswordlessLogin, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	l, ok := r.logins[id]
+	if !ok {
+		return nil, errors.NotFound("auth.passwordless", "login not found")
+	}
+	found := *l
+	return &found, nil
+}
+
+func (r *memPasswordless) UsePasswordlessAttempt(_ context.Context, id uuid.UUID, max int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.attempts[id] >= max {
+		return false, nil
+	}
+	r.attempts[id]++
+	return true, nil
+}
+
+// ConsumePasswordlessLogins keeps the logins it deletes around, as they
+// count towards the hourly limit.
+func (r *memPasswordless) ConsumePasswordlessLogins(_ context.Context, userID, id uuid.UUID) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	found := false
+	for lid, l := range r.logins {
+		if l.UserID == userID && !l.ExpiresAt.IsZero() {
+			found = found || lid == id
+			l.ExpiresAt = time.Time{}
+		}
+	}
+	return found, nil
+}
+
+func newTestPasswordless(t *testing.T, users ...*Users) (*PasswordlessUsecase, *testAuth, *memPasswordless, *memMailer, *memSMS) {
+	t.Helper()
+	auth := newTestAuth(t, &conf.Auth{Keys: []*conf.Auth_Key{testSigningKey(t)}}, users...)
+	repo := &memPasswordless{logins: make(map[uuid.UUID]*PasswordlessLogin), attempts: make(map[uuid.UUID]int)}
+	mailer, sms := newMemMailer(), newMemSMS()
+	c := &conf.Biz{
+		Verification: &conf.Biz_Verification{Secret: "secret"},
+		Passwordless: &conf.Biz_Passwordless{LinkUrl: "https://example.com/?token="},
+	}
+	uc, err := NewPasswordlessUsecase(auth.AuthUsecase, auth.AuthUsecase.users, repo, mailer, sms, c, log.NewStdLogger(testWriter{t}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uc, auth, repo, mailer, sms
+}
+
+func TestPasswordlessLogin(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		key  LookupKey
+		link bool
+	}{
+		{"email code", LookupKey{FieldEmail, "erin@example.com"}, false},
+		{"email link", LookupKey{FieldEmail, "Erin@Example.com"}, true},
+		{"sms code", LookupKey{FieldPhone, "+1 555 0100"}, false},
+	}
+	for _, tt := range tests {
+		user := &Users{ID: uuid.NewString(), Username: ptr("erin"), Email: ptr("erin@example.com"), Phone: ptr("+15550100")}
+		uc, auth, _, mailer, sms := newTestPasswordless(t, user)
+		token, ttl, err := uc.StartLogin(ctx, tt.key, tt.link)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if ttl != defaultPasswordlessTTL {
+			t.Errorf("%s: expires in %s", tt.name, ttl)
+		}
+		var code, linkToken string
+		switch {
+		case tt.key.Field == FieldPhone:
+			msg := sms.next(t)
+			if msg.To != "+15550100" {
+				t.Errorf("%s: texted %s", tt.name, msg.To)
+			}
+			code = testCode(t, msg.Body)
+		case tt.link:
+			linkToken = testToken(t, mailer.next(t).Body)
+		default:
+			code = testCode(t, mailer.next(t).Body)
+		}
+
+		pair, mfaToken, err := uc.CompleteLogin(ctx, token, code, linkToken, Client{Device: "laptop"})
+		if err != nil {
+			t.Errorf("%s: CompleteLogin: %v", tt.name, err)
+			continue
+		}
+		if mfaToken != "" {
+			t.Errorf("%s: MFA token without a second factor", tt.name)
+		}
+		p, err := auth.Authenticate(ctx, pair.AccessToken)
+		if err != nil || p.UserID != user.ID {
+			t.Errorf("%s: Authenticate = %v, %v", tt.name, p, err)
+		}
+		// receiving the code proves the destination is the user's
+		if tt.key.Field == FieldPhone && user.PhoneVerifiedAt == nil {
+			t.Errorf("%s: phone not verified", tt.name)
+		}
+		if tt.key.Field == FieldEmail && user.EmailVerifiedAt == nil {
+			t.Errorf("%s: email not verified", tt.name)
+		}
+		if _, _, err := uc.CompleteLogin(ctx, token, code, linkToken, Client{}); !errors.IsUnauthorized(err) {
+			t.Errorf("%s: completing twice err = %v, want Unauthorized", tt.name, err)
+		}
+	}
+}
+
+func TestStartPasswordlessLoginRejections(t *testing.T) {
+	ctx := context.Background()
+	uc, _, _, _, _ := newTestPasswordless(t)
+	tests := []struct {
+		name string
+		key  LookupKey
+		link bool
+	}{
+		{"username", LookupKey{FieldUsername, "erin"}, false},
+		{"link by sms", LookupKey{FieldPhone, "+15550100"}, true},
+		{"empty email", LookupKey{FieldEmail, " "}, false},
+	}
+	for _, tt := range tests {
+		if _, _, err := uc.StartLogin(ctx, tt.key, tt.link); !errors.IsBadRequest(err) {
+			t.Errorf("%s: err = %v, want BadRequest", tt.name, err)
+		}
+	}
+}
+
+func TestCompletePasswordlessLoginRejections(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		// tamper returns the login token, code and link token tried
+		tamper func(*testing.T, *PasswordlessUsecase, *memPasswordless, *memMailer, string, string) (string, string, string)
+		want   func(error) bool
+	}{
+		{"wrong code", func(t *testing.T, _ *PasswordlessUsecase, _ *memPasswordless, _ *memMailer, token, code string) (string, string, string) {
+			return token, fmt.Sprintf("%06d", (mustAtoi(t, code)+1)%1000000), ""
+		}, errors.IsUnauthorized},
+		{"no code", func(_ *testing.T, _ *PasswordlessUsecase, _ *memPasswordless, _ *memMailer, token, _ string) (string, string, string) {
+			return token, " ", ""
+		}, errors.IsBadRequest},
+		{"link token for a code", func(_ *testing.T, _ *PasswordlessUsecase, _ *memPasswordless, _ *memMailer, token, code string) (string, string, string) {
+			return token, "", code
+		}, errors.IsBadRequest},
+		{"garbage login token", func(_ *testing.T, _ *PasswordlessUsecase, _ *memPasswordless, _ *memMailer, _, code string) (string, string, string) {
+			return "garbage", code, ""
+		}, errors.IsBadRequest},
+		{"expired", func(_ *testing.T, _ *PasswordlessUsecase, repo *memPasswordless, _ *memMailer, token, code string) (string, string, string) {
+			for _, l := range repo.logins {
+				l.ExpiresAt = time.Now().Add(-time.Second)
+			}
+			return token, code, ""
+		}, errors.IsUnauthorized},
+		{"too many attempts", func(t *testing.T, uc *PasswordlessUsecase, _ *memPasswordless, _ *memMailer, token, code string) (string, string, string) {
+			wrong := fmt.Sprintf("%06d", (mustAtoi(t, code)+1)%1000000)
+			for i := 0; i < defaultPasswordlessMaxAttempts; i++ {
+				_, _, _ = uc.CompleteLogin(ctx, token, wrong, "", Client{})
+			}
+			return token, code, ""
+		}, func(err error) bool { return errors.IsUnauthorized(err) || errors.Code(err) == 429 }},
+		// a code only works on the device that started its login
+		{"code of another login", func(t *testing.T, uc *PasswordlessUsecase, _ *memPasswordless, mailer *memMailer, token, _ string) (string, string, string) {
+			if _, _, err := uc.StartLogin(ctx, LookupKey{FieldEmail, "erin@example.com"}, false); err != nil {
+				t.Fatal(err)
+			}
+			return token, testCode(t, mailer.next(t).Body), ""
+		}, errors.IsUnauthorized},
+		// completing one login ends the others
+		{"superseded", func(t *testing.T, uc *PasswordlessUsecase, _ *memPasswordless, mailer *memMailer, token, code string) (string, string, string) {
+			other, _, err := uc.StartLogin(ctx, LookupKey{FieldEmail, "erin@example.com"}, false)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, _, err := uc.CompleteLogin(ctx, other, testCode(t, mailer.next(t).Body), "", Client{}); err != nil {
+				t.Fatal(err)
+			}
+			return token, code, ""
+		}, errors.IsUnauthorized},
+	}
+	for _, tt := range tests {
+		user := &Users{ID: uuid.NewString(), Username: ptr("erin"), Email: ptr("erin@example.com")}
+		uc, _, repo, mailer, _ := newTestPasswordless(t, user)
+		token, _, err := uc.StartLogin(ctx, LookupKey{FieldEmail, "erin@example.com"}, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		token, code, linkToken := tt.tamper(t, uc, repo, mailer, token, testCode(t, mailer.next(t).Body))
+		if _, _, err := uc.CompleteLogin(ctx, token, code, linkToken, Client{}); !tt.want(err) {
+			t.Errorf("%s: err = %v", tt.name, err)
+		}
+	}
+}
+
+func TestPasswordlessLoginUnknownUser(t *testing.T) {
+	ctx := context.Background()
+	uc, _, repo, mailer, _ := newTestPasswordless(t)
+	token, _, err := uc.StartLogin(ctx, LookupKey{FieldEmail, "mallory@example.com"}, false)
+	if err != nil || token == "" {
+		t.Fatalf("unknown user = %q, %v, want a login token like anybody", token, err)
+	}
+	mailer.none(t)
+	if len(repo.logins) != 0 {
+		t.Error("a login was stored for nobody")
+	}
+	if _, _, err := uc.CompleteLogin(ctx, token, "123456", "", Client{}); !errors.IsUnauthorized(err) {
+		t.Errorf("completing err = %v, want Unauthorized", err)
+	}
+}
+
+func TestPasswordlessLoginHourlyLimit(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin"), Email: ptr("erin@example.com")}
+	uc, _, _, mailer, _ := newTestPasswordless(t, user)
+	for i := 0; i < defaultPasswordlessHourlyLimit; i++ {
+		if _, _, err := uc.StartLogin(ctx, LookupKey{FieldEmail, "erin@example.com"}, false); err != nil {
+			t.Fatal(err)
+		}
+		mailer.next(t)
+	}
+	if _, _, err := uc.StartLogin(ctx, LookupKey{FieldEmail, "erin@example.com"}, false); err != nil {
+		t.Errorf("start beyond the limit: %v", err)
+	}
+	mailer.none(t)
+}
+
+func TestPasswordlessLoginWaitsForMFA(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin"), Email: ptr("erin@example.com")}
+	uc, auth, _, mailer, _ := newTestPasswordless(t, user)
+	auth.enableTOTP(t, uuid.MustParse(user.ID))
+	token, _, err := uc.StartLogin(ctx, LookupKey{FieldEmail, "erin@example.com"}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pair, mfaToken, err := uc.CompleteLogin(ctx, token, testCode(t, mailer.next(t).Body), "", Client{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pair != nil || mfaToken == "" {
+		t.Errorf("login with a second factor = %v, %q, want an MFA token", pair, mfaToken)
+	}
+}
@@ -0,0 +1,81 @@
+package biz
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// PrincipalType tells users from the services calling with an API key.
+type PrincipalType string
+
+const (
+	PrincipalUser   PrincipalType = "user"
+	PrincipalAPIKey PrincipalType = "api_key"
+)
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	Type PrincipalType
+	// UserID and SessionID are set for users, APIKeyID and Scopes for API
+	// keys. SessionID is empty for tokens issued without a session.
+	UserID    string
+	SessionID string
+	APIKeyID  string
+	Scopes    []Permission
+	TokenID   string
+	ExpiresAt time.Time
+	// Gateway is set for users identified by the API gateway, whose
+	// permissions follow from the Roles it forwarded rather than from the
+	// roles stored for them.
+	Gateway bool
+	Roles   []string
+}
+
+// ID identifies the principal among those of its type.
+func (p *Principal) ID() string {
+	if p.Type == PrincipalAPIKey {
+		return p.APIKeyID
+	}
+	return p.UserID
+}
+
+// String formats the principal for logs, e.g. "api_key:<id>".
+func (p *Principal) String() string {
+	return string(p.Type) + ":" + p.ID()
+}
+
+func (p *Principal) hasScope(perm Permission) bool {
+	for _, s := range p.Scopes {
+		if s == perm {
+			return true
+		}
+	}
+	return false
+}
+
+type principalKey struct{}
+
+// NewPrincipalContext returns a copy of ctx carrying p.
+func NewPrincipalContext(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFromContext returns the principal the request was authenticated
+// as, if any.
+func PrincipalFromContext(ctx context.Context) (*Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(*Principal)
+	return p, ok
+}
+
+// PrincipalValuer logs the principal of the request, or nothing outside of
+// authenticated requests.
+func PrincipalValuer() log.Valuer {
+	return func(ctx context.Context) interface{} {
+		if p, ok := PrincipalFromContext(ctx); ok {
+			return p.String()
+		}
+		return ""
+	}
+}
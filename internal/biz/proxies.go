@@ -0,0 +1,94 @@
+package biz
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+	"users/internal/conf"
+)
+
+// TrustedProxies are the reverse proxies and gateway in front of the
+// service, whose forwarded caller information is believed.
+type TrustedProxies struct {
+	prefixes []netip.Prefix
+}
+
+// NewTrustedProxies parses the addresses and CIDRs of auth.trusted_proxies.
+func NewTrustedProxies(c *conf.Auth) (*TrustedProxies, error) {
+	t := &TrustedProxies{}
+	for _, s := range c.GetTrustedProxies() {
+		s = strings.TrimSpace(s)
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("trusted proxy %q: %w", s, err)
+			}
+			t.prefixes = append(t.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("trusted proxy %q: %w", s, err)
+		}
+		t.prefixes = append(t.prefixes, prefix.Masked())
+	}
+	return t, nil
+}
+
+// Configured reports whether any proxy is trusted.
+func (t *TrustedProxies) Configured() bool {
+	return len(t.prefixes) > 0
+}
+
+// Trusted reports whether addr, an IP with or without a port, is one of
+// the proxies.
+func (t *TrustedProxies) Trusted(addr string) bool {
+	ip, ok := parseIP(addr)
+	return ok && t.trusted(ip)
+}
+
+func (t *TrustedProxies) trusted(ip netip.Addr) bool {
+	for _, p := range t.prefixes {
+		if p.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// ClientIP returns the IP of the client of a request that came from peer,
+// an address with or without a port, carrying forwardedFor, its
+// X-Forwarded-For header. The header is only believed from the proxies:
+// each of them appends the address it got the request from, so the client
+// is the right-most address that is not a proxy, and anything left of it may
+// have been made up by the client.
+func (t *TrustedProxies) ClientIP(peer, forwardedFor string) string {
+	ip, ok := parseIP(peer)
+	if !ok {
+		return ""
+	}
+	hops := strings.Split(forwardedFor, ",")
+	for i := len(hops) - 1; i >= 0 && t.trusted(ip); i-- {
+		hop, ok := parseIP(hops[i])
+		if !ok {
+			break
+		}
+		ip = hop
+	}
+	return ip.String()
+}
+
+// parseIP parses an IP with or without a port, unmapping IPv4-mapped IPv6
+// addresses.
+func parseIP(addr string) (netip.Addr, bool) {
+	addr = strings.TrimSpace(addr)
+	if host, _, err := net.SplitHostPort(addr); err == nil {
+		addr = host
+	}
+	ip, err := netip.ParseAddr(addr)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return ip.Unmap(), true
+}
@@ -0,0 +1,32 @@
+package biz
+
+import (
+	"testing"
+	"users/internal/conf"
+)
+
+func TestClientIP(t *testing.T) {
+	proxies, err := NewTrustedProxies(&conf.Auth{TrustedProxies: []string{"10.0.0.0/8", "192.0.2.1"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name, peer, forwardedFor, want string
+	}{
+		{"direct", "203.0.113.5:4242", "", "203.0.113.5"},
+		{"forged by a direct client", "203.0.113.5:4242", "198.51.100.7", "203.0.113.5"},
+		{"through a proxy", "10.1.2.3:4242", "198.51.100.7", "198.51.100.7"},
+		{"forged through a proxy", "10.1.2.3:4242", "198.51.100.7, 203.0.113.5", "203.0.113.5"},
+		{"through a chain of proxies", "10.1.2.3:4242", "198.51.100.7, 203.0.113.5, 192.0.2.1, 10.9.9.9", "203.0.113.5"},
+		{"proxy without header", "10.1.2.3:4242", "", "10.1.2.3"},
+		{"malformed hop", "10.1.2.3:4242", "198.51.100.7, garbage", "10.1.2.3"},
+		{"IPv4-mapped peer", "[::ffff:10.1.2.3]:4242", "198.51.100.7", "198.51.100.7"},
+		{"IPv6 client", "10.1.2.3:4242", "2001:db8::1", "2001:db8::1"},
+		{"no peer", "", "198.51.100.7", ""},
+	}
+	for _, tt := range tests {
+		if got := proxies.ClientIP(tt.peer, tt.forwardedFor); got != tt.want {
+			t.Errorf("%s: ClientIP(%q, %q) = %q, want %q", tt.name, tt.peer, tt.forwardedFor, got, tt.want)
+		}
+	}
+}
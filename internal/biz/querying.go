@@ -4,8 +4,57 @@ type PaginationParams struct {
 	Page     int
 	PageSize int
 	Reverse  bool
+	// PageToken continues a previous listing; when set Page is ignored.
+	PageToken string
+	// After is the decoded PageToken: rows strictly after it are returned.
+	After Keyset
+	// EstimateTotal trades an exact count for a planner estimate, which
+	// stays cheap on very large tables.
+	EstimateTotal bool
 }
-type SortParams struct {
-	SortBy    string
-	SortOrder string
+
+// SortKey orders list results by one field.
+type SortKey struct {
+	Field Field
+	Desc  bool
+}
+
+func (k SortKey) String() string {
+	if k.Desc {
+		return string(k.Field) + " desc"
+	}
+	return string(k.Field) + " asc"
 }
+
+// SortParams orders list results by each key in turn. Rows left tied by all
+// keys are ordered by id, so the ordering is always total.
+type SortParams []SortKey
+
+// DeletedScope selects how soft-deleted users are treated by a query.
+type DeletedScope int
+
+const (
+	// ExcludeDeleted only considers live users.
+	ExcludeDeleted DeletedScope = iota
+	// IncludeDeleted considers live and soft-deleted users.
+	IncludeDeleted
+	// OnlyDeleted only considers soft-deleted users.
+	OnlyDeleted
+)
+
+// QueryParams narrows down the rows and columns returned by a list query.
+type QueryParams struct {
+	// Search is a free-text term matched case-insensitively against
+	// username, email and phone. Empty means no search.
+	Search string
+	// Filters must all hold for a row to be returned.
+	Filters []Filter
+	// Fields lists the attributes to load for each row, always including
+	// FieldID. Empty means every attribute.
+	Fields []Field
+	// Deleted tells whether soft-deleted users are part of the results.
+	Deleted DeletedScope
+}
+
+// MaxSearchLength bounds the free-text query accepted by ListUsers.
+const MaxSearchLength = 256
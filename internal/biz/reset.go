@@ -0,0 +1,188 @@
+package biz
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"time"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+const (
+	defaultPasswordResetTTL         = time.Hour
+	defaultPasswordResetHourlyLimit = 5
+
+	purposePasswordReset = "password-reset"
+)
+
+// PasswordResetUsecase lets users who forgot their password set a new one
+// through a link mailed to them.
+type PasswordResetUsecase struct {
+	users       *UsersUsecase
+	creds       *CredentialsUsecase
+	attempts    *AttemptLimiter
+	mailer      Mailer
+	tokens      *signedTokens
+	ttl         time.Duration
+	hourlyLimit int
+	link        string
+	log         *log.Helper
+}
+
+// NewPasswordResetUsecase new a PasswordReset usecase.
+func NewPasswordResetUsecase(users *UsersUsecase, creds *CredentialsUsecase, attempts *AttemptLimiter, mailer Mailer, c *conf.Biz, logger log.Logger) (*PasswordResetUsecase, error) {
+	vc := c.GetVerification()
+	tokens, err := newSignedTokens(vc.GetSecret())
+	if err != nil {
+		return nil, err
+	}
+	helper := log.NewHelper(logger)
+	if vc.GetSecret() == "" {
+		helper.Warn("no verification secret configured, password reset links will not survive a restart")
+	}
+	ttl := vc.GetPasswordResetTtl().AsDuration()
+	if ttl <= 0 {
+		ttl = defaultPasswordResetTTL
+	}
+	hourlyLimit := int(vc.GetPasswordResetHourlyLimit())
+	if hourlyLimit <= 0 {
+		hourlyLimit = defaultPasswordResetHourlyLimit
+	}
+	return &PasswordResetUsecase{
+		users:       users,
+		creds:       creds,
+		attempts:    attempts,
+		mailer:      mailer,
+		tokens:      tokens,
+		ttl:         ttl,
+		hourlyLimit: hourlyLimit,
+		link:        vc.GetPasswordResetLinkUrl(),
+		log:         helper,
+	}, nil
+}
+
+// RequestPasswordReset mails a reset link to the user with the given email.
+// The outcome is the same whether or not such a user exists, and the mail is
+// sent in the background so that timing does not tell either. Users get a few
+// mails per hour at most, further requests are dropped silently.
+func (uc *PasswordResetUsecase) RequestPasswordReset(ctx context.Context, email string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Biz RequestPasswordReset")
+	defer span.End()
+	if NormalizeEmail(email) == "" {
+		err := errors.BadRequest("users.requestPasswordReset", "email must not be empty")
+		span.AddEvent(err.Error())
+		return err
+	}
+	user, err := uc.users.LookupUser(ctx, LookupKey{Field: FieldEmail, Value: email})
+	if errors.IsNotFound(err) {
+		return nil
+	}
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	go uc.sendReset(context.WithoutCancel(ctx), user)
+	return nil
+}
+
+func (uc *PasswordResetUsecase) sendReset(ctx context.Context, user *Users) {
+	uid, err := uuid.Parse(user.ID)
+	if err != nil {
+		uc.log.WithContext(ctx).Errorf("password reset for user %s: %v", user.ID, err)
+		return
+	}
+	if !uc.attempts.Allow(ctx, ScopePasswordReset, uid, uc.hourlyLimit, time.Hour) {
+		uc.log.WithContext(ctx).Warnf("password reset for user %s: hourly limit of %d reached", user.ID, uc.hourlyLimit)
+		return
+	}
+	current, err := uc.creds.currentHash(ctx, uid)
+	if err != nil {
+		uc.log.WithContext(ctx).Errorf("password reset for user %s: %v", user.ID, err)
+		return
+	}
+	token, err := uc.tokens.sign(purposePasswordReset, tokenClaims{
+		Subject: user.ID,
+		Binding: uc.tokens.digest(purposePasswordReset, current),
+		Expires: time.Now().Add(uc.ttl).Unix(),
+	})
+	if err != nil {
+		uc.log.WithContext(ctx).Errorf("password reset for user %s: %v", user.ID, err)
+		return
+	}
+	body := fmt.Sprintf("Hello %s,\n\nYou can choose a new password", *user.Username)
+	if uc.link != "" {
+		body += " by opening this link:\n\n" + uc.link + token
+	} else {
+		body += " with this token:\n\n" + token
+	}
+	body += fmt.Sprintf("\n\nIt works once and expires in %s. If you did not ask for it, ignore this email; your password stays unchanged.\n", uc.ttl)
+	err = uc.mailer.Send(ctx, &Mail{
+		To:      *user.Email,
+		Subject: "Reset your password",
+		Body:    body,
+	})
+	if err != nil {
+		uc.log.WithContext(ctx).Errorf("password reset for user %s: %v", user.ID, err)
+	}
+}
+
+// ResetPassword sets a new password with a token mailed by
+// RequestPasswordReset. The token is bound to the password it replaces, so
+// it stops working once used or once the password changes otherwise. Every
+// session of the user ends along with the previous password.
+func (uc *PasswordResetUsecase) ResetPassword(ctx context.Context, token, password string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Biz ResetPassword")
+	defer span.End()
+	claims, err := uc.tokens.verify("users.resetPassword", purposePasswordReset, token)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	if err := uc.creds.checkPassword("users.resetPassword", password); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	invalid := errors.BadRequest("users.resetPassword", "invalid or expired token")
+	uid, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return invalid
+	}
+	if _, err := uc.users.repo.FindByID(ctx, uid, ExcludeDeleted); err != nil {
+		if errors.IsNotFound(err) {
+			err = invalid
+		}
+		span.AddEvent(err.Error())
+		return err
+	}
+	current, err := uc.creds.currentHash(ctx, uid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	binding := uc.tokens.digest(purposePasswordReset, current)
+	if subtle.ConstantTimeCompare([]byte(binding), []byte(claims.Binding)) != 1 {
+		span.AddEvent(invalid.Error())
+		return invalid
+	}
+	ok, err := uc.creds.replacePassword(ctx, uid, current, password)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	if !ok {
+		// a concurrent reset or change won
+		span.AddEvent(invalid.Error())
+		return invalid
+	}
+	if err := uc.creds.endSessions(ctx, uid); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package biz
+
+import (
+	"context"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"testing"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+func newTestReset(t *testing.T, vc *conf.Biz_Verification, users ...*Users) (*PasswordResetUsecase, *testAuth, *memMailer) {
+	t.Helper()
+	auth := newTestAuth(t, &conf.Auth{}, users...)
+	mailer := newMemMailer()
+	if vc == nil {
+		vc = &conf.Biz_Verification{}
+	}
+	vc.Secret = "secret"
+	vc.PasswordResetLinkUrl = "https://example.com/?token="
+	uc, err := NewPasswordResetUsecase(auth.AuthUsecase.users, auth.creds, auth.AuthUsecase.attempts, mailer, &conf.Biz{Verification: vc}, log.NewStdLogger(testWriter{t}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uc, auth, mailer
+}
+
+func TestResetPassword(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin"), Email: ptr("erin@example.com")}
+	uid := uuid.MustParse(user.ID)
+	uc, auth, mailer := newTestReset(t, nil, user)
+	auth.setPassword(t, uid, "correct horse")
+	session := auth.startSession(t, uid)
+
+	if err := uc.RequestPasswordReset(ctx, " "); !errors.IsBadRequest(err) {
+		t.Errorf("empty email err = %v, want BadRequest", err)
+	}
+	if err := uc.RequestPasswordReset(ctx, "mallory@example.com"); err != nil {
+		t.Errorf("unknown email: %v", err)
+	}
+	mailer.none(t)
+	if err := uc.RequestPasswordReset(ctx, "Erin@Example.com"); err != nil {
+		t.Fatal(err)
+	}
+	mail := mailer.next(t)
+	if mail.To != "erin@example.com" {
+		t.Errorf("mailed to %s", mail.To)
+	}
+	token := testToken(t, mail.Body)
+
+	if err := uc.ResetPassword(ctx, token, "short"); !errors.IsBadRequest(err) {
+		t.Errorf("short password err = %v, want BadRequest", err)
+	}
+	if err := uc.ResetPassword(ctx, "garbage", "battery staple"); !errors.IsBadRequest(err) {
+		t.Errorf("garbage token err = %v, want BadRequest", err)
+	}
+	if err := uc.ResetPassword(ctx, token, "battery staple"); err != nil {
+		t.Fatal(err)
+	}
+	if ok, _ := auth.creds.VerifyPassword(ctx, user.ID, "battery staple"); !ok {
+		t.Error("the new password does not verify")
+	}
+	if auth.active(t, session) {
+		t.Error("a session outlived the reset")
+	}
+	if err := uc.ResetPassword(ctx, token, "another password"); !errors.IsBadRequest(err) {
+		t.Errorf("reused token err = %v, want BadRequest", err)
+	}
+}
+
+func TestResetTokenDiesWithPassword(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		// password is the password of the user when the token is mailed
+		password string
+		change   func(*testAuth, *Users) error
+	}{
+		{"changed", "correct horse", func(auth *testAuth, u *Users) error {
+			return auth.creds.ChangePassword(ctx, u.ID, "correct horse", "battery staple")
+		}},
+		{"set by an admin", "correct horse", func(auth *testAuth, u *Users) error {
+			return auth.creds.SetPassword(ctx, u.ID, "battery staple")
+		}},
+		{"set to the same", "correct horse", func(auth *testAuth, u *Users) error {
+			return auth.creds.SetPassword(ctx, u.ID, "correct horse")
+		}},
+		{"set where there was none", "", func(auth *testAuth, u *Users) error {
+			return auth.creds.SetPassword(ctx, u.ID, "battery staple")
+		}},
+	}
+	for _, tt := range tests {
+		user := &Users{ID: uuid.NewString(), Username: ptr("erin"), Email: ptr("erin@example.com")}
+		uc, auth, mailer := newTestReset(t, nil, user)
+		if tt.password != "" {
+			auth.setPassword(t, uuid.MustParse(user.ID), tt.password)
+		}
+		if err := uc.RequestPasswordReset(ctx, *user.Email); err != nil {
+			t.Fatal(err)
+		}
+		token := testToken(t, mailer.next(t).Body)
+		if err := tt.change(auth, user); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if err := uc.ResetPassword(ctx, token, "mallory was here"); !errors.IsBadRequest(err) {
+			t.Errorf("%s: err = %v, want BadRequest", tt.name, err)
+		}
+	}
+}
+
+func TestRequestPasswordResetLimit(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin"), Email: ptr("erin@example.com")}
+	uc, auth, mailer := newTestReset(t, &conf.Biz_Verification{PasswordResetHourlyLimit: 2}, user)
+	for i := 0; i < 2; i++ {
+		if err := uc.RequestPasswordReset(ctx, *user.Email); err != nil {
+			t.Fatal(err)
+		}
+		mailer.next(t)
+	}
+	// the caller cannot tell the limit was reached
+	if err := uc.RequestPasswordReset(ctx, *user.Email); err != nil {
+		t.Errorf("request beyond the limit: %v", err)
+	}
+	mailer.none(t)
+
+	if err := auth.attempts.Reset(ctx, accountAttemptsKey(ScopePasswordReset, uuid.MustParse(user.ID))); err != nil {
+		t.Fatal(err)
+	}
+	if err := uc.RequestPasswordReset(ctx, *user.Email); err != nil {
+		t.Fatal(err)
+	}
+	mailer.next(t)
+}
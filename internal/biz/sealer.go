@@ -0,0 +1,52 @@
+package biz
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+)
+
+// sealer encrypts secrets at rest with AES-256-GCM. The associated data
+// binds a ciphertext to its owner, so that it cannot be moved to another row.
+type sealer struct {
+	aead cipher.AEAD
+}
+
+// newSealer takes a base64 encoded 32 byte key.
+func newSealer(key string) (*sealer, error) {
+	raw, err := base64.StdEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("malformed encryption key: %w", err)
+	}
+	if len(raw) != 32 {
+		return nil, fmt.Errorf("encryption key must be 32 bytes, got %d", len(raw))
+	}
+	block, err := aes.NewCipher(raw)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &sealer{aead: aead}, nil
+}
+
+// seal returns the nonce followed by the ciphertext of plaintext.
+func (s *sealer) seal(plaintext, ad []byte) ([]byte, error) {
+	nonce := make([]byte, s.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(nonce, nonce, plaintext, ad), nil
+}
+
+func (s *sealer) open(sealed, ad []byte) ([]byte, error) {
+	n := s.aead.NonceSize()
+	if len(sealed) < n {
+		return nil, fmt.Errorf("sealed secret too short")
+	}
+	return s.aead.Open(nil, sealed[:n], sealed[n:], ad)
+}
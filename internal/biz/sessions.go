@@ -0,0 +1,294 @@
+package biz
+
+import (
+	"context"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"github.com/hashicorp/golang-lru/v2/expirable"
+	"go.opentelemetry.io/otel"
+	"strings"
+	"time"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+const (
+	defaultSessionCacheTTL  = 10 * time.Second
+	defaultSessionCacheSize = 10000
+	// sessionTouchInterval bounds how often last activity is written per
+	// session.
+	sessionTouchInterval = time.Minute
+	// maxClientFieldLength bounds the client details stored with a session,
+	// which come straight from request headers.
+	maxClientFieldLength = 512
+)
+
+// Session is a login of a user from some client. Sessions started by Login
+// share their ID with the refresh token family they issue, and access tokens
+// name it in their "sid" claim, so ending the session ends both.
+type Session struct {
+	ID        uuid.UUID
+	UserID    uuid.UUID
+	Device    string
+	UserAgent string
+	IP        string
+	// ClientID is the OpenID Connect client the session was started for
+	// and Scope what it was granted; both are empty for direct logins
+	ClientID   string
+	Scope      string
+	CreatedAt  time.Time
+	LastSeenAt time.Time
+	ExpiresAt  time.Time
+	RevokedAt  *time.Time
+}
+
+func (s *Session) active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// Client describes where a session is started from. Device is a name chosen
+// by the client, the rest comes from the request.
+type Client struct {
+	Device    string
+	UserAgent string
+	IP        string
+}
+
+type SessionsRepo interface {
+	SaveSession(context.Context, *Session) (*Session, error)
+	// FindSession returns a session, revoked or not, or a NotFound error.
+	FindSession(context.Context, uuid.UUID) (*Session, error)
+	// ListSessions returns the unrevoked, unexpired sessions of a user, most
+	// recently seen first.
+	ListSessions(context.Context, uuid.UUID) ([]Session, error)
+	// TouchSession records activity on a session, extending it to expiresAt
+	// when set.
+	TouchSession(ctx context.Context, id uuid.UUID, seenAt time.Time, expiresAt *time.Time) error
+	// RevokeSession revokes an active session of a user and deletes its
+	// refresh tokens, returning a NotFound error when there is none.
+	RevokeSession(ctx context.Context, userID, id uuid.UUID) (*Session, error)
+	// RevokeSessions revokes every active session of a user but except,
+	// returning the IDs of the revoked ones, and deletes the refresh tokens
+	// of the user outside of the family of except, session or not.
+	RevokeSessions(ctx context.Context, userID uuid.UUID, except *uuid.UUID) ([]uuid.UUID, error)
+}
+
+// SessionsUsecase tracks where users are logged in. Validation results are
+// cached for a short while, so that gateways can validate a session on every
+// request without a database round trip each time.
+type SessionsUsecase struct {
+	repo  SessionsRepo
+	users UsersRepo
+	ttl   time.Duration
+	// cache holds nil for sessions found invalid
+	cache *expirable.LRU[uuid.UUID, *Session]
+	log   *log.Helper
+}
+
+// NewSessionsUsecase new a Sessions usecase. Sessions last as long as refresh
+// tokens.
+func NewSessionsUsecase(repo SessionsRepo, users UsersRepo, c *conf.Auth, logger log.Logger) *SessionsUsecase {
+	ttl := c.GetRefreshTokenTtl().AsDuration()
+	if ttl <= 0 {
+		ttl = defaultRefreshTokenTTL
+	}
+	cacheTTL := c.GetSessions().GetCacheTtl().AsDuration()
+	if cacheTTL <= 0 {
+		cacheTTL = defaultSessionCacheTTL
+	}
+	cacheSize := int(c.GetSessions().GetCacheSize())
+	if cacheSize <= 0 {
+		cacheSize = defaultSessionCacheSize
+	}
+	return &SessionsUsecase{
+		repo:  repo,
+		users: users,
+		ttl:   ttl,
+		cache: expirable.NewLRU[uuid.UUID, *Session](cacheSize, nil, cacheTTL),
+		log:   log.NewHelper(logger),
+	}
+}
+
+// CreateSession starts a session for a live user, for callers such as
+// gateways that keep their own session cookies.
+func (uc *SessionsUsecase) CreateSession(ctx context.Context, userID string, client Client) (*Session, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz CreateSession")
+	defer span.End()
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if _, err := uc.users.FindByID(ctx, uid, ExcludeDeleted); err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	res, err := uc.start(ctx, uid, client, "", "")
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+// ListSessions returns the active sessions of a user, most recently seen
+// first.
+func (uc *SessionsUsecase) ListSessions(ctx context.Context, userID string) ([]Session, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz ListSessions")
+	defer span.End()
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	res, err := uc.repo.ListSessions(ctx, uid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+// RevokeSession ends an active session of a user.
+func (uc *SessionsUsecase) RevokeSession(ctx context.Context, userID, id string) (*Session, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz RevokeSession")
+	defer span.End()
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	sid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	res, err := uc.repo.RevokeSession(ctx, uid, sid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	uc.cache.Remove(sid)
+	return res, nil
+}
+
+// RevokeAllSessions ends every active session of a user, but except when it
+// is set, e.g. to log out everywhere else. It returns how many ended.
+func (uc *SessionsUsecase) RevokeAllSessions(ctx context.Context, userID, except string) (int, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz RevokeAllSessions")
+	defer span.End()
+	uid, err := uuid.Parse(userID)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return 0, err
+	}
+	var keep *uuid.UUID
+	if except != "" {
+		id, err := uuid.Parse(except)
+		if err != nil {
+			span.AddEvent(err.Error())
+			return 0, err
+		}
+		keep = &id
+	}
+	ids, err := uc.repo.RevokeSessions(ctx, uid, keep)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return 0, err
+	}
+	for _, id := range ids {
+		uc.cache.Remove(id)
+	}
+	return len(ids), nil
+}
+
+// ValidateSession returns the session with the given ID when it is active
+// and its user is live, and nil otherwise. Results are cached, so a session
+// revoked through another instance may validate for up to the cache TTL.
+func (uc *SessionsUsecase) ValidateSession(ctx context.Context, id string) (*Session, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz ValidateSession")
+	defer span.End()
+	sid, err := uuid.Parse(id)
+	if err != nil {
+		return nil, nil
+	}
+	now := time.Now()
+	s, ok := uc.cache.Get(sid)
+	if !ok {
+		s, err = uc.load(ctx, sid, now)
+		if err != nil {
+			span.AddEvent(err.Error())
+			return nil, err
+		}
+		uc.cache.Add(sid, s)
+	}
+	if s == nil || !s.active(now) {
+		return nil, nil
+	}
+	return s, nil
+}
+
+// load reads a session for the cache, recording activity on the way.
+func (uc *SessionsUsecase) load(ctx context.Context, id uuid.UUID, now time.Time) (*Session, error) {
+	s, err := uc.repo.FindSession(ctx, id)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !s.active(now) {
+		return s, nil
+	}
+	_, err = uc.users.FindByID(ctx, s.UserID, ExcludeDeleted)
+	if errors.IsNotFound(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if now.Sub(s.LastSeenAt) >= sessionTouchInterval {
+		if err := uc.repo.TouchSession(ctx, id, now, nil); err != nil {
+			uc.log.WithContext(ctx).Warnf("failed recording activity of session %s: %v", id, err)
+		} else {
+			s.LastSeenAt = now
+		}
+	}
+	return s, nil
+}
+
+func (uc *SessionsUsecase) start(ctx context.Context, userID uuid.UUID, client Client, clientID, scope string) (*Session, error) {
+	now := time.Now()
+	return uc.repo.SaveSession(ctx, &Session{
+		ID:         uuid.New(),
+		UserID:     userID,
+		Device:     clientField(client.Device),
+		UserAgent:  clientField(client.UserAgent),
+		IP:         clientField(client.IP),
+		ClientID:   clientID,
+		Scope:      scope,
+		CreatedAt:  now,
+		LastSeenAt: now,
+		ExpiresAt:  now.Add(uc.ttl),
+	})
+}
+
+// extend records a refresh of a session, which then lasts until expiresAt.
+func (uc *SessionsUsecase) extend(ctx context.Context, id uuid.UUID, expiresAt time.Time) error {
+	uc.cache.Remove(id)
+	return uc.repo.TouchSession(ctx, id, time.Now(), &expiresAt)
+}
+
+// forget drops a session ended elsewhere from the cache.
+func (uc *SessionsUsecase) forget(id uuid.UUID) {
+	uc.cache.Remove(id)
+}
+
+func clientField(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > maxClientFieldLength {
+		s = strings.ToValidUTF8(s[:maxClientFieldLength], "")
+	}
+	return s
+}
@@ -0,0 +1,133 @@
+package biz
+
+import (
+	"context"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"strings"
+	"testing"
+	"time"
+	"users/internal/conf"
+)
+
+func TestSessions(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin")}
+	other := &Users{ID: uuid.NewString(), Username: ptr("frank")}
+	auth := newTestAuth(t, &conf.Auth{}, user, other)
+	uc := auth.AuthUsecase.sessions
+
+	if _, err := uc.CreateSession(ctx, uuid.NewString(), Client{}); !errors.IsNotFound(err) {
+		t.Errorf("session of an unknown user err = %v, want NotFound", err)
+	}
+	laptop, err := uc.CreateSession(ctx, user.ID, Client{Device: " laptop ", UserAgent: strings.Repeat("a", 2*maxClientFieldLength), IP: "203.0.113.5"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if laptop.Device != "laptop" || len(laptop.UserAgent) != maxClientFieldLength {
+		t.Errorf("client details %q, %d bytes of user agent", laptop.Device, len(laptop.UserAgent))
+	}
+	phone, err := uc.CreateSession(ctx, user.ID, Client{Device: "phone"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tablet, err := uc.CreateSession(ctx, user.ID, Client{Device: "tablet"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	foreign, err := uc.CreateSession(ctx, other.ID, Client{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := uc.ListSessions(ctx, user.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != 3 {
+		t.Errorf("%d sessions listed, want 3", len(list))
+	}
+
+	// a session can only be revoked through its own user
+	if _, err := uc.RevokeSession(ctx, other.ID, laptop.ID.String()); !errors.IsNotFound(err) {
+		t.Errorf("revoking the session of another user err = %v, want NotFound", err)
+	}
+	if _, err := uc.RevokeSession(ctx, user.ID, laptop.ID.String()); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := uc.RevokeSession(ctx, user.ID, laptop.ID.String()); !errors.IsNotFound(err) {
+		t.Errorf("revoking twice err = %v, want NotFound", err)
+	}
+
+	n, err := uc.RevokeAllSessions(ctx, user.ID, tablet.ID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 1 {
+		t.Errorf("%d sessions ended, want 1", n)
+	}
+	for _, tt := range []struct {
+		session *Session
+		valid   bool
+	}{
+		{laptop, false},
+		{phone, false},
+		{tablet, true},
+		{foreign, true},
+	} {
+		s, err := uc.ValidateSession(ctx, tt.session.ID.String())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (s != nil) != tt.valid {
+			t.Errorf("session on %q valid = %t, want %t", tt.session.Device, s != nil, tt.valid)
+		}
+	}
+}
+
+func TestValidateSession(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin")}
+	auth := newTestAuth(t, &conf.Auth{}, user)
+	uc := auth.AuthUsecase.sessions
+	uid := uuid.MustParse(user.ID)
+
+	expired := auth.startSession(t, uid)
+	auth.sessions.sessions[expired].ExpiresAt = time.Now().Add(-time.Second)
+	revoked := auth.startSession(t, uid)
+	auth.sessions.sessions[revoked].RevokedAt = ptr(time.Now())
+	tests := []struct {
+		name, id string
+	}{
+		{"malformed", "not a uuid"},
+		{"unknown", uuid.NewString()},
+		{"expired", expired.String()},
+		{"revoked", revoked.String()},
+	}
+	for _, tt := range tests {
+		s, err := uc.ValidateSession(ctx, tt.id)
+		if err != nil || s != nil {
+			t.Errorf("%s session = %v, %v, want nil", tt.name, s, err)
+		}
+	}
+
+	active := auth.startSession(t, uid)
+	if s, err := uc.ValidateSession(ctx, active.String()); err != nil || s == nil {
+		t.Fatalf("active session = %v, %v", s, err)
+	}
+	// ending it through the usecase takes effect right away, despite the
+	// cache
+	if _, err := uc.RevokeSession(ctx, user.ID, active.String()); err != nil {
+		t.Fatal(err)
+	}
+	if s, _ := uc.ValidateSession(ctx, active.String()); s != nil {
+		t.Error("revoked session still valid")
+	}
+
+	// sessions of deleted users are not valid
+	deleted := auth.startSession(t, uid)
+	delete(auth.users.users, uid)
+	if s, _ := uc.ValidateSession(ctx, deleted.String()); s != nil {
+		t.Error("session of a deleted user valid")
+	}
+}
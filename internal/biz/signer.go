@@ -0,0 +1,235 @@
+package biz
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"users/internal/conf"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// JWK is the public half of a signing key, as published in the JWKS.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+}
+
+// JWKS is a JSON Web Key Set.
+type JWKS struct {
+	Keys []JWK `json:"keys"`
+}
+
+type signingKey struct {
+	id     string
+	method jwt.SigningMethod
+	key    crypto.Signer
+}
+
+type verifyingKey struct {
+	id  string
+	alg string
+	key crypto.PublicKey
+}
+
+// tokenSigner signs access tokens with the first configured key and
+// verifies them with any of them, or with the keys of a JWKS file.
+type tokenSigner struct {
+	keys     []signingKey
+	verifies []verifyingKey
+}
+
+func newTokenSigner(keys []*conf.Auth_Key) (*tokenSigner, error) {
+	s := &tokenSigner{}
+	for _, k := range keys {
+		key, err := parseSigningKey(k.GetId(), k.GetPrivateKey())
+		if err != nil {
+			return nil, err
+		}
+		s.keys = append(s.keys, key)
+	}
+	if len(s.keys) == 0 {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, err
+		}
+		id := make([]byte, 8)
+		if _, err := rand.Read(id); err != nil {
+			return nil, err
+		}
+		s.keys = append(s.keys, signingKey{id: hex.EncodeToString(id), method: jwt.SigningMethodEdDSA, key: priv})
+	}
+	return s, nil
+}
+
+func parseSigningKey(id, pemKey string) (signingKey, error) {
+	if id == "" {
+		return signingKey{}, fmt.Errorf("signing key without id")
+	}
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return signingKey{}, fmt.Errorf("signing key %q is not PEM encoded", id)
+	}
+	priv, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return signingKey{}, fmt.Errorf("signing key %q: %w", id, err)
+	}
+	switch priv := priv.(type) {
+	case ed25519.PrivateKey:
+		return signingKey{id: id, method: jwt.SigningMethodEdDSA, key: priv}, nil
+	case *ecdsa.PrivateKey:
+		if priv.Curve != elliptic.P256() {
+			return signingKey{}, fmt.Errorf("signing key %q: only the P-256 curve is supported", id)
+		}
+		return signingKey{id: id, method: jwt.SigningMethodES256, key: priv}, nil
+	case *rsa.PrivateKey:
+		if priv.N.BitLen() < 2048 {
+			return signingKey{}, fmt.Errorf("signing key %q: RSA keys must be at least 2048 bits", id)
+		}
+		return signingKey{id: id, method: jwt.SigningMethodRS256, key: priv}, nil
+	default:
+		return signingKey{}, fmt.Errorf("signing key %q: unsupported key type %T", id, priv)
+	}
+}
+
+// The "typ" headers of issued tokens. Access tokens use the type of RFC 9068
+// so that the ID tokens signed with the same keys are not mistaken for them.
+const (
+	typeAccessToken = "at+jwt"
+	typeIDToken     = "JWT"
+	typeMFAToken    = "mfa+jwt"
+)
+
+// sign returns the compact JWS of claims, signed with the active key.
+func (s *tokenSigner) sign(typ string, claims jwt.Claims) (string, error) {
+	k := s.keys[0]
+	t := jwt.NewWithClaims(k.method, claims)
+	t.Header["kid"] = k.id
+	t.Header["typ"] = typ
+	return t.SignedString(k.key)
+}
+
+// signedHere reports whether t was signed with one of the configured keys,
+// as opposed to a key of another issuer.
+func (s *tokenSigner) signedHere(t *jwt.Token) bool {
+	kid, _ := t.Header["kid"].(string)
+	for _, k := range s.keys {
+		if k.id == kid {
+			return true
+		}
+	}
+	return false
+}
+
+// keyfunc resolves the verification key of a token from its "kid" header.
+func (s *tokenSigner) keyfunc(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	for _, k := range s.keys {
+		if k.id == kid {
+			if t.Method.Alg() != k.method.Alg() {
+				return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+			}
+			return k.key.Public(), nil
+		}
+	}
+	for _, k := range s.verifies {
+		if k.id == kid {
+			if t.Method.Alg() != k.alg {
+				return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+			}
+			return k.key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// addJWKS makes the public keys of a JWKS document valid for verification.
+func (s *tokenSigner) addJWKS(data []byte) error {
+	var set JWKS
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("malformed JWKS: %w", err)
+	}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+		key, err := jwk.publicKey()
+		if err != nil {
+			return err
+		}
+		s.verifies = append(s.verifies, verifyingKey{id: jwk.Kid, alg: jwk.Alg, key: key})
+	}
+	return nil
+}
+
+func (jwk JWK) publicKey() (crypto.PublicKey, error) {
+	b64 := base64.RawURLEncoding.DecodeString
+	switch {
+	case jwk.Kty == "OKP" && jwk.Crv == "Ed25519" && jwk.Alg == jwt.SigningMethodEdDSA.Alg():
+		x, err := b64(jwk.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("JWK %q: malformed Ed25519 key", jwk.Kid)
+		}
+		return ed25519.PublicKey(x), nil
+	case jwk.Kty == "EC" && jwk.Crv == "P-256" && jwk.Alg == jwt.SigningMethodES256.Alg():
+		x, errX := b64(jwk.X)
+		y, errY := b64(jwk.Y)
+		if errX != nil || errY != nil {
+			return nil, fmt.Errorf("JWK %q: malformed EC key", jwk.Kid)
+		}
+		pub := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !pub.Curve.IsOnCurve(pub.X, pub.Y) {
+			return nil, fmt.Errorf("JWK %q: point is not on the P-256 curve", jwk.Kid)
+		}
+		return pub, nil
+	case jwk.Kty == "RSA" && jwk.Alg == jwt.SigningMethodRS256.Alg():
+		n, errN := b64(jwk.N)
+		e, errE := b64(jwk.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("JWK %q: malformed RSA key", jwk.Kid)
+		}
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}, nil
+	default:
+		return nil, fmt.Errorf("JWK %q: unsupported key type %s %s %s", jwk.Kid, jwk.Kty, jwk.Crv, jwk.Alg)
+	}
+}
+
+// jwks returns the public keys of every configured key.
+func (s *tokenSigner) jwks() JWKS {
+	set := JWKS{Keys: make([]JWK, 0, len(s.keys))}
+	b64 := base64.RawURLEncoding.EncodeToString
+	for _, k := range s.keys {
+		jwk := JWK{Kid: k.id, Use: "sig", Alg: k.method.Alg()}
+		switch pub := k.key.Public().(type) {
+		case ed25519.PublicKey:
+			jwk.Kty, jwk.Crv, jwk.X = "OKP", "Ed25519", b64(pub)
+		case *ecdsa.PublicKey:
+			jwk.Kty, jwk.Crv = "EC", "P-256"
+			jwk.X = b64(pub.X.FillBytes(make([]byte, 32)))
+			jwk.Y = b64(pub.Y.FillBytes(make([]byte, 32)))
+		case *rsa.PublicKey:
+			jwk.Kty = "RSA"
+			jwk.N = b64(pub.N.Bytes())
+			jwk.E = b64(big.NewInt(int64(pub.E)).Bytes())
+		}
+		set.Keys = append(set.Keys, jwk)
+	}
+	return set
+}
@@ -2,10 +2,13 @@ package biz
 
 import (
 	"context"
+	"fmt"
 	"github.com/go-kratos/kratos/v2/errors"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
+	"strings"
 	"time"
+	"users/internal/conf"
 
 	"github.com/go-kratos/kratos/v2/log"
 )
@@ -19,37 +22,84 @@ type Users struct {
 	CreatedAt *time.Time
 	UpdatedAt *time.Time
 	DeletedAt *time.Time
+	// EmailVerifiedAt is nil until the user proves they own Email.
+	EmailVerifiedAt *time.Time
+	// PhoneVerifiedAt is nil until the user proves they own Phone.
+	PhoneVerifiedAt *time.Time
 }
 
 type UsersRepo interface {
 	Save(context.Context, *Users) (*Users, error)
 	Update(context.Context, *Users) (*Users, error)
-	FindByID(context.Context, uuid.UUID) (*Users, error)
-	ListAll(context.Context, PaginationParams, SortParams) ([]Users, error)
+	FindByID(context.Context, uuid.UUID, DeletedScope) (*Users, error)
+	// FindByKey returns the live user holding the given identifier,
+	// comparing usernames and emails case-insensitively.
+	FindByKey(context.Context, LookupKey) (*Users, error)
+	// MarkEmailVerified records that a live user verified email, returning
+	// a NotFound error when it is no longer their email.
+	MarkEmailVerified(ctx context.Context, id uuid.UUID, email string) (*Users, error)
+	// MarkPhoneVerified records that a live user verified phone, returning
+	// a NotFound error when it is no longer their phone.
+	MarkPhoneVerified(ctx context.Context, id uuid.UUID, phone string) (*Users, error)
+	// FindByIDs returns the live users among ids, in no particular order.
+	FindByIDs(context.Context, []uuid.UUID) ([]Users, error)
+	// ListAll returns a page of users along with the keyset of its last row,
+	// which is nil when no rows follow the page.
+	ListAll(context.Context, PaginationParams, SortParams, QueryParams) ([]Users, Keyset, error)
 	Delete(context.Context, uuid.UUID) (uuid.UUID, error)
-	Count(ctx context.Context) (int, error)
+	// Count returns the number of users ListAll can return for the query.
+	Count(context.Context, QueryParams) (int, error)
+	// EstimateCount approximates Count from the planner statistics.
+	EstimateCount(context.Context, QueryParams) (int, error)
+	// Restore clears the soft delete of a user.
+	Restore(context.Context, uuid.UUID) (*Users, error)
+	// Purge permanently deletes a user, whether soft-deleted or not.
+	Purge(context.Context, uuid.UUID) (uuid.UUID, error)
+	// PurgeDeleted permanently deletes the users soft-deleted before the
+	// given time and returns how many were removed.
+	PurgeDeleted(context.Context, time.Time) (int, error)
 }
 
 type UsersUsecase struct {
-	repo UsersRepo
-	log  *log.Helper
+	repo       UsersRepo
+	pageTokens *pageTokenCodec
+	retention  time.Duration
+	log        *log.Helper
 }
 
 type ListUsersResponse struct {
 	PaginationParams
-	Total      int
-	TotalPages int
-	Users      []Users
+	Total          int
+	TotalPages     int
+	TotalEstimated bool
+	HasMore        bool
+	NextPageToken  string
+	Users          []Users
 }
 
 // NewUsersUsecase new a Users usecase.
-func NewUsersUsecase(repo UsersRepo, logger log.Logger) *UsersUsecase {
-	return &UsersUsecase{repo: repo, log: log.NewHelper(logger)}
+func NewUsersUsecase(repo UsersRepo, c *conf.Biz, logger log.Logger) (*UsersUsecase, error) {
+	helper := log.NewHelper(logger)
+	secret := c.GetPagination().GetCursorSecret()
+	if secret == "" {
+		helper.Warn("no pagination cursor secret configured, page tokens will not survive restarts")
+	}
+	pageTokens, err := newPageTokenCodec(secret)
+	if err != nil {
+		return nil, err
+	}
+	return &UsersUsecase{
+		repo:       repo,
+		pageTokens: pageTokens,
+		retention:  c.GetRetention().GetDeletedUsers().AsDuration(),
+		log:        helper,
+	}, nil
 }
 
 func (uc *UsersUsecase) CreateUsers(ctx context.Context, u *Users) (*Users, error) {
 	_, span := otel.Tracer("users").Start(ctx, "Biz CreateUsers")
 	defer span.End()
+	normalizeUser(u)
 	res, err := uc.repo.Save(ctx, u)
 	if err != nil {
 		span.AddEvent(err.Error())
@@ -58,7 +108,7 @@ func (uc *UsersUsecase) CreateUsers(ctx context.Context, u *Users) (*Users, erro
 	return res, nil
 }
 
-func (uc *UsersUsecase) GetByID(ctx context.Context, id string) (*Users, error) {
+func (uc *UsersUsecase) GetByID(ctx context.Context, id string, deleted DeletedScope) (*Users, error) {
 	_, span := otel.Tracer("users").Start(ctx, "Biz GetByID")
 	defer span.End()
 	uid, err := uuid.Parse(id)
@@ -66,7 +116,7 @@ func (uc *UsersUsecase) GetByID(ctx context.Context, id string) (*Users, error)
 		span.AddEvent(err.Error())
 		return nil, err
 	}
-	res, err := uc.repo.FindByID(ctx, uid)
+	res, err := uc.repo.FindByID(ctx, uid, deleted)
 	if err != nil {
 		span.AddEvent(err.Error())
 		return nil, err
@@ -74,6 +124,75 @@ func (uc *UsersUsecase) GetByID(ctx context.Context, id string) (*Users, error)
 	return res, nil
 }
 
+// LookupUser finds a live user by username, email or phone. The value is
+// normalized first, and a NotFound error is returned when nobody holds it.
+func (uc *UsersUsecase) LookupUser(ctx context.Context, key LookupKey) (*Users, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz LookupUser")
+	defer span.End()
+	switch key.Field {
+	case FieldUsername:
+		key.Value = NormalizeUsername(key.Value)
+	case FieldEmail:
+		key.Value = NormalizeEmail(key.Value)
+	case FieldPhone:
+		key.Value = NormalizePhone(key.Value)
+	default:
+		err := errors.BadRequest("users.lookup", "one of username, email or phone is required")
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if key.Value == "" {
+		err := errors.BadRequest("users.lookup", fmt.Sprintf("%s must not be empty", key.Field))
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	res, err := uc.repo.FindByKey(ctx, key)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+// MaxBatchGetUsers bounds the number of ids accepted by BatchGetUsers.
+const MaxBatchGetUsers = 100
+
+// BatchGetUsers looks up users by id with a single query. The result is
+// aligned with ids, holding nil for the ids that match no live user.
+func (uc *UsersUsecase) BatchGetUsers(ctx context.Context, ids []string) ([]*Users, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz BatchGetUsers")
+	defer span.End()
+	if len(ids) > MaxBatchGetUsers {
+		err := errors.BadRequest("users.batchGet", fmt.Sprintf("at most %d ids can be requested at once", MaxBatchGetUsers))
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	uids := make([]uuid.UUID, len(ids))
+	for i, id := range ids {
+		uid, err := uuid.Parse(id)
+		if err != nil {
+			err = errors.BadRequest("users.batchGet", fmt.Sprintf("invalid id %q", id))
+			span.AddEvent(err.Error())
+			return nil, err
+		}
+		uids[i] = uid
+	}
+	found, err := uc.repo.FindByIDs(ctx, uids)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	byID := make(map[string]*Users, len(found))
+	for i := range found {
+		byID[found[i].ID] = &found[i]
+	}
+	res := make([]*Users, len(uids))
+	for i, uid := range uids {
+		res[i] = byID[uid.String()]
+	}
+	return res, nil
+}
+
 func (uc *UsersUsecase) DeleteUsers(ctx context.Context, id string) (string, error) {
 	_, span := otel.Tracer("users").Start(ctx, "Biz DeleteUsers")
 	defer span.End()
@@ -90,40 +209,121 @@ func (uc *UsersUsecase) DeleteUsers(ctx context.Context, id string) (string, err
 	return res.String(), nil
 }
 
-func (uc *UsersUsecase) ListUsers(ctx context.Context, pp PaginationParams, sp SortParams) (ListUsersResponse, error) {
+func (uc *UsersUsecase) RestoreUsers(ctx context.Context, id string) (*Users, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz RestoreUsers")
+	defer span.End()
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	res, err := uc.repo.Restore(ctx, uid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+func (uc *UsersUsecase) PurgeUsers(ctx context.Context, id string) (string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz PurgeUsers")
+	defer span.End()
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", err
+	}
+	res, err := uc.repo.Purge(ctx, uid)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", err
+	}
+	return res.String(), nil
+}
+
+// PurgeExpiredUsers permanently deletes the users whose soft delete is older
+// than the configured retention period. It does nothing when no retention
+// period is set.
+func (uc *UsersUsecase) PurgeExpiredUsers(ctx context.Context) (int, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz PurgeExpiredUsers")
+	defer span.End()
+	if uc.retention <= 0 {
+		return 0, nil
+	}
+	n, err := uc.repo.PurgeDeleted(ctx, time.Now().Add(-uc.retention))
+	if err != nil {
+		span.AddEvent(err.Error())
+		return 0, err
+	}
+	return n, nil
+}
+
+func (uc *UsersUsecase) ListUsers(ctx context.Context, pp PaginationParams, sp SortParams, qp QueryParams) (ListUsersResponse, error) {
 	_, span := otel.Tracer("users").Start(ctx, "Biz ListUsers")
 	defer span.End()
-	if sp.SortOrder != "" && sp.SortBy != "" {
-		if sp.SortOrder != "asc" && sp.SortOrder != "desc" {
-			span.AddEvent("invalid sort order")
-			return ListUsersResponse{}, errors.BadRequest("users.list", "invalid sort order")
+	qp.Search = strings.TrimSpace(qp.Search)
+	if len(qp.Search) > MaxSearchLength {
+		span.AddEvent("query too long")
+		return ListUsersResponse{}, errors.BadRequest("users.list", fmt.Sprintf("query must be at most %d characters", MaxSearchLength))
+	}
+
+	fingerprint := listFingerprint(pp, sp, qp)
+	if pp.PageToken != "" {
+		after, err := uc.pageTokens.decode(pp.PageToken, fingerprint)
+		if err != nil {
+			span.AddEvent(err.Error())
+			return ListUsersResponse{}, err
 		}
+		pp.After = after
 	}
 
-	res, err := uc.repo.ListAll(ctx, pp, sp)
+	res, next, err := uc.repo.ListAll(ctx, pp, sp, qp)
 	if err != nil {
 		span.AddEvent(err.Error())
 		return ListUsersResponse{}, err
 	}
+	var nextPageToken string
+	if next != nil {
+		nextPageToken, err = uc.pageTokens.encode(fingerprint, next)
+		if err != nil {
+			span.AddEvent(err.Error())
+			return ListUsersResponse{}, err
+		}
+	}
 
-	totalPages, err := uc.repo.Count(ctx)
+	var total int
+	if pp.EstimateTotal {
+		total, err = uc.repo.EstimateCount(ctx, qp)
+	} else {
+		total, err = uc.repo.Count(ctx, qp)
+	}
 	if err != nil {
 		span.AddEvent(err.Error())
 		return ListUsersResponse{}, err
 	}
+	totalPages := 0
+	if pp.PageSize > 0 {
+		totalPages = (total + pp.PageSize - 1) / pp.PageSize
+	}
+	pp.After = nil
 
 	return ListUsersResponse{
-		Users:      res,
-		Total:      len(res),
-		TotalPages: totalPages,
+		PaginationParams: pp,
+		Users:            res,
+		Total:            total,
+		TotalPages:       totalPages,
+		TotalEstimated:   pp.EstimateTotal,
+		HasMore:          next != nil,
+		NextPageToken:    nextPageToken,
 	}, nil
 }
 
 func (uc *UsersUsecase) UpdateUsers(ctx context.Context, u *Users) (*Users, error) {
 	_, span := otel.Tracer("users").Start(ctx, "Biz UpdateUsers")
 	defer span.End()
+	normalizeUser(u)
 	if (u.Username == nil || *u.Username == "") || (u.Email == nil || *u.Email == "") || (u.Phone == nil || *u.Phone == "") {
-		old, err := uc.GetByID(ctx, u.ID)
+		old, err := uc.GetByID(ctx, u.ID, ExcludeDeleted)
 		if err != nil {
 			span.AddEvent(err.Error())
 			return nil, err
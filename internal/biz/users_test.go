@@ -0,0 +1,141 @@
+package biz
+
+import (
+	"context"
+	"github.com/go-kratos/kratos/v2/errors"
+	"reflect"
+	"strings"
+	"testing"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+// pagedUsers answers every page with a single row followed by next, and
+// counts total rows, or estimate of them.
+type pagedUsers struct {
+	UsersRepo
+	total, estimate int
+	next            Keyset
+	listed          PaginationParams
+}
+
+func (r *pagedUsers) ListAll(_ context.Context, pp PaginationParams, _ SortParams, _ QueryParams) ([]Users, Keyset, error) {
+	r.listed = pp
+	return []Users{{ID: "6f1c1b8e-3c4f-4a8e-9d1c-2b7e5f0a9c31"}}, r.next, nil
+}
+
+func (r *pagedUsers) Count(context.Context, QueryParams) (int, error) {
+	return r.total, nil
+}
+
+func (r *pagedUsers) EstimateCount(context.Context, QueryParams) (int, error) {
+	return r.estimate, nil
+}
+
+func newTestUsers(t *testing.T, repo UsersRepo) *UsersUsecase {
+	t.Helper()
+	uc, err := NewUsersUsecase(repo, &conf.Biz{Pagination: &conf.Biz_Pagination{CursorSecret: "secret"}}, log.NewStdLogger(testWriter{t}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return uc
+}
+
+func TestListUsersMetadata(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name            string
+		repo            pagedUsers
+		pp              PaginationParams
+		total, pages    int
+		estimated, more bool
+	}{
+		{"empty", pagedUsers{}, PaginationParams{Page: 1, PageSize: 10}, 0, 0, false, false},
+		{"one partial page", pagedUsers{total: 7}, PaginationParams{Page: 1, PageSize: 10}, 7, 1, false, false},
+		{"exact pages", pagedUsers{total: 20, next: Keyset{"a"}}, PaginationParams{Page: 1, PageSize: 10}, 20, 2, false, true},
+		{"rounded up", pagedUsers{total: 21, next: Keyset{"a"}}, PaginationParams{Page: 2, PageSize: 10}, 21, 3, false, true},
+		{"no page size", pagedUsers{total: 21}, PaginationParams{Page: 1}, 21, 0, false, false},
+		{"estimated", pagedUsers{total: 21, estimate: 1000, next: Keyset{"a"}}, PaginationParams{Page: 1, PageSize: 100, EstimateTotal: true}, 1000, 10, true, true},
+	}
+	for _, tt := range tests {
+		uc := newTestUsers(t, &tt.repo)
+		res, err := uc.ListUsers(ctx, tt.pp, nil, QueryParams{})
+		if err != nil {
+			t.Errorf("%s: %v", tt.name, err)
+			continue
+		}
+		if res.Total != tt.total || res.TotalPages != tt.pages || res.TotalEstimated != tt.estimated || res.HasMore != tt.more {
+			t.Errorf("%s: total %d, pages %d, estimated %t, more %t, want %d, %d, %t, %t", tt.name,
+				res.Total, res.TotalPages, res.TotalEstimated, res.HasMore, tt.total, tt.pages, tt.estimated, tt.more)
+		}
+		if (res.NextPageToken != "") != tt.more {
+			t.Errorf("%s: next page token %q with more %t", tt.name, res.NextPageToken, tt.more)
+		}
+		if res.Page != tt.pp.Page || res.PageSize != tt.pp.PageSize {
+			t.Errorf("%s: page %d of size %d, want %d of size %d", tt.name, res.Page, res.PageSize, tt.pp.Page, tt.pp.PageSize)
+		}
+	}
+}
+
+func TestListUsersPageTokens(t *testing.T) {
+	ctx := context.Background()
+	keyset := Keyset{"alice", "6f1c1b8e-3c4f-4a8e-9d1c-2b7e5f0a9c31"}
+	repo := &pagedUsers{total: 30, next: keyset}
+	uc := newTestUsers(t, repo)
+	sp := SortParams{{FieldUsername, false}}
+	qp := QueryParams{Search: "a"}
+	first, err := uc.ListUsers(ctx, PaginationParams{Page: 1, PageSize: 10}, sp, qp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if repo.listed.After != nil {
+		t.Errorf("first page listed after %v", repo.listed.After)
+	}
+
+	// the token resumes the listing right after the last row
+	if _, err := uc.ListUsers(ctx, PaginationParams{PageSize: 10, PageToken: first.NextPageToken}, sp, qp); err != nil {
+		t.Fatalf("next page: %v", err)
+	}
+	if !reflect.DeepEqual(repo.listed.After, keyset) {
+		t.Errorf("next page listed after %v, want %v", repo.listed.After, keyset)
+	}
+
+	tests := []struct {
+		name  string
+		token string
+		pp    PaginationParams
+		sp    SortParams
+		qp    QueryParams
+	}{
+		{"tampered", strings.Replace(first.NextPageToken, ".", "x.", 1), PaginationParams{}, sp, qp},
+		{"another search", first.NextPageToken, PaginationParams{}, sp, QueryParams{Search: "b"}},
+		{"another sort", first.NextPageToken, PaginationParams{}, SortParams{{FieldUsername, true}}, qp},
+		{"reversed", first.NextPageToken, PaginationParams{Reverse: true}, sp, qp},
+		{"deleted users", first.NextPageToken, PaginationParams{}, sp, QueryParams{Search: "a", Deleted: IncludeDeleted}},
+	}
+	for _, tt := range tests {
+		tt.pp.PageToken = tt.token
+		if _, err := uc.ListUsers(ctx, tt.pp, tt.sp, tt.qp); !errors.IsBadRequest(err) {
+			t.Errorf("%s: err = %v, want BadRequest", tt.name, err)
+		}
+	}
+
+	// tokens of another instance are only accepted with the same secret
+	other := newTestUsers(t, repo)
+	if _, err := other.ListUsers(ctx, PaginationParams{PageToken: first.NextPageToken}, sp, qp); err != nil {
+		t.Errorf("token from an instance with the same secret: %v", err)
+	}
+}
+
+func TestListUsersSearchLength(t *testing.T) {
+	uc := newTestUsers(t, &pagedUsers{})
+	long := strings.Repeat("a", MaxSearchLength+1)
+	if _, err := uc.ListUsers(context.Background(), PaginationParams{}, nil, QueryParams{Search: long}); !errors.IsBadRequest(err) {
+		t.Errorf("search of %d characters err = %v, want BadRequest", len(long), err)
+	}
+	padded := "  " + strings.Repeat("a", MaxSearchLength) + "  "
+	if _, err := uc.ListUsers(context.Background(), PaginationParams{}, nil, QueryParams{Search: padded}); err != nil {
+		t.Errorf("padded search: %v", err)
+	}
+}
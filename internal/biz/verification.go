@@ -0,0 +1,335 @@
+package biz
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"fmt"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"math/big"
+	"time"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+const (
+	defaultEmailTokenTTL           = 24 * time.Hour
+	defaultPhoneCodeTTL            = 10 * time.Minute
+	defaultPhoneCodeMaxAttempts    = 5
+	defaultPhoneCodeResendInterval = time.Minute
+	phoneCodeDigits                = 6
+
+	purposeEmailVerification = "email-verification"
+	purposePhoneCode         = "phone-code"
+)
+
+// Mail is a plain text email.
+type Mail struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers mail. The data layer provides an SMTP implementation and
+// one writing mail to files or logs for development.
+type Mailer interface {
+	Send(context.Context, *Mail) error
+}
+
+// SMSSender delivers text messages. The data layer only ships a stub that
+// logs them.
+type SMSSender interface {
+	Send(ctx context.Context, to, body string) error
+}
+
+// PhoneCode is a pending phone verification. Only a keyed hash of the code
+// is stored.
+type PhoneCode struct {
+	UserID    uuid.UUID
+	Phone     string
+	Hash      string
+	ExpiresAt time.Time
+	SentAt    time.Time
+}
+
+type VerificationRepo interface {
+	// SavePhoneCode replaces the pending verification of a user, resetting
+	// its attempts.
+	SavePhoneCode(context.Context, *PhoneCode) error
+	// FindPhoneCode returns the pending verification of a user, or a
+	// NotFound error.
+	FindPhoneCode(context.Context, uuid.UUID) (*PhoneCode, error)
+	// UsePhoneCodeAttempt counts an attempt against the pending verification
+	// of a user, reporting false when max attempts were already made.
+	UsePhoneCodeAttempt(ctx context.Context, id uuid.UUID, max int) (bool, error)
+	DeletePhoneCode(context.Context, uuid.UUID) error
+}
+
+// VerificationUsecase proves that users own the contact details they gave.
+type VerificationUsecase struct {
+	users       UsersRepo
+	repo        VerificationRepo
+	mailer      Mailer
+	sms         SMSSender
+	tokens      *signedTokens
+	emailTTL    time.Duration
+	emailLink   string
+	codeTTL     time.Duration
+	maxAttempts int
+	resendAfter time.Duration
+	log         *log.Helper
+}
+
+// NewVerificationUsecase new a Verification usecase.
+func NewVerificationUsecase(users UsersRepo, repo VerificationRepo, mailer Mailer, sms SMSSender, c *conf.Biz, logger log.Logger) (*VerificationUsecase, error) {
+	helper := log.NewHelper(logger)
+	secret := c.GetVerification().GetSecret()
+	if secret == "" {
+		helper.Warn("no verification secret configured, verification links will not survive restarts")
+	}
+	tokens, err := newSignedTokens(secret)
+	if err != nil {
+		return nil, err
+	}
+	vc := c.GetVerification()
+	emailTTL := vc.GetEmailTokenTtl().AsDuration()
+	if emailTTL <= 0 {
+		emailTTL = defaultEmailTokenTTL
+	}
+	codeTTL := vc.GetPhoneCodeTtl().AsDuration()
+	if codeTTL <= 0 {
+		codeTTL = defaultPhoneCodeTTL
+	}
+	maxAttempts := int(vc.GetPhoneCodeMaxAttempts())
+	if maxAttempts <= 0 {
+		maxAttempts = defaultPhoneCodeMaxAttempts
+	}
+	resendAfter := vc.GetPhoneCodeResendInterval().AsDuration()
+	if resendAfter <= 0 {
+		resendAfter = defaultPhoneCodeResendInterval
+	}
+	return &VerificationUsecase{
+		users:       users,
+		repo:        repo,
+		mailer:      mailer,
+		sms:         sms,
+		tokens:      tokens,
+		emailTTL:    emailTTL,
+		emailLink:   vc.GetEmailLinkUrl(),
+		codeTTL:     codeTTL,
+		maxAttempts: maxAttempts,
+		resendAfter: resendAfter,
+		log:         helper,
+	}, nil
+}
+
+// SendEmailVerification mails a user a link proving they own their email.
+func (uc *VerificationUsecase) SendEmailVerification(ctx context.Context, id string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Biz SendEmailVerification")
+	defer span.End()
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	user, err := uc.users.FindByID(ctx, uid, ExcludeDeleted)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	if user.EmailVerifiedAt != nil {
+		err := errors.Conflict("users.sendEmailVerification", "email is already verified")
+		span.AddEvent(err.Error())
+		return err
+	}
+	token, err := uc.tokens.sign(purposeEmailVerification, tokenClaims{
+		Subject: user.ID,
+		Binding: *user.Email,
+		Expires: time.Now().Add(uc.emailTTL).Unix(),
+	})
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	body := fmt.Sprintf("Hello %s,\n\nPlease confirm your email address", *user.Username)
+	if uc.emailLink != "" {
+		body += " by opening this link:\n\n" + uc.emailLink + token
+	} else {
+		body += " with this token:\n\n" + token
+	}
+	body += fmt.Sprintf("\n\nIt expires in %s. If you did not ask for it, ignore this email.\n", uc.emailTTL)
+	err = uc.mailer.Send(ctx, &Mail{
+		To:      *user.Email,
+		Subject: "Confirm your email address",
+		Body:    body,
+	})
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
+
+// ConfirmEmail marks the email of a token as verified, provided the user
+// still has that email.
+func (uc *VerificationUsecase) ConfirmEmail(ctx context.Context, token string) (*Users, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz ConfirmEmail")
+	defer span.End()
+	claims, err := uc.tokens.verify("users.confirmEmail", purposeEmailVerification, token)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	uid, err := uuid.Parse(claims.Subject)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	res, err := uc.users.MarkEmailVerified(ctx, uid, claims.Binding)
+	if errors.IsNotFound(err) {
+		err = errors.BadRequest("users.confirmEmail", "invalid or expired token")
+	}
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+// SendPhoneVerification texts a user a one-time code proving they own their
+// phone. A new code replaces the previous one, but no sooner than the
+// resend interval.
+func (uc *VerificationUsecase) SendPhoneVerification(ctx context.Context, id string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Biz SendPhoneVerification")
+	defer span.End()
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	user, err := uc.users.FindByID(ctx, uid, ExcludeDeleted)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	if user.Phone == nil || *user.Phone == "" {
+		err := errors.BadRequest("users.sendPhoneVerification", "user has no phone")
+		span.AddEvent(err.Error())
+		return err
+	}
+	if user.PhoneVerifiedAt != nil {
+		err := errors.Conflict("users.sendPhoneVerification", "phone is already verified")
+		span.AddEvent(err.Error())
+		return err
+	}
+	pending, err := uc.repo.FindPhoneCode(ctx, uid)
+	if err != nil && !errors.IsNotFound(err) {
+		span.AddEvent(err.Error())
+		return err
+	}
+	now := time.Now()
+	if pending != nil && pending.Phone == *user.Phone && now.Sub(pending.SentAt) < uc.resendAfter {
+		wait := pending.SentAt.Add(uc.resendAfter).Sub(now).Round(time.Second)
+		err := errors.New(429, "users.sendPhoneVerification", fmt.Sprintf("a code was just sent, retry in %s", wait))
+		span.AddEvent(err.Error())
+		return err
+	}
+	code, err := newNumericCode(phoneCodeDigits)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	err = uc.repo.SavePhoneCode(ctx, &PhoneCode{
+		UserID:    uid,
+		Phone:     *user.Phone,
+		Hash:      uc.phoneCodeDigest(uid, *user.Phone, code),
+		ExpiresAt: now.Add(uc.codeTTL),
+		SentAt:    now,
+	})
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	body := fmt.Sprintf("Your verification code is %s. It expires in %s.", code, uc.codeTTL)
+	if err := uc.sms.Send(ctx, *user.Phone, body); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
+
+// ConfirmPhone checks a code sent by SendPhoneVerification. Every attempt
+// counts, and a code stops working after the maximum number of attempts.
+func (uc *VerificationUsecase) ConfirmPhone(ctx context.Context, id, code string) (*Users, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Biz ConfirmPhone")
+	defer span.End()
+	uid, err := uuid.Parse(id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	pending, err := uc.repo.FindPhoneCode(ctx, uid)
+	if errors.IsNotFound(err) {
+		err = errors.BadRequest("users.confirmPhone", "no pending phone verification")
+	}
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if time.Now().After(pending.ExpiresAt) {
+		err := errors.BadRequest("users.confirmPhone", "code expired, request a new one")
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	// the attempt is counted before the comparison, so that concurrent
+	// guesses cannot exceed the limit
+	ok, err := uc.repo.UsePhoneCodeAttempt(ctx, uid, uc.maxAttempts)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if !ok {
+		err := errors.BadRequest("users.confirmPhone", "too many attempts, request a new code")
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	want := uc.phoneCodeDigest(uid, pending.Phone, normalizeCode(code))
+	if subtle.ConstantTimeCompare([]byte(want), []byte(pending.Hash)) != 1 {
+		err := errors.BadRequest("users.confirmPhone", "invalid code")
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	res, err := uc.users.MarkPhoneVerified(ctx, uid, pending.Phone)
+	if errors.IsNotFound(err) {
+		// the phone changed since the code was sent
+		err = errors.BadRequest("users.confirmPhone", "no pending phone verification")
+	}
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	if err := uc.repo.DeletePhoneCode(ctx, uid); err != nil {
+		uc.log.WithContext(ctx).Warnf("failed deleting phone code of user %s: %v", uid, err)
+	}
+	return res, nil
+}
+
+func (uc *VerificationUsecase) phoneCodeDigest(uid uuid.UUID, phone, code string) string {
+	return uc.tokens.digest(purposePhoneCode, uid.String()+":"+phone+":"+code)
+}
+
+// newNumericCode returns a uniformly random code of n digits.
+func newNumericCode(n int) (string, error) {
+	max := big.NewInt(1)
+	for i := 0; i < n; i++ {
+		max.Mul(max, big.NewInt(10))
+	}
+	v, err := rand.Int(rand.Reader, max)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%0*d", n, v), nil
+}
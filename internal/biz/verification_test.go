@@ -0,0 +1,227 @@
+package biz
+
+import (
+	"context"
+	"fmt"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"sync"
+	"testing"
+	"time"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+type memVerification struct {
+	VerificationRepo
+	mu       sync.Mutex
+	codes    map[uuid.UUID]*PhoneCode
+	attempts map[uuid.UUID]int
+}
+
+func (r *memVerification) SavePhoneCode(_ context.Context, c *PhoneCode) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved := *c
+	r.codes[c.UserID] = &saved
+	r.attempts[c.UserID] = 0
+	return nil
+}
+
+func (r *memVerification) FindPhoneCode(_ context.Context, id uuid.UUID) (*PhoneCode, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	c, ok := r.codes[id]
+	if !ok {
+		return nil, errors.NotFound("users.phoneCode", "no pending phone verification")
+	}
+	found := *c
+	return &found, nil
+}
+
+func (r *memVerification) UsePhoneCodeAttempt(_ context.Context, id uuid.UUID, max int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.attempts[id] >= max {
+		return false, nil
+	}
+	r.attempts[id]++
+	return true, nil
+}
+
+func (r *memVerification) DeletePhoneCode(_ context.Context, id uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.codes, id)
+	return nil
+}
+
+type testVerification struct {
+	*VerificationUsecase
+	users  *memUsers
+	repo   *memVerification
+	mailer *memMailer
+	sms    *memSMS
+}
+
+func newTestVerification(t *testing.T, users ...*Users) *testVerification {
+	t.Helper()
+	tv := &testVerification{
+		users:  newMemUsers(users...),
+		repo:   &memVerification{codes: make(map[uuid.UUID]*PhoneCode), attempts: make(map[uuid.UUID]int)},
+		mailer: newMemMailer(),
+		sms:    newMemSMS(),
+	}
+	c := &conf.Biz{Verification: &conf.Biz_Verification{Secret: "secret", EmailLinkUrl: "https://example.com/?token="}}
+	var err error
+	tv.VerificationUsecase, err = NewVerificationUsecase(tv.users, tv.repo, tv.mailer, tv.sms, c, log.NewStdLogger(testWriter{t}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tv
+}
+
+func TestConfirmEmail(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin"), Email: ptr("erin@example.com")}
+	v := newTestVerification(t, user)
+
+	if err := v.SendEmailVerification(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	mail := v.mailer.next(t)
+	if mail.To != "erin@example.com" {
+		t.Errorf("mailed to %s", mail.To)
+	}
+	token := testToken(t, mail.Body)
+
+	tests := []struct {
+		name, token string
+	}{
+		{"garbage", "garbage"},
+		{"tampered", token[:len(token)-2] + "xx"},
+		{"of another purpose", mustSign(t, v.tokens, purposePhoneCode, tokenClaims{Subject: user.ID, Binding: *user.Email, Expires: time.Now().Add(time.Hour).Unix()})},
+		{"expired", mustSign(t, v.tokens, purposeEmailVerification, tokenClaims{Subject: user.ID, Binding: *user.Email, Expires: time.Now().Add(-time.Second).Unix()})},
+		{"for a former email", mustSign(t, v.tokens, purposeEmailVerification, tokenClaims{Subject: user.ID, Binding: "old@example.com", Expires: time.Now().Add(time.Hour).Unix()})},
+	}
+	for _, tt := range tests {
+		if _, err := v.ConfirmEmail(ctx, tt.token); !errors.IsBadRequest(err) {
+			t.Errorf("%s token err = %v, want BadRequest", tt.name, err)
+		}
+	}
+	if user.EmailVerifiedAt != nil {
+		t.Fatal("email verified by a bad token")
+	}
+
+	res, err := v.ConfirmEmail(ctx, token)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if res.EmailVerifiedAt == nil {
+		t.Error("email not verified")
+	}
+	if err := v.SendEmailVerification(ctx, user.ID); !errors.IsConflict(err) {
+		t.Errorf("verified email err = %v, want Conflict", err)
+	}
+}
+
+func mustSign(t *testing.T, tokens *signedTokens, purpose string, c tokenClaims) string {
+	t.Helper()
+	token, err := tokens.sign(purpose, c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return token
+}
+
+func TestConfirmPhone(t *testing.T) {
+	ctx := context.Background()
+	user := &Users{ID: uuid.NewString(), Username: ptr("erin"), Phone: ptr("+15550100")}
+	nophone := &Users{ID: uuid.NewString(), Username: ptr("frank")}
+	v := newTestVerification(t, user, nophone)
+	uid := uuid.MustParse(user.ID)
+
+	if err := v.SendPhoneVerification(ctx, nophone.ID); !errors.IsBadRequest(err) {
+		t.Errorf("user without phone err = %v, want BadRequest", err)
+	}
+	if _, err := v.ConfirmPhone(ctx, user.ID, "123456"); !errors.IsBadRequest(err) {
+		t.Errorf("nothing pending err = %v, want BadRequest", err)
+	}
+	if err := v.SendPhoneVerification(ctx, user.ID); err != nil {
+		t.Fatal(err)
+	}
+	sms := v.sms.next(t)
+	if sms.To != "+15550100" {
+		t.Errorf("texted %s", sms.To)
+	}
+	code := testCode(t, sms.Body)
+	if err := v.SendPhoneVerification(ctx, user.ID); errors.Code(err) != 429 {
+		t.Errorf("resend right away err = %v, want 429", err)
+	}
+
+	wrong := fmt.Sprintf("%06d", (mustAtoi(t, code)+1)%1000000)
+	if _, err := v.ConfirmPhone(ctx, user.ID, wrong); !errors.IsBadRequest(err) {
+		t.Errorf("wrong code err = %v, want BadRequest", err)
+	}
+	res, err := v.ConfirmPhone(ctx, user.ID, " "+code[:3]+" "+code[3:])
+	if err != nil {
+		t.Fatalf("spaced out code: %v", err)
+	}
+	if res.PhoneVerifiedAt == nil {
+		t.Error("phone not verified")
+	}
+	if _, err := v.repo.FindPhoneCode(ctx, uid); !errors.IsNotFound(err) {
+		t.Error("the code outlived its use")
+	}
+	if err := v.SendPhoneVerification(ctx, user.ID); !errors.IsConflict(err) {
+		t.Errorf("verified phone err = %v, want Conflict", err)
+	}
+}
+
+func TestConfirmPhoneRejections(t *testing.T) {
+	ctx := context.Background()
+	tests := []struct {
+		name string
+		// prepare runs after the code was sent and returns the code tried
+		prepare func(*testVerification, *Users, string) string
+	}{
+		{"too many attempts", func(v *testVerification, u *Users, code string) string {
+			wrong := fmt.Sprintf("%06d", (mustAtoi(t, code)+1)%1000000)
+			for i := 0; i < defaultPhoneCodeMaxAttempts; i++ {
+				_, _ = v.ConfirmPhone(ctx, u.ID, wrong)
+			}
+			return code
+		}},
+		{"expired", func(v *testVerification, u *Users, code string) string {
+			v.repo.codes[uuid.MustParse(u.ID)].ExpiresAt = time.Now().Add(-time.Second)
+			return code
+		}},
+		{"phone changed", func(v *testVerification, u *Users, code string) string {
+			u.Phone = ptr("+15550199")
+			return code
+		}},
+		{"code of another user", func(v *testVerification, u *Users, code string) string {
+			other := &Users{ID: uuid.NewString(), Username: ptr("grace"), Phone: ptr("+15550142")}
+			v.users.users[uuid.MustParse(other.ID)] = other
+			if err := v.SendPhoneVerification(ctx, other.ID); err != nil {
+				t.Fatal(err)
+			}
+			return testCode(t, v.sms.next(t).Body)
+		}},
+	}
+	for _, tt := range tests {
+		user := &Users{ID: uuid.NewString(), Username: ptr("erin"), Phone: ptr("+15550100")}
+		v := newTestVerification(t, user)
+		if err := v.SendPhoneVerification(ctx, user.ID); err != nil {
+			t.Fatal(err)
+		}
+		code := tt.prepare(v, user, testCode(t, v.sms.next(t).Body))
+		if _, err := v.ConfirmPhone(ctx, user.ID, code); !errors.IsBadRequest(err) {
+			t.Errorf("%s: err = %v, want BadRequest", tt.name, err)
+		}
+		if user.PhoneVerifiedAt != nil {
+			t.Errorf("%s: phone verified", tt.name)
+		}
+	}
+}
@@ -0,0 +1,261 @@
+package biz
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Flags of WebAuthn authenticator data.
+const (
+	authDataUserPresent    = 0x01
+	authDataUserVerified   = 0x04
+	authDataBackupEligible = 0x08
+	authDataBackedUp       = 0x10
+	authDataAttested       = 0x40
+)
+
+// COSE algorithms accepted for passkeys, in order of preference.
+const (
+	coseEdDSA = -8
+	coseES256 = -7
+	coseRS256 = -257
+)
+
+var passkeyAlgorithms = []int64{coseEdDSA, coseES256, coseRS256}
+
+// credentialDescriptor names a credential in ceremony options.
+type credentialDescriptor struct {
+	Type       string   `json:"type"`
+	ID         string   `json:"id"`
+	Transports []string `json:"transports,omitempty"`
+}
+
+// credentialParameter offers a credential algorithm in creation options.
+type credentialParameter struct {
+	Type string `json:"type"`
+	Alg  int64  `json:"alg"`
+}
+
+// creationOptions is PublicKeyCredentialCreationOptionsJSON.
+type creationOptions struct {
+	Challenge string `json:"challenge"`
+	RP        struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	} `json:"rp"`
+	User struct {
+		ID          string `json:"id"`
+		Name        string `json:"name"`
+		DisplayName string `json:"displayName"`
+	} `json:"user"`
+	PubKeyCredParams       []credentialParameter  `json:"pubKeyCredParams"`
+	Timeout                int64                  `json:"timeout"`
+	ExcludeCredentials     []credentialDescriptor `json:"excludeCredentials"`
+	AuthenticatorSelection struct {
+		ResidentKey        string `json:"residentKey"`
+		RequireResidentKey bool   `json:"requireResidentKey"`
+		UserVerification   string `json:"userVerification"`
+	} `json:"authenticatorSelection"`
+	Attestation string `json:"attestation"`
+}
+
+// requestOptions is PublicKeyCredentialRequestOptionsJSON.
+type requestOptions struct {
+	Challenge        string                 `json:"challenge"`
+	Timeout          int64                  `json:"timeout"`
+	RPID             string                 `json:"rpId"`
+	AllowCredentials []credentialDescriptor `json:"allowCredentials"`
+	UserVerification string                 `json:"userVerification"`
+}
+
+// publicKeyCredential is the RegistrationResponseJSON or
+// AuthenticationResponseJSON of a ceremony, binary fields base64url-encoded.
+type publicKeyCredential struct {
+	ID       string `json:"id"`
+	RawID    string `json:"rawId"`
+	Type     string `json:"type"`
+	Response struct {
+		ClientDataJSON    string   `json:"clientDataJSON"`
+		AttestationObject string   `json:"attestationObject"`
+		Transports        []string `json:"transports"`
+		AuthenticatorData string   `json:"authenticatorData"`
+		Signature         string   `json:"signature"`
+		UserHandle        string   `json:"userHandle"`
+	} `json:"response"`
+}
+
+// collectedClientData is the part of the client data of a ceremony the
+// relying party checks.
+type collectedClientData struct {
+	Type        string `json:"type"`
+	Challenge   string `json:"challenge"`
+	Origin      string `json:"origin"`
+	CrossOrigin bool   `json:"crossOrigin"`
+}
+
+// authenticatorData is the parsed authenticator data of a ceremony; the
+// attested credential is only set on registration.
+type authenticatorData struct {
+	RPIDHash     []byte
+	Flags        byte
+	SignCount    uint32
+	AAGUID       uuid.UUID
+	CredentialID []byte
+	PublicKey    []byte
+}
+
+// decodeBase64URL accepts base64url with or without padding, as browsers
+// and libraries differ.
+func decodeBase64URL(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(strings.TrimRight(s, "="))
+}
+
+func parseCredential(data string) (*publicKeyCredential, error) {
+	var c publicKeyCredential
+	if err := json.Unmarshal([]byte(data), &c); err != nil {
+		return nil, fmt.Errorf("credential: %w", err)
+	}
+	if c.Type != "public-key" {
+		return nil, fmt.Errorf("credential: unexpected type %q", c.Type)
+	}
+	return &c, nil
+}
+
+func parseClientData(raw []byte) (*collectedClientData, error) {
+	var c collectedClientData
+	if err := json.Unmarshal(raw, &c); err != nil {
+		return nil, fmt.Errorf("client data: %w", err)
+	}
+	return &c, nil
+}
+
+func parseAuthenticatorData(b []byte) (*authenticatorData, error) {
+	if len(b) < 37 {
+		return nil, fmt.Errorf("authenticator data too short")
+	}
+	d := &authenticatorData{
+		RPIDHash:  b[:32],
+		Flags:     b[32],
+		SignCount: binary.BigEndian.Uint32(b[33:37]),
+	}
+	if d.Flags&authDataAttested == 0 {
+		return d, nil
+	}
+	rest := b[37:]
+	if len(rest) < 18 {
+		return nil, fmt.Errorf("attested credential data too short")
+	}
+	copy(d.AAGUID[:], rest[:16])
+	n := int(binary.BigEndian.Uint16(rest[16:18]))
+	rest = rest[18:]
+	if n == 0 || len(rest) < n {
+		return nil, fmt.Errorf("bad credential ID length")
+	}
+	d.CredentialID = rest[:n]
+	rest = rest[n:]
+	_, after, err := decodeCBOR(rest)
+	if err != nil {
+		return nil, fmt.Errorf("credential public key: %w", err)
+	}
+	d.PublicKey = rest[:len(rest)-len(after)]
+	return d, nil
+}
+
+// parseAttestationObject returns the authenticator data of an attestation
+// object. Attestation statements are not verified: passkeys are registered
+// with attestation "none", trusting the authenticator of the logged-in user.
+func parseAttestationObject(b []byte) (*authenticatorData, error) {
+	v, _, err := decodeCBOR(b)
+	if err != nil {
+		return nil, fmt.Errorf("attestation object: %w", err)
+	}
+	m, ok := v.(map[any]any)
+	if !ok {
+		return nil, fmt.Errorf("attestation object is not a map")
+	}
+	raw, ok := m["authData"].([]byte)
+	if !ok {
+		return nil, fmt.Errorf("attestation object has no authenticator data")
+	}
+	return parseAuthenticatorData(raw)
+}
+
+// coseKey is a credential public key in COSE_Key form.
+type coseKey struct {
+	alg int64
+	key crypto.PublicKey
+}
+
+func parseCOSEKey(b []byte) (*coseKey, error) {
+	v, _, err := decodeCBOR(b)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := v.(map[any]any)
+	if !ok {
+		return nil, fmt.Errorf("COSE key is not a map")
+	}
+	kty, _ := m[int64(1)].(int64)
+	alg, _ := m[int64(3)].(int64)
+	crv, _ := m[int64(-1)].(int64)
+	x, _ := m[int64(-2)].([]byte)
+	y, _ := m[int64(-3)].([]byte)
+	switch {
+	case alg == coseEdDSA && kty == 1 && crv == 6:
+		if len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("bad Ed25519 key")
+		}
+		return &coseKey{alg: alg, key: ed25519.PublicKey(x)}, nil
+	case alg == coseES256 && kty == 2 && crv == 1:
+		if len(x) != 32 || len(y) != 32 {
+			return nil, fmt.Errorf("bad P-256 key")
+		}
+		k := &ecdsa.PublicKey{Curve: elliptic.P256(), X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !k.Curve.IsOnCurve(k.X, k.Y) {
+			return nil, fmt.Errorf("P-256 point is not on the curve")
+		}
+		return &coseKey{alg: alg, key: k}, nil
+	case alg == coseRS256 && kty == 3:
+		// for RSA keys, -1 is the modulus and -2 the exponent
+		n, _ := m[int64(-1)].([]byte)
+		if len(n) < 256 || len(x) == 0 || len(x) > 4 {
+			return nil, fmt.Errorf("bad RSA key")
+		}
+		e := int(new(big.Int).SetBytes(x).Int64())
+		if e < 3 || e%2 == 0 {
+			return nil, fmt.Errorf("bad RSA exponent")
+		}
+		return &coseKey{alg: alg, key: &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: e}}, nil
+	}
+	return nil, fmt.Errorf("unsupported COSE key type %d with algorithm %d", kty, alg)
+}
+
+// verify checks an assertion signature over the authenticator data and the
+// hash of the client data.
+func (k *coseKey) verify(authData, clientData, sig []byte) bool {
+	clientHash := sha256.Sum256(clientData)
+	signed := append(append([]byte{}, authData...), clientHash[:]...)
+	switch pub := k.key.(type) {
+	case ed25519.PublicKey:
+		return ed25519.Verify(pub, signed, sig)
+	case *ecdsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		return ecdsa.VerifyASN1(pub, digest[:], sig)
+	case *rsa.PublicKey:
+		digest := sha256.Sum256(signed)
+		return rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig) == nil
+	}
+	return false
+}
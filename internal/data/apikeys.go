@@ -0,0 +1,176 @@
+package data
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"time"
+	"users/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+)
+
+type APIKey struct {
+	ID         uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Name       string    `gorm:"not null"`
+	Prefix     string    `gorm:"not null"`
+	SecretHash string    `gorm:"not null;uniqueIndex"`
+	Scopes     []string  `gorm:"type:jsonb;serializer:json;not null"`
+	CreatedBy  string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	RevokedAt  *time.Time
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+type apiKeysRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+func NewAPIKeysRepo(data *Data, logger log.Logger) biz.APIKeysRepo {
+	return &apiKeysRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+func (r *apiKeysRepo) SaveAPIKey(ctx context.Context, k *biz.APIKey) (*biz.APIKey, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data SaveAPIKey")
+	defer span.End()
+	scopes := make([]string, len(k.Scopes))
+	for i, s := range k.Scopes {
+		scopes[i] = string(s)
+	}
+	key := &APIKey{
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		SecretHash: k.Hash,
+		Scopes:     scopes,
+		CreatedBy:  k.CreatedBy,
+		ExpiresAt:  k.ExpiresAt,
+	}
+	t := r.data.client.WithContext(ctx).Create(key)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	return apiKey(key), nil
+}
+
+func (r *apiKeysRepo) FindAPIKeyByHash(ctx context.Context, hash string) (*biz.APIKey, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data FindAPIKeyByHash")
+	defer span.End()
+	var key APIKey
+	t := r.data.client.WithContext(ctx).Where("secret_hash = ?", hash).Take(&key)
+	if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+		return nil, errors.NotFound("auth.apiKey", "API key not found")
+	}
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	return apiKey(&key), nil
+}
+
+func (r *apiKeysRepo) FindAPIKey(ctx context.Context, id uuid.UUID) (*biz.APIKey, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data FindAPIKey")
+	defer span.End()
+	res, err := r.find(ctx, id)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return res, nil
+}
+
+func (r *apiKeysRepo) ListAPIKeys(ctx context.Context) ([]biz.APIKey, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data ListAPIKeys")
+	defer span.End()
+	var keys []APIKey
+	t := r.data.client.WithContext(ctx).Order("created_at, id").Find(&keys)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	resp := make([]biz.APIKey, len(keys))
+	for i := range keys {
+		resp[i] = *apiKey(&keys[i])
+	}
+	return resp, nil
+}
+
+func (r *apiKeysRepo) RevokeAPIKey(ctx context.Context, id uuid.UUID) (*biz.APIKey, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data RevokeAPIKey")
+	defer span.End()
+	t := r.data.client.WithContext(ctx).Model(&APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Update("revoked_at", time.Now())
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	return r.find(ctx, id)
+}
+
+func (r *apiKeysRepo) RotateAPIKey(ctx context.Context, id uuid.UUID, hash, prefix string) (*biz.APIKey, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data RotateAPIKey")
+	defer span.End()
+	t := r.data.client.WithContext(ctx).Model(&APIKey{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(map[string]interface{}{"secret_hash": hash, "prefix": prefix, "last_used_at": nil})
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	if t.RowsAffected == 0 {
+		return nil, errors.NotFound("auth.apiKey", "API key not found or revoked")
+	}
+	return r.find(ctx, id)
+}
+
+func (r *apiKeysRepo) TouchAPIKey(ctx context.Context, id uuid.UUID, at time.Time) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data TouchAPIKey")
+	defer span.End()
+	// UpdateColumn leaves updated_at alone, it tracks changes to the key
+	t := r.data.client.WithContext(ctx).Model(&APIKey{}).Where("id = ?", id).UpdateColumn("last_used_at", at)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return t.Error
+	}
+	return nil
+}
+
+func (r *apiKeysRepo) find(ctx context.Context, id uuid.UUID) (*biz.APIKey, error) {
+	var key APIKey
+	t := r.data.client.WithContext(ctx).Where("id = ?", id).Take(&key)
+	if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+		return nil, errors.NotFound("auth.apiKey", "API key not found")
+	}
+	if t.Error != nil {
+		return nil, t.Error
+	}
+	return apiKey(&key), nil
+}
+
+func apiKey(k *APIKey) *biz.APIKey {
+	scopes := make([]biz.Permission, len(k.Scopes))
+	for i, s := range k.Scopes {
+		scopes[i] = biz.Permission(s)
+	}
+	return &biz.APIKey{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Hash:       k.SecretHash,
+		Scopes:     scopes,
+		CreatedBy:  k.CreatedBy,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		CreatedAt:  k.CreatedAt,
+		RevokedAt:  k.RevokedAt,
+	}
+}
@@ -0,0 +1,176 @@
+package data
+
+import (
+	"context"
+	"go.opentelemetry.io/otel"
+	"strconv"
+	"sync"
+	"time"
+	"users/internal/biz"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/redis/go-redis/v9"
+)
+
+// Prefixes of the Redis keys of attempt counters and their locks.
+const (
+	attemptsKeyPrefix = "users:attempts:"
+	lockKeyPrefix     = "users:lockout:"
+)
+
+// NewAttemptStore returns an attempt store shared through Redis when one is
+// configured, and one local to the process otherwise.
+func NewAttemptStore(c *conf.Data, logger log.Logger) (biz.AttemptStore, func(), error) {
+	helper := log.NewHelper(logger)
+	rc := c.GetRedis()
+	if rc.GetAddr() == "" {
+		helper.Warn("no redis configured, failed attempts are counted per instance")
+		return &memoryAttemptStore{entries: make(map[string]*attemptEntry)}, func() {}, nil
+	}
+	client := redis.NewClient(&redis.Options{
+		Network:      rc.GetNetwork(),
+		Addr:         rc.GetAddr(),
+		ReadTimeout:  rc.GetReadTimeout().AsDuration(),
+		WriteTimeout: rc.GetWriteTimeout().AsDuration(),
+	})
+	cleanup := func() {
+		if err := client.Close(); err != nil {
+			helper.Errorf("failed closing redis: %v", err)
+		}
+	}
+	return &redisAttemptStore{client: client}, cleanup, nil
+}
+
+type redisAttemptStore struct {
+	client *redis.Client
+}
+
+func (s *redisAttemptStore) Fail(ctx context.Context, key string, ttl time.Duration) (int, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data FailAttempt")
+	defer span.End()
+	pipe := s.client.TxPipeline()
+	n := pipe.Incr(ctx, attemptsKeyPrefix+key)
+	pipe.PExpire(ctx, attemptsKeyPrefix+key, ttl)
+	if _, err := pipe.Exec(ctx); err != nil {
+		span.AddEvent(err.Error())
+		return 0, err
+	}
+	return int(n.Val()), nil
+}
+
+func (s *redisAttemptStore) Lock(ctx context.Context, key string, until time.Time) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data LockAttempts")
+	defer span.End()
+	err := s.client.SetArgs(ctx, lockKeyPrefix+key, until.UnixMilli(), redis.SetArgs{ExpireAt: until}).Err()
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (s *redisAttemptStore) LockedUntil(ctx context.Context, key string) (time.Time, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data LockedUntil")
+	defer span.End()
+	v, err := s.client.Get(ctx, lockKeyPrefix+key).Result()
+	if err == redis.Nil {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		span.AddEvent(err.Error())
+		return time.Time{}, err
+	}
+	ms, err := strconv.ParseInt(v, 10, 64)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return time.Time{}, err
+	}
+	return time.UnixMilli(ms), nil
+}
+
+func (s *redisAttemptStore) Reset(ctx context.Context, keys ...string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data ResetAttempts")
+	defer span.End()
+	names := make([]string, 0, 2*len(keys))
+	for _, key := range keys {
+		names = append(names, attemptsKeyPrefix+key, lockKeyPrefix+key)
+	}
+	if err := s.client.Del(ctx, names...).Err(); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
+
+// attemptEntry is the counter of a key in a memoryAttemptStore.
+type attemptEntry struct {
+	failures    int
+	expiresAt   time.Time
+	lockedUntil time.Time
+}
+
+// memoryAttemptStore keeps counters in the process, for single-instance
+// deployments and development. Expired counters are dropped once a minute
+// as failures come in.
+type memoryAttemptStore struct {
+	mu        sync.Mutex
+	entries   map[string]*attemptEntry
+	lastSweep time.Time
+}
+
+func (s *memoryAttemptStore) Fail(_ context.Context, key string, ttl time.Duration) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	if now.Sub(s.lastSweep) > time.Minute {
+		for k, e := range s.entries {
+			if now.After(e.expiresAt) && now.After(e.lockedUntil) {
+				delete(s.entries, k)
+			}
+		}
+		s.lastSweep = now
+	}
+	e, ok := s.entries[key]
+	if !ok {
+		e = &attemptEntry{}
+		s.entries[key] = e
+	}
+	if now.After(e.expiresAt) {
+		e.failures = 0
+	}
+	e.failures++
+	e.expiresAt = now.Add(ttl)
+	return e.failures, nil
+}
+
+func (s *memoryAttemptStore) Lock(_ context.Context, key string, until time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok {
+		e = &attemptEntry{}
+		s.entries[key] = e
+	}
+	e.lockedUntil = until
+	return nil
+}
+
+func (s *memoryAttemptStore) LockedUntil(_ context.Context, key string) (time.Time, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[key]
+	if !ok || time.Now().After(e.lockedUntil) {
+		return time.Time{}, nil
+	}
+	return e.lockedUntil, nil
+}
+
+func (s *memoryAttemptStore) Reset(_ context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		delete(s.entries, key)
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package data
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"time"
+	"users/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+)
+
+// RefreshToken stores the hash of an issued refresh token.
+type RefreshToken struct {
+	ID        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	FamilyID  uuid.UUID `gorm:"type:uuid;not null;index"`
+	UserID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	User      Users     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+type refreshTokenRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+func NewRefreshTokenRepo(data *Data, logger log.Logger) biz.RefreshTokenRepo {
+	return &refreshTokenRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+func (r *refreshTokenRepo) SaveRefreshToken(ctx context.Context, rt *biz.RefreshToken) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data SaveRefreshToken")
+	defer span.End()
+	t := r.data.client.WithContext(ctx).Omit("User").Create(newRefreshTokenRow(rt))
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return t.Error
+	}
+	return nil
+}
+
+func (r *refreshTokenRepo) FindRefreshToken(ctx context.Context, hash string) (*biz.RefreshToken, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data FindRefreshToken")
+	defer span.End()
+	var row RefreshToken
+	t := r.data.client.WithContext(ctx).Where("token_hash = ?", hash).Take(&row)
+	if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+		return nil, errors.NotFound("auth.refresh", "refresh token not found")
+	}
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	return &biz.RefreshToken{
+		ID:        row.ID,
+		FamilyID:  row.FamilyID,
+		UserID:    row.UserID,
+		Hash:      row.TokenHash,
+		ExpiresAt: row.ExpiresAt,
+		RevokedAt: row.RevokedAt,
+	}, nil
+}
+
+func (r *refreshTokenRepo) RotateRefreshToken(ctx context.Context, old uuid.UUID, next *biz.RefreshToken) (bool, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data RotateRefreshToken")
+	defer span.End()
+	rotated := false
+	err := r.data.client.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// the conditional update serializes concurrent exchanges of the same
+		// token: only one of them sees it unrevoked
+		t := tx.Model(&RefreshToken{}).
+			Where("id = ? AND revoked_at IS NULL", old).
+			Update("revoked_at", time.Now())
+		if t.Error != nil {
+			return t.Error
+		}
+		if t.RowsAffected == 0 {
+			return nil
+		}
+		rotated = true
+		return tx.Omit("User").Create(newRefreshTokenRow(next)).Error
+	})
+	if err != nil {
+		span.AddEvent(err.Error())
+		return false, err
+	}
+	return rotated, nil
+}
+
+func (r *refreshTokenRepo) RevokeRefreshTokenFamily(ctx context.Context, family uuid.UUID) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data RevokeRefreshTokenFamily")
+	defer span.End()
+	now := time.Now()
+	err := r.data.client.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		t := tx.Model(&RefreshToken{}).
+			Where("family_id = ? AND revoked_at IS NULL", family).
+			Update("revoked_at", now)
+		if t.Error != nil {
+			return t.Error
+		}
+		return tx.Model(&Session{}).
+			Where("id = ? AND revoked_at IS NULL", family).
+			Update("revoked_at", now).Error
+	})
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
+
+func newRefreshTokenRow(rt *biz.RefreshToken) *RefreshToken {
+	return &RefreshToken{
+		ID:        rt.ID,
+		FamilyID:  rt.FamilyID,
+		UserID:    rt.UserID,
+		TokenHash: rt.Hash,
+		ExpiresAt: rt.ExpiresAt,
+	}
+}
@@ -0,0 +1,90 @@
+package data
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"time"
+	"users/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Credentials holds the secrets a user authenticates with, one row per user.
+// Rows go away with the user when it is purged.
+type Credentials struct {
+	UserID       uuid.UUID `gorm:"type:uuid;primaryKey"`
+	User         Users     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	PasswordHash string    `gorm:"not null"`
+	CreatedAt    time.Time
+	UpdatedAt    time.Time
+}
+
+type credentialsRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+func NewCredentialsRepo(data *Data, logger log.Logger) biz.CredentialsRepo {
+	return &credentialsRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+func (r *credentialsRepo) FindPasswordHash(ctx context.Context, id uuid.UUID) (string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data FindPasswordHash")
+	defer span.End()
+	var cred Credentials
+	t := r.data.client.WithContext(ctx).Select("password_hash").Where("user_id = ?", id).Take(&cred)
+	if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+		return "", errors.NotFound("users.password", "no password set")
+	}
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return "", t.Error
+	}
+	return cred.PasswordHash, nil
+}
+
+func (r *credentialsRepo) SavePasswordHash(ctx context.Context, id uuid.UUID, hash string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data SavePasswordHash")
+	defer span.End()
+	cred := &Credentials{UserID: id, PasswordHash: hash}
+	t := r.data.client.WithContext(ctx).
+		Omit("User").
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"password_hash", "updated_at"}),
+		}).
+		Create(cred)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return t.Error
+	}
+	return nil
+}
+
+func (r *credentialsRepo) ReplacePasswordHash(ctx context.Context, id uuid.UUID, previous, hash string) (bool, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data ReplacePasswordHash")
+	defer span.End()
+	var t *gorm.DB
+	if previous == "" {
+		t = r.data.client.WithContext(ctx).
+			Omit("User").
+			Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&Credentials{UserID: id, PasswordHash: hash})
+	} else {
+		t = r.data.client.WithContext(ctx).Model(&Credentials{}).
+			Where("user_id = ? AND password_hash = ?", id, previous).
+			Update("password_hash", hash)
+	}
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return false, t.Error
+	}
+	return t.RowsAffected == 1, nil
+}
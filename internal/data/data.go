@@ -2,8 +2,10 @@ package data
 
 import (
 	"context"
+	"fmt"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
+	"regexp"
 	"time"
 	"users/internal/conf"
 
@@ -18,7 +20,7 @@ import (
 	gormlogger "gorm.io/gorm/logger"
 )
 
-var ProviderSet = wire.NewSet(NewData, NewUsersRepo)
+var ProviderSet = wire.NewSet(NewData, NewUsersRepo, NewCredentialsRepo, NewRefreshTokenRepo, NewRolesRepo, NewAPIKeysRepo, NewMFARepo, NewVerificationRepo, NewSessionsRepo, NewOIDCRepo, NewExternalIdentitiesRepo, NewUpstream, NewPasskeysRepo, NewPasswordlessRepo, NewAttemptStore, NewMailer, NewSMSSender)
 
 type Data struct {
 	// TODO wrapped database client
@@ -64,19 +66,39 @@ func NewData(c *conf.Data, logger log.Logger) (*Data, func(), error) {
 	return &Data{client}, cleanup, nil
 }
 
-func Migrate(ctx context.Context, c *conf.Data, logger log.Logger) {
+// Migrate brings the schema up to date. The service must not start when it
+// fails, as lookups rely on the constraints it creates.
+func Migrate(ctx context.Context, c *conf.Data, logger log.Logger) error {
 	_, span := otel.Tracer("data").Start(ctx, "Migrate")
 	defer span.End()
-	log.NewHelper(logger).Info("migrating the schema")
+	helper := log.NewHelper(logger)
+	helper.Info("migrating the schema")
 	client, err := openDB(c, logger)
 	if err != nil {
-		log.NewHelper(logger).Error("failed opening database: %v", err)
+		return fmt.Errorf("opening database: %w", err)
 	}
-	err = client.AutoMigrate(&Users{})
+	err = client.AutoMigrate(&Users{}, &Credentials{}, &RefreshToken{}, &Role{}, &RolePermission{}, &UserRole{}, &APIKey{}, &TOTPCredential{}, &RecoveryCode{}, &PhoneCode{}, &Session{}, &OIDCClient{}, &OIDCConsent{}, &OIDCCode{}, &ExternalIdentity{}, &Passkey{}, &WebAuthnChallenge{}, &PasswordlessLogin{})
 	if err != nil {
-		log.NewHelper(logger).Error("failed migrating the schema: %v", err)
-
+		return fmt.Errorf("migrating the schema: %w", err)
 	}
+	// the statements run together, so that the old indexes are not left
+	// dropped without the ones replacing them
+	return client.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkIdentifiers(tx); err != nil {
+			return err
+		}
+		if n, err := countUnnormalized(tx); err != nil {
+			return fmt.Errorf("checking the identifiers of users: %w", err)
+		} else if n > 0 {
+			helper.Warnf("%d users have identifiers stored before they were normalized, which lookups miss; run once with -normalize-identifiers", n)
+		}
+		for _, stmt := range migrations {
+			if err := tx.Exec(stmt).Error; err != nil {
+				return fmt.Errorf("running migration %q: %w", stmt, err)
+			}
+		}
+		return nil
+	})
 }
 
 type adaptedGormLogger struct {
@@ -113,10 +135,22 @@ func (l *adaptedGormLogger) Error(ctx context.Context, msg string, data ...inter
 	l.logger.Error(args...)
 }
 
+// ParamsFilter drops the query variables before gorm renders statements
+// for Trace: they hold password hashes, token hashes and sealed secrets,
+// which must never reach traces or logs.
+func (l *adaptedGormLogger) ParamsFilter(ctx context.Context, sql string, params ...interface{}) (string, []interface{}) {
+	return sql, nil
+}
+
+// unboundPlaceholder matches the placeholders gorm leaves as "$1$" when
+// rendering statements without their variables.
+var unboundPlaceholder = regexp.MustCompile(`\$(\d+)\$`)
+
 func (l *adaptedGormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
 	_, span := otel.Tracer("gorm").Start(ctx, "Query")
 	defer span.End()
 	sql, rowsAffected := fc()
+	sql = unboundPlaceholder.ReplaceAllString(sql, "$$$1")
 	span.SetAttributes(attribute.String("db.system", "postgresql"))
 	span.SetAttributes(attribute.String("db.statement", sql))
 	span.SetAttributes(attribute.Int64("db.rows_affected", int64(rowsAffected)))
@@ -0,0 +1,132 @@
+package data
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"time"
+	"users/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+)
+
+// ExternalIdentity links an account at an upstream OpenID Connect provider
+// to a user.
+type ExternalIdentity struct {
+	ID          uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;index"`
+	User        Users     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Provider    string    `gorm:"not null"`
+	Issuer      string    `gorm:"not null;uniqueIndex:idx_external_identities_issuer_subject"`
+	Subject     string    `gorm:"not null;uniqueIndex:idx_external_identities_issuer_subject"`
+	Email       string    `gorm:"not null;default:''"`
+	CreatedAt   time.Time
+	LastLoginAt time.Time `gorm:"not null"`
+}
+
+type externalIdentitiesRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+func NewExternalIdentitiesRepo(data *Data, logger log.Logger) biz.ExternalIdentitiesRepo {
+	return &externalIdentitiesRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+func (r *externalIdentitiesRepo) SaveIdentity(ctx context.Context, i *biz.ExternalIdentity) (*biz.ExternalIdentity, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data SaveIdentity")
+	defer span.End()
+	row := &ExternalIdentity{
+		UserID:      i.UserID,
+		Provider:    i.Provider,
+		Issuer:      i.Issuer,
+		Subject:     i.Subject,
+		Email:       i.Email,
+		LastLoginAt: i.LastLoginAt,
+	}
+	t := r.data.client.WithContext(ctx).Omit("User").Create(row)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		var pgErr *pgconn.PgError
+		if errors.As(t.Error, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, errors.Conflict("federation.link", "this account is linked to another user")
+		}
+		return nil, t.Error
+	}
+	return newBizExternalIdentity(row), nil
+}
+
+func (r *externalIdentitiesRepo) FindIdentity(ctx context.Context, issuer, subject string) (*biz.ExternalIdentity, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data FindIdentity")
+	defer span.End()
+	var row ExternalIdentity
+	t := r.data.client.WithContext(ctx).Where("issuer = ? AND subject = ?", issuer, subject).Take(&row)
+	if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+		return nil, errors.NotFound("federation.identity", "identity not found")
+	}
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	return newBizExternalIdentity(&row), nil
+}
+
+func (r *externalIdentitiesRepo) ListIdentities(ctx context.Context, userID uuid.UUID) ([]biz.ExternalIdentity, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data ListIdentities")
+	defer span.End()
+	var rows []ExternalIdentity
+	t := r.data.client.WithContext(ctx).Where("user_id = ?", userID).Order("created_at").Find(&rows)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	res := make([]biz.ExternalIdentity, len(rows))
+	for i := range rows {
+		res[i] = *newBizExternalIdentity(&rows[i])
+	}
+	return res, nil
+}
+
+func (r *externalIdentitiesRepo) DeleteIdentity(ctx context.Context, userID, id uuid.UUID) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data DeleteIdentity")
+	defer span.End()
+	t := r.data.client.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&ExternalIdentity{})
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return t.Error
+	}
+	if t.RowsAffected == 0 {
+		return errors.NotFound("federation.identity", "identity not found")
+	}
+	return nil
+}
+
+func (r *externalIdentitiesRepo) TouchIdentity(ctx context.Context, id uuid.UUID, at time.Time) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data TouchIdentity")
+	defer span.End()
+	t := r.data.client.WithContext(ctx).Model(&ExternalIdentity{}).Where("id = ?", id).Update("last_login_at", at)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return t.Error
+	}
+	return nil
+}
+
+func newBizExternalIdentity(row *ExternalIdentity) *biz.ExternalIdentity {
+	return &biz.ExternalIdentity{
+		ID:          row.ID,
+		UserID:      row.UserID,
+		Provider:    row.Provider,
+		Issuer:      row.Issuer,
+		Subject:     row.Subject,
+		Email:       row.Email,
+		CreatedAt:   row.CreatedAt,
+		LastLoginAt: row.LastLoginAt,
+	}
+}
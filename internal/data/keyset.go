@@ -0,0 +1,207 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"users/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type keyKind int
+
+const (
+	keyText keyKind = iota
+	keyUUID
+	keyTime
+	keyFloat
+)
+
+// sortKind returns the key kind of a field users can be ordered by, as
+// allowed by biz.SortableFields.
+func sortKind(field biz.Field) (keyKind, bool) {
+	if !biz.SortableFields[field] {
+		return 0, false
+	}
+	switch field {
+	case biz.FieldID:
+		return keyUUID, true
+	case biz.FieldCreatedAt, biz.FieldUpdatedAt:
+		return keyTime, true
+	}
+	return keyText, true
+}
+
+// orderKey is one term of a list ordering; expr is a clause.Column or a
+// clause.Expr and is bound as a query variable.
+type orderKey struct {
+	expr interface{}
+	kind keyKind
+	desc bool
+}
+
+// listOrder is the total ordering of a list query. Its last key is always
+// the id, which makes every row's position unique and lets a page resume
+// strictly after the keyset of the previous page's last row.
+type listOrder []orderKey
+
+func userOrder(sp biz.SortParams, qp biz.QueryParams, reverse bool) (listOrder, error) {
+	var order listOrder
+	idKey := orderKey{expr: clause.Column{Name: "id"}, kind: keyUUID}
+	for _, key := range sp {
+		kind, ok := sortKind(key.Field)
+		if !ok {
+			return nil, errors.BadRequest("users.list", fmt.Sprintf("field %q is not sortable", key.Field))
+		}
+		if key.Field == biz.FieldID {
+			// the id is unique, later keys could never break a tie
+			idKey.desc = key.Desc
+			break
+		}
+		order = append(order, orderKey{expr: clause.Column{Name: userColumns[key.Field]}, kind: kind, desc: key.Desc})
+	}
+	if len(sp) == 0 && qp.Search != "" {
+		order = append(order, orderKey{expr: searchRank(qp.Search), kind: keyFloat, desc: true})
+	}
+	order = append(order, idKey)
+	if reverse {
+		for i := range order {
+			order[i].desc = !order[i].desc
+		}
+	}
+	return order, nil
+}
+
+func (o listOrder) orderBy() clause.OrderBy {
+	terms := make([]string, len(o))
+	vars := make([]interface{}, len(o))
+	for i, k := range o {
+		terms[i] = "? ASC"
+		if k.desc {
+			terms[i] = "? DESC"
+		}
+		vars[i] = k.expr
+	}
+	return clause.OrderBy{Expression: clause.Expr{SQL: strings.Join(terms, ", "), Vars: vars, WithoutParentheses: true}}
+}
+
+// after returns the condition selecting the rows that follow ks, expanded
+// as (k1 > v1) OR (k1 = v1 AND k2 > v2) OR ... so mixed directions work.
+func (o listOrder) after(ks biz.Keyset) (clause.Expression, error) {
+	values, err := o.decode(ks)
+	if err != nil {
+		return nil, err
+	}
+	var ors []string
+	var vars []interface{}
+	for i, k := range o {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, "? = ?")
+			vars = append(vars, o[j].expr, values[j])
+		}
+		if k.desc {
+			ands = append(ands, "? < ?")
+		} else {
+			ands = append(ands, "? > ?")
+		}
+		vars = append(vars, k.expr, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return clause.Expr{SQL: "(" + strings.Join(ors, " OR ") + ")", Vars: vars}, nil
+}
+
+// decode restores the typed key values from a keyset that went through a
+// page token.
+func (o listOrder) decode(ks biz.Keyset) ([]interface{}, error) {
+	invalid := errors.BadRequest("users.list", "invalid page_token")
+	if len(ks) != len(o) {
+		return nil, invalid
+	}
+	values := make([]interface{}, len(o))
+	for i, k := range o {
+		switch k.kind {
+		case keyFloat:
+			f, ok := ks[i].(float64)
+			if !ok {
+				return nil, invalid
+			}
+			values[i] = f
+		case keyTime:
+			s, ok := ks[i].(string)
+			if !ok {
+				return nil, invalid
+			}
+			t, err := time.Parse(time.RFC3339Nano, s)
+			if err != nil {
+				return nil, invalid
+			}
+			values[i] = t
+		case keyUUID:
+			s, ok := ks[i].(string)
+			if !ok {
+				return nil, invalid
+			}
+			id, err := uuid.Parse(s)
+			if err != nil {
+				return nil, invalid
+			}
+			values[i] = id
+		default:
+			s, ok := ks[i].(string)
+			if !ok {
+				return nil, invalid
+			}
+			values[i] = s
+		}
+	}
+	return values, nil
+}
+
+// keysetOf reads the key values of the user with the given id. They are
+// queried rather than taken from the loaded row since a sparse fieldset or
+// the search rank may leave them out of it.
+func (o listOrder) keysetOf(ctx context.Context, db *gorm.DB, id uuid.UUID) (biz.Keyset, error) {
+	exprs := make([]string, len(o))
+	vars := make([]interface{}, len(o))
+	dest := make([]interface{}, len(o))
+	for i, k := range o {
+		exprs[i] = "?"
+		vars[i] = k.expr
+		switch k.kind {
+		case keyFloat:
+			dest[i] = new(float64)
+		case keyTime:
+			dest[i] = new(time.Time)
+		case keyUUID:
+			exprs[i] = "?::text"
+			dest[i] = new(string)
+		default:
+			dest[i] = new(string)
+		}
+	}
+	row := db.WithContext(ctx).Model(&Users{}).Unscoped().
+		Select(strings.Join(exprs, ", "), vars...).
+		Where("id = ?", id).
+		Row()
+	if err := row.Scan(dest...); err != nil {
+		return nil, err
+	}
+	ks := make(biz.Keyset, len(dest))
+	for i, d := range dest {
+		switch v := d.(type) {
+		case *float64:
+			ks[i] = *v
+		case *time.Time:
+			ks[i] = v.UTC().Format(time.RFC3339Nano)
+		case *string:
+			ks[i] = *v
+		}
+	}
+	return ks, nil
+}
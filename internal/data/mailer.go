@@ -0,0 +1,109 @@
+package data
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"users/internal/biz"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+)
+
+// NewMailer returns the mailer selected by the configuration.
+func NewMailer(c *conf.Data, logger log.Logger) (biz.Mailer, error) {
+	mc := c.GetMail()
+	from := mc.GetFrom()
+	if from == "" {
+		from = "no-reply@localhost"
+	}
+	switch mc.GetDriver() {
+	case "smtp":
+		if mc.GetSmtp().GetHost() == "" {
+			return nil, errors.InternalServer("data.NewMailer", "missing smtp host")
+		}
+		return &smtpMailer{c: mc.GetSmtp(), from: from}, nil
+	case "", "file":
+		return &fileMailer{dir: mc.GetDir(), from: from, log: log.NewHelper(logger)}, nil
+	default:
+		return nil, errors.InternalServer("data.NewMailer", fmt.Sprintf("unknown mail driver %q", mc.GetDriver()))
+	}
+}
+
+// formatMail renders m as an RFC 5322 message.
+func formatMail(from string, m *biz.Mail) ([]byte, error) {
+	if strings.ContainsAny(m.To+m.Subject, "\r\n") {
+		return nil, errors.BadRequest("data.mail", "line breaks in mail headers")
+	}
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", from)
+	fmt.Fprintf(&b, "To: %s\r\n", m.To)
+	fmt.Fprintf(&b, "Subject: %s\r\n", m.Subject)
+	fmt.Fprintf(&b, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(m.Body, "\n", "\r\n"))
+	return b.Bytes(), nil
+}
+
+// smtpMailer sends mail through an SMTP relay, upgrading to TLS when the
+// relay offers it.
+type smtpMailer struct {
+	c    *conf.Data_Mail_Smtp
+	from string
+}
+
+func (s *smtpMailer) Send(ctx context.Context, m *biz.Mail) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data SendMail")
+	defer span.End()
+	msg, err := formatMail(s.from, m)
+	if err != nil {
+		return err
+	}
+	port := s.c.GetPort()
+	if port == 0 {
+		port = 587
+	}
+	addr := net.JoinHostPort(s.c.GetHost(), strconv.Itoa(int(port)))
+	var auth smtp.Auth
+	if s.c.GetUsername() != "" {
+		auth = smtp.PlainAuth("", s.c.GetUsername(), s.c.GetPassword(), s.c.GetHost())
+	}
+	if err := smtp.SendMail(addr, auth, s.from, []string{m.To}, msg); err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
+
+// fileMailer writes each mail to a .eml file in dir, or logs it when dir is
+// empty. Meant for local development and tests.
+type fileMailer struct {
+	dir  string
+	from string
+	log  *log.Helper
+}
+
+func (f *fileMailer) Send(ctx context.Context, m *biz.Mail) error {
+	msg, err := formatMail(f.from, m)
+	if err != nil {
+		return err
+	}
+	if f.dir == "" {
+		f.log.WithContext(ctx).Infof("mail to %s:\n%s", m.To, msg)
+		return nil
+	}
+	name := fmt.Sprintf("%s-%s.eml", time.Now().UTC().Format("20060102T150405"), uuid.NewString())
+	return os.WriteFile(filepath.Join(f.dir, name), msg, 0o600)
+}
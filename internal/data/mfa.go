@@ -0,0 +1,231 @@
+package data
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"time"
+	"users/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// TOTPCredential holds the sealed TOTP secret of a user.
+type TOTPCredential struct {
+	UserID      uuid.UUID `gorm:"type:uuid;primaryKey"`
+	User        Users     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Secret      []byte    `gorm:"not null"`
+	ConfirmedAt *time.Time
+	// LastStep is the time step of the last accepted code, so that no code
+	// is accepted twice
+	LastStep int64 `gorm:"not null;default:0"`
+	// Attempts counts the verifications tried since AttemptsSince, bounding
+	// guesses per user whatever the instance or client
+	Attempts      int `gorm:"not null;default:0"`
+	AttemptsSince *time.Time
+	CreatedAt     time.Time
+	UpdatedAt     time.Time
+}
+
+type RecoveryCode struct {
+	ID       uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID   uuid.UUID `gorm:"type:uuid;not null;uniqueIndex:idx_recovery_codes_user_hash"`
+	User     Users     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	CodeHash string    `gorm:"not null;uniqueIndex:idx_recovery_codes_user_hash"`
+	UsedAt   *time.Time
+}
+
+type mfaRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+func NewMFARepo(data *Data, logger log.Logger) biz.MFARepo {
+	return &mfaRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+func (r *mfaRepo) FindTOTP(ctx context.Context, id uuid.UUID) (*biz.TOTP, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data FindTOTP")
+	defer span.End()
+	var cred TOTPCredential
+	t := r.data.client.WithContext(ctx).Where("user_id = ?", id).Take(&cred)
+	if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+		return nil, errors.NotFound("users.mfa", "TOTP is not enabled")
+	}
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	return &biz.TOTP{
+		UserID:      cred.UserID,
+		Secret:      cred.Secret,
+		ConfirmedAt: cred.ConfirmedAt,
+		LastStep:    cred.LastStep,
+	}, nil
+}
+
+func (r *mfaRepo) SaveTOTP(ctx context.Context, totp *biz.TOTP) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data SaveTOTP")
+	defer span.End()
+	cred := &TOTPCredential{UserID: totp.UserID, Secret: totp.Secret}
+	t := r.data.client.WithContext(ctx).
+		Omit("User").
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"secret":         totp.Secret,
+				"confirmed_at":   nil,
+				"last_step":      0,
+				"attempts":       0,
+				"attempts_since": nil,
+				"updated_at":     time.Now(),
+			}),
+		}).
+		Create(cred)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return t.Error
+	}
+	return nil
+}
+
+func (r *mfaRepo) ConfirmTOTP(ctx context.Context, id uuid.UUID, step int64, hashes []string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data ConfirmTOTP")
+	defer span.End()
+	err := r.data.client.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		t := tx.Model(&TOTPCredential{}).
+			Where("user_id = ? AND confirmed_at IS NULL", id).
+			Updates(map[string]interface{}{"confirmed_at": time.Now(), "last_step": step})
+		if t.Error != nil {
+			return t.Error
+		}
+		if t.RowsAffected == 0 {
+			return errors.Conflict("users.mfa", "TOTP is already enabled")
+		}
+		return replaceRecoveryCodes(tx, id, hashes)
+	})
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (r *mfaRepo) UseTOTPStep(ctx context.Context, id uuid.UUID, step int64) (bool, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data UseTOTPStep")
+	defer span.End()
+	t := r.data.client.WithContext(ctx).Model(&TOTPCredential{}).
+		Where("user_id = ? AND last_step < ?", id, step).
+		Update("last_step", step)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return false, t.Error
+	}
+	return t.RowsAffected == 1, nil
+}
+
+func (r *mfaRepo) UseTOTPAttempt(ctx context.Context, id uuid.UUID, limit int, window time.Duration) (bool, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data UseTOTPAttempt")
+	defer span.End()
+	// a single conditional update, so that concurrent verifications cannot
+	// both take the last attempt
+	now := time.Now()
+	expired := "attempts_since IS NULL OR attempts_since <= ?"
+	t := r.data.client.WithContext(ctx).Model(&TOTPCredential{}).
+		Where("user_id = ? AND ("+expired+" OR attempts < ?)", id, now.Add(-window), limit).
+		UpdateColumns(map[string]interface{}{
+			"attempts":       gorm.Expr("CASE WHEN "+expired+" THEN 1 ELSE attempts + 1 END", now.Add(-window)),
+			"attempts_since": gorm.Expr("CASE WHEN "+expired+" THEN ? ELSE attempts_since END", now.Add(-window), now),
+		})
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return false, t.Error
+	}
+	return t.RowsAffected == 1, nil
+}
+
+func (r *mfaRepo) ResetTOTPAttempts(ctx context.Context, id uuid.UUID) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data ResetTOTPAttempts")
+	defer span.End()
+	t := r.data.client.WithContext(ctx).Model(&TOTPCredential{}).
+		Where("user_id = ?", id).
+		UpdateColumns(map[string]interface{}{"attempts": 0, "attempts_since": nil})
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return t.Error
+	}
+	return nil
+}
+
+func (r *mfaRepo) UseRecoveryCode(ctx context.Context, id uuid.UUID, hash string) (bool, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data UseRecoveryCode")
+	defer span.End()
+	t := r.data.client.WithContext(ctx).Model(&RecoveryCode{}).
+		Where("user_id = ? AND code_hash = ? AND used_at IS NULL", id, hash).
+		Update("used_at", time.Now())
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return false, t.Error
+	}
+	return t.RowsAffected == 1, nil
+}
+
+func (r *mfaRepo) CountRecoveryCodes(ctx context.Context, id uuid.UUID) (int, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data CountRecoveryCodes")
+	defer span.End()
+	var n int64
+	t := r.data.client.WithContext(ctx).Model(&RecoveryCode{}).
+		Where("user_id = ? AND used_at IS NULL", id).
+		Count(&n)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return 0, t.Error
+	}
+	return int(n), nil
+}
+
+func (r *mfaRepo) ReplaceRecoveryCodes(ctx context.Context, id uuid.UUID, hashes []string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data ReplaceRecoveryCodes")
+	defer span.End()
+	err := r.data.client.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		return replaceRecoveryCodes(tx, id, hashes)
+	})
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (r *mfaRepo) DeleteTOTP(ctx context.Context, id uuid.UUID) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data DeleteTOTP")
+	defer span.End()
+	err := r.data.client.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ?", id).Delete(&RecoveryCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Where("user_id = ?", id).Delete(&TOTPCredential{}).Error
+	})
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
+
+func replaceRecoveryCodes(tx *gorm.DB, id uuid.UUID, hashes []string) error {
+	if err := tx.Where("user_id = ?", id).Delete(&RecoveryCode{}).Error; err != nil {
+		return err
+	}
+	codes := make([]RecoveryCode, len(hashes))
+	for i, h := range hashes {
+		codes[i] = RecoveryCode{UserID: id, CodeHash: h}
+	}
+	return tx.Omit("User").Create(&codes).Error
+}
@@ -0,0 +1,139 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"go.opentelemetry.io/otel"
+	"gorm.io/gorm"
+)
+
+// migrations holds schema changes that AutoMigrate cannot express, such as
+// extensions and specialised indexes. Each statement must be idempotent since
+// the whole list runs on every start after AutoMigrate.
+var migrations = []string{
+	// trigram indexes back the ILIKE matching and ranking of ListUsers search
+	`CREATE EXTENSION IF NOT EXISTS pg_trgm`,
+	`CREATE INDEX IF NOT EXISTS idx_users_username_trgm ON users USING gin (username gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_users_email_trgm ON users USING gin (email gin_trgm_ops)`,
+	`CREATE INDEX IF NOT EXISTS idx_users_phone_trgm ON users USING gin (phone gin_trgm_ops)`,
+	// identifiers are unique among live users only, so they can be reused
+	// once their owner is deleted; these replace the former uniqueIndex tags
+	`DROP INDEX IF EXISTS idx_users_username`,
+	`DROP INDEX IF EXISTS idx_users_email`,
+	`DROP INDEX IF EXISTS idx_users_phone`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_phone_live ON users (phone) WHERE deleted_at IS NULL`,
+	// usernames and emails are unique regardless of case, which also lets
+	// lookups by lower(username) and lower(email) use these indexes
+	`DROP INDEX IF EXISTS idx_users_username_live`,
+	`DROP INDEX IF EXISTS idx_users_email_live`,
+	// rows written before identifiers were normalized must not collide
+	// once they are, see checkIdentifiers and NormalizeIdentifiers
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_username_lower_live ON users (lower(username)) WHERE deleted_at IS NULL`,
+	`CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email_lower_live ON users (lower(email)) WHERE deleted_at IS NULL`,
+	// built-in roles, see biz.RoleAdmin and friends
+	`INSERT INTO roles (name, description) VALUES
+		('admin', 'full access to users and roles'),
+		('support', 'reads users and helps them recover their accounts'),
+		('user', 'self-service access to ones own record')
+		ON CONFLICT (name) DO NOTHING`,
+	`INSERT INTO role_permissions (role_name, permission) VALUES
+		('admin', 'users.create'), ('admin', 'users.get'), ('admin', 'users.list'),
+		('admin', 'users.update'), ('admin', 'users.delete'), ('admin', 'users.restore'),
+		('admin', 'users.purge'), ('admin', 'users.password'), ('admin', 'roles.manage'),
+		('admin', 'apikeys.manage'), ('admin', 'users.mfa.verify'), ('admin', 'users.mfa.reset'),
+		('support', 'users.get'), ('support', 'users.list'), ('support', 'users.restore'),
+		('support', 'users.mfa.reset'),
+		('admin', 'sessions.create'), ('admin', 'sessions.list'), ('admin', 'sessions.revoke'),
+		('admin', 'sessions.validate'),
+		('support', 'sessions.list'), ('support', 'sessions.revoke'),
+		('admin', 'oidc.clients.manage'),
+		('admin', 'users.unlock'), ('support', 'users.unlock')
+		ON CONFLICT DO NOTHING`,
+}
+
+// identifierSpace are the characters biz trims around identifiers.
+const identifierSpace = `E' \t\n\r\v\f'`
+
+// normalizedPhone keeps the digits of a phone and a leading plus sign.
+const normalizedPhone = `CASE WHEN left(btrim(phone, ` + identifierSpace + `), 1) = '+' THEN '+' ELSE '' END ||
+	regexp_replace(phone, '[^0-9]', '', 'g')`
+
+// identifierColumns are the identifiers of users with SQL expressions of
+// their normalized form, as biz.NormalizeUsername, NormalizeEmail and
+// NormalizePhone compute it, and of the key they are unique by.
+var identifierColumns = []struct {
+	column, normalized, key string
+}{
+	{"username", `btrim(username, ` + identifierSpace + `)`, `lower(btrim(username, ` + identifierSpace + `))`},
+	{"email", `lower(btrim(email, ` + identifierSpace + `))`, `lower(btrim(email, ` + identifierSpace + `))`},
+	{"phone", normalizedPhone, normalizedPhone},
+}
+
+// checkIdentifiers fails when live users share an identifier once it is
+// normalized, listing them so that an operator can resolve the conflicts;
+// user data is never rewritten on start.
+func checkIdentifiers(tx *gorm.DB) error {
+	var conflicts []string
+	for _, c := range identifierColumns {
+		var rows []struct {
+			Identifier string
+			IDs        string `gorm:"column:ids"`
+		}
+		err := tx.Raw(`SELECT ` + c.key + ` AS identifier, string_agg(id::text, ', ' ORDER BY id) AS ids
+			FROM users WHERE deleted_at IS NULL AND ` + c.key + ` <> ''
+			GROUP BY 1 HAVING count(*) > 1 ORDER BY 1 LIMIT 20`).Scan(&rows).Error
+		if err != nil {
+			return fmt.Errorf("checking the %ss of users: %w", c.column, err)
+		}
+		for _, r := range rows {
+			conflicts = append(conflicts, fmt.Sprintf("%s %q: %s", c.column, r.Identifier, r.IDs))
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("live users share identifiers that must be unique, change or delete all but one of each and start again: %s", strings.Join(conflicts, "; "))
+	}
+	return nil
+}
+
+// countUnnormalized counts the users whose identifiers were stored before
+// they were normalized, which lookups miss.
+func countUnnormalized(tx *gorm.DB) (int64, error) {
+	conds := make([]string, len(identifierColumns))
+	for i, c := range identifierColumns {
+		conds[i] = c.column + ` <> ` + c.normalized
+	}
+	var n int64
+	err := tx.Table("users").Where(strings.Join(conds, " OR ")).Count(&n).Error
+	return n, err
+}
+
+// NormalizeIdentifiers is a one-off data migration an operator runs with
+// -normalize-identifiers: it stores the identifiers of users written before
+// they were normalized the way biz normalizes them, so that lookups find
+// them. It changes nothing when live users would collide.
+func NormalizeIdentifiers(ctx context.Context, c *conf.Data, logger log.Logger) error {
+	_, span := otel.Tracer("data").Start(ctx, "NormalizeIdentifiers")
+	defer span.End()
+	helper := log.NewHelper(logger)
+	client, err := openDB(c, logger)
+	if err != nil {
+		return fmt.Errorf("opening database: %w", err)
+	}
+	return client.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := checkIdentifiers(tx); err != nil {
+			return err
+		}
+		for _, c := range identifierColumns {
+			res := tx.Exec(`UPDATE users SET ` + c.column + ` = ` + c.normalized + ` WHERE ` + c.column + ` <> ` + c.normalized)
+			if res.Error != nil {
+				return fmt.Errorf("normalizing the %ss of users: %w", c.column, res.Error)
+			}
+			helper.Infof("normalized the %s of %d users", c.column, res.RowsAffected)
+		}
+		return nil
+	})
+}
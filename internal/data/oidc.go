@@ -0,0 +1,234 @@
+package data
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"time"
+	"users/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// OIDCClient is an application allowed to log users in through OpenID
+// Connect.
+type OIDCClient struct {
+	ID           uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	Name         string    `gorm:"not null"`
+	SecretHash   string    `gorm:"not null;default:''"`
+	RedirectURIs []string  `gorm:"type:jsonb;serializer:json;not null"`
+	Trusted      bool      `gorm:"not null;default:false"`
+	CreatedAt    time.Time
+}
+
+func (OIDCClient) TableName() string {
+	return "oidc_clients"
+}
+
+// OIDCConsent holds the scopes a user granted a client.
+type OIDCConsent struct {
+	UserID    uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	User      Users      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	ClientID  uuid.UUID  `gorm:"type:uuid;primaryKey;index"`
+	Client    OIDCClient `gorm:"foreignKey:ClientID;constraint:OnDelete:CASCADE"`
+	Scope     string     `gorm:"not null"`
+	UpdatedAt time.Time
+}
+
+func (OIDCConsent) TableName() string {
+	return "oidc_consents"
+}
+
+// OIDCCode is an authorization code waiting to be exchanged, by hash.
+type OIDCCode struct {
+	Hash          string     `gorm:"primaryKey"`
+	ClientID      uuid.UUID  `gorm:"type:uuid;not null;index"`
+	Client        OIDCClient `gorm:"foreignKey:ClientID;constraint:OnDelete:CASCADE"`
+	UserID        uuid.UUID  `gorm:"type:uuid;not null;index"`
+	User          Users      `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	RedirectURI   string     `gorm:"not null"`
+	Scope         string     `gorm:"not null"`
+	Nonce         string     `gorm:"not null;default:''"`
+	CodeChallenge string     `gorm:"not null"`
+	AuthTime      time.Time  `gorm:"not null"`
+	ExpiresAt     time.Time  `gorm:"not null;index"`
+}
+
+func (OIDCCode) TableName() string {
+	return "oidc_codes"
+}
+
+type oidcRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+func NewOIDCRepo(data *Data, logger log.Logger) biz.OIDCRepo {
+	return &oidcRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+func (r *oidcRepo) SaveClient(ctx context.Context, c *biz.OIDCClient) (*biz.OIDCClient, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data SaveClient")
+	defer span.End()
+	row := &OIDCClient{
+		Name:         c.Name,
+		SecretHash:   c.SecretHash,
+		RedirectURIs: c.RedirectURIs,
+		Trusted:      c.Trusted,
+	}
+	if t := r.data.client.WithContext(ctx).Create(row); t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	return newBizOIDCClient(row), nil
+}
+
+func (r *oidcRepo) FindClient(ctx context.Context, id uuid.UUID) (*biz.OIDCClient, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data FindClient")
+	defer span.End()
+	var row OIDCClient
+	t := r.data.client.WithContext(ctx).Where("id = ?", id).Take(&row)
+	if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+		return nil, errors.NotFound("oidc.client", "client not found")
+	}
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	return newBizOIDCClient(&row), nil
+}
+
+func (r *oidcRepo) ListClients(ctx context.Context) ([]biz.OIDCClient, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data ListClients")
+	defer span.End()
+	var rows []OIDCClient
+	if t := r.data.client.WithContext(ctx).Order("created_at").Find(&rows); t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	res := make([]biz.OIDCClient, len(rows))
+	for i := range rows {
+		res[i] = *newBizOIDCClient(&rows[i])
+	}
+	return res, nil
+}
+
+func (r *oidcRepo) DeleteClient(ctx context.Context, id uuid.UUID) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data DeleteClient")
+	defer span.End()
+	t := r.data.client.WithContext(ctx).Where("id = ?", id).Delete(&OIDCClient{})
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return t.Error
+	}
+	if t.RowsAffected == 0 {
+		return errors.NotFound("oidc.client", "client not found")
+	}
+	return nil
+}
+
+func (r *oidcRepo) FindConsent(ctx context.Context, userID, clientID uuid.UUID) (*biz.Consent, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data FindConsent")
+	defer span.End()
+	var row OIDCConsent
+	t := r.data.client.WithContext(ctx).Where("user_id = ? AND client_id = ?", userID, clientID).Take(&row)
+	if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+		return nil, errors.NotFound("oidc.consent", "consent not found")
+	}
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	return &biz.Consent{UserID: row.UserID, ClientID: row.ClientID, Scope: row.Scope}, nil
+}
+
+func (r *oidcRepo) SaveConsent(ctx context.Context, c *biz.Consent) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data SaveConsent")
+	defer span.End()
+	row := &OIDCConsent{UserID: c.UserID, ClientID: c.ClientID, Scope: c.Scope}
+	t := r.data.client.WithContext(ctx).Omit("User", "Client").
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "client_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"scope", "updated_at"}),
+		}).
+		Create(row)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return t.Error
+	}
+	return nil
+}
+
+func (r *oidcRepo) SaveAuthorizationCode(ctx context.Context, c *biz.AuthorizationCode) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data SaveAuthorizationCode")
+	defer span.End()
+	row := &OIDCCode{
+		Hash:          c.Hash,
+		ClientID:      c.ClientID,
+		UserID:        c.UserID,
+		RedirectURI:   c.RedirectURI,
+		Scope:         c.Scope,
+		Nonce:         c.Nonce,
+		CodeChallenge: c.CodeChallenge,
+		AuthTime:      c.AuthTime,
+		ExpiresAt:     c.ExpiresAt,
+	}
+	err := r.data.client.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// codes are short-lived, expired ones are cleared as new ones come
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&OIDCCode{}).Error; err != nil {
+			return err
+		}
+		return tx.Omit("User", "Client").Create(row).Error
+	})
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (r *oidcRepo) UseAuthorizationCode(ctx context.Context, hash string) (*biz.AuthorizationCode, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data UseAuthorizationCode")
+	defer span.End()
+	var rows []OIDCCode
+	t := r.data.client.WithContext(ctx).
+		Clauses(clause.Returning{}).
+		Where("hash = ?", hash).
+		Delete(&rows)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	if len(rows) == 0 {
+		return nil, errors.NotFound("oidc.code", "authorization code not found")
+	}
+	row := rows[0]
+	return &biz.AuthorizationCode{
+		Hash:          row.Hash,
+		ClientID:      row.ClientID,
+		UserID:        row.UserID,
+		RedirectURI:   row.RedirectURI,
+		Scope:         row.Scope,
+		Nonce:         row.Nonce,
+		CodeChallenge: row.CodeChallenge,
+		AuthTime:      row.AuthTime,
+		ExpiresAt:     row.ExpiresAt,
+	}, nil
+}
+
+func newBizOIDCClient(row *OIDCClient) *biz.OIDCClient {
+	return &biz.OIDCClient{
+		ID:           row.ID,
+		Name:         row.Name,
+		SecretHash:   row.SecretHash,
+		RedirectURIs: row.RedirectURIs,
+		Trusted:      row.Trusted,
+		CreatedAt:    row.CreatedAt,
+	}
+}
@@ -0,0 +1,233 @@
+package data
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"time"
+	"users/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Passkey is a WebAuthn credential of a user.
+type Passkey struct {
+	ID             uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primaryKey"`
+	UserID         uuid.UUID `gorm:"type:uuid;not null;index"`
+	User           Users     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Name           string    `gorm:"not null"`
+	CredentialID   []byte    `gorm:"not null;uniqueIndex"`
+	PublicKey      []byte    `gorm:"not null"`
+	SignCount      int64     `gorm:"not null;default:0"`
+	Transports     []string  `gorm:"type:jsonb;serializer:json;not null"`
+	AAGUID         uuid.UUID `gorm:"column:aaguid;type:uuid;not null"`
+	BackupEligible bool      `gorm:"not null;default:false"`
+	BackedUp       bool      `gorm:"not null;default:false"`
+	CreatedAt      time.Time
+	LastUsedAt     *time.Time
+}
+
+// WebAuthnChallenge is a WebAuthn ceremony waiting to be finished, by hash
+// of its challenge. UserID has no foreign key: logins started for unknown
+// users are bound to a random one.
+type WebAuthnChallenge struct {
+	Hash      string     `gorm:"primaryKey"`
+	Purpose   string     `gorm:"not null"`
+	UserID    *uuid.UUID `gorm:"type:uuid"`
+	ExpiresAt time.Time  `gorm:"not null;index"`
+}
+
+func (WebAuthnChallenge) TableName() string {
+	return "webauthn_challenges"
+}
+
+type passkeysRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+func NewPasskeysRepo(data *Data, logger log.Logger) biz.PasskeysRepo {
+	return &passkeysRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+func (r *passkeysRepo) SavePasskey(ctx context.Context, p *biz.Passkey) (*biz.Passkey, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data SavePasskey")
+	defer span.End()
+	transports := p.Transports
+	if transports == nil {
+		transports = []string{}
+	}
+	row := &Passkey{
+		UserID:         p.UserID,
+		Name:           p.Name,
+		CredentialID:   p.CredentialID,
+		PublicKey:      p.PublicKey,
+		SignCount:      int64(p.SignCount),
+		Transports:     transports,
+		AAGUID:         p.AAGUID,
+		BackupEligible: p.BackupEligible,
+		BackedUp:       p.BackedUp,
+	}
+	t := r.data.client.WithContext(ctx).Omit("User").Create(row)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		var pgErr *pgconn.PgError
+		if errors.As(t.Error, &pgErr) && pgErr.Code == uniqueViolation {
+			return nil, errors.Conflict("users.passkeys", "this passkey is registered already")
+		}
+		return nil, t.Error
+	}
+	return newBizPasskey(row), nil
+}
+
+func (r *passkeysRepo) FindPasskey(ctx context.Context, credentialID []byte) (*biz.Passkey, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data FindPasskey")
+	defer span.End()
+	var row Passkey
+	t := r.data.client.WithContext(ctx).Where("credential_id = ?", credentialID).Take(&row)
+	if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+		return nil, errors.NotFound("users.passkeys", "passkey not found")
+	}
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	return newBizPasskey(&row), nil
+}
+
+func (r *passkeysRepo) ListPasskeys(ctx context.Context, userID uuid.UUID) ([]biz.Passkey, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data ListPasskeys")
+	defer span.End()
+	var rows []Passkey
+	t := r.data.client.WithContext(ctx).Where("user_id = ?", userID).Order("created_at").Find(&rows)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	res := make([]biz.Passkey, len(rows))
+	for i := range rows {
+		res[i] = *newBizPasskey(&rows[i])
+	}
+	return res, nil
+}
+
+func (r *passkeysRepo) RenamePasskey(ctx context.Context, userID, id uuid.UUID, name string) (*biz.Passkey, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data RenamePasskey")
+	defer span.End()
+	t := r.data.client.WithContext(ctx).Model(&Passkey{}).
+		Where("id = ? AND user_id = ?", id, userID).
+		Update("name", name)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	if t.RowsAffected == 0 {
+		return nil, errors.NotFound("users.passkeys", "passkey not found")
+	}
+	var row Passkey
+	if err := r.data.client.WithContext(ctx).Where("id = ?", id).Take(&row).Error; err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return newBizPasskey(&row), nil
+}
+
+func (r *passkeysRepo) DeletePasskey(ctx context.Context, userID, id uuid.UUID) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data DeletePasskey")
+	defer span.End()
+	t := r.data.client.WithContext(ctx).Where("id = ? AND user_id = ?", id, userID).Delete(&Passkey{})
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return t.Error
+	}
+	if t.RowsAffected == 0 {
+		return errors.NotFound("users.passkeys", "passkey not found")
+	}
+	return nil
+}
+
+func (r *passkeysRepo) UsePasskey(ctx context.Context, id uuid.UUID, signCount uint32, backedUp bool, at time.Time) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data UsePasskey")
+	defer span.End()
+	t := r.data.client.WithContext(ctx).Model(&Passkey{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"sign_count":   int64(signCount),
+		"backed_up":    backedUp,
+		"last_used_at": at,
+	})
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return t.Error
+	}
+	return nil
+}
+
+func (r *passkeysRepo) SaveChallenge(ctx context.Context, c *biz.WebAuthnChallenge) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data SaveChallenge")
+	defer span.End()
+	row := &WebAuthnChallenge{
+		Hash:      c.Hash,
+		Purpose:   c.Purpose,
+		UserID:    c.UserID,
+		ExpiresAt: c.ExpiresAt,
+	}
+	err := r.data.client.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// ceremonies are short-lived, expired ones are cleared as new ones
+		// start
+		if err := tx.Where("expires_at < ?", time.Now()).Delete(&WebAuthnChallenge{}).Error; err != nil {
+			return err
+		}
+		return tx.Create(row).Error
+	})
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (r *passkeysRepo) UseChallenge(ctx context.Context, hash string) (*biz.WebAuthnChallenge, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data UseChallenge")
+	defer span.End()
+	var rows []WebAuthnChallenge
+	t := r.data.client.WithContext(ctx).
+		Clauses(clause.Returning{}).
+		Where("hash = ?", hash).
+		Delete(&rows)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	if len(rows) == 0 {
+		return nil, errors.NotFound("users.passkeys", "challenge not found")
+	}
+	return &biz.WebAuthnChallenge{
+		Hash:      rows[0].Hash,
+		Purpose:   rows[0].Purpose,
+		UserID:    rows[0].UserID,
+		ExpiresAt: rows[0].ExpiresAt,
+	}, nil
+}
+
+func newBizPasskey(row *Passkey) *biz.Passkey {
+	return &biz.Passkey{
+		ID:             row.ID,
+		UserID:         row.UserID,
+		Name:           row.Name,
+		CredentialID:   row.CredentialID,
+		PublicKey:      row.PublicKey,
+		SignCount:      uint32(row.SignCount),
+		Transports:     row.Transports,
+		AAGUID:         row.AAGUID,
+		BackupEligible: row.BackupEligible,
+		BackedUp:       row.BackedUp,
+		CreatedAt:      row.CreatedAt,
+		LastUsedAt:     row.LastUsedAt,
+	}
+}
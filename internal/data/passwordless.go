@@ -0,0 +1,141 @@
+package data
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"time"
+	"users/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// passwordlessRetention is how long logins are kept past their expiry, so
+// that they still count against the hourly limit of their user.
+const passwordlessRetention = time.Hour
+
+// PasswordlessLogin is a pending passwordless login, its ID bound into the
+// login token of the device that started it.
+type PasswordlessLogin struct {
+	ID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID      uuid.UUID `gorm:"type:uuid;not null;index"`
+	User        Users     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Channel     string    `gorm:"not null"`
+	Destination string    `gorm:"not null"`
+	Link        bool      `gorm:"not null;default:false"`
+	Hash        string    `gorm:"not null"`
+	Attempts    int       `gorm:"not null;default:0"`
+	ExpiresAt   time.Time `gorm:"not null;index"`
+	CreatedAt   time.Time
+}
+
+type passwordlessRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+func NewPasswordlessRepo(data *Data, logger log.Logger) biz.PasswordlessRepo {
+	return &passwordlessRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+func (r *passwordlessRepo) SavePasswordlessLogin(ctx context.Context, l *biz.PasswordlessLogin) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data SavePasswordlessLogin")
+	defer span.End()
+	row := &PasswordlessLogin{
+		ID:          l.ID,
+		UserID:      l.UserID,
+		Channel:     l.Channel,
+		Destination: l.Destination,
+		Link:        l.Link,
+		Hash:        l.Hash,
+		ExpiresAt:   l.ExpiresAt,
+	}
+	err := r.data.client.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("expires_at < ?", time.Now().Add(-passwordlessRetention)).Delete(&PasswordlessLogin{}).Error; err != nil {
+			return err
+		}
+		return tx.Omit("User").Create(row).Error
+	})
+	if err != nil {
+		span.AddEvent(err.Error())
+		return err
+	}
+	return nil
+}
+
+func (r *passwordlessRepo) CountPasswordlessLogins(ctx context.Context, userID uuid.UUID, since time.Time) (int, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data CountPasswordlessLogins")
+	defer span.End()
+	var n int64
+	t := r.data.client.WithContext(ctx).Model(&PasswordlessLogin{}).
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Count(&n)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return 0, t.Error
+	}
+	return int(n), nil
+}
+
+func (r *passwordlessRepo) FindPasswordlessLogin(ctx context.Context, id uuid.UUID) (*biz.PasswordlessLogin, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data FindPasswordlessLogin")
+	defer span.End()
+	var row PasswordlessLogin
+	t := r.data.client.WithContext(ctx).Where("id = ?", id).Take(&row)
+	if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+		return nil, errors.NotFound("auth.passwordless", "login not found")
+	}
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	return &biz.PasswordlessLogin{
+		ID:          row.ID,
+		UserID:      row.UserID,
+		Channel:     row.Channel,
+		Destination: row.Destination,
+		Link:        row.Link,
+		Hash:        row.Hash,
+		ExpiresAt:   row.ExpiresAt,
+		CreatedAt:   row.CreatedAt,
+	}, nil
+}
+
+func (r *passwordlessRepo) UsePasswordlessAttempt(ctx context.Context, id uuid.UUID, max int) (bool, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data UsePasswordlessAttempt")
+	defer span.End()
+	t := r.data.client.WithContext(ctx).Model(&PasswordlessLogin{}).
+		Where("id = ? AND attempts < ?", id, max).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return false, t.Error
+	}
+	return t.RowsAffected == 1, nil
+}
+
+func (r *passwordlessRepo) ConsumePasswordlessLogins(ctx context.Context, userID, id uuid.UUID) (bool, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data ConsumePasswordlessLogins")
+	defer span.End()
+	var rows []PasswordlessLogin
+	t := r.data.client.WithContext(ctx).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+		Where("user_id = ?", userID).
+		Delete(&rows)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return false, t.Error
+	}
+	for _, row := range rows {
+		if row.ID == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
@@ -0,0 +1,127 @@
+package data
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"time"
+	"users/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm/clause"
+)
+
+// Role is a named set of permissions. The built-in roles are seeded in
+// migrations.
+type Role struct {
+	Name        string `gorm:"primaryKey"`
+	Description string
+}
+
+type RolePermission struct {
+	RoleName   string `gorm:"primaryKey"`
+	Role       Role   `gorm:"foreignKey:RoleName;constraint:OnDelete:CASCADE"`
+	Permission string `gorm:"primaryKey"`
+}
+
+type UserRole struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	User      Users     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	RoleName  string    `gorm:"primaryKey"`
+	Role      Role      `gorm:"foreignKey:RoleName;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time
+}
+
+type rolesRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+func NewRolesRepo(data *Data, logger log.Logger) biz.RolesRepo {
+	return &rolesRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+func (r *rolesRepo) UserRoles(ctx context.Context, id uuid.UUID) ([]string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data UserRoles")
+	defer span.End()
+	roles := []string{}
+	t := r.data.client.WithContext(ctx).Model(&UserRole{}).
+		Where("user_id = ?", id).
+		Order("role_name").
+		Pluck("role_name", &roles)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	return roles, nil
+}
+
+func (r *rolesRepo) UserPermissions(ctx context.Context, id uuid.UUID) ([]biz.Permission, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data UserPermissions")
+	defer span.End()
+	var perms []biz.Permission
+	t := r.data.client.WithContext(ctx).Model(&RolePermission{}).
+		Distinct("role_permissions.permission").
+		Joins("JOIN user_roles ON user_roles.role_name = role_permissions.role_name").
+		Where("user_roles.user_id = ?", id).
+		Pluck("role_permissions.permission", &perms)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	return perms, nil
+}
+
+func (r *rolesRepo) RolePermissions(ctx context.Context, roles []string) ([]biz.Permission, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data RolePermissions")
+	defer span.End()
+	var perms []biz.Permission
+	t := r.data.client.WithContext(ctx).Model(&RolePermission{}).
+		Distinct("permission").
+		Where("role_name IN ?", roles).
+		Pluck("permission", &perms)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	return perms, nil
+}
+
+func (r *rolesRepo) AssignRole(ctx context.Context, id uuid.UUID, role string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data AssignRole")
+	defer span.End()
+	var n int64
+	if t := r.data.client.WithContext(ctx).Model(&Role{}).Where("name = ?", role).Count(&n); t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return t.Error
+	}
+	if n == 0 {
+		return errors.NotFound("users.assignRole", "role not found")
+	}
+	t := r.data.client.WithContext(ctx).
+		Omit("User", "Role").
+		Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&UserRole{UserID: id, RoleName: role})
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return t.Error
+	}
+	return nil
+}
+
+func (r *rolesRepo) RevokeRole(ctx context.Context, id uuid.UUID, role string) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data RevokeRole")
+	defer span.End()
+	t := r.data.client.WithContext(ctx).
+		Where("user_id = ? AND role_name = ?", id, role).
+		Delete(&UserRole{})
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return t.Error
+	}
+	return nil
+}
@@ -0,0 +1,189 @@
+package data
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"time"
+	"users/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Session is a login of a user. Sessions started by Login have the ID of
+// their refresh token family.
+type Session struct {
+	ID         uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;index"`
+	User       Users     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Device     string    `gorm:"not null;default:''"`
+	UserAgent  string    `gorm:"not null;default:''"`
+	IP         string    `gorm:"not null;default:''"`
+	ClientID   string    `gorm:"not null;default:''"`
+	Scope      string    `gorm:"not null;default:''"`
+	CreatedAt  time.Time
+	LastSeenAt time.Time `gorm:"not null"`
+	ExpiresAt  time.Time `gorm:"not null"`
+	RevokedAt  *time.Time
+}
+
+type sessionsRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+func NewSessionsRepo(data *Data, logger log.Logger) biz.SessionsRepo {
+	return &sessionsRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+func (r *sessionsRepo) SaveSession(ctx context.Context, s *biz.Session) (*biz.Session, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data SaveSession")
+	defer span.End()
+	row := &Session{
+		ID:         s.ID,
+		UserID:     s.UserID,
+		Device:     s.Device,
+		UserAgent:  s.UserAgent,
+		IP:         s.IP,
+		ClientID:   s.ClientID,
+		Scope:      s.Scope,
+		CreatedAt:  s.CreatedAt,
+		LastSeenAt: s.LastSeenAt,
+		ExpiresAt:  s.ExpiresAt,
+	}
+	t := r.data.client.WithContext(ctx).Omit("User").Create(row)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	return newBizSession(row), nil
+}
+
+func (r *sessionsRepo) FindSession(ctx context.Context, id uuid.UUID) (*biz.Session, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data FindSession")
+	defer span.End()
+	var row Session
+	t := r.data.client.WithContext(ctx).Where("id = ?", id).Take(&row)
+	if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+		return nil, errors.NotFound("auth.session", "session not found")
+	}
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	return newBizSession(&row), nil
+}
+
+func (r *sessionsRepo) ListSessions(ctx context.Context, userID uuid.UUID) ([]biz.Session, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data ListSessions")
+	defer span.End()
+	var rows []Session
+	t := r.data.client.WithContext(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, time.Now()).
+		Order("last_seen_at DESC").
+		Find(&rows)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	res := make([]biz.Session, len(rows))
+	for i := range rows {
+		res[i] = *newBizSession(&rows[i])
+	}
+	return res, nil
+}
+
+func (r *sessionsRepo) TouchSession(ctx context.Context, id uuid.UUID, seenAt time.Time, expiresAt *time.Time) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data TouchSession")
+	defer span.End()
+	updates := map[string]interface{}{"last_seen_at": seenAt}
+	if expiresAt != nil {
+		updates["expires_at"] = *expiresAt
+	}
+	t := r.data.client.WithContext(ctx).Model(&Session{}).
+		Where("id = ? AND revoked_at IS NULL", id).
+		Updates(updates)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return t.Error
+	}
+	return nil
+}
+
+func (r *sessionsRepo) RevokeSession(ctx context.Context, userID, id uuid.UUID) (*biz.Session, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data RevokeSession")
+	defer span.End()
+	err := r.data.client.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		t := tx.Model(&Session{}).
+			Where("id = ? AND user_id = ? AND revoked_at IS NULL AND expires_at > ?", id, userID, time.Now()).
+			Update("revoked_at", time.Now())
+		if t.Error != nil {
+			return t.Error
+		}
+		if t.RowsAffected == 0 {
+			return errors.NotFound("auth.session", "session not found")
+		}
+		// deleted rather than revoked, so that using them later is not
+		// mistaken for a replay
+		return tx.Where("family_id = ?", id).Delete(&RefreshToken{}).Error
+	})
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return r.FindSession(ctx, id)
+}
+
+func (r *sessionsRepo) RevokeSessions(ctx context.Context, userID uuid.UUID, except *uuid.UUID) ([]uuid.UUID, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data RevokeSessions")
+	defer span.End()
+	var rows []Session
+	err := r.data.client.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		q := tx.Model(&rows).
+			Clauses(clause.Returning{Columns: []clause.Column{{Name: "id"}}}).
+			Where("user_id = ? AND revoked_at IS NULL", userID)
+		if except != nil {
+			q = q.Where("id <> ?", *except)
+		}
+		if err := q.Update("revoked_at", time.Now()).Error; err != nil {
+			return err
+		}
+		// families of tokens issued without a session go too
+		tokens := tx.Where("user_id = ?", userID)
+		if except != nil {
+			tokens = tokens.Where("family_id <> ?", *except)
+		}
+		return tokens.Delete(&RefreshToken{}).Error
+	})
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	ids := make([]uuid.UUID, len(rows))
+	for i := range rows {
+		ids[i] = rows[i].ID
+	}
+	return ids, nil
+}
+
+func newBizSession(row *Session) *biz.Session {
+	return &biz.Session{
+		ID:         row.ID,
+		UserID:     row.UserID,
+		Device:     row.Device,
+		UserAgent:  row.UserAgent,
+		IP:         row.IP,
+		ClientID:   row.ClientID,
+		Scope:      row.Scope,
+		CreatedAt:  row.CreatedAt,
+		LastSeenAt: row.LastSeenAt,
+		ExpiresAt:  row.ExpiresAt,
+		RevokedAt:  row.RevokedAt,
+	}
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"users/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"go.opentelemetry.io/otel"
+)
+
+// maxUpstreamResponse bounds what is read from upstream providers.
+const maxUpstreamResponse = 1 << 20
+
+// NewUpstream returns the HTTP client of upstream OpenID Connect providers.
+func NewUpstream(logger log.Logger) biz.Upstream {
+	return &httpUpstream{
+		client: &http.Client{Timeout: 10 * time.Second},
+		log:    log.NewHelper(logger),
+	}
+}
+
+type httpUpstream struct {
+	client *http.Client
+	log    *log.Helper
+}
+
+func (u *httpUpstream) Discover(ctx context.Context, issuer string) (*biz.UpstreamMetadata, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data Discover")
+	defer span.End()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := u.do(req)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	var meta biz.UpstreamMetadata
+	if err := json.Unmarshal(body, &meta); err != nil {
+		span.AddEvent(err.Error())
+		return nil, fmt.Errorf("malformed discovery document: %w", err)
+	}
+	return &meta, nil
+}
+
+func (u *httpUpstream) FetchJWKS(ctx context.Context, uri string) ([]byte, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data FetchJWKS")
+	defer span.End()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, uri, nil)
+	if err != nil {
+		return nil, err
+	}
+	body, err := u.do(req)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, err
+	}
+	return body, nil
+}
+
+func (u *httpUpstream) ExchangeCode(ctx context.Context, endpoint, clientID, clientSecret string, form url.Values) (string, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data ExchangeCode")
+	defer span.End()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth(url.QueryEscape(clientID), url.QueryEscape(clientSecret))
+	body, err := u.do(req)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return "", err
+	}
+	var res struct {
+		IDToken string `json:"id_token"`
+	}
+	if err := json.Unmarshal(body, &res); err != nil {
+		span.AddEvent(err.Error())
+		return "", fmt.Errorf("malformed token response: %w", err)
+	}
+	if res.IDToken == "" {
+		return "", fmt.Errorf("token response has no ID token")
+	}
+	return res.IDToken, nil
+}
+
+func (u *httpUpstream) do(req *http.Request) ([]byte, error) {
+	req.Header.Set("Accept", "application/json")
+	resp, err := u.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxUpstreamResponse))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s %s: %s: %s", req.Method, req.URL.Redacted(), resp.Status, strings.TrimSpace(string(body)))
+	}
+	return body, nil
+}
@@ -2,22 +2,35 @@ package data
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
 	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
 	"go.uber.org/zap"
+	"strings"
+	"time"
 	"users/internal/biz"
 
+	"github.com/go-kratos/kratos/v2/errors"
 	"github.com/go-kratos/kratos/v2/log"
+	"github.com/jackc/pgx/v5/pgconn"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type Users struct {
 	gorm.Model
-	ID       uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primary_key"`
-	Username string    `gorm:"not null;uniqueIndex"`
-	Email    string    `gorm:"not null;uniqueIndex"`
-	Phone    *string   `gorm:"not null;uniqueIndex"`
+	ID uuid.UUID `gorm:"type:uuid;default:gen_random_uuid();primary_key"`
+	// username, email and phone are unique among live users only, see the
+	// partial indexes in migrations
+	Username string  `gorm:"not null"`
+	Email    string  `gorm:"not null"`
+	Phone    *string `gorm:"not null"`
 	Avatar   *string
+	// EmailVerifiedAt and PhoneVerifiedAt are cleared whenever Email and
+	// Phone change
+	EmailVerifiedAt *time.Time
+	PhoneVerifiedAt *time.Time
 }
 
 type usersRepo struct {
@@ -44,17 +57,19 @@ func (r *usersRepo) Save(ctx context.Context, u *biz.Users) (*biz.Users, error)
 	t := r.data.client.Save(user)
 
 	if t.Error != nil {
-		return nil, t.Error
+		return nil, conflictError("users.create", t.Error)
 	}
 	resp := &biz.Users{
-		ID:        user.ID.String(),
-		Username:  &user.Username,
-		Email:     &user.Email,
-		Phone:     user.Phone,
-		Avatar:    user.Avatar,
-		CreatedAt: &user.CreatedAt,
-		UpdatedAt: &user.UpdatedAt,
-		DeletedAt: &user.DeletedAt.Time,
+		ID:              user.ID.String(),
+		Username:        &user.Username,
+		Email:           &user.Email,
+		Phone:           user.Phone,
+		Avatar:          user.Avatar,
+		CreatedAt:       &user.CreatedAt,
+		UpdatedAt:       &user.UpdatedAt,
+		DeletedAt:       deletedAt(user),
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		PhoneVerifiedAt: user.PhoneVerifiedAt,
 	}
 	return resp, nil
 }
@@ -76,84 +91,388 @@ func (r *usersRepo) Update(ctx context.Context, u *biz.Users) (*biz.Users, error
 		Phone:    u.Phone,
 		Avatar:   u.Avatar,
 	}
-	t := r.data.client.Model(&Users{}).Where("id = ?", uid).Updates(user)
-	if t.Error != nil {
-		return nil, t.Error
+	err = r.data.client.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		// a new email or phone is unverified, whoever verified the old one
+		t := tx.Model(&Users{}).
+			Where("id = ? AND lower(email) <> lower(?)", uid, user.Email).
+			Update("email_verified_at", nil)
+		if t.Error != nil {
+			return t.Error
+		}
+		if user.Phone != nil {
+			t = tx.Model(&Users{}).
+				Where("id = ? AND phone IS DISTINCT FROM ?", uid, *user.Phone).
+				Update("phone_verified_at", nil)
+			if t.Error != nil {
+				return t.Error
+			}
+		}
+		return tx.Model(&Users{}).Where("id = ?", uid).Updates(user).Error
+	})
+	if err != nil {
+		return nil, conflictError("users.update", err)
 	}
 
 	resp := &biz.Users{
-		ID:        user.ID.String(),
-		Username:  &user.Username,
-		Email:     &user.Email,
-		Phone:     user.Phone,
-		Avatar:    user.Avatar,
-		CreatedAt: &user.CreatedAt,
-		UpdatedAt: &user.UpdatedAt,
-		DeletedAt: &user.DeletedAt.Time,
+		ID:              user.ID.String(),
+		Username:        &user.Username,
+		Email:           &user.Email,
+		Phone:           user.Phone,
+		Avatar:          user.Avatar,
+		CreatedAt:       &user.CreatedAt,
+		UpdatedAt:       &user.UpdatedAt,
+		DeletedAt:       deletedAt(user),
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		PhoneVerifiedAt: user.PhoneVerifiedAt,
 	}
 	return resp, nil
 }
 
-func (r *usersRepo) FindByID(ctx context.Context, id uuid.UUID) (*biz.Users, error) {
+func (r *usersRepo) FindByID(ctx context.Context, id uuid.UUID, deleted biz.DeletedScope) (*biz.Users, error) {
 	_, span := otel.Tracer("users").Start(ctx, "Data FindByID")
 	defer span.End()
 	user := &Users{
 		ID: id,
 	}
-	t := r.data.client.First(&user)
+	t := scopeDeleted(r.data.client, deleted).First(&user)
+	if t.Error != nil {
+		return nil, t.Error
+	}
+	resp := &biz.Users{
+		ID:              user.ID.String(),
+		Username:        &user.Username,
+		Email:           &user.Email,
+		Phone:           user.Phone,
+		Avatar:          user.Avatar,
+		DeletedAt:       deletedAt(user),
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		PhoneVerifiedAt: user.PhoneVerifiedAt,
+	}
+	return resp, nil
+}
+
+func (r *usersRepo) FindByKey(ctx context.Context, key biz.LookupKey) (*biz.Users, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data FindByKey")
+	defer span.End()
+	q := r.data.client.WithContext(ctx)
+	// the lower() comparisons match the expressions of the unique indexes
+	switch key.Field {
+	case biz.FieldUsername:
+		q = q.Where("lower(username) = lower(?)", key.Value)
+	case biz.FieldEmail:
+		q = q.Where("lower(email) = lower(?)", key.Value)
+	case biz.FieldPhone:
+		q = q.Where("phone = ?", key.Value)
+	default:
+		return nil, errors.BadRequest("users.lookup", fmt.Sprintf("field %q is not a lookup key", key.Field))
+	}
+	var user Users
+	t := q.Take(&user)
+	if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+		return nil, errors.NotFound("users.lookup", "user not found")
+	}
 	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
 		return nil, t.Error
 	}
 	resp := &biz.Users{
-		ID:       user.ID.String(),
-		Username: &user.Username,
-		Email:    &user.Email,
-		Phone:    user.Phone,
-		Avatar:   user.Avatar,
+		ID:              user.ID.String(),
+		Username:        &user.Username,
+		Email:           &user.Email,
+		Phone:           user.Phone,
+		Avatar:          user.Avatar,
+		CreatedAt:       &user.CreatedAt,
+		UpdatedAt:       &user.UpdatedAt,
+		DeletedAt:       deletedAt(&user),
+		EmailVerifiedAt: user.EmailVerifiedAt,
+		PhoneVerifiedAt: user.PhoneVerifiedAt,
 	}
 	return resp, nil
 }
 
-func (r *usersRepo) ListAll(ctx context.Context, pp biz.PaginationParams, sp biz.SortParams) ([]biz.Users, error) {
+func (r *usersRepo) MarkEmailVerified(ctx context.Context, id uuid.UUID, email string) (*biz.Users, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data MarkEmailVerified")
+	defer span.End()
+	// matching the email makes tokens issued for a previous address useless,
+	// and COALESCE keeps the first verification time on repeated confirms
+	t := r.data.client.WithContext(ctx).Model(&Users{}).
+		Where("id = ? AND lower(email) = lower(?)", id, email).
+		Update("email_verified_at", gorm.Expr("COALESCE(email_verified_at, ?)", time.Now()))
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	if t.RowsAffected == 0 {
+		return nil, errors.NotFound("users.confirmEmail", "no user with this email")
+	}
+	return r.FindByID(ctx, id, biz.ExcludeDeleted)
+}
+
+func (r *usersRepo) MarkPhoneVerified(ctx context.Context, id uuid.UUID, phone string) (*biz.Users, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data MarkPhoneVerified")
+	defer span.End()
+	t := r.data.client.WithContext(ctx).Model(&Users{}).
+		Where("id = ? AND phone = ?", id, phone).
+		Update("phone_verified_at", gorm.Expr("COALESCE(phone_verified_at, ?)", time.Now()))
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	if t.RowsAffected == 0 {
+		return nil, errors.NotFound("users.confirmPhone", "no user with this phone")
+	}
+	return r.FindByID(ctx, id, biz.ExcludeDeleted)
+}
+
+func (r *usersRepo) FindByIDs(ctx context.Context, ids []uuid.UUID) ([]biz.Users, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data FindByIDs")
+	defer span.End()
+	if len(ids) == 0 {
+		return nil, nil
+	}
+	var usersList []Users
+	t := r.data.client.WithContext(ctx).Where("id IN ?", ids).Find(&usersList)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	result := make([]biz.Users, 0, len(usersList))
+	for _, user := range usersList {
+		result = append(result, biz.Users{
+			ID:              user.ID.String(),
+			Username:        &user.Username,
+			Email:           &user.Email,
+			Phone:           user.Phone,
+			Avatar:          user.Avatar,
+			CreatedAt:       &user.CreatedAt,
+			UpdatedAt:       &user.UpdatedAt,
+			DeletedAt:       deletedAt(&user),
+			EmailVerifiedAt: user.EmailVerifiedAt,
+			PhoneVerifiedAt: user.PhoneVerifiedAt,
+		})
+	}
+	return result, nil
+}
+
+func (r *usersRepo) ListAll(ctx context.Context, pp biz.PaginationParams, sp biz.SortParams, qp biz.QueryParams) ([]biz.Users, biz.Keyset, error) {
 	_, span := otel.Tracer("users").Start(ctx, "Data ListAll")
 	defer span.End()
-	offset := pp.PageSize * pp.Page
+	order, err := userOrder(sp, qp, pp.Reverse)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, nil, err
+	}
 
 	var usersList []Users
-	q := r.data.client.Offset(offset).Limit(pp.PageSize)
-	if sp.SortBy != "" {
-		sortOrder := "asc"
-		if sp.SortOrder != "asc" && sp.SortOrder != "desc" {
-			sortOrder = "asc"
-		} else {
-			sortOrder = sp.SortOrder
+	q, err := r.listQuery(qp)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, nil, err
+	}
+	// the extra row tells whether another page follows this one
+	q = q.Limit(pp.PageSize + 1)
+	if pp.After != nil {
+		after, err := order.after(pp.After)
+		if err != nil {
+			span.AddEvent(err.Error())
+			return nil, nil, err
 		}
-		q = q.Order(sp.SortBy + " " + sortOrder)
+		q = q.Where(after)
+	} else {
+		q = q.Offset(pp.PageSize * pp.Page)
 	}
-	q = q.Find(&usersList)
+	if len(qp.Fields) > 0 {
+		columns, err := selectColumns(qp.Fields)
+		if err != nil {
+			span.AddEvent(err.Error())
+			return nil, nil, err
+		}
+		q = q.Select(columns)
+	}
+	q = q.Clauses(order.orderBy()).Find(&usersList)
 
 	if q.Error != nil {
-		return nil, q.Error
+		return nil, nil, q.Error
+	}
+
+	var next biz.Keyset
+	if len(usersList) > pp.PageSize {
+		usersList = usersList[:pp.PageSize]
+		next, err = order.keysetOf(ctx, r.data.client, usersList[len(usersList)-1].ID)
+		if err != nil {
+			span.AddEvent(err.Error())
+			return nil, nil, err
+		}
 	}
 
 	var result []biz.Users
 	for _, user := range usersList {
-		result = append(result, biz.Users{
-			ID:        user.ID.String(),
-			Username:  &user.Username,
-			Email:     &user.Email,
-			Phone:     user.Phone,
-			Avatar:    user.Avatar,
-			CreatedAt: &user.CreatedAt,
-			UpdatedAt: &user.UpdatedAt,
-			DeletedAt: &user.DeletedAt.Time,
-		})
+		u := biz.Users{
+			ID:              user.ID.String(),
+			Username:        &user.Username,
+			Email:           &user.Email,
+			Phone:           user.Phone,
+			Avatar:          user.Avatar,
+			CreatedAt:       &user.CreatedAt,
+			UpdatedAt:       &user.UpdatedAt,
+			DeletedAt:       deletedAt(&user),
+			EmailVerifiedAt: user.EmailVerifiedAt,
+			PhoneVerifiedAt: user.PhoneVerifiedAt,
+		}
+		if len(qp.Fields) > 0 {
+			u = projectUser(u, qp.Fields)
+		}
+		result = append(result, u)
 	}
 
-	res := make([]biz.Users, len(result))
-	copy(res, result)
+	return result, next, nil
+}
 
-	return result, nil
+// listQuery selects the users matching qp, the row set shared by ListAll
+// and the counts.
+func (r *usersRepo) listQuery(qp biz.QueryParams) (*gorm.DB, error) {
+	q := scopeDeleted(r.data.client.Model(&Users{}), qp.Deleted)
+	if qp.Search != "" {
+		q = searchUsers(q, qp.Search)
+	}
+	return filterUsers(q, qp.Filters)
+}
+
+// scopeDeleted applies the requested handling of soft-deleted users to q.
+func scopeDeleted(q *gorm.DB, scope biz.DeletedScope) *gorm.DB {
+	switch scope {
+	case biz.IncludeDeleted:
+		return q.Unscoped()
+	case biz.OnlyDeleted:
+		return q.Unscoped().Where("deleted_at IS NOT NULL")
+	}
+	return q
+}
+
+// deletedAt reports when u was soft-deleted, nil for a live user.
+func deletedAt(u *Users) *time.Time {
+	if !u.DeletedAt.Valid {
+		return nil
+	}
+	return &u.DeletedAt.Time
+}
+
+// searchUsers restricts q to users whose username, email or phone contains
+// term, case-insensitively. The ILIKE predicates are served by the trigram
+// indexes created in migrations.
+func searchUsers(q *gorm.DB, term string) *gorm.DB {
+	pattern := "%" + escapeLike(term) + "%"
+	return q.Where("(username ILIKE ? OR email ILIKE ? OR phone ILIKE ?)", pattern, pattern, pattern)
+}
+
+// searchRank scores a row by the best trigram word similarity of term to its
+// username, email or phone; search results are ordered by it by default.
+func searchRank(term string) clause.Expr {
+	return clause.Expr{
+		SQL:  "GREATEST(word_similarity(?, username), word_similarity(?, email), word_similarity(?, COALESCE(phone, '')))",
+		Vars: []interface{}{term, term, term},
+	}
+}
+
+// userColumns is the allow-list of columns list queries may reference.
+var userColumns = map[biz.Field]string{
+	biz.FieldID:              "id",
+	biz.FieldUsername:        "username",
+	biz.FieldEmail:           "email",
+	biz.FieldPhone:           "phone",
+	biz.FieldAvatar:          "avatar",
+	biz.FieldCreatedAt:       "created_at",
+	biz.FieldUpdatedAt:       "updated_at",
+	biz.FieldDeletedAt:       "deleted_at",
+	biz.FieldEmailVerifiedAt: "email_verified_at",
+	biz.FieldPhoneVerifiedAt: "phone_verified_at",
+}
+
+// selectColumns maps the requested fields to the columns to SELECT.
+func selectColumns(fields []biz.Field) ([]string, error) {
+	columns := make([]string, 0, len(fields))
+	for _, f := range fields {
+		col, ok := userColumns[f]
+		if !ok {
+			return nil, errors.BadRequest("users.list", fmt.Sprintf("field %q is not selectable", f))
+		}
+		columns = append(columns, col)
+	}
+	return columns, nil
+}
+
+// projectUser keeps only the requested fields of u, so columns that were
+// not loaded are reported as absent rather than as zero values.
+func projectUser(u biz.Users, fields []biz.Field) biz.Users {
+	p := biz.Users{ID: u.ID}
+	for _, f := range fields {
+		switch f {
+		case biz.FieldUsername:
+			p.Username = u.Username
+		case biz.FieldEmail:
+			p.Email = u.Email
+		case biz.FieldPhone:
+			p.Phone = u.Phone
+		case biz.FieldAvatar:
+			p.Avatar = u.Avatar
+		case biz.FieldCreatedAt:
+			p.CreatedAt = u.CreatedAt
+		case biz.FieldUpdatedAt:
+			p.UpdatedAt = u.UpdatedAt
+		case biz.FieldDeletedAt:
+			p.DeletedAt = u.DeletedAt
+		case biz.FieldEmailVerifiedAt:
+			p.EmailVerifiedAt = u.EmailVerifiedAt
+		case biz.FieldPhoneVerifiedAt:
+			p.PhoneVerifiedAt = u.PhoneVerifiedAt
+		}
+	}
+	return p
+}
+
+// filterUsers adds a parameterized WHERE clause to q for each filter.
+func filterUsers(q *gorm.DB, filters []biz.Filter) (*gorm.DB, error) {
+	for _, f := range filters {
+		col, ok := userColumns[f.Field]
+		if !ok {
+			return nil, errors.BadRequest("users.list", fmt.Sprintf("field %q is not filterable", f.Field))
+		}
+		column := clause.Column{Name: col}
+		switch f.Op {
+		case biz.FilterEq:
+			q = q.Where(clause.Eq{Column: column, Value: f.Value})
+		case biz.FilterNeq:
+			q = q.Where(clause.Neq{Column: column, Value: f.Value})
+		case biz.FilterGt:
+			q = q.Where(clause.Gt{Column: column, Value: f.Value})
+		case biz.FilterGte:
+			q = q.Where(clause.Gte{Column: column, Value: f.Value})
+		case biz.FilterLt:
+			q = q.Where(clause.Lt{Column: column, Value: f.Value})
+		case biz.FilterLte:
+			q = q.Where(clause.Lte{Column: column, Value: f.Value})
+		case biz.FilterContains:
+			q = q.Where("? ILIKE ?", column, "%"+escapeLike(fmt.Sprint(f.Value))+"%")
+		case biz.FilterStartsWith:
+			q = q.Where("? ILIKE ?", column, escapeLike(fmt.Sprint(f.Value))+"%")
+		case biz.FilterEndsWith:
+			q = q.Where("? ILIKE ?", column, "%"+escapeLike(fmt.Sprint(f.Value)))
+		case biz.FilterIsNull:
+			if isNull, _ := f.Value.(bool); isNull {
+				q = q.Where(clause.Eq{Column: column, Value: nil})
+			} else {
+				q = q.Where(clause.Neq{Column: column, Value: nil})
+			}
+		default:
+			return nil, errors.BadRequest("users.list", fmt.Sprintf("operator %q is not supported", f.Op))
+		}
+	}
+	return q, nil
+}
+
+// escapeLike escapes the LIKE wildcards in s so it is matched literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
 }
 
 func (r *usersRepo) Delete(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
@@ -166,14 +485,157 @@ func (r *usersRepo) Delete(ctx context.Context, id uuid.UUID) (uuid.UUID, error)
 	return id, nil
 }
 
-func (r *usersRepo) Count(ctx context.Context) (int, error) {
+func (r *usersRepo) Restore(ctx context.Context, id uuid.UUID) (*biz.Users, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data Restore")
+	defer span.End()
+	err := r.data.client.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var user Users
+		t := tx.Unscoped().Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND deleted_at IS NOT NULL", id).
+			Take(&user)
+		if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+			return errors.NotFound("users.restore", "deleted user not found")
+		}
+		if t.Error != nil {
+			return t.Error
+		}
+		if err := checkIdentifiersFree(tx, &user); err != nil {
+			return err
+		}
+		return tx.Unscoped().Model(&Users{}).Where("id = ?", id).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		span.AddEvent(err.Error())
+		return nil, conflictError("users.restore", err)
+	}
+	return r.FindByID(ctx, id, biz.ExcludeDeleted)
+}
+
+// checkIdentifiersFree reports a Conflict when a live user other than u
+// holds u's username, email or phone, as happens when they were reused
+// while u was deleted.
+func checkIdentifiersFree(tx *gorm.DB, u *Users) error {
+	var taken []Users
+	t := tx.Select("username", "email", "phone").
+		Where("id <> ? AND (lower(username) = lower(?) OR lower(email) = lower(?) OR phone = ?)", u.ID, u.Username, u.Email, u.Phone).
+		Find(&taken)
+	if t.Error != nil {
+		return t.Error
+	}
+	for _, other := range taken {
+		switch {
+		case strings.EqualFold(other.Username, u.Username):
+			return errors.Conflict("users.restore", "username is in use by another user")
+		case strings.EqualFold(other.Email, u.Email):
+			return errors.Conflict("users.restore", "email is in use by another user")
+		default:
+			return errors.Conflict("users.restore", "phone is in use by another user")
+		}
+	}
+	return nil
+}
+
+// uniqueViolation is the Postgres SQLSTATE of unique constraint failures.
+const uniqueViolation = "23505"
+
+// conflictError turns a unique violation raised by Postgres into a Conflict
+// error and returns any other error unchanged.
+func conflictError(reason string, err error) error {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == uniqueViolation {
+		return errors.Conflict(reason, "username, email or phone is in use by another user")
+	}
+	return err
+}
+
+func (r *usersRepo) Purge(ctx context.Context, id uuid.UUID) (uuid.UUID, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data Purge")
+	defer span.End()
+	t := r.data.client.WithContext(ctx).Unscoped().Where("id = ?", id).Delete(&Users{})
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return id, t.Error
+	}
+	if t.RowsAffected == 0 {
+		return id, errors.NotFound("users.purge", "user not found")
+	}
+	return id, nil
+}
+
+func (r *usersRepo) PurgeDeleted(ctx context.Context, before time.Time) (int, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data PurgeDeleted")
+	defer span.End()
+	t := r.data.client.WithContext(ctx).Unscoped().Where("deleted_at < ?", before).Delete(&Users{})
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return 0, t.Error
+	}
+	return int(t.RowsAffected), nil
+}
+
+func (r *usersRepo) Count(ctx context.Context, qp biz.QueryParams) (int, error) {
 	_, span := otel.Tracer("users").Start(ctx, "Data Count")
 	defer span.End()
 	var count int64
 
-	t := r.data.client.Model(&Users{}).Count(&count)
+	q, err := r.listQuery(qp)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return 0, err
+	}
+	t := q.WithContext(ctx).Count(&count)
 	if t.Error != nil {
 		return 0, t.Error
 	}
 	return int(count), nil
 }
+
+// EstimateCount reads the row estimate of the planner. Without conditions
+// that is the table's reltuples statistic, otherwise the row estimate of the
+// plan for the list query. It falls back to an exact count while the table
+// has never been analyzed. reltuples counts soft-deleted rows too, so it is
+// only used when those are included.
+func (r *usersRepo) EstimateCount(ctx context.Context, qp biz.QueryParams) (int, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data EstimateCount")
+	defer span.End()
+
+	if qp.Search == "" && len(qp.Filters) == 0 && qp.Deleted == biz.IncludeDeleted {
+		var reltuples float64
+		t := r.data.client.WithContext(ctx).
+			Raw("SELECT reltuples FROM pg_class WHERE oid = 'users'::regclass").
+			Scan(&reltuples)
+		if t.Error != nil {
+			return 0, t.Error
+		}
+		if reltuples < 0 {
+			return r.Count(ctx, qp)
+		}
+		return int(reltuples), nil
+	}
+
+	q, err := r.listQuery(qp)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return 0, err
+	}
+	stmt := q.Session(&gorm.Session{DryRun: true}).Select("1").Find(&[]Users{}).Statement
+	sqlDB, err := r.data.client.DB()
+	if err != nil {
+		return 0, err
+	}
+	var raw []byte
+	err = sqlDB.QueryRowContext(ctx, "EXPLAIN (FORMAT JSON) "+stmt.SQL.String(), stmt.Vars...).Scan(&raw)
+	if err != nil {
+		span.AddEvent(err.Error())
+		return 0, err
+	}
+	var plans []struct {
+		Plan struct {
+			Rows float64 `json:"Plan Rows"`
+		} `json:"Plan"`
+	}
+	if err := json.Unmarshal(raw, &plans); err != nil || len(plans) == 0 {
+		return 0, errors.InternalServer("users.count", "unreadable query plan")
+	}
+	return int(plans[0].Plan.Rows), nil
+}
@@ -0,0 +1,113 @@
+package data
+
+import (
+	"context"
+	"github.com/google/uuid"
+	"go.opentelemetry.io/otel"
+	"time"
+	"users/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/log"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// PhoneCode is the pending phone verification of a user, at most one per
+// user.
+type PhoneCode struct {
+	UserID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	User      Users     `gorm:"foreignKey:UserID;constraint:OnDelete:CASCADE"`
+	Phone     string    `gorm:"not null"`
+	CodeHash  string    `gorm:"not null"`
+	ExpiresAt time.Time `gorm:"not null"`
+	Attempts  int       `gorm:"not null;default:0"`
+	SentAt    time.Time `gorm:"not null"`
+}
+
+type verificationRepo struct {
+	data *Data
+	log  *log.Helper
+}
+
+func NewVerificationRepo(data *Data, logger log.Logger) biz.VerificationRepo {
+	return &verificationRepo{
+		data: data,
+		log:  log.NewHelper(logger),
+	}
+}
+
+func (r *verificationRepo) SavePhoneCode(ctx context.Context, code *biz.PhoneCode) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data SavePhoneCode")
+	defer span.End()
+	pc := &PhoneCode{
+		UserID:    code.UserID,
+		Phone:     code.Phone,
+		CodeHash:  code.Hash,
+		ExpiresAt: code.ExpiresAt,
+		SentAt:    code.SentAt,
+	}
+	t := r.data.client.WithContext(ctx).
+		Omit("User").
+		Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{
+				"phone":      code.Phone,
+				"code_hash":  code.Hash,
+				"expires_at": code.ExpiresAt,
+				"attempts":   0,
+				"sent_at":    code.SentAt,
+			}),
+		}).
+		Create(pc)
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return t.Error
+	}
+	return nil
+}
+
+func (r *verificationRepo) FindPhoneCode(ctx context.Context, id uuid.UUID) (*biz.PhoneCode, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data FindPhoneCode")
+	defer span.End()
+	var pc PhoneCode
+	t := r.data.client.WithContext(ctx).Where("user_id = ?", id).Take(&pc)
+	if errors.Is(t.Error, gorm.ErrRecordNotFound) {
+		return nil, errors.NotFound("users.phoneCode", "no pending phone verification")
+	}
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return nil, t.Error
+	}
+	return &biz.PhoneCode{
+		UserID:    pc.UserID,
+		Phone:     pc.Phone,
+		Hash:      pc.CodeHash,
+		ExpiresAt: pc.ExpiresAt,
+		SentAt:    pc.SentAt,
+	}, nil
+}
+
+func (r *verificationRepo) UsePhoneCodeAttempt(ctx context.Context, id uuid.UUID, max int) (bool, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Data UsePhoneCodeAttempt")
+	defer span.End()
+	t := r.data.client.WithContext(ctx).Model(&PhoneCode{}).
+		Where("user_id = ? AND attempts < ?", id, max).
+		Update("attempts", gorm.Expr("attempts + 1"))
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return false, t.Error
+	}
+	return t.RowsAffected == 1, nil
+}
+
+func (r *verificationRepo) DeletePhoneCode(ctx context.Context, id uuid.UUID) error {
+	_, span := otel.Tracer("users").Start(ctx, "Data DeletePhoneCode")
+	defer span.End()
+	t := r.data.client.WithContext(ctx).Where("user_id = ?", id).Delete(&PhoneCode{})
+	if t.Error != nil {
+		span.AddEvent(t.Error.Error())
+		return t.Error
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package server
+
+import (
+	"context"
+	authV1 "users/api/auth/v1"
+	usersV1 "users/api/users/v1"
+	"users/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/selector"
+)
+
+// operationRules is the access each authenticated operation requires.
+// Operations missing from it are denied.
+var operationRules = map[string]biz.Rule{
+	usersV1.OperationUsersCreateUsers:               {Permission: biz.PermUsersCreate},
+	usersV1.OperationUsersGetUsers:                  {Permission: biz.PermUsersGet, Self: true},
+	usersV1.OperationUsersUpdateUsers:               {Permission: biz.PermUsersUpdate, Self: true},
+	usersV1.OperationUsersDeleteUsers:               {Permission: biz.PermUsersDelete},
+	usersV1.OperationUsersListUsers:                 {Permission: biz.PermUsersList},
+	usersV1.OperationUsersLookupUser:                {Permission: biz.PermUsersGet},
+	usersV1.OperationUsersBatchGetUsers:             {Permission: biz.PermUsersGet},
+	usersV1.OperationUsersRestoreUsers:              {Permission: biz.PermUsersRestore},
+	usersV1.OperationUsersPurgeUsers:                {Permission: biz.PermUsersPurge},
+	usersV1.OperationUsersSetPassword:               {Permission: biz.PermUsersPassword},
+	usersV1.OperationUsersChangePassword:            {Self: true},
+	usersV1.OperationUsersVerifyPassword:            {Permission: biz.PermUsersPassword},
+	usersV1.OperationUsersListUserRoles:             {Permission: biz.PermRolesManage, Self: true},
+	usersV1.OperationUsersAssignRole:                {Permission: biz.PermRolesManage},
+	usersV1.OperationUsersRevokeRole:                {Permission: biz.PermRolesManage},
+	usersV1.OperationUsersEnrollTOTP:                {Self: true},
+	usersV1.OperationUsersConfirmTOTP:               {Self: true},
+	usersV1.OperationUsersVerifyTOTP:                {Permission: biz.PermMFAVerify},
+	usersV1.OperationUsersRegenerateRecoveryCodes:   {Self: true},
+	usersV1.OperationUsersResetTOTP:                 {Permission: biz.PermMFAReset},
+	usersV1.OperationUsersUnlockUser:                {Permission: biz.PermUsersUnlock},
+	usersV1.OperationUsersSendEmailVerification:     {Permission: biz.PermUsersUpdate, Self: true},
+	usersV1.OperationUsersSendPhoneVerification:     {Permission: biz.PermUsersUpdate, Self: true},
+	usersV1.OperationUsersConfirmPhone:              {Self: true},
+	usersV1.OperationUsersListExternalIdentities:    {Permission: biz.PermUsersGet, Self: true},
+	usersV1.OperationUsersUnlinkExternalIdentity:    {Permission: biz.PermUsersUpdate, Self: true},
+	usersV1.OperationUsersBeginPasskeyRegistration:  {Self: true},
+	usersV1.OperationUsersFinishPasskeyRegistration: {Self: true},
+	usersV1.OperationUsersListPasskeys:              {Permission: biz.PermUsersGet, Self: true},
+	usersV1.OperationUsersRenamePasskey:             {Self: true},
+	usersV1.OperationUsersDeletePasskey:             {Permission: biz.PermUsersUpdate, Self: true},
+	authV1.OperationAuthCreateAPIKey:                {Permission: biz.PermAPIKeysManage},
+	authV1.OperationAuthListAPIKeys:                 {Permission: biz.PermAPIKeysManage},
+	authV1.OperationAuthRevokeAPIKey:                {Permission: biz.PermAPIKeysManage},
+	authV1.OperationAuthRotateAPIKey:                {Permission: biz.PermAPIKeysManage},
+	authV1.OperationAuthCreateSession:               {Permission: biz.PermSessionsCreate},
+	authV1.OperationAuthListSessions:                {Permission: biz.PermSessionsList, Self: true},
+	authV1.OperationAuthRevokeSession:               {Permission: biz.PermSessionsRevoke, Self: true},
+	authV1.OperationAuthRevokeAllSessions:           {Permission: biz.PermSessionsRevoke, Self: true},
+	authV1.OperationAuthValidateSession:             {Permission: biz.PermSessionsValidate},
+	authV1.OperationAuthRegisterOIDCClient:          {Permission: biz.PermOIDCClientsManage},
+	authV1.OperationAuthListOIDCClients:             {Permission: biz.PermOIDCClientsManage},
+	authV1.OperationAuthDeleteOIDCClient:            {Permission: biz.PermOIDCClientsManage},
+}
+
+// authorization checks the principal put into the context by authentication
+// against operationRules. It must come after authentication in the chain.
+func authorization(access *biz.AccessUsecase) middleware.Middleware {
+	return selector.Server(authorize(access)).
+		Match(func(ctx context.Context, operation string) bool {
+			_, public := publicOperations[operation]
+			return !public
+		}).
+		Build()
+}
+
+func authorize(access *biz.AccessUsecase) middleware.Middleware {
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			operation, _ := operationFromContext(ctx)
+			rule, ok := operationRules[operation]
+			if !ok {
+				return nil, errors.Forbidden("auth.forbidden", "operation not permitted")
+			}
+			var target string
+			if r, ok := req.(interface{ GetId() string }); ok {
+				target = r.GetId()
+			}
+			p, _ := biz.PrincipalFromContext(ctx)
+			if err := access.Authorize(ctx, p, rule, target); err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}
+	}
+}
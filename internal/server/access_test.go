@@ -0,0 +1,157 @@
+package server
+
+import (
+	"context"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/google/uuid"
+	"testing"
+	authV1 "users/api/auth/v1"
+	usersV1 "users/api/users/v1"
+	"users/internal/biz"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// memRoles grants the permissions of roles as configured, and roles to
+// users as assigned.
+type memRoles struct {
+	biz.RolesRepo
+	perms map[string][]biz.Permission
+	roles map[uuid.UUID][]string
+}
+
+func (r *memRoles) UserPermissions(ctx context.Context, id uuid.UUID) ([]biz.Permission, error) {
+	return r.RolePermissions(ctx, r.roles[id])
+}
+
+func (r *memRoles) RolePermissions(_ context.Context, roles []string) ([]biz.Permission, error) {
+	var res []biz.Permission
+	for _, role := range roles {
+		res = append(res, r.perms[role]...)
+	}
+	return res, nil
+}
+
+// testTransport is the transport of a request to operation, with the
+// given request headers.
+type testTransport struct {
+	operation string
+	header    headerCarrier
+}
+
+func (t *testTransport) Kind() transport.Kind            { return transport.KindHTTP }
+func (t *testTransport) Endpoint() string                { return "" }
+func (t *testTransport) Operation() string               { return t.operation }
+func (t *testTransport) RequestHeader() transport.Header { return t.header }
+func (t *testTransport) ReplyHeader() transport.Header   { return headerCarrier{} }
+
+type headerCarrier map[string]string
+
+func (h headerCarrier) Get(key string) string      { return h[key] }
+func (h headerCarrier) Set(key, value string)      { h[key] = value }
+func (h headerCarrier) Add(key, value string)      { h[key] = value }
+func (h headerCarrier) Keys() []string             { return nil }
+func (h headerCarrier) Values(key string) []string { return []string{h[key]} }
+
+func operationContext(operation string) context.Context {
+	return transport.NewServerContext(context.Background(), &testTransport{operation: operation, header: headerCarrier{}})
+}
+
+type getIDRequest struct{ id string }
+
+func (r getIDRequest) GetId() string { return r.id }
+
+func ok(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+
+func TestOperationRulesCoverServices(t *testing.T) {
+	perms := make(map[biz.Permission]bool)
+	for _, p := range biz.Permissions {
+		perms[p] = true
+	}
+	for _, file := range []protoreflect.FileDescriptor{authV1.File_auth_v1_auth_proto, usersV1.File_users_v1_users_proto} {
+		services := file.Services()
+		for i := 0; i < services.Len(); i++ {
+			methods := services.Get(i).Methods()
+			for j := 0; j < methods.Len(); j++ {
+				operation := "/" + string(services.Get(i).FullName()) + "/" + string(methods.Get(j).Name())
+				rule, ruled := operationRules[operation]
+				_, public := publicOperations[operation]
+				if ruled == public {
+					t.Errorf("%s: ruled %t, public %t", operation, ruled, public)
+				}
+				if ruled && rule.Permission != "" && !perms[rule.Permission] {
+					t.Errorf("%s: unknown permission %q", operation, rule.Permission)
+				}
+				if ruled && rule.Permission == "" && !rule.Self {
+					t.Errorf("%s: nobody may call it", operation)
+				}
+			}
+		}
+	}
+}
+
+func TestAuthorize(t *testing.T) {
+	admin, user, other := uuid.New(), uuid.New(), uuid.New()
+	roles := &memRoles{
+		perms: map[string][]biz.Permission{biz.RoleAdmin: biz.Permissions},
+		roles: map[uuid.UUID][]string{admin: {biz.RoleAdmin}, user: {biz.RoleUser}},
+	}
+	access := biz.NewAccessUsecase(roles, nil, &conf.Biz{}, log.DefaultLogger)
+	mw := authorize(access)(ok)
+
+	for operation, rule := range operationRules {
+		tests := []struct {
+			name   string
+			p      *biz.Principal
+			target string
+			want   bool
+		}{
+			{"admin", &biz.Principal{Type: biz.PrincipalUser, UserID: admin.String()}, other.String(), rule.Permission != ""},
+			{"admin on itself", &biz.Principal{Type: biz.PrincipalUser, UserID: admin.String()}, admin.String(), true},
+			{"user", &biz.Principal{Type: biz.PrincipalUser, UserID: user.String()}, other.String(), false},
+			{"user on itself", &biz.Principal{Type: biz.PrincipalUser, UserID: user.String()}, user.String(), rule.Self},
+			{"gateway admin", &biz.Principal{Type: biz.PrincipalUser, UserID: other.String(), Gateway: true, Roles: []string{biz.RoleAdmin}}, user.String(), rule.Permission != ""},
+			{"gateway user on itself", &biz.Principal{Type: biz.PrincipalUser, UserID: other.String(), Gateway: true}, other.String(), rule.Self},
+			// API keys act for no user, so self rules never apply to them
+			{"API key in scope", &biz.Principal{Type: biz.PrincipalAPIKey, APIKeyID: uuid.NewString(), Scopes: []biz.Permission{rule.Permission}}, user.String(), rule.Permission != ""},
+			{"API key out of scope", &biz.Principal{Type: biz.PrincipalAPIKey, APIKeyID: uuid.NewString(), Scopes: []biz.Permission{biz.PermUsersGet}}, user.String(), rule.Permission == biz.PermUsersGet},
+			{"API key without a target", &biz.Principal{Type: biz.PrincipalAPIKey, APIKeyID: uuid.NewString(), Scopes: biz.Permissions}, "", rule.Permission != ""},
+		}
+		for _, tt := range tests {
+			ctx := biz.NewPrincipalContext(operationContext(operation), tt.p)
+			_, err := mw(ctx, getIDRequest{tt.target})
+			if tt.want && err != nil {
+				t.Errorf("%s by %s: %v", operation, tt.name, err)
+			}
+			if !tt.want && !errors.IsForbidden(err) {
+				t.Errorf("%s by %s: err = %v, want Forbidden", operation, tt.name, err)
+			}
+		}
+		if _, err := mw(operationContext(operation), getIDRequest{}); !errors.IsUnauthorized(err) {
+			t.Errorf("%s without a principal: err = %v, want Unauthorized", operation, err)
+		}
+	}
+}
+
+func TestAuthorizeUnknownOperation(t *testing.T) {
+	admin := uuid.New()
+	access := biz.NewAccessUsecase(&memRoles{}, nil, &conf.Biz{Access: &conf.Biz_Access{AdminUserIds: []string{admin.String()}}}, log.DefaultLogger)
+	mw := authorize(access)(ok)
+	tests := []struct {
+		operation string
+		p         *biz.Principal
+	}{
+		{"/api.users.v1.Users/Unknown", &biz.Principal{Type: biz.PrincipalUser, UserID: admin.String()}},
+		{"/api.users.v1.Users/Unknown", &biz.Principal{Type: biz.PrincipalAPIKey, Scopes: biz.Permissions}},
+		{"", &biz.Principal{Type: biz.PrincipalUser, UserID: admin.String()}},
+	}
+	for _, tt := range tests {
+		ctx := biz.NewPrincipalContext(operationContext(tt.operation), tt.p)
+		if _, err := mw(ctx, getIDRequest{admin.String()}); !errors.IsForbidden(err) {
+			t.Errorf("%q by %s: err = %v, want Forbidden", tt.operation, tt.p, err)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"context"
+	"strings"
+	authV1 "users/api/auth/v1"
+	usersV1 "users/api/users/v1"
+	"users/internal/biz"
+
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/go-kratos/kratos/v2/middleware"
+	"github.com/go-kratos/kratos/v2/middleware/selector"
+	"github.com/go-kratos/kratos/v2/transport"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/peer"
+)
+
+// publicOperations can be called without an access token.
+var publicOperations = map[string]struct{}{
+	authV1.OperationAuthLogin:   {},
+	authV1.OperationAuthRefresh: {},
+	authV1.OperationAuthLogout:  {},
+	// the passkey assertion authenticates the login
+	authV1.OperationAuthBeginPasskeyLogin:  {},
+	authV1.OperationAuthFinishPasskeyLogin: {},
+	// the code or link sent to the user authenticates the login
+	authV1.OperationAuthStartPasswordlessLogin:    {},
+	authV1.OperationAuthCompletePasswordlessLogin: {},
+	// the MFA token of a login proves the first factor
+	authV1.OperationAuthCompleteMFALogin: {},
+	// the emailed token authenticates the call
+	usersV1.OperationUsersConfirmEmail:         {},
+	usersV1.OperationUsersRequestPasswordReset: {},
+	usersV1.OperationUsersResetPassword:        {},
+}
+
+// authentication requires a valid API key or bearer access token on every
+// operation but the public ones, and puts its principal into the context.
+func authentication(auth *biz.AuthUsecase, apiKeys *biz.APIKeysUsecase) middleware.Middleware {
+	return selector.Server(authenticate(auth, apiKeys)).
+		Match(func(ctx context.Context, operation string) bool {
+			_, public := publicOperations[operation]
+			return !public
+		}).
+		Build()
+}
+
+func operationFromContext(ctx context.Context) (string, bool) {
+	tr, ok := transport.FromServerContext(ctx)
+	if !ok {
+		return "", false
+	}
+	return tr.Operation(), true
+}
+
+func authenticate(auth *biz.AuthUsecase, apiKeys *biz.APIKeysUsecase) middleware.Middleware {
+	return func(handler middleware.Handler) middleware.Handler {
+		return func(ctx context.Context, req interface{}) (interface{}, error) {
+			tr, ok := transport.FromServerContext(ctx)
+			if !ok {
+				return nil, errors.Unauthorized("auth.unauthenticated", "missing transport")
+			}
+			var (
+				p   *biz.Principal
+				err error
+			)
+			h := tr.RequestHeader()
+			if id := h.Get("x-user-id"); id != "" && auth.GatewayEnabled() {
+				p, err = auth.AuthenticateGateway(ctx, peerAddr(ctx), h.Get("x-gateway-secret"), id, h.Get("x-roles"))
+			} else if key := h.Get("x-api-key"); key != "" {
+				p, err = apiKeys.AuthenticateAPIKey(ctx, key)
+			} else {
+				scheme, token, found := strings.Cut(h.Get("Authorization"), " ")
+				if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+					return nil, errors.Unauthorized("auth.unauthenticated", "missing bearer token or API key")
+				}
+				p, err = auth.Authenticate(ctx, strings.TrimSpace(token))
+			}
+			if err != nil {
+				return nil, err
+			}
+			trace.SpanFromContext(ctx).SetAttributes(
+				attribute.String("principal.type", string(p.Type)),
+				attribute.String("principal.id", p.ID()),
+			)
+			return handler(biz.NewPrincipalContext(ctx, p), req)
+		}
+	}
+}
+
+// peerAddr returns the address the request came from, the last proxy when
+// there are any.
+func peerAddr(ctx context.Context) string {
+	if r, ok := khttp.RequestFromServerContext(ctx); ok {
+		return r.RemoteAddr
+	}
+	if p, ok := peer.FromContext(ctx); ok {
+		return p.Addr.String()
+	}
+	return ""
+}
@@ -0,0 +1,268 @@
+package server
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"github.com/go-kratos/kratos/v2/errors"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"sync"
+	"testing"
+	"time"
+	usersV1 "users/api/users/v1"
+	"users/internal/biz"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+)
+
+type memAPIKeys struct {
+	biz.APIKeysRepo
+	mu   sync.Mutex
+	keys map[uuid.UUID]*biz.APIKey
+}
+
+func (r *memAPIKeys) SaveAPIKey(_ context.Context, k *biz.APIKey) (*biz.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved := *k
+	saved.ID = uuid.New()
+	saved.CreatedAt = time.Now()
+	r.keys[saved.ID] = &saved
+	return &saved, nil
+}
+
+func (r *memAPIKeys) FindAPIKeyByHash(_ context.Context, hash string) (*biz.APIKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, k := range r.keys {
+		if k.Hash == hash {
+			found := *k
+			return &found, nil
+		}
+	}
+	return nil, errors.NotFound("auth.apiKey", "API key not found")
+}
+
+func (r *memAPIKeys) TouchAPIKey(_ context.Context, id uuid.UUID, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.keys[id].LastUsedAt = &at
+	return nil
+}
+
+// testAuthenticate is the authentication middleware in front of a handler
+// returning the principal it was called with.
+type testAuthenticate struct {
+	handler    func(context.Context, interface{}) (interface{}, error)
+	key        *ecdsa.PrivateKey
+	apiKeys    *biz.APIKeysUsecase
+	apiKeyRepo *memAPIKeys
+}
+
+func newTestAuthenticate(t *testing.T, c *conf.Auth) *testAuthenticate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Keys = []*conf.Auth_Key{{Id: "test-key", PrivateKey: string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))}}
+	proxies, err := biz.NewTrustedProxies(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the paths tested need neither users nor sessions
+	auth, err := biz.NewAuthUsecase(nil, nil, nil, nil, nil, nil, proxies, c, log.DefaultLogger)
+	if err != nil {
+		t.Fatal(err)
+	}
+	roles := &memRoles{}
+	repo := &memAPIKeys{keys: make(map[uuid.UUID]*biz.APIKey)}
+	ta := &testAuthenticate{
+		key:        key,
+		apiKeys:    biz.NewAPIKeysUsecase(repo, biz.NewAccessUsecase(roles, nil, &conf.Biz{}, log.DefaultLogger), log.DefaultLogger),
+		apiKeyRepo: repo,
+	}
+	ta.handler = authenticate(auth, ta.apiKeys)(func(ctx context.Context, _ interface{}) (interface{}, error) {
+		p, _ := biz.PrincipalFromContext(ctx)
+		return p, nil
+	})
+	return ta
+}
+
+// call makes a request to an authenticated operation with the headers,
+// given as pairs of names and values.
+func (ta *testAuthenticate) call(header ...string) (*biz.Principal, error) {
+	h := headerCarrier{}
+	for i := 0; i+1 < len(header); i += 2 {
+		h.Set(header[i], header[i+1])
+	}
+	ctx := transport.NewServerContext(context.Background(), &testTransport{operation: usersV1.OperationUsersGetUsers, header: h})
+	res, err := ta.handler(ctx, nil)
+	if err != nil {
+		return nil, err
+	}
+	return res.(*biz.Principal), nil
+}
+
+// sign issues a token like the access tokens of the service, with typ and
+// key ID overridable through header.
+func (ta *testAuthenticate) sign(t *testing.T, claims jwt.MapClaims, header ...string) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodES256, claims)
+	token.Header["kid"] = "test-key"
+	token.Header["typ"] = "at+jwt"
+	for i := 0; i+1 < len(header); i += 2 {
+		token.Header[header[i]] = header[i+1]
+	}
+	s, err := token.SignedString(ta.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func (ta *testAuthenticate) createAPIKey(t *testing.T, scopes ...string) (*biz.APIKey, string) {
+	t.Helper()
+	// the creator holds every scope, so that any may be handed out
+	ctx := biz.NewPrincipalContext(context.Background(), &biz.Principal{Type: biz.PrincipalAPIKey, APIKeyID: uuid.NewString(), Scopes: biz.Permissions})
+	key, secret, err := ta.apiKeys.CreateAPIKey(ctx, "billing", scopes, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return key, secret
+}
+
+func TestAuthenticateBearer(t *testing.T) {
+	ta := newTestAuthenticate(t, &conf.Auth{})
+	uid := uuid.NewString()
+	exp := time.Now().Add(time.Minute).Unix()
+
+	p, err := ta.call("Authorization", "bearer "+ta.sign(t, jwt.MapClaims{"sub": uid, "exp": exp, "jti": "token-1"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Type != biz.PrincipalUser || p.UserID != uid || p.TokenID != "token-1" {
+		t.Errorf("principal %+v", p)
+	}
+
+	tests := []struct {
+		name  string
+		value string
+	}{
+		{"no scheme", ta.sign(t, jwt.MapClaims{"sub": uid, "exp": exp})},
+		{"basic scheme", "Basic " + ta.sign(t, jwt.MapClaims{"sub": uid, "exp": exp})},
+		{"empty token", "Bearer "},
+		{"garbage", "Bearer garbage"},
+		{"expired", "Bearer " + ta.sign(t, jwt.MapClaims{"sub": uid, "exp": time.Now().Add(-time.Minute).Unix()})},
+		{"no expiry", "Bearer " + ta.sign(t, jwt.MapClaims{"sub": uid})},
+		{"no subject", "Bearer " + ta.sign(t, jwt.MapClaims{"exp": exp})},
+		{"refresh token type", "Bearer " + ta.sign(t, jwt.MapClaims{"sub": uid, "exp": exp}, "typ", "rt+jwt")},
+		{"unknown key", "Bearer " + ta.sign(t, jwt.MapClaims{"sub": uid, "exp": exp}, "kid", "other-key")},
+		{"issued to an OpenID Connect client", "Bearer " + ta.sign(t, jwt.MapClaims{"sub": uid, "exp": exp, "scope": "openid"})},
+	}
+	for _, tt := range tests {
+		if _, err := ta.call("Authorization", tt.value); !errors.IsUnauthorized(err) {
+			t.Errorf("%s: err = %v, want Unauthorized", tt.name, err)
+		}
+	}
+	if _, err := ta.call(); !errors.IsUnauthorized(err) {
+		t.Errorf("no credentials: err = %v, want Unauthorized", err)
+	}
+	if _, err := authenticate(nil, nil)(ok)(context.Background(), nil); !errors.IsUnauthorized(err) {
+		t.Errorf("no transport: err = %v, want Unauthorized", err)
+	}
+}
+
+func TestAuthenticateAPIKey(t *testing.T) {
+	ta := newTestAuthenticate(t, &conf.Auth{})
+	key, secret := ta.createAPIKey(t, string(biz.PermUsersGet), string(biz.PermUsersList))
+
+	p, err := ta.call("x-api-key", secret)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Type != biz.PrincipalAPIKey || p.APIKeyID != key.ID.String() || p.UserID != "" {
+		t.Errorf("principal %+v", p)
+	}
+	if len(p.Scopes) != 2 || p.Scopes[0] != biz.PermUsersGet || p.Scopes[1] != biz.PermUsersList {
+		t.Errorf("scopes %v", p.Scopes)
+	}
+	if ta.apiKeyRepo.keys[key.ID].LastUsedAt == nil {
+		t.Error("use not recorded")
+	}
+
+	// an API key wins over a bearer token sent along
+	p, err = ta.call("x-api-key", secret, "Authorization", "Bearer "+ta.sign(t, jwt.MapClaims{"sub": uuid.NewString(), "exp": time.Now().Add(time.Minute).Unix()}))
+	if err != nil || p.Type != biz.PrincipalAPIKey {
+		t.Errorf("API key with a bearer token = %+v, %v", p, err)
+	}
+
+	revoked, revokedSecret := ta.createAPIKey(t, string(biz.PermUsersGet))
+	ta.apiKeyRepo.keys[revoked.ID].RevokedAt = ptr(time.Now())
+	expired, expiredSecret := ta.createAPIKey(t, string(biz.PermUsersGet))
+	ta.apiKeyRepo.keys[expired.ID].ExpiresAt = ptr(time.Now().Add(-time.Second))
+	tests := []struct {
+		name, secret string
+	}{
+		{"unknown", "uk_" + uuid.NewString()},
+		{"without prefix", secret[len("uk_"):]},
+		{"revoked", revokedSecret},
+		{"expired", expiredSecret},
+	}
+	for _, tt := range tests {
+		if _, err := ta.call("x-api-key", tt.secret); !errors.IsUnauthorized(err) {
+			t.Errorf("%s key: err = %v, want Unauthorized", tt.name, err)
+		}
+	}
+}
+
+func TestAuthenticateGateway(t *testing.T) {
+	uid := uuid.NewString()
+	tests := []struct {
+		name   string
+		c      *conf.Auth
+		header []string
+		want   func(*biz.Principal, error) bool
+	}{
+		{"gateway disabled", &conf.Auth{}, []string{"x-user-id", uid, "x-roles", "admin"},
+			// the forwarded identity is ignored, the caller has to authenticate
+			func(_ *biz.Principal, err error) bool { return errors.IsUnauthorized(err) }},
+		{"with secret", &conf.Auth{Gateway: &conf.Auth_Gateway{Enabled: true, Secret: "s3cret"}},
+			[]string{"x-user-id", uid, "x-roles", " admin, support ,", "x-gateway-secret", "s3cret"},
+			func(p *biz.Principal, err error) bool {
+				return err == nil && p.Gateway && p.UserID == uid && len(p.Roles) == 2 && p.Roles[0] == "admin" && p.Roles[1] == "support"
+			}},
+		{"without roles", &conf.Auth{Gateway: &conf.Auth_Gateway{Enabled: true, Secret: "s3cret"}},
+			[]string{"x-user-id", uid, "x-gateway-secret", "s3cret"},
+			func(p *biz.Principal, err error) bool { return err == nil && p.Gateway && len(p.Roles) == 0 }},
+		{"wrong secret", &conf.Auth{Gateway: &conf.Auth_Gateway{Enabled: true, Secret: "s3cret"}},
+			[]string{"x-user-id", uid, "x-gateway-secret", "guess"},
+			func(_ *biz.Principal, err error) bool { return errors.IsUnauthorized(err) }},
+		{"malformed user ID", &conf.Auth{Gateway: &conf.Auth_Gateway{Enabled: true, Secret: "s3cret"}},
+			[]string{"x-user-id", "erin", "x-gateway-secret", "s3cret"},
+			func(_ *biz.Principal, err error) bool { return errors.IsUnauthorized(err) }},
+		{"untrusted peer", &conf.Auth{Gateway: &conf.Auth_Gateway{Enabled: true}, TrustedProxies: []string{"10.0.0.0/8"}},
+			[]string{"x-user-id", uid},
+			func(_ *biz.Principal, err error) bool { return errors.IsUnauthorized(err) }},
+	}
+	for _, tt := range tests {
+		p, err := newTestAuthenticate(t, tt.c).call(tt.header...)
+		if !tt.want(p, err) {
+			t.Errorf("%s: %+v, %v", tt.name, p, err)
+		}
+	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
@@ -1,7 +1,9 @@
 package server
 
 import (
+	authV1 "users/api/auth/v1"
 	usersV1 "users/api/users/v1"
+	"users/internal/biz"
 	"users/internal/conf"
 	"users/internal/service"
 	"github.com/go-kratos/kratos/v2/middleware/logging"
@@ -15,7 +17,7 @@ import (
 	"github.com/go-kratos/kratos/v2/transport/grpc"
 )
 
-func NewGRPCServer(c *conf.Server, users *service.UsersService, logger log.Logger, meter metric.Meter, tp trace.TracerProvider) (*grpc.Server, error) {
+func NewGRPCServer(c *conf.Server, users *service.UsersService, auth *service.AuthService, authUC *biz.AuthUsecase, apiKeys *biz.APIKeysUsecase, access *biz.AccessUsecase, logger log.Logger, meter metric.Meter, tp trace.TracerProvider) (*grpc.Server, error) {
 	counter, err := metrics.DefaultRequestsCounter(meter, metrics.DefaultServerRequestsCounterName)
 	if err != nil {
 		return nil, err
@@ -35,6 +37,8 @@ func NewGRPCServer(c *conf.Server, users *service.UsersService, logger log.Logge
 				metrics.WithRequests(counter),
 				metrics.WithSeconds(seconds),
 			),
+			authentication(authUC, apiKeys),
+			authorization(access),
 		),
 	}
 	if c.Grpc.Network != "" {
@@ -48,5 +52,6 @@ func NewGRPCServer(c *conf.Server, users *service.UsersService, logger log.Logge
 	}
 	srv := grpc.NewServer(opts...)
 	usersV1.RegisterUsersServer(srv, users)
+	authV1.RegisterAuthServer(srv, auth)
 	return srv, nil
 }
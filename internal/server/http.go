@@ -1,7 +1,9 @@
 package server
 
 import (
+	authV1 "users/api/auth/v1"
 	usersV1 "users/api/users/v1"
+	"users/internal/biz"
 	"users/internal/conf"
 	"users/internal/service"
 	"github.com/go-kratos/kratos/v2/middleware/logging"
@@ -15,9 +17,10 @@ import (
 	"github.com/go-kratos/kratos/v2/log"
 	"github.com/go-kratos/kratos/v2/middleware/recovery"
 	"github.com/go-kratos/kratos/v2/transport/http"
+	stdhttp "net/http"
 )
 
-func NewHTTPServer(c *conf.Server, users *service.UsersService, logger log.Logger, meter metric.Meter, tp trace.TracerProvider) (*http.Server, error) {
+func NewHTTPServer(c *conf.Server, users *service.UsersService, auth *service.AuthService, authUC *biz.AuthUsecase, apiKeys *biz.APIKeysUsecase, access *biz.AccessUsecase, logger log.Logger, meter metric.Meter, tp trace.TracerProvider) (*http.Server, error) {
 	counter, err := metrics.DefaultRequestsCounter(meter, metrics.DefaultServerRequestsCounterName)
 	if err != nil {
 		return nil, err
@@ -38,6 +41,8 @@ func NewHTTPServer(c *conf.Server, users *service.UsersService, logger log.Logge
 				metrics.WithRequests(counter),
 				metrics.WithSeconds(seconds),
 			),
+			authentication(authUC, apiKeys),
+			authorization(access),
 		),
 	}
 	if c.Http.Network != "" {
@@ -56,6 +61,13 @@ func NewHTTPServer(c *conf.Server, users *service.UsersService, logger log.Logge
 			EnableOpenMetrics: true,
 		},
 	))
+	srv.HandleFunc("/.well-known/jwks.json", auth.JWKS)
+	srv.HandleFunc(biz.OIDCDiscoveryPath, auth.OIDCDiscovery)
+	srv.HandleFunc(biz.OIDCAuthorizePath, auth.OIDCAuthorize)
+	srv.HandleFunc(biz.OIDCTokenPath, auth.OIDCToken)
+	srv.HandleFunc(biz.OIDCUserInfoPath, auth.OIDCUserInfo)
+	srv.HandlePrefix(biz.FederationPath, stdhttp.HandlerFunc(auth.Federation))
 	usersV1.RegisterUsersHTTPServer(srv, users)
+	authV1.RegisterAuthHTTPServer(srv, auth)
 	return srv, nil
 }
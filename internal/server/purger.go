@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"sync"
+	"time"
+	"users/internal/biz"
+	"users/internal/conf"
+
+	"github.com/go-kratos/kratos/v2/log"
+)
+
+const defaultPurgeInterval = time.Hour
+
+// Purger is a background server that periodically hard deletes the users
+// whose soft delete is older than the configured retention period.
+type Purger struct {
+	users    *biz.UsersUsecase
+	interval time.Duration
+	enabled  bool
+	stop     chan struct{}
+	once     sync.Once
+	log      *log.Helper
+}
+
+func NewPurger(c *conf.Biz, users *biz.UsersUsecase, logger log.Logger) *Purger {
+	interval := c.GetRetention().GetPurgeInterval().AsDuration()
+	if interval <= 0 {
+		interval = defaultPurgeInterval
+	}
+	return &Purger{
+		users:    users,
+		interval: interval,
+		enabled:  c.GetRetention().GetDeletedUsers().AsDuration() > 0,
+		stop:     make(chan struct{}),
+		log:      log.NewHelper(logger),
+	}
+}
+
+func (p *Purger) Start(ctx context.Context) error {
+	if !p.enabled {
+		p.log.Info("no retention period for deleted users, purger disabled")
+		return nil
+	}
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+	for {
+		p.purge(ctx)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-p.stop:
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func (p *Purger) Stop(context.Context) error {
+	p.once.Do(func() { close(p.stop) })
+	return nil
+}
+
+func (p *Purger) purge(ctx context.Context) {
+	n, err := p.users.PurgeExpiredUsers(ctx)
+	if err != nil {
+		p.log.WithContext(ctx).Errorf("purging deleted users: %s", err)
+		return
+	}
+	if n > 0 {
+		p.log.WithContext(ctx).Infof("purged %d deleted users", n)
+	}
+}
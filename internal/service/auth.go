@@ -0,0 +1,361 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kratos/kratos/v2/log"
+	"github.com/go-kratos/kratos/v2/transport"
+	khttp "github.com/go-kratos/kratos/v2/transport/http"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"net/http"
+	"time"
+	"users/internal/biz"
+
+	pb "users/api/auth/v1"
+)
+
+type AuthService struct {
+	pb.UnimplementedAuthServer
+	uc           *biz.AuthUsecase
+	apiKeys      *biz.APIKeysUsecase
+	sessions     *biz.SessionsUsecase
+	oidc         *biz.OIDCUsecase
+	federation   *biz.FederationUsecase
+	passkeys     *biz.PasskeysUsecase
+	passwordless *biz.PasswordlessUsecase
+	proxies      *biz.TrustedProxies
+	log          *log.Helper
+}
+
+func NewAuthService(uc *biz.AuthUsecase, apiKeys *biz.APIKeysUsecase, sessions *biz.SessionsUsecase, oidc *biz.OIDCUsecase, federation *biz.FederationUsecase, passkeys *biz.PasskeysUsecase, passwordless *biz.PasswordlessUsecase, proxies *biz.TrustedProxies, logger log.Logger) *AuthService {
+	return &AuthService{uc: uc, apiKeys: apiKeys, sessions: sessions, oidc: oidc, federation: federation, passkeys: passkeys, passwordless: passwordless, proxies: proxies, log: log.NewHelper(logger)}
+}
+
+func (s *AuthService) Login(ctx context.Context, req *pb.LoginRequest) (*pb.LoginReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Login")
+	defer span.End()
+	var key biz.LookupKey
+	switch k := req.GetIdentifier().(type) {
+	case *pb.LoginRequest_Username:
+		key = biz.LookupKey{Field: biz.FieldUsername, Value: k.Username}
+	case *pb.LoginRequest_Email:
+		key = biz.LookupKey{Field: biz.FieldEmail, Value: k.Email}
+	}
+	res, mfaToken, err := s.uc.Login(ctx, key, req.GetPassword(), s.clientFromContext(ctx, req.GetDevice()))
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("Login: %s", err)
+		return nil, err
+	}
+	if mfaToken != "" {
+		s.log.WithContext(ctx).Infof("Login: %s, second factor required", key.Field)
+		return &pb.LoginReply{MfaToken: mfaToken}, nil
+	}
+	s.log.WithContext(ctx).Infof("Login: %s", key.Field)
+	return &pb.LoginReply{Tokens: tokenPair(res)}, nil
+}
+func (s *AuthService) Refresh(ctx context.Context, req *pb.RefreshRequest) (*pb.RefreshReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Refresh")
+	defer span.End()
+	res, err := s.uc.Refresh(ctx, req.GetRefreshToken())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("Refresh: %s", err)
+		return nil, err
+	}
+	return &pb.RefreshReply{Tokens: tokenPair(res)}, nil
+}
+func (s *AuthService) Logout(ctx context.Context, req *pb.LogoutRequest) (*pb.LogoutReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "Logout")
+	defer span.End()
+	if err := s.uc.Logout(ctx, req.GetRefreshToken()); err != nil {
+		s.log.WithContext(ctx).Warnf("Logout: %s", err)
+		return nil, err
+	}
+	return &pb.LogoutReply{}, nil
+}
+func (s *AuthService) CreateAPIKey(ctx context.Context, req *pb.CreateAPIKeyRequest) (*pb.CreateAPIKeyReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "CreateAPIKey")
+	defer span.End()
+	var expiresAt *time.Time
+	if req.ExpireTime != nil {
+		t := req.GetExpireTime().AsTime()
+		expiresAt = &t
+	}
+	res, secret, err := s.apiKeys.CreateAPIKey(ctx, req.GetName(), req.GetScopes(), expiresAt)
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("CreateAPIKey: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("CreateAPIKey: id %s", res.ID)
+	return &pb.CreateAPIKeyReply{ApiKey: apiKey(res), Secret: secret}, nil
+}
+func (s *AuthService) ListAPIKeys(ctx context.Context, req *pb.ListAPIKeysRequest) (*pb.ListAPIKeysReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "ListAPIKeys")
+	defer span.End()
+	res, err := s.apiKeys.ListAPIKeys(ctx)
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("ListAPIKeys: %s", err)
+		return nil, err
+	}
+	keys := make([]*pb.APIKey, len(res))
+	for i := range res {
+		keys[i] = apiKey(&res[i])
+	}
+	return &pb.ListAPIKeysReply{ApiKeys: keys}, nil
+}
+func (s *AuthService) RevokeAPIKey(ctx context.Context, req *pb.RevokeAPIKeyRequest) (*pb.RevokeAPIKeyReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "RevokeAPIKey")
+	defer span.End()
+	res, err := s.apiKeys.RevokeAPIKey(ctx, req.GetId())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("RevokeAPIKey: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("RevokeAPIKey: id %s", res.ID)
+	return &pb.RevokeAPIKeyReply{ApiKey: apiKey(res)}, nil
+}
+func (s *AuthService) RotateAPIKey(ctx context.Context, req *pb.RotateAPIKeyRequest) (*pb.RotateAPIKeyReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "RotateAPIKey")
+	defer span.End()
+	res, secret, err := s.apiKeys.RotateAPIKey(ctx, req.GetId())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("RotateAPIKey: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("RotateAPIKey: id %s", res.ID)
+	return &pb.RotateAPIKeyReply{ApiKey: apiKey(res), Secret: secret}, nil
+}
+func (s *AuthService) CreateSession(ctx context.Context, req *pb.CreateSessionRequest) (*pb.CreateSessionReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "CreateSession")
+	defer span.End()
+	client := biz.Client{Device: req.GetDevice(), UserAgent: req.GetUserAgent(), IP: req.GetIp()}
+	res, err := s.sessions.CreateSession(ctx, req.GetId(), client)
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("CreateSession: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("CreateSession: id %s", res.ID)
+	return &pb.CreateSessionReply{Session: session(ctx, res)}, nil
+}
+func (s *AuthService) ListSessions(ctx context.Context, req *pb.ListSessionsRequest) (*pb.ListSessionsReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "ListSessions")
+	defer span.End()
+	res, err := s.sessions.ListSessions(ctx, req.GetId())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("ListSessions: %s", err)
+		return nil, err
+	}
+	sessions := make([]*pb.Session, len(res))
+	for i := range res {
+		sessions[i] = session(ctx, &res[i])
+	}
+	return &pb.ListSessionsReply{Sessions: sessions}, nil
+}
+func (s *AuthService) RevokeSession(ctx context.Context, req *pb.RevokeSessionRequest) (*pb.RevokeSessionReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "RevokeSession")
+	defer span.End()
+	res, err := s.sessions.RevokeSession(ctx, req.GetId(), req.GetSessionId())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("RevokeSession: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("RevokeSession: id %s", res.ID)
+	return &pb.RevokeSessionReply{Session: session(ctx, res)}, nil
+}
+func (s *AuthService) RevokeAllSessions(ctx context.Context, req *pb.RevokeAllSessionsRequest) (*pb.RevokeAllSessionsReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "RevokeAllSessions")
+	defer span.End()
+	var except string
+	if p, ok := biz.PrincipalFromContext(ctx); ok && req.GetKeepCurrent() && p.UserID == req.GetId() {
+		except = p.SessionID
+	}
+	n, err := s.sessions.RevokeAllSessions(ctx, req.GetId(), except)
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("RevokeAllSessions: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("RevokeAllSessions: id %s revoked %d", req.GetId(), n)
+	return &pb.RevokeAllSessionsReply{Revoked: int32(n)}, nil
+}
+func (s *AuthService) ValidateSession(ctx context.Context, req *pb.ValidateSessionRequest) (*pb.ValidateSessionReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "ValidateSession")
+	defer span.End()
+	res, err := s.sessions.ValidateSession(ctx, req.GetSessionId())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("ValidateSession: %s", err)
+		return nil, err
+	}
+	if res == nil {
+		return &pb.ValidateSessionReply{}, nil
+	}
+	return &pb.ValidateSessionReply{
+		Valid:      true,
+		UserId:     res.UserID.String(),
+		ExpireTime: timestamppb.New(res.ExpiresAt),
+	}, nil
+}
+func (s *AuthService) BeginPasskeyLogin(ctx context.Context, req *pb.BeginPasskeyLoginRequest) (*pb.BeginPasskeyLoginReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "BeginPasskeyLogin")
+	defer span.End()
+	var key *biz.LookupKey
+	switch k := req.GetIdentifier().(type) {
+	case *pb.BeginPasskeyLoginRequest_Username:
+		key = &biz.LookupKey{Field: biz.FieldUsername, Value: k.Username}
+	case *pb.BeginPasskeyLoginRequest_Email:
+		key = &biz.LookupKey{Field: biz.FieldEmail, Value: k.Email}
+	}
+	options, err := s.passkeys.BeginLogin(ctx, key)
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("BeginPasskeyLogin: %s", err)
+		return nil, err
+	}
+	return &pb.BeginPasskeyLoginReply{Options: options}, nil
+}
+func (s *AuthService) FinishPasskeyLogin(ctx context.Context, req *pb.FinishPasskeyLoginRequest) (*pb.FinishPasskeyLoginReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "FinishPasskeyLogin")
+	defer span.End()
+	res, mfaToken, err := s.passkeys.FinishLogin(ctx, req.GetCredential(), s.clientFromContext(ctx, req.GetDevice()))
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("FinishPasskeyLogin: %s", err)
+		return nil, err
+	}
+	if mfaToken != "" {
+		s.log.WithContext(ctx).Info("FinishPasskeyLogin: second factor required")
+		return &pb.FinishPasskeyLoginReply{MfaToken: mfaToken}, nil
+	}
+	s.log.WithContext(ctx).Info("FinishPasskeyLogin")
+	return &pb.FinishPasskeyLoginReply{Tokens: tokenPair(res)}, nil
+}
+func (s *AuthService) StartPasswordlessLogin(ctx context.Context, req *pb.StartPasswordlessLoginRequest) (*pb.StartPasswordlessLoginReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "StartPasswordlessLogin")
+	defer span.End()
+	var key biz.LookupKey
+	switch k := req.GetIdentifier().(type) {
+	case *pb.StartPasswordlessLoginRequest_Email:
+		key = biz.LookupKey{Field: biz.FieldEmail, Value: k.Email}
+	case *pb.StartPasswordlessLoginRequest_Phone:
+		key = biz.LookupKey{Field: biz.FieldPhone, Value: k.Phone}
+	}
+	token, ttl, err := s.passwordless.StartLogin(ctx, key, req.GetMagicLink())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("StartPasswordlessLogin: %s", err)
+		return nil, err
+	}
+	return &pb.StartPasswordlessLoginReply{LoginToken: token, ExpiresIn: int64(ttl.Seconds())}, nil
+}
+func (s *AuthService) CompletePasswordlessLogin(ctx context.Context, req *pb.CompletePasswordlessLoginRequest) (*pb.CompletePasswordlessLoginReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "CompletePasswordlessLogin")
+	defer span.End()
+	res, mfaToken, err := s.passwordless.CompleteLogin(ctx, req.GetLoginToken(), req.GetCode(), req.GetLinkToken(), s.clientFromContext(ctx, req.GetDevice()))
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("CompletePasswordlessLogin: %s", err)
+		return nil, err
+	}
+	if mfaToken != "" {
+		s.log.WithContext(ctx).Info("CompletePasswordlessLogin: second factor required")
+		return &pb.CompletePasswordlessLoginReply{MfaToken: mfaToken}, nil
+	}
+	s.log.WithContext(ctx).Info("CompletePasswordlessLogin")
+	return &pb.CompletePasswordlessLoginReply{Tokens: tokenPair(res)}, nil
+}
+func (s *AuthService) CompleteMFALogin(ctx context.Context, req *pb.CompleteMFALoginRequest) (*pb.CompleteMFALoginReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "CompleteMFALogin")
+	defer span.End()
+	res, err := s.uc.CompleteMFALogin(ctx, req.GetMfaToken(), req.GetCode(), s.clientFromContext(ctx, req.GetDevice()))
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("CompleteMFALogin: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Info("CompleteMFALogin")
+	return &pb.CompleteMFALoginReply{Tokens: tokenPair(res)}, nil
+}
+
+// JWKS serves the public signing keys at /.well-known/jwks.json.
+func (s *AuthService) JWKS(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := json.NewEncoder(w).Encode(s.uc.JWKS()); err != nil {
+		s.log.WithContext(r.Context()).Warnf("JWKS: %s", err)
+	}
+}
+
+func tokenPair(t *biz.TokenPair) *pb.TokenPair {
+	now := time.Now()
+	return &pb.TokenPair{
+		AccessToken:      t.AccessToken,
+		TokenType:        "Bearer",
+		ExpiresIn:        int64(t.AccessExpiresAt.Sub(now).Seconds()),
+		RefreshToken:     t.RefreshToken,
+		RefreshExpiresIn: int64(t.RefreshExpiresAt.Sub(now).Seconds()),
+	}
+}
+
+func apiKey(k *biz.APIKey) *pb.APIKey {
+	scopes := make([]string, len(k.Scopes))
+	for i, s := range k.Scopes {
+		scopes[i] = string(s)
+	}
+	res := &pb.APIKey{
+		Id:         k.ID.String(),
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     scopes,
+		CreatedBy:  k.CreatedBy,
+		CreateTime: timestamppb.New(k.CreatedAt),
+	}
+	if k.ExpiresAt != nil {
+		res.ExpireTime = timestamppb.New(*k.ExpiresAt)
+	}
+	if k.LastUsedAt != nil {
+		res.LastUsedTime = timestamppb.New(*k.LastUsedAt)
+	}
+	if k.RevokedAt != nil {
+		res.RevokeTime = timestamppb.New(*k.RevokedAt)
+	}
+	return res
+}
+
+func session(ctx context.Context, s *biz.Session) *pb.Session {
+	res := &pb.Session{
+		Id:           s.ID.String(),
+		UserId:       s.UserID.String(),
+		Device:       s.Device,
+		UserAgent:    s.UserAgent,
+		Ip:           s.IP,
+		ClientId:     s.ClientID,
+		CreateTime:   timestamppb.New(s.CreatedAt),
+		LastSeenTime: timestamppb.New(s.LastSeenAt),
+		ExpireTime:   timestamppb.New(s.ExpiresAt),
+	}
+	if s.RevokedAt != nil {
+		res.RevokeTime = timestamppb.New(*s.RevokedAt)
+	}
+	if p, ok := biz.PrincipalFromContext(ctx); ok {
+		res.Current = p.SessionID == res.Id
+	}
+	return res
+}
+
+// clientFromContext describes the client of a request for its session.
+// Its address is the one of the peer, or the one the trusted proxies in
+// front of the service forwarded.
+func (s *AuthService) clientFromContext(ctx context.Context, device string) biz.Client {
+	tr, ok := transport.FromServerContext(ctx)
+	if !ok {
+		return biz.Client{Device: device}
+	}
+	var addr string
+	if r, ok := khttp.RequestFromServerContext(ctx); ok {
+		addr = r.RemoteAddr
+	} else if p, ok := peer.FromContext(ctx); ok {
+		addr = p.Addr.String()
+	}
+	h := tr.RequestHeader()
+	return biz.Client{Device: device, UserAgent: h.Get("User-Agent"), IP: s.proxies.ClientIP(addr, h.Get("X-Forwarded-For"))}
+}
+
+// clientFromRequest is clientFromContext for plain HTTP handlers.
+func (s *AuthService) clientFromRequest(r *http.Request, device string) biz.Client {
+	return biz.Client{Device: device, UserAgent: r.UserAgent(), IP: s.proxies.ClientIP(r.RemoteAddr, r.Header.Get("X-Forwarded-For"))}
+}
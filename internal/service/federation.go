@@ -0,0 +1,159 @@
+package service
+
+import (
+	"context"
+	"github.com/go-kratos/kratos/v2/errors"
+	"go.opentelemetry.io/otel"
+	"net/http"
+	"net/url"
+	"strings"
+	"users/internal/biz"
+)
+
+// federationCookie ties the callback of a federated login to the browser
+// that started it.
+const federationCookie = "users_federation"
+
+// Federation serves federated login under biz.FederationPath.
+// <provider>/login sends the browser to an upstream provider, back to the
+// return_to path afterwards; with link=1 the upstream account is linked to
+// the user the browser is logged in as instead. <provider>/callback is where
+// the provider sends the browser back to.
+func (s *AuthService) Federation(w http.ResponseWriter, r *http.Request) {
+	if !s.oidc.Enabled() {
+		http.NotFound(w, r)
+		return
+	}
+	ctx, span := otel.Tracer("users").Start(r.Context(), "Federation")
+	defer span.End()
+	provider, endpoint, _ := strings.Cut(strings.TrimPrefix(r.URL.Path, biz.FederationPath), "/")
+	if endpoint != "login" && endpoint != "callback" {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method != http.MethodGet {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if endpoint == "login" {
+		s.federationLogin(ctx, w, r, provider)
+		return
+	}
+	s.federationCallback(ctx, w, r, provider)
+}
+
+func (s *AuthService) federationLogin(ctx context.Context, w http.ResponseWriter, r *http.Request, provider string) {
+	var link *biz.Session
+	if r.URL.Query().Get("link") == "1" {
+		if c, err := r.Cookie(oidcCookie); err == nil {
+			session, err := s.oidc.BrowserSession(ctx, c.Value)
+			if err != nil {
+				s.federationError(ctx, w, err)
+				return
+			}
+			link = session
+		}
+		if link == nil {
+			s.federationError(ctx, w, errors.Unauthorized("federation.link", "log in before linking an account"))
+			return
+		}
+	}
+	location, cookie, err := s.federation.StartLogin(ctx, provider, r.URL.Query().Get("return_to"), link)
+	if err != nil {
+		s.federationError(ctx, w, err)
+		return
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     federationCookie,
+		Value:    cookie,
+		Path:     biz.FederationPath,
+		MaxAge:   int(biz.FederationFlowTTL.Seconds()),
+		Secure:   s.oidc.SecureCookies(),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	http.Redirect(w, r, location, http.StatusFound)
+}
+
+func (s *AuthService) federationCallback(ctx context.Context, w http.ResponseWriter, r *http.Request, provider string) {
+	var cookie string
+	if c, err := r.Cookie(federationCookie); err == nil {
+		cookie = c.Value
+	}
+	// the flow is over whatever the outcome
+	http.SetCookie(w, &http.Cookie{
+		Name:     federationCookie,
+		Path:     biz.FederationPath,
+		MaxAge:   -1,
+		Secure:   s.oidc.SecureCookies(),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+	res, err := s.federation.Callback(ctx, provider, r.URL.Query(), cookie, s.clientFromRequest(r, ""))
+	if err != nil {
+		s.federationError(ctx, w, err)
+		return
+	}
+	if res.Cookie != "" {
+		s.setLoginCookie(w, res.Session, res.Cookie)
+	}
+	if res.PendingMFA != "" {
+		// the authorization request asks for the second factor once back
+		s.setMFACookie(w, res.PendingMFA)
+		s.log.WithContext(ctx).Infof("Federation: %s login waiting for a second factor", provider)
+		http.Redirect(w, r, res.ReturnTo, http.StatusFound)
+		return
+	}
+	if res.Linked {
+		s.log.WithContext(ctx).Infof("Federation: linked %s account to user %s", provider, res.Session.UserID)
+	} else {
+		s.log.WithContext(ctx).Infof("Federation: %s login to session %s", provider, res.Session.ID)
+	}
+	http.Redirect(w, r, res.ReturnTo, http.StatusFound)
+}
+
+func (s *AuthService) federationError(ctx context.Context, w http.ResponseWriter, err error) {
+	e := errors.FromError(err)
+	if e.Code >= http.StatusInternalServerError {
+		s.log.WithContext(ctx).Errorf("Federation: %s", err)
+		if e.Code != http.StatusServiceUnavailable {
+			e.Message = "something went wrong, please try again later"
+		}
+	} else {
+		s.log.WithContext(ctx).Warnf("Federation: %s", err)
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	http.Error(w, e.Message, int(e.Code))
+}
+
+// providerLinks returns the federated logins offered on the login page of an
+// authorization request, which they come back to.
+func (s *AuthService) providerLinks(params url.Values) []providerLink {
+	providers := s.federation.Providers()
+	if len(providers) == 0 {
+		return nil
+	}
+	// the request must not ask for a login again once back
+	back := url.Values{}
+	for k, v := range params {
+		back[k] = v
+	}
+	if prompt := back.Get("prompt"); prompt != "" {
+		var kept []string
+		for _, p := range strings.Fields(prompt) {
+			if p != "login" {
+				kept = append(kept, p)
+			}
+		}
+		back.Set("prompt", strings.Join(kept, " "))
+	}
+	returnTo := biz.OIDCAuthorizePath + "?" + back.Encode()
+	res := make([]providerLink, len(providers))
+	for i, p := range providers {
+		res[i] = providerLink{
+			Name: p.DisplayName,
+			URL:  biz.FederationPath + p.Name + "/login?" + url.Values{"return_to": {returnTo}}.Encode(),
+		}
+	}
+	return res
+}
@@ -0,0 +1,469 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/go-kratos/kratos/v2/errors"
+	"go.opentelemetry.io/otel"
+	"google.golang.org/protobuf/types/known/timestamppb"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"users/internal/biz"
+
+	pb "users/api/auth/v1"
+)
+
+// oidcCookie holds the session browsers are logged in to the provider with,
+// and oidcMFACookie a login waiting for the second factor of its user.
+const (
+	oidcCookie    = "users_sso"
+	oidcMFACookie = "users_sso_mfa"
+)
+
+// scopeDescriptions are shown on the consent page.
+var scopeDescriptions = map[string]string{
+	"openid":  "Know who you are",
+	"profile": "See your username and picture",
+	"email":   "See your email address",
+	"phone":   "See your phone number",
+}
+
+var oidcPages = template.Must(template.New("oidc").Parse(`{{define "login"}}<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Log in</title></head>
+<body>
+<h1>Log in to continue to {{.Client}}</h1>
+{{with .Error}}<p role="alert">{{.}}</p>{{end}}
+<form method="post" action="{{.Action}}">
+{{range $k, $v := .Params}}{{range $v}}<input type="hidden" name="{{$k}}" value="{{.}}">
+{{end}}{{end}}<label>Username or email <input name="login" autocomplete="username" required autofocus></label>
+<label>Password <input name="password" type="password" autocomplete="current-password" required></label>
+<button name="action" value="login">Log in</button>
+</form>
+{{with .Providers}}<p>Or log in with</p>
+<ul>
+{{range .}}<li><a href="{{.URL}}">{{.Name}}</a></li>
+{{end}}</ul>
+{{end}}</body>
+</html>
+{{end}}{{define "mfa"}}<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Verify it is you</title></head>
+<body>
+<h1>Enter the code of your authenticator app to continue to {{.Client}}</h1>
+{{with .Error}}<p role="alert">{{.}}</p>{{end}}
+<form method="post" action="{{.Action}}">
+{{range $k, $v := .Params}}{{range $v}}<input type="hidden" name="{{$k}}" value="{{.}}">
+{{end}}{{end}}<label>Code or recovery code <input name="mfa_code" autocomplete="one-time-code" required autofocus></label>
+<button name="action" value="mfa">Verify</button>
+</form>
+</body>
+</html>
+{{end}}{{define "consent"}}<!DOCTYPE html>
+<html>
+<head><meta charset="utf-8"><title>Authorize {{.Client}}</title></head>
+<body>
+<h1>{{.Client}} would like to</h1>
+<ul>
+{{range .Scopes}}<li>{{.}}</li>
+{{end}}</ul>
+<form method="post" action="{{.Action}}">
+{{range $k, $v := .Params}}{{range $v}}<input type="hidden" name="{{$k}}" value="{{.}}">
+{{end}}{{end}}<input type="hidden" name="consent" value="{{.Token}}">
+<button name="action" value="allow">Allow</button>
+<button name="action" value="deny">Deny</button>
+</form>
+</body>
+</html>
+{{end}}`))
+
+// oidcPage is what the login and consent pages are rendered from. Params
+// are the parameters of the authorization request, posted back along with
+// the form.
+type oidcPage struct {
+	Client    string
+	Action    string
+	Params    url.Values
+	Error     string
+	Scopes    []string
+	Token     string
+	Providers []providerLink
+}
+
+// providerLink starts a federated login from the login page.
+type providerLink struct {
+	Name string
+	URL  string
+}
+
+func (s *AuthService) RegisterOIDCClient(ctx context.Context, req *pb.RegisterOIDCClientRequest) (*pb.RegisterOIDCClientReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "RegisterOIDCClient")
+	defer span.End()
+	res, secret, err := s.oidc.RegisterClient(ctx, req.GetName(), req.GetRedirectUris(), req.GetPublic(), req.GetTrusted())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("RegisterOIDCClient: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("RegisterOIDCClient: id %s", res.ID)
+	return &pb.RegisterOIDCClientReply{Client: oidcClient(res), Secret: secret}, nil
+}
+func (s *AuthService) ListOIDCClients(ctx context.Context, req *pb.ListOIDCClientsRequest) (*pb.ListOIDCClientsReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "ListOIDCClients")
+	defer span.End()
+	res, err := s.oidc.ListClients(ctx)
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("ListOIDCClients: %s", err)
+		return nil, err
+	}
+	clients := make([]*pb.OIDCClient, len(res))
+	for i := range res {
+		clients[i] = oidcClient(&res[i])
+	}
+	return &pb.ListOIDCClientsReply{Clients: clients}, nil
+}
+func (s *AuthService) DeleteOIDCClient(ctx context.Context, req *pb.DeleteOIDCClientRequest) (*pb.DeleteOIDCClientReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "DeleteOIDCClient")
+	defer span.End()
+	if err := s.oidc.DeleteClient(ctx, req.GetId()); err != nil {
+		s.log.WithContext(ctx).Warnf("DeleteOIDCClient: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("DeleteOIDCClient: id %s", req.GetId())
+	return &pb.DeleteOIDCClientReply{}, nil
+}
+
+// OIDCDiscovery serves the provider metadata at biz.OIDCDiscoveryPath.
+func (s *AuthService) OIDCDiscovery(w http.ResponseWriter, r *http.Request) {
+	if !s.oidc.Enabled() {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "public, max-age=300")
+	if err := json.NewEncoder(w).Encode(s.oidc.Discovery()); err != nil {
+		s.log.WithContext(r.Context()).Warnf("OIDCDiscovery: %s", err)
+	}
+}
+
+// OIDCAuthorize serves the authorization endpoint. It logs the browser in
+// and asks for consent as needed, through forms posted back to itself, then
+// redirects to the client with an authorization code.
+func (s *AuthService) OIDCAuthorize(w http.ResponseWriter, r *http.Request) {
+	if !s.oidc.Enabled() {
+		http.NotFound(w, r)
+		return
+	}
+	ctx, span := otel.Tracer("users").Start(r.Context(), "OIDCAuthorize")
+	defer span.End()
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, "malformed request", http.StatusBadRequest)
+		return
+	}
+	// the forms post the request back with their own fields, kept out of it
+	params := url.Values{}
+	for k, v := range r.Form {
+		switch k {
+		case "login", "password", "mfa_code", "action", "consent":
+		default:
+			params[k] = v
+		}
+	}
+	req, err := s.oidc.ParseAuthorizeRequest(ctx, params)
+	if req == nil {
+		s.log.WithContext(ctx).Warnf("OIDCAuthorize: %s", err)
+		e := errors.FromError(err)
+		http.Error(w, e.Message, int(e.Code))
+		return
+	}
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("OIDCAuthorize: %s", err)
+		http.Redirect(w, r, req.RedirectError(err), http.StatusFound)
+		return
+	}
+	page := &oidcPage{Client: req.Client.Name, Action: r.URL.Path, Params: params}
+	action := ""
+	if r.Method == http.MethodPost {
+		action = r.PostForm.Get("action")
+	}
+
+	var session *biz.Session
+	switch action {
+	case "login", "mfa":
+		var res *biz.BrowserAuth
+		if action == "login" {
+			res, err = s.oidc.BrowserLogin(ctx, r.PostForm.Get("login"), r.PostForm.Get("password"), s.clientFromRequest(r, ""))
+		} else {
+			var pending string
+			if c, err := r.Cookie(oidcMFACookie); err == nil {
+				pending = c.Value
+			}
+			res, err = s.oidc.VerifyBrowserMFA(ctx, pending, r.PostForm.Get("mfa_code"), s.clientFromRequest(r, ""))
+		}
+		if e := errors.FromError(err); err != nil && (e.Code == http.StatusUnauthorized || (action == "mfa" && e.Code == http.StatusTooManyRequests)) {
+			s.log.WithContext(ctx).Warnf("OIDCAuthorize: %s", err)
+			switch {
+			case action == "login":
+				page.Error = "Invalid username, email or password."
+			case e.Reason == "oidc.mfa":
+				// the pending login expired, start over
+				action = "login"
+				page.Error = "The login expired, please log in again."
+			case e.Code == http.StatusTooManyRequests:
+				page.Error = "Too many attempts, please try again later."
+			default:
+				page.Error = "Invalid code."
+			}
+			if action == "login" {
+				page.Providers = s.providerLinks(params)
+			}
+			s.renderOIDCPage(w, r, action, page)
+			return
+		}
+		if err != nil {
+			s.log.WithContext(ctx).Errorf("OIDCAuthorize: %s", err)
+			http.Redirect(w, r, req.RedirectError(err), http.StatusFound)
+			return
+		}
+		if res.PendingMFA != "" {
+			s.setMFACookie(w, res.PendingMFA)
+			s.log.WithContext(ctx).Info("OIDCAuthorize: second factor required")
+			s.renderOIDCPage(w, r, "mfa", page)
+			return
+		}
+		session = res.Session
+		if action == "mfa" {
+			s.setMFACookie(w, "")
+		}
+		s.setLoginCookie(w, session, res.Cookie)
+		s.log.WithContext(ctx).Infof("OIDCAuthorize: logged in session %s", session.ID)
+	default:
+		if c, err := r.Cookie(oidcCookie); err == nil {
+			session, err = s.oidc.BrowserSession(ctx, c.Value)
+			if err != nil {
+				s.log.WithContext(ctx).Errorf("OIDCAuthorize: %s", err)
+				http.Redirect(w, r, req.RedirectError(err), http.StatusFound)
+				return
+			}
+		}
+		// a login the client asked for must happen now, however recent the
+		// session
+		if session != nil && req.Prompts("login") && action == "" {
+			session = nil
+		}
+		// e.g. a federated login came back for a user with a second factor
+		if c, err := r.Cookie(oidcMFACookie); err == nil && session == nil && !req.Prompts("none") && s.oidc.HasPendingMFA(c.Value) {
+			s.renderOIDCPage(w, r, "mfa", page)
+			return
+		}
+	}
+	if session == nil {
+		if req.Prompts("none") {
+			http.Redirect(w, r, req.RedirectError(errors.BadRequest("login_required", "the user is not logged in")), http.StatusFound)
+			return
+		}
+		page.Providers = s.providerLinks(params)
+		s.renderOIDCPage(w, r, "login", page)
+		return
+	}
+
+	switch action {
+	case "allow", "deny":
+		err = s.oidc.Consent(ctx, session, req, r.PostForm.Get("consent"), action == "allow")
+	default:
+		var needed bool
+		needed, err = s.oidc.NeedsConsent(ctx, session, req)
+		if err == nil && (needed || req.Prompts("consent")) {
+			if req.Prompts("none") {
+				err = errors.BadRequest("consent_required", "the user has not granted the requested scopes")
+				break
+			}
+			page.Token, err = s.oidc.ConsentToken(session, req)
+			if err == nil {
+				for _, scope := range strings.Fields(req.Scope) {
+					page.Scopes = append(page.Scopes, scopeDescriptions[scope])
+				}
+				s.renderOIDCPage(w, r, "consent", page)
+				return
+			}
+		}
+	}
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("OIDCAuthorize: %s", err)
+		http.Redirect(w, r, req.RedirectError(err), http.StatusFound)
+		return
+	}
+	location, err := s.oidc.IssueCode(ctx, session, req)
+	if err != nil {
+		s.log.WithContext(ctx).Errorf("OIDCAuthorize: %s", err)
+		http.Redirect(w, r, req.RedirectError(err), http.StatusFound)
+		return
+	}
+	s.log.WithContext(ctx).Infof("OIDCAuthorize: code issued to %s for session %s", req.Client.ID, session.ID)
+	http.Redirect(w, r, location, http.StatusFound)
+}
+
+// OIDCToken serves the token endpoint. Clients authenticate with HTTP basic
+// authentication or with client_id and client_secret form fields; public
+// clients send their client_id only.
+func (s *AuthService) OIDCToken(w http.ResponseWriter, r *http.Request) {
+	if !s.oidc.Enabled() {
+		http.NotFound(w, r)
+		return
+	}
+	ctx, span := otel.Tracer("users").Start(r.Context(), "OIDCToken")
+	defer span.End()
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		writeOAuthError(w, errors.New(http.StatusMethodNotAllowed, "invalid_request", "the token endpoint only accepts POST"))
+		return
+	}
+	if err := r.ParseForm(); err != nil {
+		writeOAuthError(w, errors.BadRequest("invalid_request", "malformed request"))
+		return
+	}
+	req := &biz.TokenRequest{
+		GrantType:    r.PostForm.Get("grant_type"),
+		Code:         r.PostForm.Get("code"),
+		RedirectURI:  r.PostForm.Get("redirect_uri"),
+		CodeVerifier: r.PostForm.Get("code_verifier"),
+		RefreshToken: r.PostForm.Get("refresh_token"),
+		ClientID:     r.PostForm.Get("client_id"),
+		ClientSecret: r.PostForm.Get("client_secret"),
+	}
+	id, secret, basic := r.BasicAuth()
+	if basic {
+		// credentials are form encoded before going into the header
+		id, _ = url.QueryUnescape(id)
+		secret, _ = url.QueryUnescape(secret)
+		req.ClientID, req.ClientSecret = id, secret
+	}
+	res, err := s.oidc.Token(ctx, req, s.clientFromRequest(r, ""))
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("OIDCToken: %s", err)
+		if basic && errors.IsUnauthorized(err) {
+			w.Header().Set("WWW-Authenticate", `Basic realm="token"`)
+		}
+		writeOAuthError(w, err)
+		return
+	}
+	s.log.WithContext(ctx).Infof("OIDCToken: %s grant for %s", req.GrantType, req.ClientID)
+	body := map[string]interface{}{
+		"access_token":  res.AccessToken,
+		"token_type":    "Bearer",
+		"expires_in":    int64(time.Until(res.AccessExpiresAt).Seconds()),
+		"refresh_token": res.RefreshToken,
+	}
+	if res.IDToken != "" {
+		body["id_token"] = res.IDToken
+	}
+	if res.Scope != "" {
+		body["scope"] = res.Scope
+	}
+	writeOAuthJSON(w, http.StatusOK, body)
+}
+
+// OIDCUserInfo serves the claims about the user of a bearer access token.
+func (s *AuthService) OIDCUserInfo(w http.ResponseWriter, r *http.Request) {
+	if !s.oidc.Enabled() {
+		http.NotFound(w, r)
+		return
+	}
+	ctx, span := otel.Tracer("users").Start(r.Context(), "OIDCUserInfo")
+	defer span.End()
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer`)
+		writeOAuthError(w, errors.Unauthorized("invalid_token", "missing bearer token"))
+		return
+	}
+	res, err := s.oidc.UserInfo(ctx, token)
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("OIDCUserInfo: %s", err)
+		e := errors.FromError(err)
+		if e.Code < http.StatusInternalServerError {
+			w.Header().Set("WWW-Authenticate", `Bearer error="`+e.Reason+`"`)
+		}
+		writeOAuthError(w, err)
+		return
+	}
+	writeOAuthJSON(w, http.StatusOK, res)
+}
+
+// setLoginCookie logs the browser in to the provider with session.
+func (s *AuthService) setLoginCookie(w http.ResponseWriter, session *biz.Session, value string) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcCookie,
+		Value:    value,
+		Path:     "/oauth2/",
+		Expires:  session.ExpiresAt,
+		Secure:   s.oidc.SecureCookies(),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// setMFACookie holds a login waiting for a second factor in the browser,
+// or clears it when value is empty.
+func (s *AuthService) setMFACookie(w http.ResponseWriter, value string) {
+	maxAge := int(biz.MFALoginTTL.Seconds())
+	if value == "" {
+		maxAge = -1
+	}
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcMFACookie,
+		Value:    value,
+		Path:     "/oauth2/",
+		MaxAge:   maxAge,
+		Secure:   s.oidc.SecureCookies(),
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (s *AuthService) renderOIDCPage(w http.ResponseWriter, r *http.Request, name string, page *oidcPage) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	// the pages must not be framed, or users could be tricked into consenting
+	w.Header().Set("X-Frame-Options", "DENY")
+	w.Header().Set("Content-Security-Policy", "frame-ancestors 'none'")
+	if err := oidcPages.ExecuteTemplate(w, name, page); err != nil {
+		s.log.WithContext(r.Context()).Warnf("OIDCAuthorize: %s", err)
+	}
+}
+
+// writeOAuthError writes an error response of the token and userinfo
+// endpoints, whose error code is the reason of err.
+func writeOAuthError(w http.ResponseWriter, err error) {
+	e := errors.FromError(err)
+	body := map[string]string{"error": e.Reason, "error_description": e.Message}
+	if e.Code >= http.StatusInternalServerError || e.Reason == "" {
+		body = map[string]string{"error": "server_error"}
+	}
+	writeOAuthJSON(w, int(e.Code), body)
+}
+
+func writeOAuthJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+func oidcClient(c *biz.OIDCClient) *pb.OIDCClient {
+	return &pb.OIDCClient{
+		Id:           c.ID.String(),
+		Name:         c.Name,
+		RedirectUris: c.RedirectURIs,
+		Public:       c.Public(),
+		Trusted:      c.Trusted,
+		CreateTime:   timestamppb.New(c.CreatedAt),
+	}
+}
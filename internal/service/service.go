@@ -2,4 +2,4 @@ package service
 
 import "github.com/google/wire"
 
-var ProviderSet = wire.NewSet(NewUsersService)
+var ProviderSet = wire.NewSet(NewUsersService, NewAuthService)
@@ -2,23 +2,35 @@ package service
 
 import (
 	"context"
+	"encoding/base64"
+	"strings"
 	"users/internal/biz"
 	"github.com/go-kratos/kratos/v2/errors"
 	"github.com/go-kratos/kratos/v2/log"
+	"github.com/google/uuid"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"google.golang.org/protobuf/types/known/timestamppb"
 
 	pb "users/api/users/v1"
 )
 
 type UsersService struct {
 	pb.UnimplementedUsersServer
-	uc  *biz.UsersUsecase
-	log *log.Helper
+	uc         *biz.UsersUsecase
+	creds      *biz.CredentialsUsecase
+	access     *biz.AccessUsecase
+	mfa        *biz.MFAUsecase
+	verify     *biz.VerificationUsecase
+	reset      *biz.PasswordResetUsecase
+	federation *biz.FederationUsecase
+	passkeys   *biz.PasskeysUsecase
+	attempts   *biz.AttemptLimiter
+	log        *log.Helper
 }
 
-func NewUsersService(uc *biz.UsersUsecase, logger log.Logger) *UsersService {
-	return &UsersService{uc: uc, log: log.NewHelper(logger)}
+func NewUsersService(uc *biz.UsersUsecase, creds *biz.CredentialsUsecase, access *biz.AccessUsecase, mfa *biz.MFAUsecase, verify *biz.VerificationUsecase, reset *biz.PasswordResetUsecase, federation *biz.FederationUsecase, passkeys *biz.PasskeysUsecase, attempts *biz.AttemptLimiter, logger log.Logger) *UsersService {
+	return &UsersService{uc: uc, creds: creds, access: access, mfa: mfa, verify: verify, reset: reset, federation: federation, passkeys: passkeys, attempts: attempts, log: log.NewHelper(logger)}
 }
 
 func (s *UsersService) CreateUsers(ctx context.Context, req *pb.CreateUsersRequest) (*pb.CreateUsersReply, error) {
@@ -110,7 +122,11 @@ func (s *UsersService) GetUsers(ctx context.Context, req *pb.GetUsersRequest) (*
 	_, span := otel.Tracer("users").Start(ctx, "GetUsers")
 	defer span.End()
 	id := req.GetId()
-	res, err := s.uc.GetByID(ctx, id)
+	deleted := biz.ExcludeDeleted
+	if req.GetShowDeleted() {
+		deleted = biz.IncludeDeleted
+	}
+	res, err := s.uc.GetByID(ctx, id, deleted)
 	if err != nil {
 		s.log.WithContext(ctx).Warnf("GetUsers: %s", err)
 		return nil, err
@@ -121,9 +137,88 @@ func (s *UsersService) GetUsers(ctx context.Context, req *pb.GetUsersRequest) (*
 		Email:    *res.Email,
 		Phone:    res.Phone,
 	}
+	if res.DeletedAt != nil {
+		resp.DeletedAt = timestamppb.New(*res.DeletedAt)
+	}
+	if res.EmailVerifiedAt != nil {
+		resp.EmailVerifiedAt = timestamppb.New(*res.EmailVerifiedAt)
+	}
+	if res.PhoneVerifiedAt != nil {
+		resp.PhoneVerifiedAt = timestamppb.New(*res.PhoneVerifiedAt)
+	}
 	s.log.WithContext(ctx).Infof("GetUsers: id %s", resp.Id)
 	return resp, nil
 }
+func (s *UsersService) LookupUser(ctx context.Context, req *pb.LookupUserRequest) (*pb.LookupUserReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "LookupUser")
+	defer span.End()
+	var key biz.LookupKey
+	switch k := req.GetKey().(type) {
+	case *pb.LookupUserRequest_Username:
+		key = biz.LookupKey{Field: biz.FieldUsername, Value: k.Username}
+	case *pb.LookupUserRequest_Email:
+		key = biz.LookupKey{Field: biz.FieldEmail, Value: k.Email}
+	case *pb.LookupUserRequest_Phone:
+		key = biz.LookupKey{Field: biz.FieldPhone, Value: k.Phone}
+	}
+	span.SetAttributes(attribute.String("lookup.key", string(key.Field)))
+	res, err := s.uc.LookupUser(ctx, key)
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("LookupUser: %s", err)
+		return nil, err
+	}
+	resp := &pb.LookupUserReply{
+		Id:       res.ID,
+		Username: *res.Username,
+		Email:    *res.Email,
+		Phone:    res.Phone,
+	}
+	s.log.WithContext(ctx).Infof("LookupUser: id %s", resp.Id)
+	return resp, nil
+}
+func (s *UsersService) BatchGetUsers(ctx context.Context, req *pb.BatchGetUsersRequest) (*pb.BatchGetUsersReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "BatchGetUsers")
+	defer span.End()
+	// accept ids=a,b as well as repeated ids=a&ids=b
+	var ids []string
+	for _, entry := range req.GetIds() {
+		for _, id := range strings.Split(entry, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	res, err := s.uc.BatchGetUsers(ctx, ids)
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("BatchGetUsers: %s", err)
+		return nil, err
+	}
+	results := make([]*pb.BatchGetUsersResult, len(ids))
+	for i, user := range res {
+		results[i] = &pb.BatchGetUsersResult{Id: ids[i]}
+		if user == nil {
+			continue
+		}
+		results[i].Found = true
+		results[i].User = &pb.GetUsersReply{
+			Id:       user.ID,
+			Username: *user.Username,
+			Email:    *user.Email,
+			Phone:    user.Phone,
+		}
+		if user.EmailVerifiedAt != nil {
+			results[i].User.EmailVerifiedAt = timestamppb.New(*user.EmailVerifiedAt)
+		}
+		if user.PhoneVerifiedAt != nil {
+			results[i].User.PhoneVerifiedAt = timestamppb.New(*user.PhoneVerifiedAt)
+		}
+	}
+	resp := &pb.BatchGetUsersReply{
+		Results: results,
+	}
+	s.log.WithContext(ctx).Infof("BatchGetUsers: %d requested", len(ids))
+	return resp, nil
+}
 func (s *UsersService) ListUsers(ctx context.Context, req *pb.ListUsersRequest) (*pb.ListUsersReply, error) {
 	_, span := otel.Tracer("users").Start(ctx, "ListUsers")
 	defer span.End()
@@ -140,20 +235,42 @@ func (s *UsersService) ListUsers(ctx context.Context, req *pb.ListUsersRequest)
 		s.log.WithContext(ctx).Warn("pageSize must be greater than 0, default to 20")
 	}
 	pp := biz.PaginationParams{
-		Page:     int(page),
-		PageSize: int(pageSize),
-		Reverse:  reverse,
+		Page:          int(page),
+		PageSize:      int(pageSize),
+		Reverse:       reverse,
+		PageToken:     req.GetPageToken(),
+		EstimateTotal: req.GetEstimateTotal(),
 	}
 
-	sp := biz.SortParams{}
-	if req.SortBy != nil {
-		sp.SortBy = *req.SortBy
+	sp, err := biz.ParseSort(req.GetSortBy(), req.GetSortOrder())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("ListUsers: %s", err)
+		return nil, err
 	}
-	if req.SortOrder != nil {
-		sp.SortOrder = *req.SortOrder
+
+	filters, err := biz.ParseFilters(req.GetFilters())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("ListUsers: %s", err)
+		return nil, err
+	}
+	fields, err := biz.ParseFields(req.GetFields())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("ListUsers: %s", err)
+		return nil, err
+	}
+	qp := biz.QueryParams{
+		Search:  req.GetQuery(),
+		Filters: filters,
+		Fields:  fields,
+	}
+	switch {
+	case req.GetOnlyDeleted():
+		qp.Deleted = biz.OnlyDeleted
+	case req.GetShowDeleted():
+		qp.Deleted = biz.IncludeDeleted
 	}
 
-	res, err := s.uc.ListUsers(ctx, pp, sp)
+	res, err := s.uc.ListUsers(ctx, pp, sp, qp)
 	if err != nil {
 		s.log.WithContext(ctx).Warnf("ListUsers: %s", err)
 		return nil, err
@@ -166,15 +283,397 @@ func (s *UsersService) ListUsers(ctx context.Context, req *pb.ListUsersRequest)
 			Email:    user.Email,
 			Phone:    user.Phone,
 		}
+		if user.DeletedAt != nil {
+			listUsers[i].DeletedAt = timestamppb.New(*user.DeletedAt)
+		}
+		if user.EmailVerifiedAt != nil {
+			listUsers[i].EmailVerifiedAt = timestamppb.New(*user.EmailVerifiedAt)
+		}
+		if user.PhoneVerifiedAt != nil {
+			listUsers[i].PhoneVerifiedAt = timestamppb.New(*user.PhoneVerifiedAt)
+		}
 	}
 	resp := &pb.ListUsersReply{
-		Users:      listUsers,
-		Page:       int32(res.Page),
-		PageSize:   int32(res.PageSize),
-		Total:      int32(res.Total),
-		TotalPages: int32(res.TotalPages),
-		Reverse:    res.Reverse,
+		Users:          listUsers,
+		Page:           int32(res.Page),
+		PageSize:       int32(res.PageSize),
+		Total:          int32(res.Total),
+		TotalPages:     int32(res.TotalPages),
+		Reverse:        res.Reverse,
+		NextPageToken:  res.NextPageToken,
+		HasMore:        res.HasMore,
+		TotalEstimated: res.TotalEstimated,
 	}
 	s.log.WithContext(ctx).Infof("ListUsers: list of %d elements", len(resp.Users))
 	return resp, nil
 }
+func (s *UsersService) RestoreUsers(ctx context.Context, req *pb.RestoreUsersRequest) (*pb.RestoreUsersReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "RestoreUsers")
+	defer span.End()
+	id := req.GetId()
+	res, err := s.uc.RestoreUsers(ctx, id)
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("RestoreUsers: %s", err)
+		return nil, err
+	}
+	resp := &pb.RestoreUsersReply{
+		Id:       res.ID,
+		Username: *res.Username,
+		Email:    *res.Email,
+		Phone:    res.Phone,
+	}
+	s.log.WithContext(ctx).Infof("RestoreUsers: id %s", resp.Id)
+	return resp, nil
+}
+func (s *UsersService) PurgeUsers(ctx context.Context, req *pb.PurgeUsersRequest) (*pb.PurgeUsersReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "PurgeUsers")
+	defer span.End()
+	id := req.GetId()
+	_, err := s.uc.PurgeUsers(ctx, id)
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("PurgeUsers: %s", err)
+		return nil, err
+	}
+	resp := &pb.PurgeUsersReply{
+		Id: id,
+	}
+	s.log.WithContext(ctx).Infof("PurgeUsers: id %s", resp.Id)
+	return resp, nil
+}
+func (s *UsersService) SetPassword(ctx context.Context, req *pb.SetPasswordRequest) (*pb.SetPasswordReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "SetPassword")
+	defer span.End()
+	id := req.GetId()
+	err := s.creds.SetPassword(ctx, id, req.GetPassword())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("SetPassword: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("SetPassword: id %s", id)
+	return &pb.SetPasswordReply{Id: id}, nil
+}
+func (s *UsersService) ChangePassword(ctx context.Context, req *pb.ChangePasswordRequest) (*pb.ChangePasswordReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "ChangePassword")
+	defer span.End()
+	id := req.GetId()
+	// changing a password takes the current one, so it is only for oneself
+	if p, ok := biz.PrincipalFromContext(ctx); !ok || p.UserID != id {
+		err := errors.Forbidden("users.changePassword", "users can only change their own password")
+		s.log.WithContext(ctx).Warnf("ChangePassword: %s", err)
+		return nil, err
+	}
+	err := s.creds.ChangePassword(ctx, id, req.GetCurrentPassword(), req.GetNewPassword())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("ChangePassword: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("ChangePassword: id %s", id)
+	return &pb.ChangePasswordReply{Id: id}, nil
+}
+func (s *UsersService) VerifyPassword(ctx context.Context, req *pb.VerifyPasswordRequest) (*pb.VerifyPasswordReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "VerifyPassword")
+	defer span.End()
+	id := req.GetId()
+	valid, err := s.creds.VerifyPassword(ctx, id, req.GetPassword())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("VerifyPassword: %s", err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.Bool("password.valid", valid))
+	s.log.WithContext(ctx).Infof("VerifyPassword: id %s valid %t", id, valid)
+	return &pb.VerifyPasswordReply{Valid: valid}, nil
+}
+func (s *UsersService) ListUserRoles(ctx context.Context, req *pb.ListUserRolesRequest) (*pb.ListUserRolesReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "ListUserRoles")
+	defer span.End()
+	id := req.GetId()
+	roles, err := s.access.UserRoles(ctx, id)
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("ListUserRoles: %s", err)
+		return nil, err
+	}
+	return &pb.ListUserRolesReply{Id: id, Roles: roles}, nil
+}
+func (s *UsersService) AssignRole(ctx context.Context, req *pb.AssignRoleRequest) (*pb.AssignRoleReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "AssignRole")
+	defer span.End()
+	id := req.GetId()
+	span.SetAttributes(attribute.String("role", req.GetRole()))
+	roles, err := s.access.AssignRole(ctx, id, req.GetRole())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("AssignRole: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("AssignRole: id %s role %s", id, req.GetRole())
+	return &pb.AssignRoleReply{Id: id, Roles: roles}, nil
+}
+func (s *UsersService) RevokeRole(ctx context.Context, req *pb.RevokeRoleRequest) (*pb.RevokeRoleReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "RevokeRole")
+	defer span.End()
+	id := req.GetId()
+	span.SetAttributes(attribute.String("role", req.GetRole()))
+	roles, err := s.access.RevokeRole(ctx, id, req.GetRole())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("RevokeRole: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("RevokeRole: id %s role %s", id, req.GetRole())
+	return &pb.RevokeRoleReply{Id: id, Roles: roles}, nil
+}
+func (s *UsersService) EnrollTOTP(ctx context.Context, req *pb.EnrollTOTPRequest) (*pb.EnrollTOTPReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "EnrollTOTP")
+	defer span.End()
+	id := req.GetId()
+	secret, uri, err := s.mfa.EnrollTOTP(ctx, id)
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("EnrollTOTP: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("EnrollTOTP: id %s", id)
+	return &pb.EnrollTOTPReply{Secret: secret, ProvisioningUri: uri}, nil
+}
+func (s *UsersService) ConfirmTOTP(ctx context.Context, req *pb.ConfirmTOTPRequest) (*pb.ConfirmTOTPReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "ConfirmTOTP")
+	defer span.End()
+	id := req.GetId()
+	codes, err := s.mfa.ConfirmTOTP(ctx, id, req.GetCode())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("ConfirmTOTP: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("ConfirmTOTP: id %s", id)
+	return &pb.ConfirmTOTPReply{RecoveryCodes: codes}, nil
+}
+func (s *UsersService) VerifyTOTP(ctx context.Context, req *pb.VerifyTOTPRequest) (*pb.VerifyTOTPReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "VerifyTOTP")
+	defer span.End()
+	id := req.GetId()
+	res, err := s.mfa.VerifyTOTP(ctx, id, req.GetCode())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("VerifyTOTP: %s", err)
+		return nil, err
+	}
+	span.SetAttributes(attribute.Bool("mfa.valid", res.Valid))
+	s.log.WithContext(ctx).Infof("VerifyTOTP: id %s valid %t", id, res.Valid)
+	return &pb.VerifyTOTPReply{
+		Valid:                  res.Valid,
+		RecoveryCodeUsed:       res.RecoveryCodeUsed,
+		RecoveryCodesRemaining: int32(res.RecoveryCodesRemaining),
+	}, nil
+}
+func (s *UsersService) RegenerateRecoveryCodes(ctx context.Context, req *pb.RegenerateRecoveryCodesRequest) (*pb.RegenerateRecoveryCodesReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "RegenerateRecoveryCodes")
+	defer span.End()
+	id := req.GetId()
+	codes, err := s.mfa.RegenerateRecoveryCodes(ctx, id)
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("RegenerateRecoveryCodes: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("RegenerateRecoveryCodes: id %s", id)
+	return &pb.RegenerateRecoveryCodesReply{RecoveryCodes: codes}, nil
+}
+func (s *UsersService) ResetTOTP(ctx context.Context, req *pb.ResetTOTPRequest) (*pb.ResetTOTPReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "ResetTOTP")
+	defer span.End()
+	id := req.GetId()
+	if err := s.mfa.ResetTOTP(ctx, id); err != nil {
+		s.log.WithContext(ctx).Warnf("ResetTOTP: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("ResetTOTP: id %s", id)
+	return &pb.ResetTOTPReply{Id: id}, nil
+}
+func (s *UsersService) UnlockUser(ctx context.Context, req *pb.UnlockUserRequest) (*pb.UnlockUserReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "UnlockUser")
+	defer span.End()
+	id := req.GetId()
+	if err := s.attempts.Unlock(ctx, id); err != nil {
+		s.log.WithContext(ctx).Warnf("UnlockUser: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("UnlockUser: id %s", id)
+	return &pb.UnlockUserReply{Id: id}, nil
+}
+func (s *UsersService) SendEmailVerification(ctx context.Context, req *pb.SendEmailVerificationRequest) (*pb.SendEmailVerificationReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "SendEmailVerification")
+	defer span.End()
+	id := req.GetId()
+	if err := s.verify.SendEmailVerification(ctx, id); err != nil {
+		s.log.WithContext(ctx).Warnf("SendEmailVerification: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("SendEmailVerification: id %s", id)
+	return &pb.SendEmailVerificationReply{Id: id}, nil
+}
+func (s *UsersService) ConfirmEmail(ctx context.Context, req *pb.ConfirmEmailRequest) (*pb.ConfirmEmailReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "ConfirmEmail")
+	defer span.End()
+	res, err := s.verify.ConfirmEmail(ctx, req.GetToken())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("ConfirmEmail: %s", err)
+		return nil, err
+	}
+	resp := &pb.ConfirmEmailReply{
+		Id:    res.ID,
+		Email: *res.Email,
+	}
+	if res.EmailVerifiedAt != nil {
+		resp.EmailVerifiedAt = timestamppb.New(*res.EmailVerifiedAt)
+	}
+	s.log.WithContext(ctx).Infof("ConfirmEmail: id %s", resp.Id)
+	return resp, nil
+}
+func (s *UsersService) SendPhoneVerification(ctx context.Context, req *pb.SendPhoneVerificationRequest) (*pb.SendPhoneVerificationReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "SendPhoneVerification")
+	defer span.End()
+	id := req.GetId()
+	if err := s.verify.SendPhoneVerification(ctx, id); err != nil {
+		s.log.WithContext(ctx).Warnf("SendPhoneVerification: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("SendPhoneVerification: id %s", id)
+	return &pb.SendPhoneVerificationReply{Id: id}, nil
+}
+func (s *UsersService) ConfirmPhone(ctx context.Context, req *pb.ConfirmPhoneRequest) (*pb.ConfirmPhoneReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "ConfirmPhone")
+	defer span.End()
+	res, err := s.verify.ConfirmPhone(ctx, req.GetId(), req.GetCode())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("ConfirmPhone: %s", err)
+		return nil, err
+	}
+	resp := &pb.ConfirmPhoneReply{
+		Id:    res.ID,
+		Phone: *res.Phone,
+	}
+	if res.PhoneVerifiedAt != nil {
+		resp.PhoneVerifiedAt = timestamppb.New(*res.PhoneVerifiedAt)
+	}
+	s.log.WithContext(ctx).Infof("ConfirmPhone: id %s", resp.Id)
+	return resp, nil
+}
+func (s *UsersService) RequestPasswordReset(ctx context.Context, req *pb.RequestPasswordResetRequest) (*pb.RequestPasswordResetReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "RequestPasswordReset")
+	defer span.End()
+	if err := s.reset.RequestPasswordReset(ctx, req.GetEmail()); err != nil {
+		s.log.WithContext(ctx).Warnf("RequestPasswordReset: %s", err)
+		return nil, err
+	}
+	return &pb.RequestPasswordResetReply{}, nil
+}
+func (s *UsersService) ResetPassword(ctx context.Context, req *pb.ResetPasswordRequest) (*pb.ResetPasswordReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "ResetPassword")
+	defer span.End()
+	if err := s.reset.ResetPassword(ctx, req.GetToken(), req.GetNewPassword()); err != nil {
+		s.log.WithContext(ctx).Warnf("ResetPassword: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("ResetPassword: password reset")
+	return &pb.ResetPasswordReply{}, nil
+}
+func (s *UsersService) ListExternalIdentities(ctx context.Context, req *pb.ListExternalIdentitiesRequest) (*pb.ListExternalIdentitiesReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "ListExternalIdentities")
+	defer span.End()
+	res, err := s.federation.ListIdentities(ctx, req.GetId())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("ListExternalIdentities: %s", err)
+		return nil, err
+	}
+	identities := make([]*pb.ExternalIdentity, len(res))
+	for i, identity := range res {
+		identities[i] = &pb.ExternalIdentity{
+			Id:            identity.ID.String(),
+			Provider:      identity.Provider,
+			Issuer:        identity.Issuer,
+			Subject:       identity.Subject,
+			Email:         identity.Email,
+			CreateTime:    timestamppb.New(identity.CreatedAt),
+			LastLoginTime: timestamppb.New(identity.LastLoginAt),
+		}
+	}
+	return &pb.ListExternalIdentitiesReply{Identities: identities}, nil
+}
+func (s *UsersService) UnlinkExternalIdentity(ctx context.Context, req *pb.UnlinkExternalIdentityRequest) (*pb.UnlinkExternalIdentityReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "UnlinkExternalIdentity")
+	defer span.End()
+	if err := s.federation.UnlinkIdentity(ctx, req.GetId(), req.GetIdentityId()); err != nil {
+		s.log.WithContext(ctx).Warnf("UnlinkExternalIdentity: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("UnlinkExternalIdentity: id %s identity %s", req.GetId(), req.GetIdentityId())
+	return &pb.UnlinkExternalIdentityReply{}, nil
+}
+func (s *UsersService) BeginPasskeyRegistration(ctx context.Context, req *pb.BeginPasskeyRegistrationRequest) (*pb.BeginPasskeyRegistrationReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "BeginPasskeyRegistration")
+	defer span.End()
+	options, err := s.passkeys.BeginRegistration(ctx, req.GetId())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("BeginPasskeyRegistration: %s", err)
+		return nil, err
+	}
+	return &pb.BeginPasskeyRegistrationReply{Options: options}, nil
+}
+func (s *UsersService) FinishPasskeyRegistration(ctx context.Context, req *pb.FinishPasskeyRegistrationRequest) (*pb.FinishPasskeyRegistrationReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "FinishPasskeyRegistration")
+	defer span.End()
+	res, err := s.passkeys.FinishRegistration(ctx, req.GetId(), req.GetCredential(), req.GetName())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("FinishPasskeyRegistration: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("FinishPasskeyRegistration: id %s passkey %s", req.GetId(), res.ID)
+	return &pb.FinishPasskeyRegistrationReply{Passkey: passkey(res)}, nil
+}
+func (s *UsersService) ListPasskeys(ctx context.Context, req *pb.ListPasskeysRequest) (*pb.ListPasskeysReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "ListPasskeys")
+	defer span.End()
+	res, err := s.passkeys.ListPasskeys(ctx, req.GetId())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("ListPasskeys: %s", err)
+		return nil, err
+	}
+	passkeys := make([]*pb.Passkey, len(res))
+	for i := range res {
+		passkeys[i] = passkey(&res[i])
+	}
+	return &pb.ListPasskeysReply{Passkeys: passkeys}, nil
+}
+func (s *UsersService) RenamePasskey(ctx context.Context, req *pb.RenamePasskeyRequest) (*pb.RenamePasskeyReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "RenamePasskey")
+	defer span.End()
+	res, err := s.passkeys.RenamePasskey(ctx, req.GetId(), req.GetPasskeyId(), req.GetName())
+	if err != nil {
+		s.log.WithContext(ctx).Warnf("RenamePasskey: %s", err)
+		return nil, err
+	}
+	return &pb.RenamePasskeyReply{Passkey: passkey(res)}, nil
+}
+func (s *UsersService) DeletePasskey(ctx context.Context, req *pb.DeletePasskeyRequest) (*pb.DeletePasskeyReply, error) {
+	_, span := otel.Tracer("users").Start(ctx, "DeletePasskey")
+	defer span.End()
+	if err := s.passkeys.DeletePasskey(ctx, req.GetId(), req.GetPasskeyId()); err != nil {
+		s.log.WithContext(ctx).Warnf("DeletePasskey: %s", err)
+		return nil, err
+	}
+	s.log.WithContext(ctx).Infof("DeletePasskey: id %s passkey %s", req.GetId(), req.GetPasskeyId())
+	return &pb.DeletePasskeyReply{}, nil
+}
+
+func passkey(p *biz.Passkey) *pb.Passkey {
+	res := &pb.Passkey{
+		Id:           p.ID.String(),
+		Name:         p.Name,
+		CredentialId: base64.RawURLEncoding.EncodeToString(p.CredentialID),
+		Transports:   p.Transports,
+		BackedUp:     p.BackedUp,
+		CreateTime:   timestamppb.New(p.CreatedAt),
+	}
+	if p.AAGUID != uuid.Nil {
+		res.Aaguid = p.AAGUID.String()
+	}
+	if p.LastUsedAt != nil {
+		res.LastUsedTime = timestamppb.New(*p.LastUsedAt)
+	}
+	return res
+}